@@ -2,20 +2,104 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
 // AccessToken represents a GitHub App access token: the token a caller receives from
 // Get, and the form the backends (keyring, text file, agent) persist.
 //
-// It never carries a refresh token. Only the ghtkn agent obtains refresh tokens, and it
-// keeps them server-side: it stores them in its own encrypted store and strips them from
-// the token it hands back, so a refresh token never reaches an SDK caller.
+// The client-side backends (keyring, text file) also persist the refresh token GitHub
+// issues with an expiring token, so Get can renew the access token without the device
+// flow. The refresh token never reaches an SDK caller: Get strips it from the token it
+// returns, just as the ghtkn agent strips the refresh tokens it keeps server-side.
 type AccessToken struct {
 	AccessToken string `json:"access_token"` // The OAuth access token for GitHub API authentication
 	// ExpirationDate is when the token expires. The zero time means it never expires,
 	// which is what a GitHub App with user-token expiration disabled issues.
 	ExpirationDate time.Time `json:"expiration_date"`
+	// RefreshToken is the refresh token GitHub issued with the access token. It is empty
+	// in a token returned by Get, and for a token that never expires, which GitHub
+	// issues without a refresh token.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// RefreshTokenExpirationDate is when RefreshToken expires. The zero time means there
+	// is no refresh token or its expiration is unknown.
+	RefreshTokenExpirationDate time.Time `json:"refresh_token_expiration_date,omitzero"`
+
+	// The fields below describe the token rather than authenticate with it, so a caller
+	// can tell who a token is for and how old it is without calling GitHub. A payload
+	// stored before they existed (SchemaVersion 0) lacks them; the backends fill in what
+	// they can infer and leave the rest empty.
+
+	// SchemaVersion is the version of the stored JSON layout (see
+	// AccessTokenSchemaVersion). It is 0 for a payload written before it was versioned.
+	SchemaVersion int `json:"schema_version,omitempty"`
+	// IssuedAt is when GitHub issued the access token, by the device flow or a refresh.
+	// The zero time means it is unknown, such as for a token minted by the ghtkn agent.
+	IssuedAt time.Time `json:"issued_at,omitzero"`
+	// AppName is the name of the app in the ghtkn configuration the token was issued for.
+	AppName string `json:"app_name,omitempty"`
+	// ClientID is the client ID of the GitHub App the token was issued for.
+	ClientID string `json:"client_id,omitempty"`
+	// Login is the GitHub login of the user who authorized the token. It is empty when
+	// it could not be looked up.
+	Login string `json:"login,omitempty"`
+	// Host is the GitHub host the token is valid for, such as github.com.
+	Host string `json:"host,omitempty"`
+}
+
+// AccessTokenSchemaVersion is the current version of the AccessToken JSON layout the
+// backends store. Version history:
+//
+//	0: access_token, expiration_date, and the refresh token fields only (no
+//	   schema_version field).
+//	1: adds the metadata fields: issued_at, app_name, client_id, login, and host.
+const AccessTokenSchemaVersion = 1
+
+// DefaultHost is the GitHub host a token is for when nothing else is recorded. Every
+// token stored before AccessToken had a Host was issued by github.com.
+const DefaultHost = "github.com"
+
+// StorageKey returns the key the token of the GitHub App clientID on host is stored
+// under. A github.com token is stored under the bare client ID, the key every token
+// was stored under before other hosts were supported, so those tokens stay readable.
+// A token for another host, such as a GitHub Enterprise Server, is stored under
+// "<client-id>@<host>", so the same client ID on two hosts can't overwrite each other.
+func StorageKey(host, clientID string) string {
+	if host == "" || host == DefaultHost {
+		return clientID
+	}
+	return clientID + "@" + host
+}
+
+// ValidateClientID checks that clientID can be part of the key its token is stored
+// under (see StorageKey), which backends such as the text backend use as a file name:
+// it must consist of letters, digits, '.', '-', and '_', and not start with '.', so it
+// can't name a path outside of where the tokens are stored, such as "../x".
+func ValidateClientID(clientID string) error {
+	if clientID == "" {
+		return errors.New("client_id is empty")
+	}
+	if clientID[0] == '.' {
+		return fmt.Errorf("client_id must not start with '.': %s", clientID)
+	}
+	for _, r := range clientID {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '.' && r != '-' && r != '_' {
+			return fmt.Errorf("client_id must consist of letters, digits, '.', '-', and '_': %q", clientID)
+		}
+	}
+	return nil
+}
+
+// ParseStorageKey returns the host and the client ID of the token stored under key,
+// the inverse of StorageKey.
+func ParseStorageKey(key string) (host, clientID string) {
+	clientID, host, ok := strings.Cut(key, "@")
+	if !ok {
+		return DefaultHost, key
+	}
+	return host, clientID
 }
 
 func (at *AccessToken) Validate() error {
@@ -27,3 +111,14 @@ func (at *AccessToken) Validate() error {
 	// read the zero time as never-expiring.
 	return nil
 }
+
+// HasUsableRefreshToken reports whether the token carries a refresh token that has
+// not expired at now. A zero RefreshTokenExpirationDate with a refresh token present is
+// treated as usable: GitHub always reports the expiry, so it only means the expiry was
+// not recorded, and GitHub itself rejects the refresh token if it is no longer valid.
+func (at *AccessToken) HasUsableRefreshToken(now time.Time) bool {
+	if at.RefreshToken == "" {
+		return false
+	}
+	return at.RefreshTokenExpirationDate.IsZero() || now.Before(at.RefreshTokenExpirationDate)
+}
@@ -47,3 +47,116 @@ func TestAccessToken_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestAccessToken_HasUsableRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		token *api.AccessToken
+		want  bool
+	}{
+		{
+			name:  "no refresh token",
+			token: &api.AccessToken{AccessToken: "token"},
+			want:  false,
+		},
+		{
+			name:  "valid refresh token",
+			token: &api.AccessToken{AccessToken: "token", RefreshToken: "ghr", RefreshTokenExpirationDate: now.Add(time.Hour)},
+			want:  true,
+		},
+		{
+			name:  "expired refresh token",
+			token: &api.AccessToken{AccessToken: "token", RefreshToken: "ghr", RefreshTokenExpirationDate: now.Add(-time.Hour)},
+			want:  false,
+		},
+		{
+			name:  "unknown refresh token expiration",
+			token: &api.AccessToken{AccessToken: "token", RefreshToken: "ghr"},
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.token.HasUsableRefreshToken(now); got != tt.want {
+				t.Errorf("HasUsableRefreshToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorageKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{name: "unknown host", host: "", want: "Iv1.xxx"},
+		{name: "github.com", host: "github.com", want: "Iv1.xxx"},
+		{name: "GHES", host: "ghes.example.com", want: "Iv1.xxx@ghes.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := api.StorageKey(tt.host, "Iv1.xxx"); got != tt.want {
+				t.Errorf("StorageKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStorageKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		key          string
+		wantHost     string
+		wantClientID string
+	}{
+		{name: "github.com", key: "Iv1.xxx", wantHost: "github.com", wantClientID: "Iv1.xxx"},
+		{name: "GHES", key: "Iv1.xxx@ghes.example.com", wantHost: "ghes.example.com", wantClientID: "Iv1.xxx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			host, clientID := api.ParseStorageKey(tt.key)
+			if host != tt.wantHost || clientID != tt.wantClientID {
+				t.Errorf("ParseStorageKey() = (%q, %q), want (%q, %q)", host, clientID, tt.wantHost, tt.wantClientID)
+			}
+		})
+	}
+}
+
+func TestValidateClientID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		clientID string
+		wantErr  bool
+	}{
+		{clientID: "Iv1.abc_DEF-123"},
+		{clientID: "", wantErr: true},
+		{clientID: "..", wantErr: true},
+		{clientID: "../x", wantErr: true},
+		{clientID: "a/b", wantErr: true},
+		{clientID: `a\b`, wantErr: true},
+		{clientID: "a@ghes.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clientID, func(t *testing.T) {
+			t.Parallel()
+			if err := api.ValidateClientID(tt.clientID); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateClientID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
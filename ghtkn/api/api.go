@@ -13,12 +13,62 @@ type InputGet struct {
 	AppName        string // Name of the app to use (defaults to GHTKN_APP environment variable)
 	ConfigFilePath string // Path to configuration file (auto-detected if empty)
 	AppOwner       string // GitHub App Owner
+	// Host is the GitHub host of the repository AppOwner was taken from, such as
+	// github.example.com for GitHub Enterprise Server. AppOwner then only selects an app
+	// whose host matches. Empty means github.com.
+	Host string
+	// RepositoryName is the name of the repository of AppOwner, without the owner, so
+	// that repository rules such as git_owner: acme/infra-* can select the app. Empty
+	// means the repository is unknown, and only owner rules select the app.
+	RepositoryName string
+	// RepositoryURL is the remote URL of the repository the token is for, such as
+	// https://github.com/owner/repo or git@github.com:owner/repo.git. The app is selected
+	// by the owner and host parsed from it, as if they were set as AppOwner and Host. It
+	// is ignored when AppOwner is set.
+	RepositoryURL string
+	// RepositoryDir is a directory in the Git working tree of the repository the token is
+	// for. The app is selected by the owner and host of the URL of its origin remote (see
+	// gitrepo.FromDir). It is ignored when AppOwner or RepositoryURL is set.
+	RepositoryDir string
 	// MinExpiration overrides the minimum time before token expiration that triggers
 	// renewal. nil means "not specified", in which case the GHTKN_MIN_EXPIRATION
 	// environment variable and then the config's min_expiration decide (default zero:
 	// renew only once the token has actually expired). A non-nil value, including a
 	// pointer to zero, takes precedence.
 	MinExpiration *time.Duration
+	// Profile is the profile the token is for, which namespaces the config file, the
+	// keyring service, the token directories, and the agent socket together, so the tokens
+	// of one profile are never read from another. Empty means the profile GHTKN_PROFILE
+	// selects, and the default profile when it is unset too.
+	Profile string
+}
+
+// InputTokenSource contains the input parameters for Client.TokenSourceWithContext.
+// The embedded InputGet selects the app and the min expiration as it does for Get;
+// the token source stops handing out a cached token once it is within that min
+// expiration of its expiry.
+type InputTokenSource struct {
+	InputGet
+	// RefreshAhead enables renewing the token in the background, RefreshAhead before it
+	// would enter the min expiration margin, so requests never wait on the backend for a
+	// renewal. Zero disables the background refresh: the token is only renewed when
+	// Token is called with an expiring one.
+	RefreshAhead time.Duration
+	// RefreshJitter is the upper bound of a random duration the background refresh is
+	// moved earlier by, so many processes sharing a backend don't renew at the same time.
+	// RefreshAhead and RefreshJitter must not be negative, and their sum must be less
+	// than 8 hours, the longest a token is valid; otherwise the token source fails.
+	RefreshJitter time.Duration
+}
+
+// InputWatchConfig contains the input parameters for Client.WatchConfig.
+type InputWatchConfig struct {
+	ConfigFilePath string // Path to configuration file (auto-detected if empty)
+	// PollInterval is how often the config files are checked for changes where they
+	// can't be watched through the OS, such as on macOS and Windows. Zero means two
+	// seconds.
+	PollInterval time.Duration
+	Profile      string // Profile whose config file is watched (see InputGet.Profile)
 }
 
 // InputAuth contains the input parameters for Client.Auth, the only operation that
@@ -37,6 +87,7 @@ type InputAuth struct {
 	// requires the consumer to inject an implementation via
 	// Client.SetCopyOnetimeCodeToClipboard.
 	Clipboard *bool
+	Profile   string // Profile the token is stored in (see InputGet.Profile)
 }
 
 // InputRevoke contains the input parameters for revoking access tokens.
@@ -53,6 +104,9 @@ type InputRevoke struct {
 	// for incident response: when the environment running ghtkn is compromised, all
 	// stored tokens can be revoked at once.
 	All bool
+	// Profile is the profile whose tokens are revoked (see InputGet.Profile). The tokens
+	// of other profiles are never revoked, even with All.
+	Profile string
 }
 
 // Revoke errors are wrapped with one of the following sentinels so callers can
@@ -71,6 +125,57 @@ var (
 	ErrBackendCleanup = errors.New("delete a revoked token from the backend")
 )
 
+// InputMigrate contains the input parameters for Client.Migrate, which moves the
+// stored tokens of apps from one backend to another.
+type InputMigrate struct {
+	// From is the type of the backend the tokens are moved from, such as keyring. It
+	// can't be agent, which doesn't hand out the refresh tokens it keeps.
+	From string
+	// To is the type of the backend the tokens are moved to, such as agent. From and To
+	// are single backend types, not auto or a list of backends.
+	To string
+	// AppNames are the names of the apps whose tokens are moved. When it is empty, the
+	// tokens of every app in the config are moved.
+	AppNames []string
+	// ConfigFilePath is the path to the configuration file (auto-detected if empty).
+	ConfigFilePath string
+	// Profile is the profile whose tokens are moved between its backends (see
+	// InputGet.Profile).
+	Profile string
+}
+
+// InputListTokens contains the input parameters for Client.ListTokens.
+type InputListTokens struct {
+	ConfigFilePath string // Path to configuration file (auto-detected if empty)
+	Profile        string // Profile whose tokens are listed (see InputGet.Profile)
+}
+
+// StoredToken describes a token stored in a backend, without the token itself, as
+// Client.ListTokens returns it.
+type StoredToken struct {
+	// Backend is the type of the backend the token is stored in, such as keyring. When
+	// backend.type is auto or a list of backends, it is the type of the backend selected
+	// from them. It is empty for a backend set with Client.SetBackend.
+	Backend string
+	// AppName is the name of the app in the config the token is for, or the name the
+	// token was issued for when no app in the config has its client ID and host.
+	AppName  string
+	ClientID string
+	Host     string
+	// Login is the GitHub login of the user who authorized the token, empty when it is
+	// unknown.
+	Login string
+	// IssuedAt is when the token was issued, the zero time when it is unknown.
+	IssuedAt time.Time
+	// ExpirationDate is when the token expires. The zero time means it never expires.
+	ExpirationDate time.Time
+	// HasRefreshToken reports whether a refresh token is stored with the token.
+	HasRefreshToken bool
+	// RefreshTokenExpirationDate is when the refresh token expires, the zero time when
+	// there is none or it is unknown.
+	RefreshTokenExpirationDate time.Time
+}
+
 // ErrDisableDeviceFlow is returned by Get and TokenSource when a GitHub App access
 // token can only be produced by the device flow: there is no valid stored token and
 // no usable refresh token to renew one from either. Only Auth, which the `ghtkn auth`
@@ -82,3 +187,17 @@ var (
 // instructs a coding agent NOT to run `ghtkn get` itself (it would fail the same
 // way) but to ask the user to run `ghtkn auth` in their own interactive terminal.
 var ErrDisableDeviceFlow = errors.New("no valid GitHub App User access token is available, and none could be obtained without asking you: there is no usable refresh token either, so the only way left is the Device Flow. The Device Flow is only started by `ghtkn auth`, so that it is never started on your behalf, and it is interactive, so it can't be completed by a background or non-interactive process. If you are a coding agent, do NOT run `ghtkn get` yourself because it would fail the same way; instead, ask the user to run `ghtkn auth` in their own interactive terminal to authenticate")
+
+// SSORequiredError is returned by the transport when GitHub rejects a request with 403
+// because the access token isn't authorized for an organization that enforces SAML
+// single sign-on. GitHub reports this with the X-GitHub-SSO header, which carries the
+// URL the user must open to authorize the token. Retrying doesn't help until they do.
+// Detect it with errors.As.
+type SSORequiredError struct {
+	// URL is the URL the user opens to authorize the token for the organization.
+	URL string
+}
+
+func (e *SSORequiredError) Error() string {
+	return "the access token must be authorized for SAML single sign-on. Open " + e.URL + " to authorize it"
+}
@@ -11,6 +11,8 @@ import (
 	"os"
 	"syscall"
 	"time"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
 )
 
 // DialTimeout bounds how long Send waits to connect to the agent.
@@ -52,10 +54,13 @@ func Send(ctx context.Context, path string, req *Request) (*Response, error) {
 	}
 	defer conn.Close() //nolint:errcheck
 
-	// Stamp the current protocol version so the server can detect and reject
-	// obsolete clients. Pre-versioning clients never set this field, so the server
-	// sees version 0 for them.
-	req.ProtocolVersion = ProtocolVersion
+	// Stamp the protocol version so the server can detect and reject obsolete clients.
+	// Pre-versioning clients never set this field, so the server sees version 0 for
+	// them. github.com is sent as no host, which every version means by it.
+	if req.Host == api.DefaultHost {
+		req.Host = ""
+	}
+	req.ProtocolVersion = RequestVersion(req)
 	b, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal the request: %w", err)
@@ -90,3 +90,35 @@ func TestSend_agentNotRunning(t *testing.T) {
 		t.Fatalf("err = %v, want ErrAgentNotRunning", err)
 	}
 }
+
+// TestSend_v1Agent verifies that an agent speaking protocol version 1, which rejects
+// newer clients, still serves the github.com requests of this client, and that only a
+// request it can't serve, for another host, is sent with a newer version.
+func TestSend_v1Agent(t *testing.T) {
+	t.Parallel()
+	socket := startFakeAgent(t, func(req *agentapi.Request) *agentapi.Response {
+		if req.ProtocolVersion > agentapi.ProtocolVersionServerLifecycle {
+			return &agentapi.Response{ProtocolVersion: 1, Error: agentapi.RespObsoleteAgent}
+		}
+		if req.Host != "" {
+			return &agentapi.Response{ProtocolVersion: 1, Error: "unexpected host"}
+		}
+		return &agentapi.Response{ProtocolVersion: 1, OK: true, Token: json.RawMessage(`{"access_token":"abc"}`)}
+	})
+	for _, host := range []string{"", "github.com"} {
+		resp, err := agentapi.Send(t.Context(), socket, &agentapi.Request{Command: agentapi.CommandGet, ClientID: "Iv1.x", Host: host})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.OK {
+			t.Errorf("a GET for the host %q was rejected by the version-1 agent: %s", host, resp.Error)
+		}
+	}
+	resp, err := agentapi.Send(t.Context(), socket, &agentapi.Request{Command: agentapi.CommandGet, ClientID: "Iv1.x", Host: "ghe.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != agentapi.RespObsoleteAgent {
+		t.Errorf("a GET for a GitHub Enterprise Server host = %+v, want RespObsoleteAgent", resp)
+	}
+}
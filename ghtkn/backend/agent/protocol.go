@@ -9,10 +9,14 @@ package agent
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
 )
 
 // ProtocolVersion is the newest version of the agent socket protocol the agent
-// speaks; the client stamps it on every request (see Send). The server accepts any
+// speaks. The client stamps each request with the oldest version that can carry it
+// (see RequestVersion), so an agent that doesn't know the newest version yet keeps
+// serving the requests it can. The server accepts any
 // version in the range [MinProtocolVersion, ProtocolVersion] and serves an older but
 // still-supported client with that older version's behavior, so the two sides never
 // speak past each other and old clients keep working after the agent is upgraded.
@@ -26,7 +30,10 @@ import (
 //	1: the server owns the token lifecycle: it runs the device flow and mints tokens
 //	   itself, checks expiration, refreshes with refresh tokens, and revokes tokens.
 //	   A version-1 client never sends SET.
-const ProtocolVersion = 1
+//	2: requests carry the GitHub host of the app (Host), so the agent runs the device
+//	   flow, refreshes, revokes, and stores tokens against that host, such as a GitHub
+//	   Enterprise Server. A version-1 request has no host and means github.com.
+const ProtocolVersion = 2
 
 // MinProtocolVersion is the oldest protocol version the agent still serves. A client
 // older than this is rejected with RespObsoleteClient. It is currently 0 so that
@@ -40,6 +47,22 @@ const MinProtocolVersion = 0
 // refresh for it. See ProtocolVersion's version history.
 const ProtocolVersionServerLifecycle = 1
 
+// ProtocolVersionHost is the protocol version at which requests started carrying the
+// GitHub host of the app. The agent serves a request below this version, or one with
+// an empty Host, for github.com. See ProtocolVersion's version history.
+const ProtocolVersionHost = 2
+
+// RequestVersion returns the protocol version req is stamped with: ProtocolVersionHost
+// for a request for a host other than github.com, and for IMPORT and LIST, which were
+// added with it, and ProtocolVersionServerLifecycle otherwise, so a version-1 agent
+// keeps serving the github.com requests of this client.
+func RequestVersion(req *Request) int {
+	if (req.Host != "" && req.Host != api.DefaultHost) || req.Command == CommandImport || req.Command == CommandList {
+		return ProtocolVersionHost
+	}
+	return ProtocolVersionServerLifecycle
+}
+
 // Command names and well-known response strings of the agent socket protocol.
 const (
 	CommandGet    = "GET"
@@ -53,6 +76,17 @@ const (
 	// only reduces access; UNLOCK re-derives the data key from the key file. It is an
 	// additive command, so an agent too old to know it answers with "unknown command".
 	CommandLock = "LOCK"
+	// CommandImport stores a token the client moves to the agent from another backend,
+	// with the refresh token it carries, if any, so that migrating to the agent doesn't
+	// require authenticating again. Unlike the legacy SET it is accepted from a client
+	// of any version, since the agent takes over the lifecycle of the imported token. It
+	// is an additive command, so an agent too old to know it answers with "unknown
+	// command".
+	CommandImport = "IMPORT"
+	// CommandList returns the keys of the tokens the agent stores (see Response.Keys),
+	// without the tokens themselves. It is an additive command, so an agent too old to
+	// know it answers with "unknown command".
+	CommandList = "LIST"
 	// CommandSet stores a client-minted token (legacy, protocol version 0 only). The
 	// agent keeps handling it so pre-versioning clients that mint tokens themselves
 	// keep working; a version-1 client never sends it because the server owns the
@@ -128,19 +162,25 @@ func zero(b []byte) {
 // The wire format is one JSON object per line (newline-delimited JSON).
 type Request struct {
 	// ProtocolVersion is the client's protocol version (see ProtocolVersion). Send
-	// stamps it automatically. The server serves any version in the range
+	// stamps it automatically with RequestVersion. The server serves any version in the range
 	// [MinProtocolVersion, ProtocolVersion]; an absent field decodes to 0 (a
 	// pre-versioning, SET-based client served in legacy mode), a version above
 	// ProtocolVersion means the agent is out of date.
 	ProtocolVersion int `json:"protocol_version,omitempty"`
 	// Command is one of CommandGet, CommandDelete, CommandRevoke, CommandStatus,
-	// CommandStop, CommandUnlock, or (legacy, version 0 only) CommandSet.
+	// CommandStop, CommandUnlock, CommandLock, CommandImport, CommandList, or (legacy,
+	// version 0 only) CommandSet.
 	Command string `json:"command"`
-	// ClientID identifies the GitHub App (used by GET, DELETE, and legacy SET).
+	// ClientID identifies the GitHub App (used by GET, DELETE, IMPORT, and legacy SET).
 	ClientID string `json:"client_id,omitempty"`
-	// Token is the client-minted access token payload to store (legacy CommandSet,
-	// protocol version 0 only). A version-1 client leaves it empty because the server
-	// mints tokens itself.
+	// Host is the GitHub host the app is registered on (used by GET, DELETE, IMPORT,
+	// and REVOKE, whose ClientIDs all share it). Together with the client ID it identifies
+	// the stored token. Empty means github.com.
+	Host string `json:"host,omitempty"`
+	// Token is the access token payload to store: one moved from another backend
+	// (IMPORT), or a client-minted one (legacy CommandSet, protocol version 0 only).
+	// A version-1 client otherwise leaves it empty because the server mints tokens
+	// itself.
 	Token json.RawMessage `json:"token,omitempty"`
 	// ClientIDs are the GitHub Apps whose stored tokens REVOKE should revoke and
 	// delete in one batch.
@@ -253,9 +293,18 @@ type Response struct {
 	// does not make OK false: the request may still succeed (or fall back to the device
 	// flow) while the warning is surfaced.
 	Warning string `json:"warning,omitempty"`
+	// Keys identifies the tokens the agent stores (returned by LIST).
+	Keys []TokenKey `json:"keys,omitempty"`
 	// RefreshTokenRemovalPending reports that an UNLOCK without EnableRefreshToken was not
 	// applied because a still-valid refresh token is stored and the removal was not yet
 	// confirmed (OK is false and the agent stays locked). The client prompts the user and,
 	// on yes, re-sends the same unlock with ConfirmRefreshTokenRemoval set.
 	RefreshTokenRemovalPending bool `json:"refresh_token_removal_pending,omitempty"`
 }
+
+// TokenKey identifies a token the agent stores: the GitHub App's client ID and the
+// GitHub host it is registered on. An empty Host means github.com.
+type TokenKey struct {
+	ClientID string `json:"client_id"`
+	Host     string `json:"host,omitempty"`
+}
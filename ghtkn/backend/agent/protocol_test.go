@@ -96,3 +96,26 @@ func TestSecretBytes_redacted(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestVersion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		req  *agent.Request
+		want int
+	}{
+		{name: "github.com", req: &agent.Request{Command: agent.CommandGet, Host: "github.com"}, want: agent.ProtocolVersionServerLifecycle},
+		{name: "no host", req: &agent.Request{Command: agent.CommandRevoke}, want: agent.ProtocolVersionServerLifecycle},
+		{name: "GitHub Enterprise Server", req: &agent.Request{Command: agent.CommandGet, Host: "ghe.example.com"}, want: agent.ProtocolVersionHost},
+		{name: "import", req: &agent.Request{Command: agent.CommandImport}, want: agent.ProtocolVersionHost},
+		{name: "list", req: &agent.Request{Command: agent.CommandList}, want: agent.ProtocolVersionHost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := agent.RequestVersion(tt.req); got != tt.want {
+				t.Errorf("RequestVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
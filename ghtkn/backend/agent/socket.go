@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
 )
 
 // goosWindows is the runtime.GOOS value for Windows.
@@ -15,24 +16,32 @@ const goosWindows = "windows"
 // back to $XDG_CACHE_HOME/ghtkn/agent.sock, then $HOME/.cache/ghtkn/agent.sock
 // (%LocalAppData%\cache\ghtkn\agent.sock on Windows). Both the agent server and the
 // client resolve the socket through this function so they always agree on the path.
+// The agent of the profile GHTKN_PROFILE selects listens in the directory of the
+// profile, such as $XDG_RUNTIME_DIR/ghtkn/profiles/<profile>/agent.sock, or
+// profiles/<profile> in the directory of GHTKN_AGENT_SOCKET, so each profile has an
+// agent of its own.
 func SocketPath(getEnv func(string) string, goos string) (string, error) {
 	if s := getEnv(env.AgentSocket); s != "" {
-		return s, nil
+		return profile.OverrideFile(getEnv, s) //nolint:wrapcheck
+	}
+	profileDir, err := profile.Dir(getEnv)
+	if err != nil {
+		return "", err //nolint:wrapcheck
 	}
 	if dir := getEnv(env.XDGRuntimeDir); dir != "" {
-		return filepath.Join(dir, "ghtkn", "agent.sock"), nil
+		return filepath.Join(dir, "ghtkn", profileDir, "agent.sock"), nil
 	}
 	if dir := getEnv(env.XDGCacheHome); dir != "" {
-		return filepath.Join(dir, "ghtkn", "agent.sock"), nil
+		return filepath.Join(dir, "ghtkn", profileDir, "agent.sock"), nil
 	}
 	if goos == goosWindows {
 		if d := getEnv(env.LocalAppData); d != "" {
-			return filepath.Join(d, "cache", "ghtkn", "agent.sock"), nil
+			return filepath.Join(d, "cache", "ghtkn", profileDir, "agent.sock"), nil
 		}
 		return "", errors.New("GHTKN_AGENT_SOCKET or LocalAppData is required to use the agent backend on Windows")
 	}
 	if home := getEnv(env.Home); home != "" {
-		return filepath.Join(home, ".cache", "ghtkn", "agent.sock"), nil
+		return filepath.Join(home, ".cache", "ghtkn", profileDir, "agent.sock"), nil
 	}
 	return "", errors.New("GHTKN_AGENT_SOCKET, XDG_RUNTIME_DIR, XDG_CACHE_HOME, or HOME is required to use the agent backend")
 }
@@ -22,6 +22,12 @@ func TestSocketPath(t *testing.T) {
 			goos: "linux",
 			want: "/tmp/custom.sock",
 		},
+		{
+			name: "explicit socket override in a profile",
+			env:  map[string]string{"GHTKN_AGENT_SOCKET": "/tmp/custom.sock", "GHTKN_PROFILE": "work"},
+			goos: "linux",
+			want: "/tmp/profiles/work/custom.sock",
+		},
 		{
 			name: "override beats xdg",
 			env:  map[string]string{"GHTKN_AGENT_SOCKET": "/o.sock", "XDG_RUNTIME_DIR": "/run/user/1000"},
@@ -34,6 +40,12 @@ func TestSocketPath(t *testing.T) {
 			goos: "linux",
 			want: "/run/user/1000/ghtkn/agent.sock",
 		},
+		{
+			name: "profile",
+			env:  map[string]string{"GHTKN_PROFILE": "work", "XDG_RUNTIME_DIR": "/run/user/1000"},
+			goos: "linux",
+			want: "/run/user/1000/ghtkn/profiles/work/agent.sock",
+		},
 		{
 			name: "xdg cache home fallback",
 			env:  map[string]string{"XDG_CACHE_HOME": "/home/me/.cache"},
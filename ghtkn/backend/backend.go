@@ -0,0 +1,149 @@
+// Package backend defines the interface of a storage backend for GitHub App access
+// tokens and the registry of the backends an application provides. Registering a
+// backend under a name lets the config select it with backend.type, the same way it
+// selects the built-in keyring, text, file, exec, and agent backends, so a token can be
+// kept in a store of the application's own without forking the SDK.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/deviceflow"
+)
+
+// Backend stores access tokens. A token is identified by the GitHub host of the app
+// and its client ID, in that order. The token is the JSON the SDK serializes, and a
+// backend stores it as is: the SDK marshals, unmarshals, and validates it.
+//
+// Get returns (nil, nil) when no token is stored for host and clientID. Delete removes
+// the token stored for them and is a no-op when none is stored. The methods may be
+// called concurrently.
+type Backend interface {
+	Get(ctx context.Context, host, clientID string) ([]byte, error)
+	Set(ctx context.Context, host, clientID, token string) error
+	Delete(ctx context.Context, host, clientID string) error
+}
+
+// DeviceFlowBackend is the optional capability of a Backend that owns the token
+// lifecycle itself, as the agent does: it checks expiration, runs the device flow, and
+// revokes tokens. When a backend implements it, the SDK drives these operations through
+// it instead of running them in the process.
+//
+// GetActive returns the token valid for at least minExpiration, or nil. Begin returns
+// such a token, or else starts the device flow and returns the one-time code to
+// display; exactly one of the two is non-nil. Poll waits for the flow Begin started
+// and returns the token it minted. RevokeTokens revokes and deletes the tokens of
+// clientIDs on host, and returns the client IDs whose token couldn't be revoked and
+// those revoked but not deleted.
+type DeviceFlowBackend interface {
+	GetActive(ctx context.Context, host, clientID string, minExpiration time.Duration) ([]byte, error)
+	Begin(ctx context.Context, host, clientID string, minExpiration time.Duration) ([]byte, *deviceflow.DeviceCodeResponse, error)
+	Poll(ctx context.Context, host, clientID string, minExpiration time.Duration) ([]byte, error)
+	RevokeTokens(ctx context.Context, host string, clientIDs []string) (revokeFailed, cleanupFailed []string, err error)
+}
+
+// Prober is the optional capability of a Backend to tell whether it is usable in the
+// environment, such as whether the service it stores tokens in is reachable. When
+// backend.type lists backends to fall back through, the first backend that can be
+// built and whose Probe succeeds is used; a backend that doesn't implement Prober is
+// usable once it is built.
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
+// Importer is the optional capability of a Backend to store a token moved to it from
+// another backend, when its Set can't, as the agent, which otherwise only stores the
+// tokens it mints itself. Import stores token for host and clientID as Set would. When
+// tokens are migrated to a backend, the SDK calls Import if the backend implements it,
+// and Set otherwise.
+type Importer interface {
+	Import(ctx context.Context, host, clientID, token string) error
+}
+
+// Lister is the optional capability of a Backend to enumerate the tokens it stores.
+// List returns the keys of all of them, in no particular order. The SDK lists the
+// tokens of a backend that doesn't implement it by reading the token of each app in
+// the config.
+type Lister interface {
+	List(ctx context.Context) ([]TokenKey, error)
+}
+
+// TokenKey identifies a stored token: the GitHub host of the app and its client ID.
+type TokenKey struct {
+	Host     string
+	ClientID string
+}
+
+// ErrBackendTimeout is returned, wrapped, by a backend operation that didn't finish
+// before its timeout passed or its context was done, such as a keyring call waiting on
+// a prompt to unlock the keyring that nobody answers. Detect it with errors.Is.
+var ErrBackendTimeout = errors.New("the backend didn't respond in time")
+
+// Input is what a Factory is given to build a backend.
+type Input struct {
+	// Getenv returns the environment variable of the process, so a backend can be
+	// configured by the environment as the built-in ones are. GHTKN_PROFILE returns the
+	// profile of the operation, even when it was selected by the caller rather than the
+	// environment, and a backend must keep the tokens of each profile apart.
+	Getenv func(string) string
+	// Logger is the logger of the operation the backend is built for.
+	Logger *slog.Logger
+}
+
+// Factory builds the backend of a registered type. It is called each time a backend of
+// the type is needed, which can be once per token operation, so expensive state such
+// as a connection should be kept by the factory and shared.
+type Factory func(input *Input) (Backend, error)
+
+// builtins are the backend types the SDK implements, which can't be registered. "auto"
+// is the fallback list of the built-in backends.
+var builtins = []string{"", "keyring", "text", "file", "exec", "agent", "auto"}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// ErrAlreadyRegistered is returned by Register for a name a backend is registered
+// under already.
+var ErrAlreadyRegistered = errors.New("a backend is already registered under the name")
+
+// Register registers factory as the backend type name, which backend.type and
+// GHTKN_BACKEND select. A name of a built-in backend can't be registered, nor a name
+// with a comma or a white space, which couldn't be told apart from a list of backends.
+// A name can only be registered once. It is meant to be called on startup, such as
+// from init.
+func Register(name string, factory Factory) error {
+	if slices.Contains(builtins, name) {
+		return fmt.Errorf("the name of a built-in backend can't be registered: %q", name)
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		return fmt.Errorf("the name of a backend must not contain a comma or a white space: %q", name)
+	}
+	if factory == nil {
+		return errors.New("factory is required")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyRegistered, name)
+	}
+	registry[name] = factory
+	return nil
+}
+
+// Lookup returns the factory registered as name.
+func Lookup(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := registry[name]
+	return f, ok
+}
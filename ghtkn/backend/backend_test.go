@@ -0,0 +1,46 @@
+package backend_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+)
+
+type nopBackend struct{}
+
+func (nopBackend) Get(context.Context, string, string) ([]byte, error) { return nil, nil }
+func (nopBackend) Set(context.Context, string, string, string) error   { return nil }
+func (nopBackend) Delete(context.Context, string, string) error        { return nil }
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+	factory := func(*backend.Input) (backend.Backend, error) { return nopBackend{}, nil }
+
+	if err := backend.Register("test-register", factory); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := backend.Lookup("test-register"); !ok {
+		t.Error("Lookup() didn't find the registered backend")
+	}
+	if _, ok := backend.Lookup("test-unregistered"); ok {
+		t.Error("Lookup() found a backend that isn't registered")
+	}
+	if err := backend.Register("test-register", factory); !errors.Is(err, backend.ErrAlreadyRegistered) {
+		t.Errorf("registering a name twice: error = %v, want ErrAlreadyRegistered", err)
+	}
+	for _, name := range []string{"", "keyring", "text", "agent", "auto"} {
+		if err := backend.Register(name, factory); err == nil {
+			t.Errorf("registering the built-in backend %q must fail", name)
+		}
+	}
+	for _, name := range []string{"keyring,text", "test register", "test\tregister", " test"} {
+		if err := backend.Register(name, factory); err == nil {
+			t.Errorf("registering the name %q, which reads as a list of backends, must fail", name)
+		}
+	}
+	if err := backend.Register("test-nil", nil); err == nil {
+		t.Error("registering a nil factory must fail")
+	}
+}
@@ -0,0 +1,39 @@
+package ghtkn
+
+import (
+	internalapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/api"
+)
+
+// TokenCache keeps access tokens in process memory in front of the backend, so that
+// calling Get many times doesn't read the OS keyring, which can be slow or prompt the
+// user, each time. See Client.SetTokenCache.
+type TokenCache struct {
+	c *internalapi.TokenCache
+}
+
+// NewTokenCache returns an empty TokenCache.
+func NewTokenCache() *TokenCache {
+	return &TokenCache{c: internalapi.NewTokenCache()}
+}
+
+// Clear drops all the cached tokens, so they are read from the backend again.
+func (c *TokenCache) Clear() {
+	c.c.Clear()
+}
+
+// SetTokenCache makes c keep the tokens it reads from and writes to the backend in
+// cache. Tokens stored are written to the backend first, and a deleted or revoked
+// token is dropped from the cache, as is one GitHub rejects through Transport. A cached
+// token is read from the backend again when it expires or is about to be renewed, since
+// another process may have renewed it.
+//
+// Give each Client its own cache with NewTokenCache, or share one among the Clients of
+// the process. nil stops caching, which is the default. The agent backend checks
+// expiration itself, so its tokens aren't cached.
+func (c *Client) SetTokenCache(cache *TokenCache) {
+	if cache == nil {
+		c.tm.SetTokenCache(nil)
+		return
+	}
+	c.tm.SetTokenCache(cache.c)
+}
@@ -4,16 +4,20 @@ package ghtkn
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"runtime"
 
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/browser"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/deviceflow"
 	internalapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/api"
 	intconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
 	"golang.org/x/oauth2"
 )
@@ -29,8 +33,18 @@ type (
 	InputShow          = deviceflow.InputShow
 	DefaultBrowser     = browser.Browser
 	InputGet           = api.InputGet
+	InputTokenSource   = api.InputTokenSource
+	SSORequiredError   = api.SSORequiredError
 	InputAuth          = api.InputAuth
 	InputRevoke        = api.InputRevoke
+	InputMigrate       = api.InputMigrate
+	InputListTokens    = api.InputListTokens
+	StoredToken        = api.StoredToken
+	InputWatchConfig   = api.InputWatchConfig
+	ConfigReload       = config.Reload
+	StorageBackend     = backend.Backend
+	BackendFactory     = backend.Factory
+	InputBackend       = backend.Input
 )
 
 // ErrDisableDeviceFlow is returned by Get and TokenSource when only the device flow
@@ -64,9 +78,14 @@ func New() (*Client, error) {
 // producing a token would need one. Call it freely from a background or
 // non-interactive process; it never blocks on a user.
 //
-// It is the device flow that is closed off, not token creation as such: with the agent
-// backend and refresh enabled, an expiring token is refreshed silently from the stored
-// refresh token, so Get can return a token minted just then.
+// It is the device flow that is closed off, not token creation as such: an expiring
+// token is refreshed silently from the stored refresh token (by the SDK for the keyring
+// and text backends, by the agent when refresh is enabled there), so Get can return a
+// token minted just then. The returned token never carries the refresh token itself.
+//
+// Besides the token, the result describes it: when it was issued, which app, GitHub
+// login, and host it is for, and when its refresh token expires (see AccessToken). A
+// token stored by an older version records less, so those fields may be empty.
 func (c *Client) Get(ctx context.Context, logger *slog.Logger, input *InputGet) (*AccessToken, *AppConfig, error) {
 	return c.tm.Get(ctx, logger, input)
 }
@@ -95,17 +114,80 @@ func (c *Client) Revoke(ctx context.Context, logger *slog.Logger, input *InputRe
 	return c.tm.Revoke(ctx, logger, input)
 }
 
+// Migrate moves the stored tokens of input.AppNames, or of every app in the config,
+// from the backend input.From to the backend input.To, with their refresh tokens, so
+// that changing backends doesn't require authenticating again. Each token is deleted
+// from the source only after it is read back from the destination. Moving tokens to
+// the agent requires an agent that supports importing them; moving them from the agent
+// isn't supported, since it doesn't hand out the refresh tokens it keeps.
+func (c *Client) Migrate(ctx context.Context, logger *slog.Logger, input *InputMigrate) error {
+	return c.tm.Migrate(ctx, logger, input)
+}
+
+// ListTokens returns which tokens are stored in the backends the config selects, and
+// when each expires, without the tokens themselves. The token of each app in the config
+// is looked up, and the keyring, text, file, and agent backends also list the other
+// tokens they store, such as those of apps removed from the config; the keyring only
+// knows the tokens stored since it started keeping an index of them. The tokens listed
+// are returned even when some backend fails, with the error.
+func (c *Client) ListTokens(ctx context.Context, logger *slog.Logger, input *InputListTokens) ([]*StoredToken, error) {
+	return c.tm.ListTokens(ctx, logger, input)
+}
+
 // TokenSource returns an oauth2.TokenSource that retrieves and caches access tokens
 // through this client. It can be used with OAuth2-aware HTTP clients.
+// A cached token is reused until it is within the min expiration of its expiry.
+// Backend reads use context.Background(); use TokenSourceWithContext to bound them or
+// to renew the token in the background.
 func (c *Client) TokenSource(logger *slog.Logger, input *InputGet) oauth2.TokenSource {
 	return c.tm.TokenSource(logger, input)
 }
 
+// TokenSourceWithContext returns an oauth2.TokenSource like TokenSource whose backend
+// reads are bound to ctx. When input.RefreshAhead is set, the token is also renewed in
+// the background ahead of its expiry, so that a long-running process never blocks a
+// request on a renewal. A failed background refresh is logged and retried while the
+// cached token remains valid. The background refresh stops when ctx is canceled. When
+// input.RefreshAhead or input.RefreshJitter is invalid, every Token call fails.
+func (c *Client) TokenSourceWithContext(ctx context.Context, logger *slog.Logger, input *InputTokenSource) oauth2.TokenSource {
+	return c.tm.TokenSourceWithContext(ctx, logger, input)
+}
+
+// Transport returns an http.RoundTripper that authenticates each request with an access
+// token from a token source created as by TokenSourceWithContext, so a GitHub API client
+// only needs to be built with it. base sends the requests; nil means
+// http.DefaultTransport.
+//
+// When GitHub rejects the token with 401, because another process rotated or revoked
+// it, the cached token is dropped and the backend is read once more, and an idempotent
+// request (GET, HEAD, OPTIONS, TRACE, PUT, DELETE) whose body can be replayed is retried
+// with the token read. When GitHub rejects a request with 403 because the token isn't
+// authorized for an organization's SAML single sign-on, it fails with a
+// *SSORequiredError carrying the URL that authorizes the token.
+func (c *Client) Transport(ctx context.Context, logger *slog.Logger, input *InputTokenSource, base http.RoundTripper) http.RoundTripper {
+	return c.tm.Transport(ctx, logger, input, base)
+}
+
 // SetLogger sets the hook functions invoked by the SDK to report notable events.
 func (c *Client) SetLogger(logger *Logger) {
 	c.tm.SetLogger(logger)
 }
 
+// SetBackend makes the client store tokens in b instead of the backend backend.type
+// and GHTKN_BACKEND select. b is used for every profile. To let the config select a backend of the application's
+// own instead, register it with RegisterBackend.
+func (c *Client) SetBackend(b StorageBackend) {
+	c.tm.SetBackend(b)
+}
+
+// RegisterBackend registers factory as the backend type name, so that backend.type or
+// GHTKN_BACKEND set to name stores tokens in the backend factory builds. The names of
+// the built-in backends (keyring, text, file, exec, and agent) can't be registered, and
+// a name can only be registered once. Call it on startup, before the config is read.
+func RegisterBackend(name string, factory BackendFactory) error {
+	return backend.Register(name, factory)
+}
+
 // SetOnetimeCodeUI sets the UI implementation used to display the one-time code (user code) during the device flow.
 func (c *Client) SetOnetimeCodeUI(ui OnetimeCodeUI) {
 	c.tm.SetOnetimeCodeUI(ui)
@@ -121,7 +203,20 @@ func (c *Client) SetCopyOnetimeCodeToClipboard(f deviceflow.CopyTextToClipboard)
 	c.tm.SetCopyOnetimeCodeToClipboard(f)
 }
 
-// GetConfigPath returns the default configuration file path for ghtkn.
+// GetConfigPath returns the default configuration file path for ghtkn, that of the
+// profile GHTKN_PROFILE selects.
 func GetConfigPath() (string, error) {
 	return intconfig.GetPath(os.Getenv, runtime.GOOS)
 }
+
+// GetProfileConfigPath returns the default configuration file path of the profile
+// name, as InputGet.Profile selects it. An empty name is the profile GHTKN_PROFILE
+// selects, as for GetConfigPath.
+func GetProfileConfigPath(name string) (string, error) {
+	if name != "" {
+		if err := profile.Validate(name); err != nil {
+			return "", fmt.Errorf("the profile is invalid: %w", err)
+		}
+	}
+	return intconfig.GetPath(profile.Getenv(os.Getenv, name), runtime.GOOS)
+}
@@ -1,35 +1,132 @@
 package config
 
-import "slices"
+import (
+	"strings"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+)
 
 // ResolveApp resolves the app ghtkn should use from cfg by applying the selection
 // priority:
-//  1. If owner is non-empty and matches an app's GitOwner or one of its GitOwners, that app.
-//  2. If key is empty (regardless of whether owner matched), the first app in the list
-//     (the default app).
+//  1. If owner is non-empty and matches a rule in the GitOwner or GitOwners of an app
+//     on github.com, that app. Owners are matched case-insensitively and may be glob
+//     patterns such as "acme-*"; the most specific matching rule wins.
+//  2. If key is empty (regardless of whether owner matched), the default app: the app
+//     with Default set, else the first app in the list.
 //  3. Otherwise the app whose Name equals key (nil when none matches).
 //
 // It returns nil when cfg is nil or has no apps. It is exported so callers (e.g. the
 // ghtkn CLI's `info` command) resolve the app exactly as token retrieval does, instead
-// of reimplementing this logic.
+// of reimplementing this logic. Use ResolveAppByHost to select an app for a repository
+// on another host, and ResolveAppForRepository to apply repository rules too.
 func ResolveApp(cfg *Config, key, owner string) *App {
+	return ResolveAppForRepository(cfg, key, "", owner, "")
+}
+
+// ResolveAppByHost is ResolveApp for a repository on host, such as a GitHub Enterprise
+// Server. owner matches only the apps on host, and when key is empty the default app
+// is the default app if it is on host, else the first app on host, because a token
+// from another host can't access the repository; it returns nil when no app is on
+// host. An empty host means the host is unknown: owner matches the apps on github.com
+// and the default app is chosen among all apps, exactly as ResolveApp. host is
+// compared case-insensitively.
+func ResolveAppByHost(cfg *Config, key, host, owner string) *App {
+	return ResolveAppForRepository(cfg, key, host, owner, "")
+}
+
+// ResolveAppForRepository is ResolveAppByHost for the repository repo of owner, so that
+// repository rules such as "acme/infra-*" match as well as owner rules. An empty repo
+// means the repository is unknown and only owner rules match.
+func ResolveAppForRepository(cfg *Config, key, host, owner, repo string) *App {
+	return SelectApp(cfg, key, host, owner, repo).App
+}
+
+// SelectedBy is the step of the selection priority of ResolveApp that selected an app.
+type SelectedBy string
+
+const (
+	// SelectedByRule means a repository owner rule of the app matched.
+	SelectedByRule SelectedBy = "rule"
+	// SelectedByName means the app was selected by its name.
+	SelectedByName SelectedBy = "name"
+	// SelectedByDefault means the app is the default app because it sets default.
+	SelectedByDefault SelectedBy = "default"
+	// SelectedByFirst means the app is the default app because it is the first app (on
+	// the host).
+	SelectedByFirst SelectedBy = "first"
+)
+
+// AppSelection is the app ResolveAppForRepository selects and why.
+type AppSelection struct {
+	// App is the selected app, nil when no app is selected.
+	App *App
+	// By is the step that selected App, empty when App is nil.
+	By SelectedBy
+	// Rule is the git_owner or git_owners element that matched when By is
+	// SelectedByRule.
+	Rule string
+}
+
+// SelectApp is ResolveAppForRepository that also reports why the app was selected. It
+// never returns nil.
+func SelectApp(cfg *Config, key, host, owner, repo string) *AppSelection {
 	if cfg == nil || len(cfg.Apps) == 0 {
-		return nil
+		return &AppSelection{}
+	}
+	ownerHost := strings.ToLower(host)
+	if ownerHost == "" {
+		ownerHost = api.DefaultHost
 	}
 	if owner != "" {
-		for _, a := range cfg.Apps {
-			if slices.Contains(a.gitOwners(), owner) {
-				return a
-			}
+		if app, r := matchApp(cfg.Apps, ownerHost, strings.ToLower(owner), strings.ToLower(repo)); app != nil {
+			return &AppSelection{App: app, By: SelectedByRule, Rule: r.text}
 		}
 	}
 	if key == "" {
-		return cfg.Apps[0]
+		return cfg.defaultApp(host)
 	}
 	for _, a := range cfg.Apps {
 		if a.Name == key {
-			return a
+			return &AppSelection{App: a, By: SelectedByName}
+		}
+	}
+	return &AppSelection{}
+}
+
+// matchApp returns the app on host with the most specific rule matching the repository
+// repo of owner and the rule, or nil when no rule matches. Of rules as specific as each
+// other the first one wins; Config.Validate rejects such rules of different apps.
+func matchApp(apps []*App, host, owner, repo string) (*App, rule) {
+	var best *App
+	var bestRule rule
+	bestRank := rankNone
+	for _, a := range apps {
+		if a.HostName() != host {
+			continue
+		}
+		for _, r := range a.rules() {
+			if rank := r.match(owner, repo); rank > bestRank {
+				best, bestRule, bestRank = a, r, rank
+			}
+		}
+	}
+	return best, bestRule
+}
+
+// defaultApp selects the default app for a repository on host: the app with Default
+// set if it is on host, else the first app on host. An empty host means any host, in
+// which case it is the app with Default set, else the first app.
+func (c *Config) defaultApp(host string) *AppSelection {
+	host = strings.ToLower(host)
+	for _, a := range c.Apps {
+		if a.Default && (host == "" || a.HostName() == host) {
+			return &AppSelection{App: a, By: SelectedByDefault}
+		}
+	}
+	for _, a := range c.Apps {
+		if host == "" || a.HostName() == host {
+			return &AppSelection{App: a, By: SelectedByFirst}
 		}
 	}
-	return nil
+	return &AppSelection{}
 }
@@ -47,3 +47,125 @@ func TestResolveApp(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveAppByHost(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Apps: []*config.App{
+		{Name: "dotcom", ClientID: "Iv1.dotcom", GitOwner: "acme"},
+		{Name: "ghes", ClientID: "Iv1.ghes", GitOwner: "acme", Host: "ghes.example.com"},
+		{Name: "ghes-2", ClientID: "Iv1.ghes2", GitOwner: "other", Host: "ghes.example.com"},
+	}}
+
+	tests := []struct {
+		name  string
+		key   string
+		host  string
+		owner string
+		want  string // expected app Name, or "" when the result is nil
+	}{
+		{name: "owner on github.com", host: "github.com", owner: "acme", want: "dotcom"},
+		{name: "owner on an unknown host is on github.com", owner: "acme", want: "dotcom"},
+		{name: "owner on GHES", host: "ghes.example.com", owner: "acme", want: "ghes"},
+		{name: "host is case-insensitive", host: "GHES.example.com", owner: "other", want: "ghes-2"},
+		{name: "owner on another host does not match", host: "github.com", owner: "other", want: "dotcom"},
+		{name: "default app is the first app on the host", host: "ghes.example.com", owner: "missing", want: "ghes"},
+		{name: "no app on the host is nil", host: "ghes2.example.com", owner: "acme", want: ""},
+		{name: "key still selects an app on another host", key: "ghes-2", host: "github.com", owner: "missing", want: "ghes-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := config.ResolveAppByHost(cfg, tt.key, tt.host, tt.owner)
+			gotName := ""
+			if got != nil {
+				gotName = got.Name
+			}
+			if gotName != tt.want {
+				t.Errorf("ResolveAppByHost() = %q, want %q", gotName, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAppForRepository(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Apps: []*config.App{
+		{Name: "first", ClientID: "Iv1.first"},
+		{Name: "acme", ClientID: "Iv1.acme", GitOwner: "Acme"},
+		{Name: "acme-orgs", ClientID: "Iv1.acme-orgs", GitOwner: "acme-*"},
+		{Name: "infra", ClientID: "Iv1.infra", GitOwners: []string{"acme/infra-*", "acme-labs/tools"}},
+		{Name: "infra-core", ClientID: "Iv1.infra-core", GitOwner: "acme/infra-core"},
+		{Name: "default", ClientID: "Iv1.default", Default: true},
+		{Name: "ghes", ClientID: "Iv1.ghes", GitOwner: "acme", Host: "ghes.example.com"},
+	}}
+
+	tests := []struct {
+		name  string
+		key   string
+		host  string
+		owner string
+		repo  string
+		want  string // expected app Name, or "" when the result is nil
+	}{
+		{name: "owner is case-insensitive", owner: "ACME", want: "acme"},
+		{name: "owner glob", owner: "acme-corp", want: "acme-orgs"},
+		{name: "owner glob is case-insensitive", owner: "Acme-Corp", want: "acme-orgs"},
+		{name: "owner glob doesn't match the bare prefix", owner: "acmecorp", want: "default"},
+		{name: "repository glob beats the owner", owner: "acme", repo: "infra-aws", want: "infra"},
+		{name: "exact repository beats the repository glob", owner: "acme", repo: "Infra-Core", want: "infra-core"},
+		{name: "other repositories of the owner", owner: "acme", repo: "web", want: "acme"},
+		{name: "exact repository beats the owner glob", owner: "acme-labs", repo: "tools", want: "infra"},
+		{name: "unknown repository matches only owner rules", owner: "acme", want: "acme"},
+		{name: "explicit default app", owner: "other", want: "default"},
+		{name: "explicit default app without an owner", want: "default"},
+		{name: "the default app on another host is the first app on the host", host: "ghes.example.com", owner: "other", want: "ghes"},
+		{name: "key", key: "first", owner: "other", want: "first"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := config.ResolveAppForRepository(cfg, tt.key, tt.host, tt.owner, tt.repo)
+			gotName := ""
+			if got != nil {
+				gotName = got.Name
+			}
+			if gotName != tt.want {
+				t.Errorf("ResolveAppForRepository() = %q, want %q", gotName, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectApp(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Apps: []*config.App{
+		{Name: "first", ClientID: "Iv1.first"},
+		{Name: "acme", ClientID: "Iv1.acme", GitOwners: []string{"acme", "acme/infra-*"}},
+		{Name: "ghes", ClientID: "Iv1.ghes", Host: "ghes.example.com", Default: true},
+	}}
+
+	tests := []struct {
+		name             string
+		key, host, owner string
+		repo             string
+		want             *config.AppSelection
+	}{
+		{name: "rule", owner: "ACME", repo: "infra-aws", want: &config.AppSelection{App: cfg.Apps[1], By: config.SelectedByRule, Rule: "acme/infra-*"}},
+		{name: "name", key: "first", want: &config.AppSelection{App: cfg.Apps[0], By: config.SelectedByName}},
+		{name: "default", want: &config.AppSelection{App: cfg.Apps[2], By: config.SelectedByDefault}},
+		{name: "first app on the host", host: "github.com", want: &config.AppSelection{App: cfg.Apps[0], By: config.SelectedByFirst}},
+		{name: "no app", key: "missing", want: &config.AppSelection{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := config.SelectApp(cfg, tt.key, tt.host, tt.owner, tt.repo)
+			if *got != *tt.want {
+				t.Errorf("SelectApp() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
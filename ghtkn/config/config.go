@@ -5,14 +5,16 @@ package config
 import (
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
 	"github.com/suzuki-shunsuke/slog-error/slogerr"
 )
 
 // Config represents the main configuration structure for ghtkn.
 // It contains settings a list of GitHub Apps.
 type Config struct {
-	Apps []*App `json:"apps" jsonschema_description:"GitHub Apps which ghtkn creates access tokens from. At least one app is required. The first app is the default app, which is used when no app is selected by name or repository owner"`
+	Apps []*App `json:"apps,omitempty" jsonschema_description:"GitHub Apps which ghtkn creates access tokens from. At least one app is required across the system, user, and project-local config files, but a single file may define none, such as a system config file that only sets backend. The app with default: true, or else the first app, is the default app, which is used when no app is selected by name or repository owner"`
 	// SkipAccountPicker skips the GitHub Device Flow account picker by appending
 	// GitHub's unofficial skip_account_picker query parameter to the verification
 	// URL. nil means "not specified" and defaults to true (the picker is skipped);
@@ -31,6 +33,18 @@ type Config struct {
 	// Clipboard configures whether the device flow copies the one-time code to the
 	// system clipboard.
 	Clipboard *Clipboard `json:"clipboard,omitempty" yaml:"clipboard" jsonschema_description:"Configure whether the device flow copies the one-time code to the system clipboard"`
+	// ProjectConfig configures project-local config files. It is only read from the
+	// system and user config files: a project config file can't enable itself.
+	ProjectConfig *ProjectConfig `json:"project_config,omitempty" yaml:"project_config" jsonschema_description:"Configure project-local config files (.ghtkn.yaml). It is ignored in a project-local config file"`
+}
+
+// ProjectConfig configures project-local config files.
+type ProjectConfig struct {
+	// Enable toggles reading the .ghtkn.yaml files found in the working directory and
+	// its parents. nil means "not specified" and defaults to false. Even when enabled, a
+	// file is only read once the user has trusted it. The GHTKN_PROJECT_CONFIG
+	// environment variable takes precedence over this value.
+	Enable *bool `json:"enable,omitempty" yaml:"enable" jsonschema:"default=false" jsonschema_description:"Read the .ghtkn.yaml files found in the working directory and its parents, on top of the system and user config files. A file is only read after it is trusted, so a cloned repository can't change which apps are used. The default value is false. The GHTKN_PROJECT_CONFIG environment variable takes precedence over this value"`
 }
 
 // OpenBrowser configures automatic browser opening for the device flow.
@@ -52,16 +66,25 @@ type Clipboard struct {
 
 // Backend selects the storage backend for access tokens.
 type Backend struct {
-	// Type is the backend type: "keyring" (the default), "text", or "agent". Empty
-	// means "not specified". The GHTKN_BACKEND environment variable takes precedence
-	// over this value.
-	Type string `json:"type,omitempty" yaml:"type" jsonschema:"default=keyring" jsonschema_description:"The backend type where access tokens are stored. Either 'keyring' (the default), 'text', or 'agent'. The GHTKN_BACKEND environment variable takes precedence over this value"`
+	// Type is the backend type: "keyring" (the default), "text", "file", "exec", "agent",
+	// or the name of a backend the application registered with ghtkn.RegisterBackend.
+	// Empty means "not specified". The GHTKN_BACKEND environment variable takes
+	// precedence over this value.
+	//
+	// It can also list backends to fall back through, separated by commas, such as
+	// "keyring,agent,text" (a YAML list in a config file), or be "auto", which falls
+	// back through the keyring, the agent, the file backend, and the text backend. The
+	// first backend that is usable in the environment is selected.
+	Type string `json:"type,omitempty" yaml:"type" jsonschema:"default=keyring,or_array" jsonschema_description:"The backend type where access tokens are stored. Either 'keyring' (the default), 'text', 'file', 'exec', 'agent', or the name of a backend the application embedding ghtkn registered. A list of backends, or 'auto' (keyring, agent, file, and text), selects the first one usable in the environment. The GHTKN_BACKEND environment variable takes precedence over this value"`
 }
 
 // Validate checks if the Config is valid.
 // It ensures the config is not nil and contains at least one app.
-// It also validates each app in the configuration, and that name, client_id, and each
-// repository owner (git_owner and the git_owners elements) are unique across the apps.
+// It also validates each app in the configuration, that name and client_id are unique
+// across the apps, that at most one app is the default app, and that no two repository
+// owner rules (git_owner and the git_owners elements) of apps on the same host overlap.
+// All overlapping rules are reported, in the order the apps and rules are declared.
+// An error about a field of an app is a *FieldError naming the field.
 func (c *Config) Validate() error {
 	if c == nil {
 		return errors.New("config is required")
@@ -69,10 +92,18 @@ func (c *Config) Validate() error {
 	if len(c.Apps) == 0 {
 		return errors.New("apps is required")
 	}
+	return c.ValidateLayer()
+}
+
+// ValidateLayer checks a single config file, one of the layers merged into the config,
+// as Validate checks the merged config, except that it may have no apps: a system config
+// file may only set backend, for example, with the apps in the user config file.
+func (c *Config) ValidateLayer() error {
+	if c == nil {
+		return errors.New("config is required")
+	}
 	names := map[string]struct{}{}
-	// owners maps a repository owner to the app that declared it, so a duplicate can
-	// name the other app instead of only the owner.
-	owners := map[string]string{}
+	defaultApp := ""
 	// clientIDs maps a client ID to the app that declared it, so a duplicate can name
 	// the other app instead of only the ID.
 	clientIDs := map[string]string{}
@@ -81,7 +112,7 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("app is invalid: %w", slogerr.With(err, "app", app.Name))
 		}
 		if _, ok := names[app.Name]; ok {
-			return fmt.Errorf("app name must be unique: %s", app.Name)
+			return app.fieldError("name", fmt.Errorf("app name must be unique: %s", app.Name))
 		}
 		names[app.Name] = struct{}{}
 		// A client ID identifies the GitHub App everywhere below the config: the stored
@@ -90,24 +121,47 @@ func (c *Config) Validate() error {
 		// one silently does it for the other. Reject that instead of letting the two
 		// entries look independent.
 		if other, ok := clientIDs[app.ClientID]; ok {
-			return fmt.Errorf(
+			return app.fieldError("client_id", fmt.Errorf(
 				"app client_id must be unique: %q and %q share the client id %s. "+
 					"They would share one access token, so revoking or minting for one would silently do it for the other. "+
 					"Keep a single app for that client id; to select it for several repository owners, "+
 					"list them in that app's git_owners instead of adding a second entry",
-				other, app.Name, app.ClientID)
+				other, app.Name, app.ClientID))
 		}
 		clientIDs[app.ClientID] = app.Name
-		for _, owner := range app.gitOwners() {
-			if other, ok := owners[owner]; ok {
-				return fmt.Errorf(
-					"a repository owner must be unique across apps: %q and %q both declare the owner %s",
-					other, app.Name, owner)
+		if app.Default {
+			if defaultApp != "" {
+				return app.fieldError("default", fmt.Errorf("only one app can be the default app: %q and %q both set default", defaultApp, app.Name))
 			}
-			owners[owner] = app.Name
+			defaultApp = app.Name
 		}
 	}
-	return nil
+	return c.validateRules()
+}
+
+// validateRules checks that no two repository owner rules of apps on the same host
+// overlap, that is, are as specific as each other and can match the same repository,
+// so the selected app never depends on the order of the apps.
+func (c *Config) validateRules() error {
+	var errs []error
+	for i, app := range c.Apps {
+		for _, other := range c.Apps[i+1:] {
+			if app.HostName() != other.HostName() {
+				// Owners on different hosts are different accounts.
+				continue
+			}
+			for _, r := range app.rules() {
+				for _, o := range other.rules() {
+					if r.overlaps(o) {
+						errs = append(errs, other.fieldError(other.ownersField(), fmt.Errorf(
+							"repository owners of apps on a host must not overlap: %q of %q and %q of %q can match the same repository on %s",
+							r.text, app.Name, o.text, other.Name, app.HostName())))
+					}
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // App represents a GitHub App configuration.
@@ -115,40 +169,135 @@ func (c *Config) Validate() error {
 type App struct {
 	Name     string `json:"name" jsonschema_description:"The app name. It must be unique across apps. It is used to select the app by the -app flag and the GHTKN_APP environment variable"`
 	ClientID string `json:"client_id" yaml:"client_id" jsonschema_description:"The client ID of the GitHub App. It must be unique across apps because access tokens are stored per client ID"`
-	GitOwner string `json:"git_owner,omitempty" yaml:"git_owner" jsonschema_description:"The repository owner which the app is used for. It must be unique across apps. It is used to select the app by the repository owner, such as in the git credential helper. git_owner and git_owners are mutually exclusive"`
+	// GitOwner is the repository owner the app is selected for. It is a rule: an owner
+	// ("acme") or a repository ("acme/infra"), either of which may contain the wildcards
+	// '*' and '?' ("acme-*", "acme/infra-*"), matched case-insensitively.
+	GitOwner string `json:"git_owner,omitempty" yaml:"git_owner" jsonschema_description:"The repository owner which the app is used for. It is used to select the app by the repository owner, such as in the git credential helper. It is either an owner such as 'acme' or a repository such as 'acme/infra', matched case-insensitively, and may contain the wildcards '*' and '?' such as 'acme-*' and 'acme/infra-*'. When several apps match, a repository beats an owner and a name without wildcards beats a pattern. Rules of apps on the same host must not overlap. git_owner and git_owners are mutually exclusive"`
 	// GitOwners is git_owner for an app shared by several repository owners, such as an
 	// Enterprise GitHub App installed on several organizations. The app can't be
 	// repeated once per owner because client_id must be unique across apps, so the
 	// owners are listed here instead. GitOwner and GitOwners are mutually exclusive.
-	GitOwners []string `json:"git_owners,omitempty" yaml:"git_owners" jsonschema_description:"git_owner for an app shared by several repository owners, such as an Enterprise GitHub App installed on several organizations. Each owner must be unique across apps. git_owner and git_owners are mutually exclusive"`
+	GitOwners []string `json:"git_owners,omitempty" yaml:"git_owners" jsonschema_description:"git_owner for an app shared by several repository owners, such as an Enterprise GitHub App installed on several organizations. Each element is a rule like git_owner. git_owner and git_owners are mutually exclusive"`
+	// Host is the GitHub host the app is registered on, such as a GitHub Enterprise
+	// Server's host name. Empty means github.com. The device flow, the refresh, the
+	// revocation, and the stored token all use it.
+	Host string `json:"host,omitempty" yaml:"host" jsonschema_description:"The host name of the GitHub instance the app is registered on, such as ghes.example.com for GitHub Enterprise Server. The default value is github.com. It is a host name, not a URL: the scheme is always https and the API is at /api/v3"`
+	// Default makes the app the default app, used when no app is selected by name or
+	// repository owner. When no app sets it, the first app is the default app.
+	Default bool `json:"default,omitempty" yaml:"default" jsonschema_description:"Make the app the default app, which is used when no app is selected by name or repository owner. At most one app can set it. When no app sets it, the first app is the default app"`
+	// MinExpiration, Backend, OpenBrowser, and Clipboard override the settings of the
+	// same name for this app, such as to renew the token of a production deploy app an
+	// hour early and keep it in the agent while a personal app stays in the keyring.
+	// The flags and the environment variables of the settings still take precedence.
+	MinExpiration string       `json:"min_expiration,omitempty" yaml:"min_expiration" jsonschema_description:"min_expiration for this app, overriding the top-level min_expiration. The -min-expiration flag and the GHTKN_MIN_EXPIRATION environment variable take precedence over this value"`
+	Backend       *Backend     `json:"backend,omitempty" yaml:"backend" jsonschema_description:"The backend for this app, overriding the top-level backend. The GHTKN_BACKEND environment variable takes precedence over this value"`
+	OpenBrowser   *OpenBrowser `json:"open_browser,omitempty" yaml:"open_browser" jsonschema_description:"open_browser for this app, overriding the top-level open_browser. The GHTKN_OPEN_BROWSER environment variable takes precedence over this value"`
+	Clipboard     *Clipboard   `json:"clipboard,omitempty" yaml:"clipboard" jsonschema_description:"clipboard for this app, overriding the top-level clipboard. The -clipboard flag and the GHTKN_CLIPBOARD environment variable take precedence over this value"`
+	// Source is the path of the config file the app was read from. It is set when the
+	// config is loaded, not read from the file.
+	Source string `json:"-" yaml:"-"`
 }
 
 // Validate checks if the App configuration is valid.
-// It ensures both Name and ClientID fields are present, and that git_owner and
-// git_owners are not both set and that git_owners holds no empty or duplicate owner.
+// It ensures both Name and ClientID fields are present, that the client ID can be part
+// of the key its token is stored under (see api.ValidateClientID), that host is a host
+// name, that git_owner and git_owners are not both set, that each of them is a valid
+// rule, and that git_owners holds no duplicate owner, compared case-insensitively.
 func (app *App) Validate() error {
 	if app.Name == "" {
-		return errors.New("name is required")
+		return app.fieldError("", errors.New("name is required"))
 	}
 	if app.ClientID == "" {
-		return errors.New("client_id is required")
+		return app.fieldError("", errors.New("client_id is required"))
+	}
+	if err := api.ValidateClientID(app.ClientID); err != nil {
+		return app.fieldError("client_id", err)
+	}
+	if err := validateHost(app.Host); err != nil {
+		return app.fieldError("host", err)
 	}
 	if app.GitOwner != "" && len(app.GitOwners) > 0 {
-		return errors.New("git_owner and git_owners are mutually exclusive: set only one of them")
+		return app.fieldError("git_owners", errors.New("git_owner and git_owners are mutually exclusive: set only one of them"))
+	}
+	if app.GitOwner != "" {
+		if err := validateRule(app.GitOwner); err != nil {
+			return app.fieldError("git_owner", fmt.Errorf("git_owner is invalid: %w", err))
+		}
 	}
 	owners := make(map[string]struct{}, len(app.GitOwners))
 	for _, owner := range app.GitOwners {
-		if owner == "" {
-			return errors.New("git_owners must not contain an empty string")
+		if err := validateRule(owner); err != nil {
+			return app.fieldError("git_owners", fmt.Errorf("git_owners is invalid: %w", err))
 		}
-		if _, ok := owners[owner]; ok {
-			return fmt.Errorf("git_owners must not contain a duplicate: %s", owner)
+		key := strings.ToLower(owner)
+		if _, ok := owners[key]; ok {
+			return app.fieldError("git_owners", fmt.Errorf("git_owners must not contain a duplicate: %s", owner))
 		}
-		owners[owner] = struct{}{}
+		owners[key] = struct{}{}
 	}
 	return nil
 }
 
+// validateHost checks that host is empty or a bare host name. A URL is rejected rather
+// than parsed, because the scheme and the API path are not configurable: ghtkn always
+// uses https and, on a host other than github.com, the /api/v3 API path.
+func validateHost(host string) error {
+	if host == "" {
+		return nil
+	}
+	if strings.Contains(host, "://") {
+		return fmt.Errorf("host must be a host name, not a URL: %s", host)
+	}
+	for _, r := range host {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '.' && r != '-' {
+			return fmt.Errorf("host must be a host name (letters, digits, '.', and '-'): %s", host)
+		}
+	}
+	return nil
+}
+
+// HostName returns the GitHub host the app is registered on, in lower case:
+// github.com when host is unset.
+func (app *App) HostName() string {
+	if app.Host == "" {
+		return api.DefaultHost
+	}
+	return strings.ToLower(app.Host)
+}
+
+// ForApp returns the config that applies to app: c with the settings app overrides
+// (MinExpiration, Backend, OpenBrowser, and Clipboard) replaced by the app's. The
+// environment overrides, which LoadConfig folds into both the top-level settings and
+// the app's, keep precedence over the app's values.
+func (c *Config) ForApp(app *App) *Config {
+	cfg := *c
+	if app == nil {
+		return &cfg
+	}
+	if app.MinExpiration != "" {
+		cfg.MinExpiration = app.MinExpiration
+	}
+	if app.Backend != nil && app.Backend.Type != "" {
+		cfg.Backend = app.Backend
+	}
+	if app.OpenBrowser != nil && app.OpenBrowser.Enable != nil {
+		cfg.OpenBrowser = app.OpenBrowser
+	}
+	if app.Clipboard != nil && app.Clipboard.Enable != nil {
+		cfg.Clipboard = app.Clipboard
+	}
+	return &cfg
+}
+
+// ownersField returns the key of the repository owner rules of the app: git_owner or
+// git_owners.
+func (app *App) ownersField() string {
+	if app.GitOwner != "" {
+		return "git_owner"
+	}
+	return "git_owners"
+}
+
 // gitOwners returns the repository owners this app is selected for. git_owner and
 // git_owners are mutually exclusive (App.Validate rejects setting both), so this
 // returns whichever one is set.
@@ -158,3 +307,13 @@ func (app *App) gitOwners() []string {
 	}
 	return app.GitOwners
 }
+
+// rules returns the parsed repository owner rules of the app.
+func (app *App) rules() []rule {
+	owners := app.gitOwners()
+	rules := make([]rule, len(owners))
+	for i, owner := range owners {
+		rules[i] = parseRule(owner)
+	}
+	return rules
+}
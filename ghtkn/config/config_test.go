@@ -209,6 +209,105 @@ func TestConfig_Validate(t *testing.T) { //nolint:funlen
 			},
 			wantErr: false,
 		},
+		{
+			name: "the same owner on different hosts",
+			config: &config.Config{
+				Apps: []*config.App{
+					{
+						Name:     "app1",
+						ClientID: "xxx",
+						GitOwner: "owner1",
+					},
+					{
+						Name:     "app2",
+						ClientID: "yyy",
+						GitOwner: "owner1",
+						Host:     "ghes.example.com",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "the same owner in a different case",
+			config: &config.Config{
+				Apps: []*config.App{
+					{Name: "app1", ClientID: "xxx", GitOwner: "Acme"},
+					{Name: "app2", ClientID: "yyy", GitOwner: "acme"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overlapping owner globs",
+			config: &config.Config{
+				Apps: []*config.App{
+					{Name: "app1", ClientID: "xxx", GitOwner: "acme-*"},
+					{Name: "app2", ClientID: "yyy", GitOwners: []string{"other", "*-corp"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "an owner and an owner glob matching it",
+			config: &config.Config{
+				Apps: []*config.App{
+					{Name: "app1", ClientID: "xxx", GitOwner: "acme-*"},
+					{Name: "app2", ClientID: "yyy", GitOwner: "acme-corp"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "a repository rule and an owner rule",
+			config: &config.Config{
+				Apps: []*config.App{
+					{Name: "app1", ClientID: "xxx", GitOwner: "acme"},
+					{Name: "app2", ClientID: "yyy", GitOwner: "acme/infra-*"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "overlapping repository globs",
+			config: &config.Config{
+				Apps: []*config.App{
+					{Name: "app1", ClientID: "xxx", GitOwner: "acme/infra-*"},
+					{Name: "app2", ClientID: "yyy", GitOwner: "acme/*-aws"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "two default apps",
+			config: &config.Config{
+				Apps: []*config.App{
+					{Name: "app1", ClientID: "xxx", Default: true},
+					{Name: "app2", ClientID: "yyy", Default: true},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "the same owner on a host spelled differently",
+			config: &config.Config{
+				Apps: []*config.App{
+					{
+						Name:     "app1",
+						ClientID: "xxx",
+						GitOwner: "owner1",
+						Host:     "GHES.example.com",
+					},
+					{
+						Name:     "app2",
+						ClientID: "yyy",
+						GitOwner: "owner1",
+						Host:     "ghes.example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -223,6 +322,52 @@ func TestConfig_Validate(t *testing.T) { //nolint:funlen
 	}
 }
 
+func TestConfig_ValidateLayer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  *config.Config
+		wantErr bool
+	}{
+		{
+			name:   "no apps",
+			config: &config.Config{Backend: &config.Backend{Type: "agent"}},
+		},
+		{
+			name: "valid apps",
+			config: &config.Config{
+				Apps: []*config.App{{Name: "app1", ClientID: "xxx"}},
+			},
+		},
+		{
+			name: "duplicate client id",
+			config: &config.Config{
+				Apps: []*config.App{
+					{Name: "app1", ClientID: "xxx"},
+					{Name: "app2", ClientID: "xxx"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "nil config",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.config.ValidateLayer()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Config.ValidateLayer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestApp_Validate(t *testing.T) {
 	t.Parallel()
 
@@ -291,6 +436,77 @@ func TestApp_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "repository rule",
+			app: &config.App{
+				Name:     "test-app",
+				ClientID: "xxx",
+				GitOwner: "acme/infra-*",
+			},
+			wantErr: false,
+		},
+		{
+			name: "repository rule without a repository",
+			app: &config.App{
+				Name:     "test-app",
+				ClientID: "xxx",
+				GitOwner: "acme/",
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule with a character class",
+			app: &config.App{
+				Name:      "test-app",
+				ClientID:  "xxx",
+				GitOwners: []string{"acme-[ab]"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate git owners in a different case",
+			app: &config.App{
+				Name:      "test-app",
+				ClientID:  "xxx",
+				GitOwners: []string{"acme", "ACME"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid app with host",
+			app: &config.App{
+				Name:     "test-app",
+				ClientID: "xxx",
+				Host:     "ghes.example.com",
+			},
+			wantErr: false,
+		},
+		{
+			name: "client_id escapes the token directory",
+			app: &config.App{
+				Name:     "test-app",
+				ClientID: "../../.ssh/id_ed25519",
+			},
+			wantErr: true,
+		},
+		{
+			name: "host is a URL",
+			app: &config.App{
+				Name:     "test-app",
+				ClientID: "xxx",
+				Host:     "https://ghes.example.com",
+			},
+			wantErr: true,
+		},
+		{
+			name: "host has a path",
+			app: &config.App{
+				Name:     "test-app",
+				ClientID: "xxx",
+				Host:     "ghes.example.com/api/v3",
+			},
+			wantErr: true,
+		},
 		{
 			name: "duplicate git owners in git_owners",
 			app: &config.App{
@@ -313,3 +529,51 @@ func TestApp_Validate(t *testing.T) {
 		})
 	}
 }
+
+// TestConfig_Validate_overlapOrder verifies that every overlap is reported, in the order
+// the apps and their rules are declared.
+func TestConfig_Validate_overlapOrder(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Apps: []*config.App{
+		{Name: "a", ClientID: "ca", GitOwners: []string{"x-*", "y"}},
+		{Name: "b", ClientID: "cb", GitOwners: []string{"y", "*-z"}},
+		{Name: "c", ClientID: "cc", GitOwner: "x-?"},
+	}}
+	want := `repository owners of apps on a host must not overlap: "x-*" of "a" and "*-z" of "b" can match the same repository on github.com
+repository owners of apps on a host must not overlap: "y" of "a" and "y" of "b" can match the same repository on github.com
+repository owners of apps on a host must not overlap: "x-*" of "a" and "x-?" of "c" can match the same repository on github.com
+repository owners of apps on a host must not overlap: "*-z" of "b" and "x-?" of "c" can match the same repository on github.com`
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error but got nil")
+	}
+	if err.Error() != want {
+		t.Errorf("error =\n%s\nwant\n%s", err, want)
+	}
+}
+
+func TestConfig_ForApp(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		MinExpiration: "10m",
+		Backend:       &config.Backend{Type: "keyring"},
+		Clipboard:     &config.Clipboard{Enable: new(true)},
+	}
+	app := &config.App{
+		Name:          "a",
+		ClientID:      "ca",
+		MinExpiration: "1h",
+		Backend:       &config.Backend{Type: "text"},
+		// An override without a value leaves the global setting.
+		Clipboard: &config.Clipboard{},
+	}
+	got := cfg.ForApp(app)
+	if got.MinExpiration != "1h" || got.Backend.Type != "text" || !*got.Clipboard.Enable {
+		t.Errorf("ForApp() = min_expiration %q, backend %q, clipboard %v, want 1h, text, and true", got.MinExpiration, got.Backend.Type, *got.Clipboard.Enable)
+	}
+	if cfg.MinExpiration != "10m" || cfg.Backend.Type != "keyring" {
+		t.Error("ForApp() must not modify the config")
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// OriginKind is the kind of place the effective value of a setting comes from.
+type OriginKind string
+
+const (
+	// OriginDefault is the built-in default, used when nothing sets the value.
+	OriginDefault OriginKind = "default"
+	// OriginFile is a config file.
+	OriginFile OriginKind = "file"
+	// OriginEnv is an environment variable.
+	OriginEnv OriginKind = "env"
+	// OriginInput is a value the caller passed, such as InputGet.AppName or the
+	// -min-expiration flag of the ghtkn CLI.
+	OriginInput OriginKind = "input"
+)
+
+// Origin is where the effective value of a setting comes from.
+type Origin struct {
+	Kind OriginKind `json:"kind"`
+	// File is the path of the config file when Kind is OriginFile.
+	File string `json:"file,omitempty"`
+	// Line and Column are the 1-based position in File the value is set at. 0 means it
+	// is unknown.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Env is the name of the environment variable when Kind is OriginEnv.
+	Env string `json:"env,omitempty"`
+}
+
+// String returns the origin as "<file>:<line>:<column>", "$<env>", "default", or
+// "input".
+func (o Origin) String() string {
+	switch o.Kind {
+	case OriginFile:
+		if o.Line == 0 {
+			return o.File
+		}
+		if o.Column == 0 {
+			return o.File + ":" + strconv.Itoa(o.Line)
+		}
+		return o.File + ":" + strconv.Itoa(o.Line) + ":" + strconv.Itoa(o.Column)
+	case OriginEnv:
+		return "$" + o.Env
+	default:
+		return string(o.Kind)
+	}
+}
+
+// Setting is the effective value of a config field and its origin.
+type Setting struct {
+	// Name is the YAML path of the field, such as "backend.type". The fields of an app
+	// are named after the app, such as "apps[work].client_id".
+	Name string `json:"name"`
+	// Value is the effective value formatted as text, the built-in default when nothing
+	// sets it.
+	Value  string `json:"value"`
+	Origin Origin `json:"origin"`
+}
+
+// AppSettingName returns the Setting name of the field of the app named app, such as
+// "apps[work].client_id", or "apps[work]" for the app itself when field is empty.
+func AppSettingName(app, field string) string {
+	name := fmt.Sprintf("apps[%s]", app)
+	if field == "" {
+		return name
+	}
+	return name + "." + field
+}
+
+// FieldError is an error about the config field Field, a Setting name such as
+// "apps[work].git_owner". It lets a caller that knows where the field is set, such as
+// the config loader, point at the position of the field in the config file. Its message
+// is the message of Err.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError returns err as an error about the field of the app, or about the app
+// itself when field is empty.
+func (app *App) fieldError(field string, err error) error {
+	return &FieldError{Field: AppSettingName(app.Name, field), Err: err}
+}
@@ -0,0 +1,14 @@
+package config
+
+// Reload is what a config watcher hands its subscribers each time it reloads the
+// configuration files after they change.
+type Reload struct {
+	// Config is the config in effect after the reload: the reloaded config, or the last
+	// good one when Err is set. It is shared, so it must not be modified.
+	Config *Config
+	// Files are the config files Config was read from.
+	Files []string
+	// Err is why the changed files couldn't be loaded or are invalid. The previous
+	// config is kept then, so a broken edit never takes the config away.
+	Err error
+}
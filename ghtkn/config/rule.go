@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A repository owner in git_owner and git_owners is a rule selecting the app for the
+// repositories it matches. It is either an owner rule ("acme", "acme-*"), matching
+// every repository of the owners it matches, or a repository rule ("acme/infra-*"),
+// matching only the repositories it matches. Both parts are matched case-insensitively,
+// as GitHub owner and repository names are, and may contain the wildcards '*' (any
+// string) and '?' (any character).
+//
+// When rules of several apps match a repository, the most specific one wins:
+//  1. a repository rule without wildcards,
+//  2. a repository rule with wildcards,
+//  3. an owner rule without wildcards,
+//  4. an owner rule with wildcards.
+//
+// Two rules of different apps on the same level that can match the same repository
+// would make the choice depend on the order of the apps, so Config.Validate rejects
+// them as overlapping.
+
+// rule is a parsed git_owner or git_owners element.
+type rule struct {
+	text  string // The element as written
+	owner string // Owner pattern in lower case
+	repo  string // Repository pattern in lower case, "" for an owner rule
+}
+
+// Ranks of the rule levels, higher wins. A rank of 0 means no match.
+const (
+	rankNone = iota
+	rankOwnerGlob
+	rankOwner
+	rankRepoGlob
+	rankRepo
+)
+
+func parseRule(text string) rule {
+	owner, repo, _ := strings.Cut(strings.ToLower(text), "/")
+	return rule{text: text, owner: owner, repo: repo}
+}
+
+// validateRule checks that text is an owner or a repository rule.
+func validateRule(text string) error {
+	if text == "" {
+		return errors.New("a repository owner must not be empty")
+	}
+	if strings.ContainsAny(text, `[]\`) {
+		return fmt.Errorf("a repository owner supports only the wildcards '*' and '?': %s", text)
+	}
+	owner, repo, ok := strings.Cut(text, "/")
+	if owner == "" || (ok && (repo == "" || strings.Contains(repo, "/"))) {
+		return fmt.Errorf("a repository owner must be <owner> or <owner>/<repository>: %s", text)
+	}
+	return nil
+}
+
+// rank returns the level of the rule.
+func (r rule) rank() int {
+	glob := strings.ContainsAny(r.owner+r.repo, "*?")
+	switch {
+	case r.repo != "" && !glob:
+		return rankRepo
+	case r.repo != "":
+		return rankRepoGlob
+	case !glob:
+		return rankOwner
+	default:
+		return rankOwnerGlob
+	}
+}
+
+// match returns the rank of the rule if it matches the repository repo of owner, which
+// must be in lower case, or rankNone. repo may be empty when it is unknown, in which
+// case only owner rules match.
+func (r rule) match(owner, repo string) int {
+	if !globMatch(r.owner, owner) {
+		return rankNone
+	}
+	if r.repo != "" && (repo == "" || !globMatch(r.repo, repo)) {
+		return rankNone
+	}
+	return r.rank()
+}
+
+// overlaps reports whether r and o are on the same level and can both match a
+// repository.
+func (r rule) overlaps(o rule) bool {
+	return r.rank() == o.rank() && globsOverlap(r.owner, o.owner) && globsOverlap(r.repo, o.repo)
+}
+
+// globMatch reports whether name matches pattern. An invalid pattern matches nothing.
+func globMatch(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+// globsOverlap reports whether some string matches both a and b, patterns with the
+// wildcards '*' and '?'. reachable[i][j] is whether a[:i] and b[:j] can match the same
+// string.
+func globsOverlap(a, b string) bool {
+	reachable := make([][]bool, len(a)+1)
+	for i := range reachable {
+		reachable[i] = make([]bool, len(b)+1)
+	}
+	reachable[0][0] = true
+	for i := 0; i <= len(a); i++ {
+		for j := 0; j <= len(b); j++ {
+			if !reachable[i][j] {
+				continue
+			}
+			if i < len(a) && a[i] == '*' {
+				// The star matches nothing more.
+				reachable[i+1][j] = true
+				// The star matches the character b[j] matches.
+				if j < len(b) {
+					reachable[i][j+1] = true
+				}
+			}
+			if j < len(b) && b[j] == '*' {
+				reachable[i][j+1] = true
+				if i < len(a) {
+					reachable[i+1][j] = true
+				}
+			}
+			if i < len(a) && j < len(b) && a[i] != '*' && b[j] != '*' &&
+				(a[i] == b[j] || a[i] == '?' || b[j] == '?') {
+				reachable[i+1][j+1] = true
+			}
+		}
+	}
+	return reachable[len(a)][len(b)]
+}
@@ -0,0 +1,35 @@
+package config
+
+import "testing"
+
+func TestGlobsOverlap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "acme", b: "acme", want: true},
+		{a: "acme", b: "other", want: false},
+		{a: "acme-*", b: "*-corp", want: true},
+		{a: "acme-*", b: "acme", want: false},
+		{a: "acme-*", b: "acme-infra-*", want: true},
+		{a: "a?c", b: "abc", want: true},
+		{a: "a?c", b: "ac", want: false},
+		{a: "*", b: "", want: true},
+		{a: "a*", b: "*b", want: true},
+		{a: "a*a", b: "b*", want: false},
+		{a: "", b: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			t.Parallel()
+			if got := globsOverlap(tt.a, tt.b); got != tt.want {
+				t.Errorf("globsOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := globsOverlap(tt.b, tt.a); got != tt.want {
+				t.Errorf("globsOverlap(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaURI is the JSON Schema dialect JSONSchema emits.
+const JSONSchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns the JSON Schema of the ghtkn configuration file, so that editors
+// and CI can validate a config file. It is derived from the Config type: a property
+// per field, named by its json tag, described by its jsonschema_description tag, and
+// with the default in its jsonschema tag. A field without omitempty is required, and
+// unknown properties are rejected as the config file reader rejects them. A string
+// field with or_array in its jsonschema tag, which the reader also accepts as a YAML
+// list, may be a string or an array of strings.
+func JSONSchema() ([]byte, error) {
+	defs := map[string]any{}
+	schemaOf(reflect.TypeFor[Config](), defs)
+	b, err := json.MarshalIndent(map[string]any{
+		"$schema": JSONSchemaURI,
+		"$ref":    "#/$defs/Config",
+		"$defs":   defs,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode the JSON Schema as JSON: %w", err)
+	}
+	return b, nil
+}
+
+// schemaOf returns the schema of t. A struct is defined in defs by its name and
+// referenced.
+func schemaOf(t reflect.Type, defs map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() { //nolint:exhaustive // the config only has these kinds
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), defs)}
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // Guard against recursion
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	panic("unsupported config field type: " + t.String())
+}
+
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for f := range t.Fields() {
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := schemaOf(f.Type, defs)
+		if hasSchemaOption(f, "or_array") {
+			prop = map[string]any{"oneOf": []any{prop, map[string]any{"type": "array", "items": prop}}}
+		}
+		if d := f.Tag.Get("jsonschema_description"); d != "" {
+			prop["description"] = d
+		}
+		if def, ok := schemaDefault(f); ok {
+			prop["default"] = def
+		}
+		properties[name] = prop
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// hasSchemaOption reports whether the jsonschema tag of f has the option opt.
+func hasSchemaOption(f reflect.StructField, opt string) bool {
+	for o := range strings.SplitSeq(f.Tag.Get("jsonschema"), ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaDefault returns the default=... value of the jsonschema tag of f, typed as the
+// field is.
+func schemaDefault(f reflect.StructField) (any, bool) {
+	for opt := range strings.SplitSeq(f.Tag.Get("jsonschema"), ",") {
+		v, ok := strings.CutPrefix(opt, "default=")
+		if !ok {
+			continue
+		}
+		t := f.Type
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Bool {
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, true
+			}
+		}
+		return v, true
+	}
+	return nil, false
+}
@@ -0,0 +1,81 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+)
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	b, err := config.JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Schema string `json:"$schema"`
+		Ref    string `json:"$ref"`
+		Defs   map[string]struct {
+			Type                 string                    `json:"type"`
+			Properties           map[string]map[string]any `json:"properties"`
+			Required             []string                  `json:"required"`
+			AdditionalProperties *bool                     `json:"additionalProperties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Schema != config.JSONSchemaURI || schema.Ref != "#/$defs/Config" {
+		t.Errorf("$schema = %q, $ref = %q", schema.Schema, schema.Ref)
+	}
+	for _, name := range []string{"Config", "App", "Backend", "OpenBrowser", "Clipboard", "ProjectConfig"} {
+		def, ok := schema.Defs[name]
+		if !ok {
+			t.Errorf("%s is not defined", name)
+			continue
+		}
+		if def.Type != "object" || def.AdditionalProperties == nil || *def.AdditionalProperties {
+			t.Errorf("%s must be an object without additional properties", name)
+		}
+	}
+
+	cfg := schema.Defs["Config"]
+	if len(cfg.Required) != 0 {
+		// A config file may leave the apps to another layer.
+		t.Errorf("Config required = %v, want none", cfg.Required)
+	}
+	if diff := cmp.Diff(map[string]any{"$ref": "#/$defs/App"}, cfg.Properties["apps"]["items"]); diff != "" {
+		t.Errorf("apps items: %s", diff)
+	}
+	if cfg.Properties["skip_account_picker"]["default"] != true {
+		t.Errorf("skip_account_picker default = %v, want true", cfg.Properties["skip_account_picker"]["default"])
+	}
+	if cfg.Properties["min_expiration"]["description"] == nil {
+		t.Error("min_expiration has no description")
+	}
+
+	app := schema.Defs["App"]
+	if diff := cmp.Diff([]string{"name", "client_id"}, app.Required); diff != "" {
+		t.Errorf("App required: %s", diff)
+	}
+	if _, ok := app.Properties["Source"]; ok {
+		t.Error("App.Source must not be a property")
+	}
+	if app.Properties["git_owners"]["type"] != "array" {
+		t.Errorf("git_owners type = %v, want array", app.Properties["git_owners"]["type"])
+	}
+	backendType := schema.Defs["Backend"].Properties["type"]
+	if got := backendType["default"]; got != "keyring" {
+		t.Errorf("backend.type default = %v, want keyring", got)
+	}
+	wantOneOf := []any{
+		map[string]any{"type": "string"},
+		map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	}
+	if diff := cmp.Diff(wantOneOf, backendType["oneOf"]); diff != "" {
+		t.Errorf("backend.type oneOf: %s", diff)
+	}
+}
@@ -0,0 +1,95 @@
+package ghtkn
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	intconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+// InputEditConfig configures EditConfig.
+type InputEditConfig struct {
+	// ConfigFilePath is the path to the configuration file to edit. Empty means the user
+	// config file: GHTKN_CONFIG, then the XDG/OS default path.
+	ConfigFilePath string
+}
+
+// ConfigEditor edits a ghtkn configuration file, such as to register a team's app in
+// each engineer's ghtkn.yaml. It edits the YAML document itself rather than a decoded
+// Config, so the comments, the order of the keys, and the quoting of values are kept.
+// Save only rewrites the lines of what changed, so the rest of the file, with its blank
+// lines and indentation, is kept as it was. Changes are made in memory and written by
+// Save.
+type ConfigEditor struct {
+	e *intconfig.Editor
+}
+
+// EditConfig returns a ConfigEditor of the configuration file. A missing file is edited
+// as an empty one and created by Save. input may be nil.
+func EditConfig(input *InputEditConfig) (*ConfigEditor, error) {
+	if input == nil {
+		input = &InputEditConfig{}
+	}
+	path := input.ConfigFilePath
+	if path == "" {
+		p, err := intconfig.GetPath(os.Getenv, runtime.GOOS)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // GetPath returns a descriptive error
+		}
+		path = p
+	}
+	e, err := intconfig.NewEditor(path, os.Getenv, runtime.GOOS)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // NewEditor returns a descriptive error
+	}
+	return &ConfigEditor{e: e}, nil
+}
+
+// Path returns the path of the configuration file being edited.
+func (e *ConfigEditor) Path() string {
+	return e.e.Path()
+}
+
+// Config returns the config the edited file holds, including unsaved changes. It is
+// the content of this file alone, without the other config files and the environment
+// LoadConfig merges, and it isn't validated.
+func (e *ConfigEditor) Config() (*config.Config, error) {
+	return e.e.Config()
+}
+
+// SetApp adds app, or updates the app with the same name in place, including the
+// settings it overrides such as min_expiration and backend. The fields of an updated
+// app keep their comments and order; a field app doesn't set is removed.
+// app.Source is ignored.
+func (e *ConfigEditor) SetApp(app *config.App) error {
+	return e.e.SetApp(app)
+}
+
+// RemoveApp removes the app named name and reports whether it was there.
+func (e *ConfigEditor) RemoveApp(name string) bool {
+	return e.e.RemoveApp(name)
+}
+
+// Set sets a setting other than apps, named by its YAML path such as "backend.type",
+// "min_expiration", or "clipboard.enable", to value. The names are those of the
+// Settings Explain reports. A boolean setting takes a value strconv.ParseBool accepts
+// and min_expiration a Go duration.
+func (e *ConfigEditor) Set(name, value string) error {
+	return e.e.Set(name, value)
+}
+
+// Unset removes a setting other than apps, so it has its default.
+func (e *ConfigEditor) Unset(name string) error {
+	return e.e.Unset(name)
+}
+
+// Save validates the edited file with config.Config.ValidateLayer, so a file that leaves
+// the apps to another config file, such as a system config file that only sets backend,
+// can be saved, and, only if it is valid, writes it atomically, so the file is never
+// left half written or invalid. The user config file is also validated with
+// config.Config.Validate merged onto the system config file, so it can't be left
+// without an app to use. An existing file keeps its file mode.
+func (e *ConfigEditor) Save() error {
+	return e.e.Save()
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"slices"
 	"strconv"
 
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
-	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+	intconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
 )
 
 // InputEnabled configures Enabled.
@@ -38,29 +40,61 @@ func Enabled(input *InputEnabled) (bool, error) {
 // environment-variable branches can be tested without mutating the real
 // environment.
 func enabled(getEnv func(string) string, input *InputEnabled) (bool, error) {
+	e, err := explainEnabled(getEnv, input)
+	if err != nil {
+		return false, err
+	}
+	return e.Enabled, nil
+}
+
+// EnabledExplanation is the result of Enabled and why.
+type EnabledExplanation struct {
+	Enabled bool `json:"enabled"`
+	// Origin is the environment variable that decided, or the config file whose
+	// existence did.
+	Origin config.Origin `json:"origin"`
+	Reason string        `json:"reason"`
+}
+
+// explainEnabled resolves Enabled and reports which step of its order decided.
+func explainEnabled(getEnv func(string) string, input *InputEnabled) (*EnabledExplanation, error) {
+	var names []string
 	if input != nil {
-		for _, name := range input.Envs {
-			a := getEnv(name)
-			if a == "" {
-				continue
-			}
-			return checkBoolEnv(a)
-		}
+		names = input.Envs
 	}
-	if a := getEnv(env.Enable); a != "" {
-		return checkBoolEnv(a)
+	for _, name := range append(slices.Clone(names), env.Enable) {
+		a := getEnv(name)
+		if a == "" {
+			continue
+		}
+		b, err := checkBoolEnv(a)
+		if err != nil {
+			return nil, err
+		}
+		return &EnabledExplanation{
+			Enabled: b,
+			Origin:  config.Origin{Kind: config.OriginEnv, Env: name},
+			Reason:  fmt.Sprintf("%s is %s", name, a),
+		}, nil
 	}
-	p, err := config.GetPath(getEnv, runtime.GOOS)
+	p, err := intconfig.GetPath(getEnv, runtime.GOOS)
 	if err != nil {
-		return false, err
+		return nil, err //nolint:wrapcheck // GetPath returns a descriptive error
+	}
+	e := &EnabledExplanation{
+		Enabled: true,
+		Origin:  config.Origin{Kind: config.OriginFile, File: p},
+		Reason:  "no environment variable is set and the config file exists",
 	}
 	if _, err := os.Stat(p); err != nil {
 		if os.IsNotExist(err) {
-			return false, nil
+			e.Enabled = false
+			e.Reason = "no environment variable is set and the config file does not exist"
+			return e, nil
 		}
-		return false, err
+		return nil, err
 	}
-	return true, nil
+	return e, nil
 }
 
 // checkBoolEnv parses a boolean environment variable value with
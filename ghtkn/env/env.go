@@ -6,29 +6,41 @@ package env
 
 // GHTKN_* variables: ghtkn's own configuration and lifecycle variables.
 const (
-	App            = "GHTKN_APP"
-	AgentKey       = "GHTKN_AGENT_KEY"
-	AgentSocket    = "GHTKN_AGENT_SOCKET"
-	AgentTokenDir  = "GHTKN_AGENT_TOKEN_DIR"
-	Backend        = "GHTKN_BACKEND"
-	Clipboard      = "GHTKN_CLIPBOARD"
-	Config         = "GHTKN_CONFIG"
-	Enable         = "GHTKN_ENABLE"
-	GitApp         = "GHTKN_GIT_APP"
-	GitHubToken    = "GHTKN_GITHUB_TOKEN"
-	LogLevel       = "GHTKN_LOG_LEVEL"
-	MinExpiration  = "GHTKN_MIN_EXPIRATION"
-	OpenBrowser    = "GHTKN_OPEN_BROWSER"
-	OutputFormat   = "GHTKN_OUTPUT_FORMAT"
-	TextBackendDir = "GHTKN_TEXT_BACKEND_DIR"
+	App                   = "GHTKN_APP"
+	AgentKey              = "GHTKN_AGENT_KEY"
+	AgentSocket           = "GHTKN_AGENT_SOCKET"
+	AgentTokenDir         = "GHTKN_AGENT_TOKEN_DIR"
+	Backend               = "GHTKN_BACKEND"
+	Clipboard             = "GHTKN_CLIPBOARD"
+	Config                = "GHTKN_CONFIG"
+	Enable                = "GHTKN_ENABLE"
+	ExecBackendCommand    = "GHTKN_EXEC_BACKEND_COMMAND"
+	ExecBackendTimeout    = "GHTKN_EXEC_BACKEND_TIMEOUT"
+	FileBackendDir        = "GHTKN_FILE_BACKEND_DIR"
+	FileBackendKey        = "GHTKN_FILE_BACKEND_KEY"
+	FileBackendKeyFile    = "GHTKN_FILE_BACKEND_KEY_FILE"
+	FileBackendPassphrase = "GHTKN_FILE_BACKEND_PASSPHRASE"
+	GitApp                = "GHTKN_GIT_APP"
+	GitHubToken           = "GHTKN_GITHUB_TOKEN"
+	KeyringTimeout        = "GHTKN_KEYRING_TIMEOUT"
+	LockDir               = "GHTKN_LOCK_DIR"
+	LogLevel              = "GHTKN_LOG_LEVEL"
+	MinExpiration         = "GHTKN_MIN_EXPIRATION"
+	OpenBrowser           = "GHTKN_OPEN_BROWSER"
+	OutputFormat          = "GHTKN_OUTPUT_FORMAT"
+	Profile               = "GHTKN_PROFILE"
+	ProjectConfig         = "GHTKN_PROJECT_CONFIG"
+	SystemConfig          = "GHTKN_SYSTEM_CONFIG"
+	TextBackendDir        = "GHTKN_TEXT_BACKEND_DIR"
 )
 
-// OS and XDG base-directory variables ghtkn reads to resolve file paths (config file,
-// agent socket, token/key/cache directories) across Linux, macOS, and Windows.
+// OS and XDG base-directory variables ghtkn reads to resolve file paths (config files,
+// agent socket, token/key/cache/data directories) across Linux, macOS, and Windows.
 const (
 	Home          = "HOME"
 	AppData       = "APPDATA"
 	LocalAppData  = "LocalAppData"
+	ProgramData   = "ProgramData"
 	XDGConfigHome = "XDG_CONFIG_HOME"
 	XDGCacheHome  = "XDG_CACHE_HOME"
 	XDGRuntimeDir = "XDG_RUNTIME_DIR"
@@ -47,16 +59,28 @@ var All = []string{ //nolint:gochecknoglobals // an intentional read-only regist
 	Clipboard,
 	Config,
 	Enable,
+	ExecBackendCommand,
+	ExecBackendTimeout,
+	FileBackendDir,
+	FileBackendKey,
+	FileBackendKeyFile,
+	FileBackendPassphrase,
 	GitApp,
 	GitHubToken,
+	KeyringTimeout,
+	LockDir,
 	LogLevel,
 	MinExpiration,
 	OpenBrowser,
 	OutputFormat,
+	Profile,
+	ProjectConfig,
+	SystemConfig,
 	TextBackendDir,
 	Home,
 	AppData,
 	LocalAppData,
+	ProgramData,
 	XDGConfigHome,
 	XDGCacheHome,
 	XDGRuntimeDir,
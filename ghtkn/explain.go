@@ -0,0 +1,184 @@
+package ghtkn
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/gitrepo"
+	internalapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/api"
+	intconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
+)
+
+// InputExplain configures Explain.
+type InputExplain struct {
+	// Get is the input of the Get call to explain: its Profile and ConfigFilePath select
+	// the config file, its app name and repository select the app, and its MinExpiration
+	// overrides min_expiration. nil is the same as an empty InputGet.
+	Get *InputGet
+	// Enabled is the input of the Enabled call to explain. It may be nil.
+	Enabled *InputEnabled
+	// Dir is the directory project config files are looked for from. Empty means the
+	// working directory.
+	Dir string
+}
+
+// Explanation is the effective configuration and where each part of it comes from.
+type Explanation struct {
+	// Config is the effective config, as LoadConfig returns it.
+	Config *config.Config `json:"config"`
+	// Files are the config files merged into Config, in increasing precedence.
+	Files []string `json:"files"`
+	// UntrustedFiles are the project config files ignored because they aren't trusted.
+	UntrustedFiles []string `json:"untrusted_files,omitempty"`
+	// Settings are the effective value and origin of every config field, including the
+	// ones left at their built-in defaults.
+	Settings []*config.Setting `json:"settings"`
+	// InvalidConfig is why Config is invalid, which Get would fail with, or empty.
+	InvalidConfig string `json:"invalid_config,omitempty"`
+	// GitHubToken reports whether GHTKN_GITHUB_TOKEN is set, in which case Get returns
+	// its value without selecting an app or reading the config.
+	GitHubToken bool                `json:"github_token"`
+	Enabled     *EnabledExplanation `json:"enabled"`
+	App         *AppExplanation     `json:"app"`
+}
+
+// AppExplanation is the app Get selects and why.
+type AppExplanation struct {
+	// App is the selected app, nil when no app is selected.
+	App *config.App `json:"app"`
+	// By is the step of the selection priority of config.ResolveApp that selected App.
+	By config.SelectedBy `json:"by,omitempty"`
+	// Rule is the git_owner or git_owners element that matched, when By is
+	// config.SelectedByRule.
+	Rule string `json:"rule,omitempty"`
+	// AppName is the app name asked for, empty when none is, and AppNameOrigin is
+	// where it comes from: InputGet.AppName or GHTKN_APP.
+	AppName       string         `json:"app_name,omitempty"`
+	AppNameOrigin *config.Origin `json:"app_name_origin,omitempty"`
+	// Repository is the repository the app is selected for. Its Owner is empty when no
+	// repository is given.
+	Repository *gitrepo.Repository `json:"repository"`
+	Reason     string              `json:"reason"`
+}
+
+// Explain returns the effective configuration with the origin of every setting (a
+// config file and line, an environment variable, the caller's input, or the built-in
+// default), how Enabled decides, and how Get selects the app. It is meant for
+// diagnosing a setup, such as by a `ghtkn config explain` command, so it reports an
+// invalid config in InvalidConfig instead of failing. input may be nil.
+func Explain(input *InputExplain) (*Explanation, error) {
+	if input == nil {
+		input = &InputExplain{}
+	}
+	dir := input.Dir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("get the working directory: %w", err)
+		}
+		dir = wd
+	}
+	return explain(os.Getenv, runtime.GOOS, input, dir)
+}
+
+// explain is the implementation of Explain with getEnv and goos injected.
+func explain(getEnv func(string) string, goos string, input *InputExplain, dir string) (*Explanation, error) {
+	get := input.Get
+	if get == nil {
+		get = &InputGet{}
+	}
+	if get.Profile != "" {
+		if err := profile.Validate(get.Profile); err != nil {
+			return nil, fmt.Errorf("the profile is invalid: %w", err)
+		}
+		// The files of the profile are explained, as Get reads them.
+		getEnv = profile.Getenv(getEnv, get.Profile)
+	}
+	enabled, err := explainEnabled(getEnv, input.Enabled)
+	if err != nil {
+		return nil, fmt.Errorf("explain whether ghtkn is enabled: %w", err)
+	}
+	cfg, result, err := loadConfigWithOrigins(getEnv, goos, get.ConfigFilePath, dir)
+	if err != nil {
+		return nil, err
+	}
+	settings := intconfig.Settings(cfg, result.Origins)
+	if get.MinExpiration != nil {
+		for _, s := range settings {
+			if s.Name == "min_expiration" {
+				s.Value = get.MinExpiration.String()
+				s.Origin = config.Origin{Kind: config.OriginInput}
+			}
+		}
+	}
+	ex := &Explanation{
+		Config:         cfg,
+		Files:          result.Files,
+		UntrustedFiles: result.Untrusted,
+		Settings:       settings,
+		GitHubToken:    getEnv(env.GitHubToken) != "",
+		Enabled:        enabled,
+	}
+	if err := cfg.Validate(); err != nil {
+		ex.InvalidConfig = intconfig.Locate(err, result.Origins).Error()
+	}
+	app, err := explainApp(getEnv, cfg, get)
+	if err != nil {
+		return nil, err
+	}
+	ex.App = app
+	return ex, nil
+}
+
+// explainApp selects the app for input as Get does and describes why.
+func explainApp(getEnv func(string) string, cfg *config.Config, input *InputGet) (*AppExplanation, error) {
+	repo, err := internalapi.ResolveRepository(input)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // ResolveRepository returns a descriptive error
+	}
+	ex := &AppExplanation{Repository: repo, AppName: input.AppName}
+	switch {
+	case input.AppName != "":
+		ex.AppNameOrigin = &config.Origin{Kind: config.OriginInput}
+	case getEnv(env.App) != "":
+		ex.AppName = getEnv(env.App)
+		ex.AppNameOrigin = &config.Origin{Kind: config.OriginEnv, Env: env.App}
+	}
+	sel := config.SelectApp(cfg, ex.AppName, repo.Host, repo.Owner, repo.Name)
+	ex.App, ex.By, ex.Rule = sel.App, sel.By, sel.Rule
+	ex.Reason = appReason(ex)
+	return ex, nil
+}
+
+// appReason describes the selection of ex in a sentence.
+func appReason(ex *AppExplanation) string {
+	onHost := ""
+	if ex.Repository.Host != "" {
+		onHost = " on " + ex.Repository.Host
+	}
+	switch ex.By {
+	case config.SelectedByRule:
+		target := "the repository owner " + ex.Repository.Owner
+		if ex.Repository.Name != "" {
+			target = "the repository " + ex.Repository.Owner + "/" + ex.Repository.Name
+		}
+		return fmt.Sprintf("%s%s matches %q of the app %q", target, onHost, ex.Rule, ex.App.Name)
+	case config.SelectedByName:
+		return fmt.Sprintf("the app name %q is given by %s", ex.AppName, ex.AppNameOrigin)
+	case config.SelectedByDefault:
+		return fmt.Sprintf("no app is selected by name or repository owner, and the app %q%s sets default", ex.App.Name, onHost)
+	case config.SelectedByFirst:
+		return fmt.Sprintf("no app is selected by name or repository owner, and the app %q is the first app%s", ex.App.Name, onHost)
+	}
+	if ex.AppName != "" {
+		return fmt.Sprintf("no app is named %q, which is given by %s", ex.AppName, ex.AppNameOrigin)
+	}
+	if ex.Repository.Host != "" {
+		return "no app is on " + ex.Repository.Host
+	}
+	return "the config has no apps"
+}
@@ -0,0 +1,188 @@
+package ghtkn
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/gitrepo"
+)
+
+func Test_explain(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ghtkn.yaml")
+	if err := os.WriteFile(path, []byte(`apps:
+  - name: work
+    client_id: Iv1.work
+    git_owners: [acme, acme/infra-*]
+  - name: personal
+    client_id: Iv1.personal
+    default: true
+min_expiration: 30m
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	baseEnv := map[string]string{
+		"GHTKN_CONFIG":        path,
+		"GHTKN_SYSTEM_CONFIG": filepath.Join(dir, "absent.yaml"),
+	}
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		input       *InputExplain
+		wantEnabled *EnabledExplanation
+		wantApp     string
+		wantBy      config.SelectedBy
+		wantReason  string
+		wantMinExp  config.Setting
+		wantBackend config.Setting
+	}{
+		{
+			name: "defaults and the default app",
+			wantEnabled: &EnabledExplanation{
+				Enabled: true,
+				Origin:  config.Origin{Kind: config.OriginFile, File: path},
+				Reason:  "no environment variable is set and the config file exists",
+			},
+			wantApp:     "personal",
+			wantBy:      config.SelectedByDefault,
+			wantReason:  `no app is selected by name or repository owner, and the app "personal" sets default`,
+			wantMinExp:  config.Setting{Name: "min_expiration", Value: "30m", Origin: config.Origin{Kind: config.OriginFile, File: path, Line: 8, Column: 1}},
+			wantBackend: config.Setting{Name: "backend.type", Value: "keyring", Origin: config.Origin{Kind: config.OriginDefault}},
+		},
+		{
+			name: "environment variables",
+			env:  map[string]string{"GHTKN_ENABLE": "false", "GHTKN_APP": "work", "GHTKN_BACKEND": "text", "GHTKN_MIN_EXPIRATION": "1h"},
+			wantEnabled: &EnabledExplanation{
+				Origin: config.Origin{Kind: config.OriginEnv, Env: "GHTKN_ENABLE"},
+				Reason: "GHTKN_ENABLE is false",
+			},
+			wantApp:     "work",
+			wantBy:      config.SelectedByName,
+			wantReason:  `the app name "work" is given by $GHTKN_APP`,
+			wantMinExp:  config.Setting{Name: "min_expiration", Value: "1h", Origin: config.Origin{Kind: config.OriginEnv, Env: "GHTKN_MIN_EXPIRATION"}},
+			wantBackend: config.Setting{Name: "backend.type", Value: "text", Origin: config.Origin{Kind: config.OriginEnv, Env: "GHTKN_BACKEND"}},
+		},
+		{
+			name: "input",
+			env:  map[string]string{"GHTKN_MIN_EXPIRATION": "1h", "MY_TOOL_GHTKN": "true"},
+			input: &InputExplain{
+				Get:     &InputGet{RepositoryURL: "https://github.com/ACME/infra-aws.git", MinExpiration: new(10 * time.Minute)},
+				Enabled: &InputEnabled{Envs: []string{"MY_TOOL_GHTKN"}},
+			},
+			wantEnabled: &EnabledExplanation{
+				Enabled: true,
+				Origin:  config.Origin{Kind: config.OriginEnv, Env: "MY_TOOL_GHTKN"},
+				Reason:  "MY_TOOL_GHTKN is true",
+			},
+			wantApp:     "work",
+			wantBy:      config.SelectedByRule,
+			wantReason:  `the repository ACME/infra-aws on github.com matches "acme/infra-*" of the app "work"`,
+			wantMinExp:  config.Setting{Name: "min_expiration", Value: "10m0s", Origin: config.Origin{Kind: config.OriginInput}},
+			wantBackend: config.Setting{Name: "backend.type", Value: "keyring", Origin: config.Origin{Kind: config.OriginDefault}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			getEnv := func(k string) string {
+				if v, ok := tt.env[k]; ok {
+					return v
+				}
+				return baseEnv[k]
+			}
+			input := tt.input
+			if input == nil {
+				input = &InputExplain{}
+			}
+			got, err := explain(getEnv, "linux", input, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]string{path}, got.Files); diff != "" {
+				t.Errorf("Files: %s", diff)
+			}
+			if got.InvalidConfig != "" {
+				t.Errorf("InvalidConfig = %q, want empty", got.InvalidConfig)
+			}
+			if diff := cmp.Diff(tt.wantEnabled, got.Enabled); diff != "" {
+				t.Errorf("Enabled: %s", diff)
+			}
+			if got.App.App == nil || got.App.App.Name != tt.wantApp {
+				t.Errorf("App = %+v, want %q", got.App.App, tt.wantApp)
+			}
+			if got.App.By != tt.wantBy {
+				t.Errorf("By = %q, want %q", got.App.By, tt.wantBy)
+			}
+			if got.App.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", got.App.Reason, tt.wantReason)
+			}
+			settings := map[string]config.Setting{}
+			for _, s := range got.Settings {
+				settings[s.Name] = *s
+			}
+			if diff := cmp.Diff(tt.wantMinExp, settings["min_expiration"]); diff != "" {
+				t.Errorf("min_expiration: %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantBackend, settings["backend.type"]); diff != "" {
+				t.Errorf("backend.type: %s", diff)
+			}
+		})
+	}
+}
+
+func Test_explain_profile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir, "ghtkn", "ghtkn.yaml"):                     "apps:\n  - name: default\n    client_id: Iv1.default\n",
+		filepath.Join(dir, "ghtkn", "profiles", "work", "ghtkn.yaml"): "apps:\n  - name: work\n    client_id: Iv1.work\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	envs := map[string]string{
+		"XDG_CONFIG_HOME":     dir,
+		"GHTKN_SYSTEM_CONFIG": filepath.Join(dir, "absent.yaml"),
+	}
+	getEnv := func(k string) string { return envs[k] }
+
+	got, err := explain(getEnv, "linux", &InputExplain{Get: &InputGet{Profile: "work"}}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{filepath.Join(dir, "ghtkn", "profiles", "work", "ghtkn.yaml")}, got.Files); diff != "" {
+		t.Errorf("Files: %s", diff)
+	}
+	if got.App.App == nil || got.App.App.Name != "work" {
+		t.Errorf("App = %+v, want work", got.App.App)
+	}
+
+	if _, err := explain(getEnv, "linux", &InputExplain{Get: &InputGet{Profile: "../work"}}, dir); err == nil {
+		t.Error("explain() with an invalid profile expected an error")
+	}
+}
+
+func Test_appReason_noApp(t *testing.T) {
+	t.Parallel()
+	got := appReason(&AppExplanation{
+		AppName:       "missing",
+		AppNameOrigin: &config.Origin{Kind: config.OriginInput},
+		Repository:    &gitrepo.Repository{},
+	})
+	if want := `no app is named "missing", which is given by input`; got != want {
+		t.Errorf("appReason() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,296 @@
+package gitrepo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultRemote is the remote FromDir prefers when a repository has several.
+const defaultRemote = "origin"
+
+// FromDir returns the repository of the Git working tree containing dir, read from the
+// URL of its origin remote, or of its first remote when it has no origin. dir may be a
+// subdirectory of the working tree, a linked worktree, or a submodule.
+//
+// The URL is rewritten by the url.<base>.insteadOf rules of the repository's config and
+// of the user's global Git config ($GIT_CONFIG_GLOBAL, or $XDG_CONFIG_HOME/git/config
+// and ~/.gitconfig) as Git does, so a remote written as a shorthand such as gh:owner/repo
+// resolves to the repository it fetches from.
+func FromDir(dir string) (*Repository, error) {
+	return fromDir(dir, globalConfigPaths(os.Getenv))
+}
+
+// fromDir is FromDir reading the insteadOf rules of the global config files globals
+// in addition to the repository's config.
+func fromDir(dir string, globals []string) (*Repository, error) {
+	configPath, err := findConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	repoCfg, err := readConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	remoteURL := repoCfg.remoteURL()
+	if remoteURL == "" {
+		return nil, fmt.Errorf("the repository has no remote: %s", configPath)
+	}
+	cfg := &gitConfig{}
+	for _, p := range globals {
+		c, err := readConfigFile(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		cfg.insteadOf = append(cfg.insteadOf, c.insteadOf...)
+	}
+	// The repository's rules are read last, so that of two rules as long as each other
+	// the repository's wins, as the later one does in Git.
+	cfg.insteadOf = append(cfg.insteadOf, repoCfg.insteadOf...)
+	return ParseURL(cfg.rewrite(remoteURL))
+}
+
+// globalConfigPaths returns the user's global Git config files in the order Git reads
+// them.
+func globalConfigPaths(getenv func(string) string) []string {
+	if p := getenv("GIT_CONFIG_GLOBAL"); p != "" {
+		return []string{p}
+	}
+	var paths []string
+	home := getenv("HOME")
+	if xdg := getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "git", "config"))
+	} else if home != "" {
+		paths = append(paths, filepath.Join(home, ".config", "git", "config"))
+	}
+	if home != "" {
+		paths = append(paths, filepath.Join(home, ".gitconfig"))
+	}
+	return paths
+}
+
+// findConfig returns the config file of the Git repository whose working tree contains
+// dir, looking for .git in dir and its parents.
+func findConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("get the absolute path of a directory: %w", err)
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		dotGit := filepath.Join(d, ".git")
+		fi, err := os.Stat(dotGit)
+		if err == nil {
+			if fi.IsDir() {
+				return filepath.Join(dotGit, "config"), nil
+			}
+			return gitFileConfig(dotGit)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("check a .git directory: %w", err)
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return "", fmt.Errorf("not in a Git working tree: %s", dir)
+		}
+	}
+}
+
+// gitFileConfig returns the config file of a .git file, which a linked worktree or a
+// submodule has instead of a .git directory. The file points at the Git directory, and
+// a linked worktree's Git directory points at the main repository's one, which holds
+// the config, with a commondir file.
+func gitFileConfig(dotGit string) (string, error) {
+	b, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("read a .git file: %w", err)
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(b)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf(".git file doesn't point at a Git directory: %s", dotGit)
+	}
+	gitDir = resolvePath(filepath.Dir(dotGit), strings.TrimSpace(gitDir))
+	commonDir, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return filepath.Join(gitDir, "config"), nil
+		}
+		return "", fmt.Errorf("read a commondir file: %w", err)
+	}
+	return filepath.Join(resolvePath(gitDir, strings.TrimSpace(string(commonDir))), "config"), nil
+}
+
+// resolvePath returns p, resolved against base when it is relative.
+func resolvePath(base, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(base, p)
+}
+
+// gitConfig is what FromDir needs from a Git config file.
+type gitConfig struct {
+	// remotes are the names of the remotes with a URL, in the order they are declared.
+	remotes []string
+	// urls maps a remote name to its first URL.
+	urls map[string]string
+	// insteadOf are the url.<base>.insteadOf rules in the order they are declared.
+	insteadOf []insteadOf
+}
+
+// insteadOf is a url.<base>.insteadOf rule: a URL starting with prefix is rewritten to
+// start with base.
+type insteadOf struct {
+	base   string
+	prefix string
+}
+
+// remoteURL returns the URL of the origin remote, or of the first remote when there is
+// no origin. It returns "" when there is no remote.
+func (c *gitConfig) remoteURL() string {
+	if u, ok := c.urls[defaultRemote]; ok {
+		return u
+	}
+	if len(c.remotes) == 0 {
+		return ""
+	}
+	return c.urls[c.remotes[0]]
+}
+
+// rewrite applies the insteadOf rule with the longest prefix matching rawURL, as Git
+// does. Of rules with the same prefix the last one wins.
+func (c *gitConfig) rewrite(rawURL string) string {
+	var best *insteadOf
+	for i, rule := range c.insteadOf {
+		if !strings.HasPrefix(rawURL, rule.prefix) {
+			continue
+		}
+		if best == nil || len(rule.prefix) >= len(best.prefix) {
+			best = &c.insteadOf[i]
+		}
+	}
+	if best == nil {
+		return rawURL
+	}
+	return best.base + strings.TrimPrefix(rawURL, best.prefix)
+}
+
+func readConfigFile(p string) (*gitConfig, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("open a Git config file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+	cfg, err := parseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("read a Git config file %s: %w", p, err)
+	}
+	return cfg, nil
+}
+
+// parseConfig reads the remote URLs and the insteadOf rules from a Git config file.
+// Everything else, including include directives, is ignored.
+func parseConfig(r io.Reader) (*gitConfig, error) {
+	cfg := &gitConfig{urls: map[string]string{}}
+	var section, subsection string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// A value continued on the next line.
+		for strings.HasSuffix(line, `\`) && scanner.Scan() {
+			line = line[:len(line)-1] + strings.TrimSpace(scanner.Text())
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			var err error
+			section, subsection, line, err = parseSection(line)
+			if err != nil {
+				return nil, err
+			}
+			if line == "" {
+				continue
+			}
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			// A boolean key without a value.
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = parseValue(value)
+		switch {
+		case section == "remote" && name == "url":
+			if _, ok := cfg.urls[subsection]; !ok {
+				cfg.remotes = append(cfg.remotes, subsection)
+				cfg.urls[subsection] = value
+			}
+		case section == "url" && name == "insteadof":
+			cfg.insteadOf = append(cfg.insteadOf, insteadOf{base: subsection, prefix: value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read a Git config: %w", err)
+	}
+	return cfg, nil
+}
+
+// parseSection parses a section header such as [remote "origin"] or the deprecated
+// [remote.origin]. It returns the section name in lower case, the subsection, and what
+// follows the header on the same line.
+func parseSection(line string) (string, string, string, error) {
+	end := strings.LastIndex(line, "]")
+	if end < 0 {
+		return "", "", "", fmt.Errorf("invalid section header: %s", line)
+	}
+	header, rest := strings.TrimSpace(line[1:end]), strings.TrimSpace(line[end+1:])
+	if name, sub, ok := strings.Cut(header, " "); ok {
+		sub = strings.TrimSpace(sub)
+		if len(sub) < 2 || sub[0] != '"' || sub[len(sub)-1] != '"' {
+			return "", "", "", fmt.Errorf("invalid section header: %s", line)
+		}
+		sub = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(sub[1 : len(sub)-1])
+		return strings.ToLower(name), sub, rest, nil
+	}
+	if name, sub, ok := strings.Cut(header, "."); ok {
+		return strings.ToLower(name), strings.ToLower(sub), rest, nil
+	}
+	return strings.ToLower(header), "", rest, nil
+}
+
+// parseValue returns a config value without its comment and quotes, with escape
+// sequences resolved.
+func parseValue(s string) string {
+	var b strings.Builder
+	quoted := false
+	escaped := false
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case escaped:
+			switch r {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			default:
+				b.WriteRune(r)
+			}
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == '#' || r == ';'):
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
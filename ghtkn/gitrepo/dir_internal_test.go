@@ -0,0 +1,163 @@
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeFile writes content to the file at the slash-separated path p under dir,
+// creating the parent directories.
+func writeFile(t *testing.T, dir, p, content string) {
+	t.Helper()
+	p = filepath.Join(dir, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+}
+
+func TestFromDir(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// files are written under a temporary directory, keyed by slash-separated path.
+		// "$ROOT" in a content is replaced with the directory.
+		files   map[string]string
+		global  string
+		dir     string
+		want    *Repository
+		wantErr bool
+	}{
+		{
+			name: "origin",
+			files: map[string]string{
+				"repo/.git/config": `[core]
+	bare = false
+[remote "upstream"]
+	url = https://github.com/upstream/repo.git
+[remote "origin"]
+	url = git@github.com:owner/repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+`,
+			},
+			dir:  "repo",
+			want: &Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+		},
+		{
+			name: "the first remote without origin",
+			files: map[string]string{
+				"repo/.git/config": `[remote "upstream"]
+	url = https://ghes.example.com/upstream/repo.git
+[remote "fork"]
+	url = https://github.com/fork/repo.git
+`,
+			},
+			dir:  "repo",
+			want: &Repository{Host: "ghes.example.com", Owner: "upstream", Name: "repo"},
+		},
+		{
+			name: "a subdirectory of the working tree",
+			files: map[string]string{
+				"repo/.git/config":   "[remote \"origin\"]\n\turl = https://github.com/owner/repo\n",
+				"repo/a/b/README.md": "",
+			},
+			dir:  "repo/a/b",
+			want: &Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+		},
+		{
+			name: "a linked worktree",
+			files: map[string]string{
+				"repo/.git/config":                 "[remote \"origin\"]\n\turl = https://github.com/owner/repo\n",
+				"repo/.git/worktrees/wt/commondir": "../..\n",
+				"wt/.git":                          "gitdir: $ROOT/repo/.git/worktrees/wt\n",
+			},
+			dir:  "wt",
+			want: &Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+		},
+		{
+			name: "a submodule",
+			files: map[string]string{
+				"repo/.git/config":             "[remote \"origin\"]\n\turl = https://github.com/owner/repo\n",
+				"repo/.git/modules/sub/config": "[remote \"origin\"]\n\turl = https://github.com/owner/sub\n",
+				"repo/sub/.git":                "gitdir: ../.git/modules/sub\n",
+			},
+			dir:  "repo/sub",
+			want: &Repository{Host: "github.com", Owner: "owner", Name: "sub"},
+		},
+		{
+			name: "insteadOf in the repository",
+			files: map[string]string{
+				"repo/.git/config": `[remote "origin"]
+	url = gh:owner/repo
+[url "https://github.com/"]
+	insteadOf = gh:
+`,
+			},
+			dir:  "repo",
+			want: &Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+		},
+		{
+			name: "the longest insteadOf wins and the global config is read",
+			files: map[string]string{
+				"repo/.git/config": "[remote \"origin\"]\n\turl = \"https://github.com/acme/repo\" # comment\n",
+			},
+			global: `[url "git@github.com:"]
+	insteadOf = https://github.com/
+[url "ssh://git@ghes.example.com/acme-mirror/"]
+	insteadOf = https://github.com/acme/
+`,
+			dir:  "repo",
+			want: &Repository{Host: "ghes.example.com", Owner: "acme-mirror", Name: "repo"},
+		},
+		{
+			name: "no remote",
+			files: map[string]string{
+				"repo/.git/config": "[core]\n\tbare = false\n",
+			},
+			dir:     "repo",
+			wantErr: true,
+		},
+		{
+			name: "not a working tree",
+			files: map[string]string{
+				"dir/README.md": "",
+			},
+			dir:     "dir",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			root := t.TempDir()
+			for p, content := range tt.files {
+				writeFile(t, root, p, strings.ReplaceAll(content, "$ROOT", root))
+			}
+			globals := []string{filepath.Join(root, "missing")}
+			if tt.global != "" {
+				writeFile(t, root, "home/.gitconfig", tt.global)
+				globals = append(globals, filepath.Join(root, "home", ".gitconfig"))
+			}
+			got, err := fromDir(filepath.Join(root, filepath.FromSlash(tt.dir)), globals)
+			if err != nil {
+				if !tt.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatalf("expected an error but got %+v", got)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Package gitrepo identifies the GitHub repository a token is requested for, from a
+// repository URL or from the remotes of a Git working tree. The host and owner it
+// returns select the app with config.ResolveAppByHost.
+package gitrepo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Repository is a GitHub repository.
+type Repository struct {
+	// Host is the host name of the GitHub instance in lower case, without a port, such
+	// as github.com or a GitHub Enterprise Server's host name.
+	Host string
+	// Owner is the user or organization owning the repository.
+	Owner string
+	// Name is the repository name without the .git suffix.
+	Name string
+}
+
+// ParseURL parses a Git remote URL of a GitHub repository. It accepts the forms Git
+// accepts for a remote:
+//
+//   - https://github.com/owner/repo (also http:// and git://)
+//   - ssh://git@github.com/owner/repo, with an optional port
+//   - git@github.com:owner/repo, the scp-like SSH syntax
+//
+// each with or without the .git suffix and a trailing slash. SSH over the HTTPS port
+// (ssh.github.com) is github.com. It doesn't apply url.<base>.insteadOf rewrites; see
+// FromDir for that.
+func ParseURL(rawURL string) (*Repository, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, errors.New("repository URL is empty")
+	}
+	host, path, err := splitURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		return nil, fmt.Errorf("repository URL has no host: %s", rawURL)
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	owner, name, ok := strings.Cut(path, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("repository URL must have the path <owner>/<repository>: %s", rawURL)
+	}
+	host = strings.ToLower(host)
+	if host == "ssh.github.com" {
+		host = "github.com"
+	}
+	return &Repository{
+		Host:  host,
+		Owner: owner,
+		Name:  name,
+	}, nil
+}
+
+// splitURL splits a remote URL into its host and path.
+func splitURL(rawURL string) (string, string, error) {
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", "", fmt.Errorf("parse a repository URL: %w", err)
+		}
+		switch u.Scheme {
+		case "https", "http", "ssh", "git", "git+ssh", "ssh+git":
+		default:
+			return "", "", fmt.Errorf("unsupported scheme of a repository URL: %s", u.Scheme)
+		}
+		return u.Hostname(), u.Path, nil
+	}
+	// The scp-like syntax [user@]host:path. Git only reads it that way when no slash
+	// comes before the first colon; otherwise it is a local path.
+	userHost, path, ok := strings.Cut(rawURL, ":")
+	if !ok || strings.Contains(userHost, "/") {
+		return "", "", fmt.Errorf("repository URL is neither a URL nor [user@]host:owner/repository: %s", rawURL)
+	}
+	if _, host, ok := strings.Cut(userHost, "@"); ok {
+		return host, path, nil
+	}
+	return userHost, path, nil
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend/text"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/flock"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+// Callers acquiring the token of the same app at the same time, such as parallel git
+// fetches through the credential helper, would all find the same expired token, and all
+// renew it or run the device flow. With a client-side backend only one of them does:
+// in the process the callers join one acquisition (a flight) and share its token, and
+// across processes the acquisitions are serialized by a file lock, under which the
+// token is read again, so a process that waited finds the token the other one stored.
+//
+// The lock files are ${XDG_CACHE_HOME}/ghtkn/locks/<storage key>.lock
+// (%LocalAppData%\cache\ghtkn\locks on Windows), whose directory can be overridden with
+// the GHTKN_LOCK_DIR environment variable. The agent serializes acquisitions itself.
+
+// tokenFlights are the acquisitions of tokens in progress in the process.
+type tokenFlights struct {
+	mu    sync.Mutex
+	calls map[flightKey]*flight
+}
+
+// flightKey identifies the token a flight acquires. scope is the backend, as for
+// cacheKey.
+type flightKey struct {
+	scope    any
+	host     string
+	clientID string
+}
+
+// flight is an acquisition of a token. The fields but done are set before done is
+// closed.
+type flight struct {
+	done  chan struct{}
+	token *pubapi.AccessToken
+	err   error
+	// renewed is whether token was renewed or created by the flight rather than read.
+	renewed bool
+	// enableDeviceFlow is whether the device flow could run in the flight.
+	enableDeviceFlow bool
+}
+
+// join returns the flight in progress for key, or starts one, in which case the caller
+// leads it and must finish it.
+func (f *tokenFlights) join(key flightKey) (*flight, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fl, ok := f.calls[key]; ok {
+		return fl, false
+	}
+	if f.calls == nil {
+		f.calls = map[flightKey]*flight{}
+	}
+	fl := &flight{done: make(chan struct{})}
+	f.calls[key] = fl
+	return fl, true
+}
+
+// finish ends the flight for key, releasing the callers waiting for it.
+func (f *tokenFlights) finish(key flightKey, fl *flight) {
+	f.mu.Lock()
+	delete(f.calls, key)
+	f.mu.Unlock()
+	close(fl.done)
+}
+
+// backendScope identifies a backend tokens are cached and acquired in: the injected
+// backend or the backend type, in a profile, since the backends of the same type in two
+// profiles store different tokens.
+type backendScope struct {
+	profile string
+	backend any
+}
+
+// backendScope returns the scope of the backend cfg selects in the profile prof.
+func (tm *TokenManager) backendScope(cfg *pubconfig.Config, prof string) backendScope {
+	if tm.input.Backend != nil {
+		return backendScope{profile: prof, backend: tm.input.Backend}
+	}
+	return backendScope{profile: prof, backend: resolveBackendType(cfg.Backend)}
+}
+
+// acquireToken obtains the token of input.App with obtainToken, once for all the
+// callers acquiring it at the same time (see above). A caller that waited for another
+// one's acquisition takes its result: its error, unless it was canceled or only the
+// caller may run the device flow, and its token, if it is valid for the caller's
+// MinExpiration or, for a caller asking for a new token (Auth), if the acquisition
+// renewed it. Otherwise, such as when the other caller read a token expiring sooner
+// than the caller accepts, the caller acquires the token itself.
+func (tm *TokenManager) acquireToken(ctx context.Context, logger *slog.Logger, scope backendScope, input *inputGetOrCreateToken) (*pubapi.AccessToken, error) {
+	if input.Backend.SupportsDeviceFlow() {
+		token, _, err := tm.obtainToken(ctx, logger, input)
+		return token, err
+	}
+	key := flightKey{scope: scope, host: input.App.HostName(), clientID: input.App.ClientID}
+	for {
+		fl, leader := tm.flights.join(key)
+		if leader {
+			return tm.leadFlight(ctx, logger, key, fl, input)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for the token being acquired by another caller: %w", ctx.Err())
+		case <-fl.done:
+		}
+		if fl.token == nil {
+			// The other caller's error is the caller's too, unless it was canceled or
+			// couldn't run the device flow the caller may run.
+			if !isContextError(fl.err) && (fl.enableDeviceFlow || !input.EnableDeviceFlow) {
+				logger.Debug("another caller failed to acquire the token")
+				return nil, fl.err
+			}
+			continue
+		}
+		if (fl.renewed && input.MinExpiration > maxTokenLifetime) || !tm.checkExpired(fl.token.ExpirationDate, input.MinExpiration) {
+			logger.Debug("reused the token acquired by another caller")
+			return fl.token, fl.err
+		}
+	}
+}
+
+// leadFlight acquires the token for the flight fl and finishes it. A panic in the
+// acquisition finishes the flight too, with an error, so the callers waiting for it
+// aren't left waiting, and is then propagated.
+func (tm *TokenManager) leadFlight(ctx context.Context, logger *slog.Logger, key flightKey, fl *flight, input *inputGetOrCreateToken) (*pubapi.AccessToken, error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fl.token, fl.renewed, fl.err = nil, false, fmt.Errorf("acquire the token: panic: %v", r)
+			tm.flights.finish(key, fl)
+			panic(r)
+		}
+		tm.flights.finish(key, fl)
+	}()
+	fl.enableDeviceFlow = input.EnableDeviceFlow
+	fl.token, fl.renewed, fl.err = tm.obtainTokenLocked(ctx, logger, input)
+	return fl.token, fl.err
+}
+
+// isContextError reports whether err is because a context was canceled or its deadline
+// passed.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// obtainTokenLocked is obtainToken under the file lock of the token (see lockToken).
+func (tm *TokenManager) obtainTokenLocked(ctx context.Context, logger *slog.Logger, input *inputGetOrCreateToken) (*pubapi.AccessToken, bool, error) {
+	unlock, err := tm.lockToken(ctx, logger, input.Profile, input.App.HostName(), input.App.ClientID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+	return tm.obtainToken(ctx, logger, input)
+}
+
+// lockToken takes the file lock of the token of clientID on host in the profile prof,
+// and returns the function releasing it. When the lock can't be taken for a reason
+// other than ctx, such as a read-only cache directory, the token is used without it and
+// the returned function does nothing.
+func (tm *TokenManager) lockToken(ctx context.Context, logger *slog.Logger, prof, host, clientID string) (func(), error) {
+	dir, err := text.Dir(tm.getenv(prof), tm.input.GOOS, env.LockDir, "locks")
+	if err != nil {
+		slogerr.WithError(logger, err).Debug("using the token without the lock")
+		return func() {}, nil
+	}
+	unlock, err := flock.Lock(ctx, filepath.Join(dir, pubapi.StorageKey(host, clientID)+".lock"))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("lock the token: %w", err)
+		}
+		slogerr.WithError(logger, err).Debug("using the token without the lock")
+		return func() {}, nil
+	}
+	return unlock, nil
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/deviceflow"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
+)
+
+// countingDeviceFlow renews tokens, counting the renewals.
+type countingDeviceFlow struct {
+	testDeviceFlow
+
+	mu        sync.Mutex
+	refreshes int
+}
+
+func (m *countingDeviceFlow) Refresh(_ context.Context, _ *deviceflow.InputRefresh) (*deviceflow.AccessToken, error) {
+	m.mu.Lock()
+	m.refreshes++
+	m.mu.Unlock()
+	// Give the other callers time to find the expired token.
+	time.Sleep(50 * time.Millisecond)
+	return &deviceflow.AccessToken{
+		AccessToken:    "gho_renewed",
+		ExpirationDate: time.Now().Add(8 * time.Hour),
+		RefreshToken:   "ghr_renewed",
+	}, nil
+}
+
+// TestTokenManager_Get_acquiresOnce verifies that callers finding the same expired
+// token at the same time renew it only once, whether they are in the same process (the
+// same TokenManager) or in different ones (TokenManagers sharing the lock directory).
+func TestTokenManager_Get_acquiresOnce(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	tokenDir := filepath.Join(dir, "tokens")
+	if err := os.MkdirAll(tokenDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tokenDir, "Iv1.x"), []byte(`{"access_token":"gho_expired","expiration_date":"2000-01-01T00:00:00Z","refresh_token":"ghr_x"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	getEnv := func(k string) string {
+		switch k {
+		case "GHTKN_BACKEND":
+			return "text"
+		case "GHTKN_TEXT_BACKEND_DIR":
+			return tokenDir
+		case "GHTKN_LOCK_DIR":
+			return filepath.Join(dir, "locks")
+		default:
+			return ""
+		}
+	}
+	df := &countingDeviceFlow{}
+	tms := make([]*TokenManager, 2)
+	for i := range tms {
+		tms[i] = New(&Input{
+			DeviceFlow:   df,
+			ConfigReader: &oneAppConfigReader{},
+			Logger:       log.NewLogger(),
+			Getenv:       getEnv,
+			GOOS:         "linux",
+		})
+	}
+
+	var wg sync.WaitGroup
+	tokens := make(chan string, 6)
+	for i := range 6 {
+		wg.Go(func() {
+			token, _, err := tms[i%2].Get(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputGet{
+				AppName:        "app1",
+				ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+			})
+			if err != nil {
+				t.Errorf("Get() error: %v", err)
+				return
+			}
+			tokens <- token.AccessToken
+		})
+	}
+	wg.Wait()
+	close(tokens)
+	for token := range tokens {
+		if token != "gho_renewed" {
+			t.Errorf("Get() = %q, want the renewed token", token)
+		}
+	}
+	if df.refreshes != 1 {
+		t.Errorf("the token was renewed %d times, want once", df.refreshes)
+	}
+}
+
+// creatingDeviceFlow runs the device flow, counting the runs, and fails with err when
+// it is set.
+type creatingDeviceFlow struct {
+	testDeviceFlow
+
+	mu      sync.Mutex
+	creates int
+}
+
+func (m *creatingDeviceFlow) Create(_ context.Context, _ *slog.Logger, _ *deviceflow.InputCreate) (*deviceflow.AccessToken, error) {
+	m.mu.Lock()
+	m.creates++
+	m.mu.Unlock()
+	// Give the other callers time to join the acquisition.
+	time.Sleep(50 * time.Millisecond)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &deviceflow.AccessToken{
+		AccessToken:    "gho_created",
+		ExpirationDate: time.Now().Add(8 * time.Hour),
+	}, nil
+}
+
+// TestTokenManager_Auth_acquiresOnce verifies that callers authenticating the same app
+// at the same time run the device flow only once, and share its token or its error.
+func TestTokenManager_Auth_acquiresOnce(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "created"},
+		{name: "declined", err: errors.New("the user declined the authorization")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			getEnv := func(k string) string {
+				switch k {
+				case "GHTKN_BACKEND":
+					return "text"
+				case "GHTKN_TEXT_BACKEND_DIR":
+					return filepath.Join(dir, "tokens")
+				case "GHTKN_LOCK_DIR":
+					return filepath.Join(dir, "locks")
+				default:
+					return ""
+				}
+			}
+			df := &creatingDeviceFlow{}
+			df.err = tt.err
+			tm := New(&Input{
+				DeviceFlow:   df,
+				ConfigReader: &oneAppConfigReader{},
+				Logger:       log.NewLogger(),
+				Getenv:       getEnv,
+				GOOS:         "linux",
+			})
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 4)
+			for range 4 {
+				wg.Go(func() {
+					errs <- tm.Auth(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputAuth{
+						AppName:        "app1",
+						ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+					})
+				})
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if (err != nil) != (tt.err != nil) || (err != nil && !errors.Is(err, tt.err)) {
+					t.Errorf("Auth() error = %v, want %v", err, tt.err)
+				}
+			}
+			if df.creates != 1 {
+				t.Errorf("the device flow ran %d times, want once", df.creates)
+			}
+		})
+	}
+}
+
+// panickingBackend is a mapBackend whose Get panics while panics is set.
+type panickingBackend struct {
+	mapBackend
+
+	panics atomic.Bool
+}
+
+func (b *panickingBackend) Get(ctx context.Context, host, clientID string) ([]byte, error) {
+	if b.panics.Load() {
+		panic("the backend is broken")
+	}
+	return b.mapBackend.Get(ctx, host, clientID)
+}
+
+// TestTokenManager_Get_panic verifies that an acquisition that panics is finished, so a
+// later caller acquires the token instead of waiting for it forever.
+func TestTokenManager_Get_panic(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	getEnv := func(k string) string {
+		if k == "GHTKN_LOCK_DIR" {
+			return filepath.Join(dir, "locks")
+		}
+		return ""
+	}
+	tm := New(&Input{
+		DeviceFlow:   &testDeviceFlow{},
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       log.NewLogger(),
+		Getenv:       getEnv,
+		GOOS:         "linux",
+	})
+	b := &panickingBackend{mapBackend: mapBackend{}}
+	b.panics.Store(true)
+	tm.SetBackend(b)
+	get := func(ctx context.Context) error {
+		_, _, err := tm.Get(ctx, slog.New(slog.DiscardHandler), &pubapi.InputGet{
+			AppName:        "app1",
+			ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+		})
+		return err
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Get() must propagate the panic of the backend")
+			}
+		}()
+		_ = get(t.Context())
+	}()
+
+	b.panics.Store(false)
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if err := get(ctx); isContextError(err) {
+		t.Fatalf("Get() waited for the acquisition that panicked: %v", err)
+	}
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
 	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
@@ -72,3 +75,137 @@ func TestTokenManager_Revoke_backendFromEnv(t *testing.T) {
 		t.Fatalf("the revoked token file must be deleted, stat err = %v", err)
 	}
 }
+
+// appBackendConfigReader is a ConfigReader whose app overrides the global backend with
+// the text backend, and the global min_expiration with minExpiration.
+type appBackendConfigReader struct {
+	minExpiration string
+}
+
+func (r *appBackendConfigReader) Read(cfg *pubconfig.Config, _ string) error {
+	cfg.Backend = &pubconfig.Backend{Type: "agent"}
+	cfg.Apps = []*pubconfig.App{{
+		Name:          "app1",
+		ClientID:      "Iv1.x",
+		Backend:       &pubconfig.Backend{Type: "text"},
+		MinExpiration: r.minExpiration,
+	}}
+	return nil
+}
+
+// TestTokenManager_appSettings verifies that Get and Revoke use the backend and the min
+// expiration of the app rather than the global ones.
+func TestTokenManager_appSettings(t *testing.T) {
+	t.Parallel()
+	const token = "gho_from_text_backend" //nolint:gosec // G101: a fake token for the test
+	newTM := func(t *testing.T, minExpiration string, revoker *mockRevoker) (*TokenManager, string) {
+		t.Helper()
+		dir := t.TempDir()
+		exp := time.Now().Add(30 * time.Minute).UTC().Format(time.RFC3339)
+		if err := os.WriteFile(filepath.Join(dir, "Iv1.x"), []byte(`{"access_token":"`+token+`","expiration_date":"`+exp+`"}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return New(&Input{
+			Revoker:      revoker,
+			ConfigReader: &appBackendConfigReader{minExpiration: minExpiration},
+			Logger:       log.NewLogger(),
+			Getenv: func(k string) string {
+				if k == "GHTKN_TEXT_BACKEND_DIR" {
+					return dir
+				}
+				return ""
+			},
+			GOOS: "linux",
+		}), dir
+	}
+	logger := slog.New(slog.DiscardHandler)
+
+	t.Run("get", func(t *testing.T) {
+		t.Parallel()
+		tm, dir := newTM(t, "", nil)
+		tk, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: filepath.Join(dir, "ghtkn.yaml")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tk.AccessToken != token {
+			t.Errorf("AccessToken = %q, want %q", tk.AccessToken, token)
+		}
+	})
+
+	t.Run("the app's min_expiration", func(t *testing.T) {
+		t.Parallel()
+		tm, dir := newTM(t, "1h", nil)
+		input := &pubapi.InputGet{ConfigFilePath: filepath.Join(dir, "ghtkn.yaml")}
+		_, _, d, err := tm.getToken(t.Context(), logger, input)
+		if !errors.Is(err, pubapi.ErrDisableDeviceFlow) {
+			t.Errorf("getToken() error = %v, want ErrDisableDeviceFlow as the token expires within the app's min_expiration", err)
+		}
+		if d != time.Hour {
+			t.Errorf("getToken() min expiration = %v, want 1h", d)
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		t.Parallel()
+		revoker := &mockRevoker{}
+		tm, dir := newTM(t, "", revoker)
+		if err := tm.Revoke(t.Context(), logger, &pubapi.InputRevoke{
+			AppNames:       []string{"app1"},
+			ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+		}); err != nil {
+			t.Fatalf("Revoke() error: %v", err)
+		}
+		if diff := cmp.Diff([][]string{{token}}, revoker.revoked); diff != "" {
+			t.Errorf("revoked tokens mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// TestTokenManager_resolveBackend_fallback verifies that a list of backends selects the
+// first usable one once, and warns when it falls back to plaintext.
+func TestTokenManager_resolveBackend_fallback(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	const token = "gho_from_text_backend" //nolint:gosec // G101: a fake token for the test
+	if err := os.WriteFile(filepath.Join(dir, "Iv1.x"), []byte(`{"access_token":"`+token+`","expiration_date":"2999-01-01T00:00:00Z"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	getEnv := func(k string) string {
+		switch k {
+		case "GHTKN_BACKEND":
+			// The exec backend can't be built without a command.
+			return "exec,text"
+		case "GHTKN_TEXT_BACKEND_DIR":
+			return dir
+		default:
+			return ""
+		}
+	}
+	var selected, plaintext []string
+	logger := log.NewLogger()
+	logger.SelectedBackend = func(_ *slog.Logger, backend string) { selected = append(selected, backend) }
+	logger.FellBackToPlaintextBackend = func(_ *slog.Logger, backend string) { plaintext = append(plaintext, backend) }
+	tm := New(&Input{
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       logger,
+		Getenv:       getEnv,
+		GOOS:         "linux",
+	})
+
+	for range 2 {
+		tk, _, err := tm.Get(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputGet{ConfigFilePath: filepath.Join(dir, "ghtkn.yaml")})
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if tk.AccessToken != token {
+			t.Fatalf("Get() = %q, want the token of the text backend", tk.AccessToken)
+		}
+	}
+	// The selection is made once.
+	if diff := cmp.Diff([]string{"text"}, selected); diff != "" {
+		t.Errorf("selected backends mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"text"}, plaintext); diff != "" {
+		t.Errorf("plaintext warnings mismatch (-want +got):\n%s", diff)
+	}
+}
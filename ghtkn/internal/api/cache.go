@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend"
+)
+
+// TokenCache keeps the decoded tokens read from and written to client-side backends in
+// process memory, so repeated Get calls don't hit a slow or prompting backend such as
+// the macOS keychain. It may be shared by the TokenManagers of a process.
+//
+// A token is kept until it expires, is deleted, or is rejected by GitHub. A token
+// close to its expiry is read from the backend again before it is renewed, since
+// another process may have renewed it already.
+type TokenCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*pubapi.AccessToken
+	now     func() time.Time
+}
+
+// cacheKey identifies a cached token. scope is the backend it was read from: the
+// backend type, or the injected backend itself, so backends sharing a cache don't see
+// each other's tokens.
+type cacheKey struct {
+	scope    any
+	host     string
+	clientID string
+}
+
+// NewTokenCache returns an empty TokenCache.
+func NewTokenCache() *TokenCache {
+	return &TokenCache{
+		entries: map[cacheKey]*pubapi.AccessToken{},
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the token cached for key, or nil when none is cached or it
+// has expired.
+func (c *TokenCache) get(key cacheKey) *pubapi.AccessToken {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	token, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !token.ExpirationDate.IsZero() && !c.now().Before(token.ExpirationDate) {
+		delete(c.entries, key)
+		return nil
+	}
+	tk := *token
+	return &tk
+}
+
+func (c *TokenCache) set(key cacheKey, token *pubapi.AccessToken) {
+	tk := *token
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &tk
+}
+
+func (c *TokenCache) delete(key cacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// DeleteToken drops the cached entries holding accessToken, such as a token GitHub
+// rejected.
+func (c *TokenCache) DeleteToken(accessToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, token := range c.entries {
+		if token.AccessToken == accessToken {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Clear drops all the cached tokens.
+func (c *TokenCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// cachedBackend is a client-side backend whose Get is served from a TokenCache. Set
+// writes through to the backend and then caches the token, and Delete drops the cached
+// token before it deletes the stored one, so a token whose deletion fails isn't served
+// either.
+type cachedBackend struct {
+	Backend
+	cache *TokenCache
+	scope any
+}
+
+func (b *cachedBackend) key(host, clientID string) cacheKey {
+	return cacheKey{scope: b.scope, host: host, clientID: clientID}
+}
+
+func (b *cachedBackend) Get(ctx context.Context, host, clientID string) (*pubapi.AccessToken, error) {
+	if token := b.cache.get(b.key(host, clientID)); token != nil {
+		return token, nil
+	}
+	return b.reload(ctx, host, clientID)
+}
+
+// reload reads the token from the backend, bypassing the cache, and caches it.
+func (b *cachedBackend) reload(ctx context.Context, host, clientID string) (*pubapi.AccessToken, error) {
+	key := b.key(host, clientID)
+	token, err := b.Backend.Get(ctx, host, clientID)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if token == nil {
+		b.cache.delete(key)
+		return nil, nil
+	}
+	b.cache.set(key, token)
+	return token, nil
+}
+
+func (b *cachedBackend) Set(ctx context.Context, host, clientID string, token *pubapi.AccessToken) error {
+	key := b.key(host, clientID)
+	if err := b.Backend.Set(ctx, host, clientID, token); err != nil {
+		b.cache.delete(key)
+		return err //nolint:wrapcheck
+	}
+	b.cache.set(key, token)
+	return nil
+}
+
+func (b *cachedBackend) Delete(ctx context.Context, host, clientID string) error {
+	b.cache.delete(b.key(host, clientID))
+	return b.Backend.Delete(ctx, host, clientID) //nolint:wrapcheck
+}
+
+// List lists the tokens of the backend, which the cache doesn't know all of.
+func (b *cachedBackend) List(ctx context.Context) ([]pubbackend.TokenKey, error) {
+	l, ok := b.Backend.(lister)
+	if !ok {
+		return nil, backend.ErrListUnsupported
+	}
+	return l.List(ctx) //nolint:wrapcheck
+}
+
+// SetTokenCache makes tm keep the tokens of client-side backends in cache. nil stops
+// caching.
+func (tm *TokenManager) SetTokenCache(cache *TokenCache) {
+	tm.cache.Store(cache)
+}
+
+// withCache returns b served from the token cache when one is set. A backend that
+// owns the token lifecycle (the agent) checks expiration itself and is never cached.
+func (tm *TokenManager) withCache(b Backend, scope any) Backend {
+	cache := tm.cache.Load()
+	if cache == nil || b.SupportsDeviceFlow() {
+		return b
+	}
+	return &cachedBackend{Backend: b, cache: cache, scope: scope}
+}
+
+// invalidateToken drops accessToken from the token cache, after GitHub rejected it.
+func (tm *TokenManager) invalidateToken(accessToken string) {
+	if cache := tm.cache.Load(); cache != nil {
+		cache.DeleteToken(accessToken)
+	}
+}
+
+// uncache drops the token cached for clientID on host from the backend of scope, after
+// the token stored there was changed outside the cached backend.
+func (tm *TokenManager) uncache(scope any, host, clientID string) {
+	if cache := tm.cache.Load(); cache != nil {
+		cache.delete(cacheKey{scope: scope, host: host, clientID: clientID})
+	}
+}
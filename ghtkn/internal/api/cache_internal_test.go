@@ -0,0 +1,108 @@
+package api
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+)
+
+func TestTokenManager_SetTokenCache(t *testing.T) {
+	t.Parallel()
+	keyring := &mockKeyring{token: &pubapi.AccessToken{AccessToken: "cached", ExpirationDate: time.Now().Add(time.Hour)}}
+	input := newMockInput()
+	input.Backend = keyring
+	input.Revoker = &mockRevoker{}
+	cache := NewTokenCache()
+	tm := New(input)
+	tm.SetTokenCache(cache)
+	logger := slog.New(slog.DiscardHandler)
+	reads := func() int {
+		t.Helper()
+		n := len(keyring.hosts)
+		if _, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: "config.yaml"}); err != nil {
+			t.Fatal(err)
+		}
+		return len(keyring.hosts) - n
+	}
+
+	if got := reads(); got != 1 {
+		t.Errorf("the first Get read the backend %d times, want 1", got)
+	}
+	if got := reads(); got != 0 {
+		t.Errorf("Get of a cached token read the backend %d times, want 0", got)
+	}
+
+	// A TokenManager sharing the cache and the backend uses the cached token.
+	other := New(input)
+	other.SetTokenCache(cache)
+	n := len(keyring.hosts)
+	if _, _, err := other.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: "config.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(keyring.hosts) - n; got != 0 {
+		t.Errorf("Get through a shared cache read the backend %d times, want 0", got)
+	}
+
+	// A token GitHub rejected is dropped.
+	tm.invalidateToken("cached")
+	if got := reads(); got != 1 {
+		t.Errorf("Get after the token was rejected read the backend %d times, want 1", got)
+	}
+
+	// A revoked token is dropped.
+	if err := tm.Revoke(t.Context(), logger, &pubapi.InputRevoke{AppNames: []string{"test-app"}, ConfigFilePath: "config.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := reads(); got != 1 {
+		t.Errorf("Get after Revoke read the backend %d times, want 1", got)
+	}
+
+	// An expiring cached token is read again, since another process may have renewed it.
+	keyring.token = &pubapi.AccessToken{AccessToken: "renewed", ExpirationDate: time.Now().Add(2 * time.Hour)}
+	minExpiration := 90 * time.Minute
+	token, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: "config.yaml", MinExpiration: &minExpiration})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "renewed" {
+		t.Errorf("Get() = %q, want the token renewed by another process", token.AccessToken)
+	}
+
+	// Without a cache every Get reads the backend.
+	tm.SetTokenCache(nil)
+	if got := reads(); got != 1 {
+		t.Errorf("Get without a cache read the backend %d times, want 1", got)
+	}
+}
+
+func TestTokenCache_get(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewTokenCache()
+	cache.now = func() time.Time { return now }
+	key := cacheKey{scope: "keyring", host: "github.com", clientID: "Iv1.x"}
+	cache.set(key, &pubapi.AccessToken{AccessToken: "a", ExpirationDate: now.Add(time.Minute)})
+	if got := cache.get(key); got == nil || got.AccessToken != "a" {
+		t.Fatalf("get() = %v, want the cached token", got)
+	}
+	// Another backend's token isn't served.
+	if got := cache.get(cacheKey{scope: "text", host: "github.com", clientID: "Iv1.x"}); got != nil {
+		t.Errorf("get() of another backend = %v, want nil", got)
+	}
+	now = now.Add(time.Minute)
+	if got := cache.get(key); got != nil {
+		t.Errorf("get() of an expired token = %v, want nil", got)
+	}
+	// A token that never expires is kept.
+	cache.set(key, &pubapi.AccessToken{AccessToken: "b"})
+	now = now.Add(24 * time.Hour)
+	if got := cache.get(key); got == nil {
+		t.Error("get() of a token without an expiry = nil")
+	}
+	cache.Clear()
+	if got := cache.get(key); got != nil {
+		t.Errorf("get() after Clear() = %v, want nil", got)
+	}
+}
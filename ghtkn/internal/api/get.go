@@ -8,9 +8,12 @@ import (
 	"time"
 
 	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
 	pubdeviceflow "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/deviceflow"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/gitrepo"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/deviceflow"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
@@ -44,13 +47,22 @@ func (tm *TokenManager) SetCopyOnetimeCodeToClipboard(f pubdeviceflow.CopyTextTo
 	tm.input.DeviceFlow.SetCopyOnetimeCodeToClipboard(f)
 }
 
+// SetBackend makes the token manager store tokens in b, whatever backend the config
+// selects.
+func (tm *TokenManager) SetBackend(b pubbackend.Backend) {
+	tm.input.Backend = backend.Wrap(b)
+}
+
 // inputGet is the resolved request Get and Auth share. The two differ only in whether
 // the device flow may run and in how the fields below are filled, so the body they both
 // need lives in get and is written once.
 type inputGet struct {
 	AppName        string
 	ConfigFilePath string
+	Profile        string
 	AppOwner       string
+	Host           string
+	Repository     string
 	MinExpiration  *time.Duration
 	Clipboard      *bool
 	// EnableDeviceFlow is true only for Auth. It is not configurable: the device flow
@@ -61,8 +73,9 @@ type inputGet struct {
 
 // Get executes the main logic for retrieving a GitHub App access token.
 // It returns whatever token the backend can supply without asking the user anything: a
-// still-valid cached one, or, on a backend that owns the lifecycle (the agent) with
-// refresh enabled, one silently refreshed from the stored refresh token. It fails with
+// still-valid cached one, or one silently refreshed from the stored refresh token (by
+// the SDK for the keyring and file-based backends, or server-side by the agent when refresh
+// is enabled there). It fails with
 // pubapi.ErrDisableDeviceFlow when neither is available, since the only way left is the
 // device flow, which only Auth may run.
 //
@@ -72,6 +85,14 @@ type inputGet struct {
 // In this case the returned app config is nil and the access token has no
 // expiration date.
 func (tm *TokenManager) Get(ctx context.Context, logger *slog.Logger, input *pubapi.InputGet) (*pubapi.AccessToken, *pubconfig.App, error) {
+	token, app, _, err := tm.getToken(ctx, logger, input)
+	return token, app, err
+}
+
+// getToken is Get that also returns the min expiration the token was checked against,
+// resolved from the same config read, so TokenSource can cache the token with it
+// without reading the config again. It is zero for GHTKN_GITHUB_TOKEN.
+func (tm *TokenManager) getToken(ctx context.Context, logger *slog.Logger, input *pubapi.InputGet) (*pubapi.AccessToken, *pubconfig.App, time.Duration, error) {
 	if input == nil {
 		input = &pubapi.InputGet{}
 	}
@@ -79,16 +100,47 @@ func (tm *TokenManager) Get(ctx context.Context, logger *slog.Logger, input *pub
 	// GHTKN_GITHUB_TOKEN holds would neither create nor store a GitHub App user token,
 	// leaving the backend untouched while reporting success.
 	if token := tm.input.Getenv(env.GitHubToken); token != "" {
-		return &pubapi.AccessToken{AccessToken: token}, nil, nil
+		return &pubapi.AccessToken{AccessToken: token}, nil, 0, nil
+	}
+	repo, err := ResolveRepository(input)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 	return tm.get(ctx, logger, &inputGet{
 		AppName:        input.AppName,
 		ConfigFilePath: input.ConfigFilePath,
-		AppOwner:       input.AppOwner,
+		Profile:        input.Profile,
+		AppOwner:       repo.Owner,
+		Host:           repo.Host,
+		Repository:     repo.Name,
 		MinExpiration:  input.MinExpiration,
 	})
 }
 
+// ResolveRepository returns the repository the app is selected for: AppOwner, Host,
+// and RepositoryName as given, else the repository of RepositoryURL, else that of the
+// remote of RepositoryDir. Its owner is empty when input names no repository.
+func ResolveRepository(input *pubapi.InputGet) (*gitrepo.Repository, error) {
+	if input.AppOwner != "" {
+		return &gitrepo.Repository{Host: input.Host, Owner: input.AppOwner, Name: input.RepositoryName}, nil
+	}
+	if input.RepositoryURL != "" {
+		repo, err := gitrepo.ParseURL(input.RepositoryURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse the repository URL: %w", err)
+		}
+		return repo, nil
+	}
+	if input.RepositoryDir != "" {
+		repo, err := gitrepo.FromDir(input.RepositoryDir)
+		if err != nil {
+			return nil, fmt.Errorf("get the repository from a directory: %w", slogerr.With(err, "repository_dir", input.RepositoryDir))
+		}
+		return repo, nil
+	}
+	return &gitrepo.Repository{Host: input.Host}, nil
+}
+
 // Auth authenticates and stores a GitHub App access token in the backend, running the
 // device flow when it needs to. It is the only operation that may run the device flow,
 // so a token is only ever created by an explicit, interactive authentication. It always
@@ -101,9 +153,10 @@ func (tm *TokenManager) Auth(ctx context.Context, logger *slog.Logger, input *pu
 		input = &pubapi.InputAuth{}
 	}
 	minExpiration := alwaysRenew
-	_, _, err := tm.get(ctx, logger, &inputGet{
+	_, _, _, err := tm.get(ctx, logger, &inputGet{
 		AppName:          input.AppName,
 		ConfigFilePath:   input.ConfigFilePath,
+		Profile:          input.Profile,
 		MinExpiration:    &minExpiration,
 		Clipboard:        input.Clipboard,
 		EnableDeviceFlow: true,
@@ -112,46 +165,44 @@ func (tm *TokenManager) Auth(ctx context.Context, logger *slog.Logger, input *pu
 }
 
 // get is the shared body of Get and Auth. See their comments for what each of them
-// means; everything below this point is identical for the two.
-func (tm *TokenManager) get(ctx context.Context, logger *slog.Logger, input *inputGet) (*pubapi.AccessToken, *pubconfig.App, error) {
+// means; everything below this point is identical for the two. Besides the token and
+// the app, it returns the min expiration the token was checked against, once resolved.
+func (tm *TokenManager) get(ctx context.Context, logger *slog.Logger, input *inputGet) (*pubapi.AccessToken, *pubconfig.App, time.Duration, error) {
 	cfg := &pubconfig.Config{}
 
+	prof, err := tm.profile(input.Profile)
+	if err != nil {
+		return nil, nil, 0, err
+	}
 	// Get a config file path and read the config file
-	configPath, err := tm.resolveConfigPath(input.ConfigFilePath)
+	configPath, err := tm.resolveConfigPath(input.ConfigFilePath, prof)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 	// The effective config: the file plus the environment overrides, so the resolvers
 	// below read values the environment has already been folded into.
-	if err := tm.loadConfig(cfg, configPath); err != nil {
-		return nil, nil, err
+	if err := tm.loadConfig(logger, cfg, configPath); err != nil {
+		return nil, nil, 0, err
 	}
 
-	// Get the app name
-	appName := input.AppName
-	if appName == "" {
-		appName = tm.input.Getenv(env.App)
-	}
-
-	logger.Debug("selecting app", "app_name", appName, "git_owner", input.AppOwner)
-
-	// Get the app config
-	app := pubconfig.ResolveApp(cfg, appName, input.AppOwner)
+	app := tm.selectApp(logger, cfg, input.AppName, input.Host, input.AppOwner, input.Repository)
 	if app == nil {
-		return nil, nil, errors.New("app is not found in the config")
+		return nil, nil, 0, errors.New("app is not found in the config")
 	}
+	// The app's own settings override the global ones.
+	appCfg := cfg.ForApp(app)
 
 	attrs := slogerr.NewAttrs(1)
 	logger = attrs.Add(logger, "app_name", app.Name)
 
-	minExpiration, err := resolveMinExpiration(input.MinExpiration, cfg.MinExpiration)
+	minExpiration, err := resolveMinExpiration(input.MinExpiration, appCfg.MinExpiration)
 	if err != nil {
-		return nil, nil, fmt.Errorf("resolve the min expiration: %w", attrs.With(err))
+		return nil, nil, 0, fmt.Errorf("resolve the min expiration: %w", attrs.With(err))
 	}
 
-	b, err := tm.resolveBackend(logger, cfg)
+	b, err := tm.resolveBackend(ctx, logger, appCfg, prof)
 	if err != nil {
-		return nil, nil, fmt.Errorf("resolve the backend: %w", attrs.With(err))
+		return nil, nil, minExpiration, fmt.Errorf("resolve the backend: %w", attrs.With(err))
 	}
 
 	// Debug Log
@@ -160,30 +211,98 @@ func (tm *TokenManager) get(ctx context.Context, logger *slog.Logger, input *inp
 		"min_expiration", minExpiration,
 	)
 
-	token, changed, err := tm.getOrCreateToken(ctx, logger, &inputGetOrCreateToken{
+	token, err := tm.acquireToken(ctx, logger, tm.backendScope(appCfg, prof), &inputGetOrCreateToken{
+		Profile:           prof,
 		MinExpiration:     minExpiration,
 		App:               app,
 		Backend:           b,
 		EnableDeviceFlow:  input.EnableDeviceFlow,
-		SkipAccountPicker: skipAccountPicker(cfg.SkipAccountPicker),
-		OpenBrowser:       openBrowser(cfg.OpenBrowser),
-		Clipboard:         clipboard(input.Clipboard, cfg.Clipboard),
+		SkipAccountPicker: skipAccountPicker(appCfg.SkipAccountPicker),
+		OpenBrowser:       openBrowser(appCfg.OpenBrowser),
+		Clipboard:         clipboard(input.Clipboard, appCfg.Clipboard),
 	})
+	if token == nil {
+		return nil, nil, minExpiration, attrs.With(err)
+	}
+	if err != nil {
+		return publicToken(token, app), app, minExpiration, attrs.With(err)
+	}
+	return publicToken(token, app), app, minExpiration, nil
+}
+
+// obtainToken gets or creates the token, and stores it in the backend when it changed.
+// It also returns whether the token was renewed or created rather than read. A token
+// that couldn't be stored is returned with errStoreToken.
+func (tm *TokenManager) obtainToken(ctx context.Context, logger *slog.Logger, input *inputGetOrCreateToken) (*pubapi.AccessToken, bool, error) {
+	token, changed, err := tm.getOrCreateToken(ctx, logger, input)
 	if err != nil {
-		return nil, nil, fmt.Errorf("get or create token: %w", attrs.With(err))
+		return nil, false, fmt.Errorf("get or create token: %w", err)
 	}
 
 	if changed {
-		// Store the token in the backend
-		if err := b.Set(ctx, app.ClientID, &pubapi.AccessToken{
-			AccessToken:    token.AccessToken,
-			ExpirationDate: token.ExpirationDate,
-		}); err != nil {
-			return token, app, attrs.With(errStoreToken)
+		// Store the token in the backend, including the refresh token it carries: GitHub
+		// rotates the refresh token on every exchange, so the stored copy must be replaced
+		// or the next refresh fails.
+		if err := input.Backend.Set(ctx, input.App.HostName(), input.App.ClientID, token); err != nil {
+			return token, true, errStoreToken
 		}
 	}
+	return token, changed, nil
+}
 
-	return token, app, nil
+// selectApp returns the app for appName, or GHTKN_APP when it is empty, and the
+// repository, or nil when there is none.
+func (tm *TokenManager) selectApp(logger *slog.Logger, cfg *pubconfig.Config, appName, host, owner, repo string) *pubconfig.App {
+	if appName == "" {
+		appName = tm.input.Getenv(env.App)
+	}
+	logger.Debug("selecting app", "app_name", appName, "git_owner", owner, "host", host, "repository", repo)
+	return pubconfig.ResolveAppForRepository(cfg, appName, host, owner, repo)
+}
+
+// publicToken returns the copy of token handed to a caller of Get. The refresh token is
+// a long-lived credential that only the backend needs, so it is removed; its expiration
+// date is kept because it is harmless metadata. A token that doesn't record its app
+// name (one stored before the metadata existed, or minted by the agent) is given the
+// name of the app it was just read for.
+func publicToken(token *pubapi.AccessToken, app *pubconfig.App) *pubapi.AccessToken {
+	tk := *token
+	tk.RefreshToken = ""
+	if tk.AppName == "" {
+		tk.AppName = app.Name
+	}
+	return &tk
+}
+
+// newStoredToken converts a token the device flow or a refresh just minted into the form
+// stored in the backend, recording which app, host, and user it was issued for.
+func newStoredToken(tk *deviceflow.AccessToken, host, clientID, appName, login string) *pubapi.AccessToken {
+	return &pubapi.AccessToken{
+		AccessToken:                tk.AccessToken,
+		ExpirationDate:             tk.ExpirationDate,
+		RefreshToken:               tk.RefreshToken,
+		RefreshTokenExpirationDate: tk.RefreshTokenExpirationDate,
+		IssuedAt:                   tk.IssuedAt,
+		AppName:                    appName,
+		ClientID:                   clientID,
+		Login:                      login,
+		Host:                       host,
+	}
+}
+
+// lookupLogin returns the GitHub login the access token for host authenticates as, or ""
+// when it can't be looked up. The login is only metadata, so a failure must not fail
+// the token retrieval: it is logged and the field is left empty.
+func (tm *TokenManager) lookupLogin(ctx context.Context, logger *slog.Logger, host, accessToken string) string {
+	if tm.input.UserClient == nil {
+		return ""
+	}
+	login, err := tm.input.UserClient.GetLogin(ctx, host, accessToken)
+	if err != nil {
+		slogerr.WithError(logger, err).Debug("could not look up the GitHub login of the access token")
+		return ""
+	}
+	return login
 }
 
 // errStoreToken is returned when the token cannot be stored in the keyring.
@@ -194,6 +313,7 @@ var errStoreToken = errors.New("could not store the token in keyring")
 // It encapsulates the app configuration and expiration requirements
 // used internally by the getOrCreateToken function.
 type inputGetOrCreateToken struct {
+	Profile           string         // Profile the token belongs to, "" for the default profile
 	App               *pubconfig.App // App configuration containing client ID and other settings
 	Backend           Backend        // Resolved storage backend for reading and writing the token
 	MinExpiration     time.Duration  // Minimum time before expiration to consider token valid
@@ -216,8 +336,9 @@ func resolveBackendType(cfg *pubconfig.Backend) string {
 
 // resolveMinExpiration resolves the minimum time before token expiration that
 // triggers renewal. An explicit override (the -min-expiration flag) takes precedence,
-// including an explicit zero; otherwise the config's min_expiration is used (with the
-// GHTKN_MIN_EXPIRATION override already folded in by config.ApplyEnvOverrides). It
+// including an explicit zero; otherwise the config's min_expiration is used, the app's
+// over the global one (with the GHTKN_MIN_EXPIRATION override already folded into both
+// by config.ApplyEnvOverrides). It
 // defaults to zero (renew only once the token has actually expired). The config value
 // is a Go duration string such as "1h" or "30m".
 func resolveMinExpiration(override *time.Duration, cfg string) (time.Duration, error) {
@@ -281,6 +402,7 @@ func (tm *TokenManager) getOrCreateToken(ctx context.Context, logger *slog.Logge
 	create := func() (*pubapi.AccessToken, bool, error) {
 		token, changed, err := tm.createToken(ctx, logger, input.Backend, input.MinExpiration, &deviceflow.InputCreate{
 			ClientID:          input.App.ClientID,
+			Host:              input.App.HostName(),
 			AppName:           input.App.Name,
 			SkipAccountPicker: input.SkipAccountPicker,
 			OpenBrowser:       input.OpenBrowser,
@@ -303,17 +425,53 @@ func (tm *TokenManager) getOrCreateToken(ctx context.Context, logger *slog.Logge
 	}
 
 	// Get an access token from keyring
-	token, err := tm.getAccessTokenFromBackend(ctx, logger, input)
+	token, stored, err := tm.getAccessTokenFromBackend(ctx, logger, input)
 	if err != nil {
 		return nil, false, err
 	}
 	if token != nil {
 		return token, false, nil
 	}
+	// The stored token is expiring. Before falling back to the device flow (which Get
+	// may not start), renew it from its refresh token, which needs no user interaction.
+	// Auth skips this: it is an explicit re-authentication and always runs the flow.
+	if stored != nil && !input.EnableDeviceFlow {
+		if token := tm.refreshToken(ctx, logger, input, stored); token != nil {
+			return token, true, nil
+		}
+	}
 	// Create access token
 	return create()
 }
 
+// refreshToken renews an expiring token stored in a client-side backend with the
+// grant_type=refresh_token exchange. It returns nil when the token has no usable
+// refresh token or the exchange fails; a failure is logged rather than returned, so the
+// caller falls through to the device flow path exactly as it would without a refresh
+// token. The returned token must be stored, since GitHub rotated the refresh token.
+func (tm *TokenManager) refreshToken(ctx context.Context, logger *slog.Logger, input *inputGetOrCreateToken, stored *pubapi.AccessToken) *pubapi.AccessToken {
+	if !stored.HasUsableRefreshToken(time.Now()) {
+		return nil
+	}
+	tk, err := tm.input.DeviceFlow.Refresh(ctx, &deviceflow.InputRefresh{
+		ClientID:     input.App.ClientID,
+		Host:         input.App.HostName(),
+		RefreshToken: stored.RefreshToken,
+	})
+	if err != nil {
+		tm.input.Logger.FailedToRefreshAccessToken(logger, err)
+		return nil
+	}
+	logger.Debug("refreshed the access token with the stored refresh token")
+	// A refresh continues the same grant, so the token is still the same user's; only
+	// a token stored without its login needs the lookup.
+	login := stored.Login
+	if login == "" {
+		login = tm.lookupLogin(ctx, logger, input.App.HostName(), tk.AccessToken)
+	}
+	return newStoredToken(tk, input.App.HostName(), input.App.ClientID, input.App.Name, login)
+}
+
 // createToken generates a new GitHub App access token using the OAuth device flow.
 // It returns the token and whether the caller must persist it (changed).
 //
@@ -332,7 +490,7 @@ func (tm *TokenManager) createToken(ctx context.Context, logger *slog.Logger, ba
 		return nil, false, pubapi.ErrDisableDeviceFlow
 	}
 	if backend.SupportsDeviceFlow() {
-		token, deviceCode, err := backend.BeginDeviceFlow(ctx, input.ClientID, minExpiration)
+		token, deviceCode, err := backend.BeginDeviceFlow(ctx, input.Host, input.ClientID, minExpiration)
 		if err != nil {
 			return nil, false, fmt.Errorf("begin the device flow on the agent: %w", err)
 		}
@@ -347,7 +505,7 @@ func (tm *TokenManager) createToken(ctx context.Context, logger *slog.Logger, ba
 		if err := tm.input.DeviceFlow.Show(ctx, logger, input, deviceCode); err != nil {
 			return nil, false, fmt.Errorf("show the one-time code: %w", err)
 		}
-		token, err = backend.PollDeviceFlow(ctx, input.ClientID, minExpiration)
+		token, err = backend.PollDeviceFlow(ctx, input.Host, input.ClientID, minExpiration)
 		if err != nil {
 			return nil, false, fmt.Errorf("wait for the agent to mint the token: %w", err)
 		}
@@ -357,43 +515,57 @@ func (tm *TokenManager) createToken(ctx context.Context, logger *slog.Logger, ba
 	if err != nil {
 		return nil, false, err //nolint:wrapcheck
 	}
-	return &pubapi.AccessToken{
-		AccessToken:    tk.AccessToken,
-		ExpirationDate: tk.ExpirationDate,
-	}, true, nil
+	return newStoredToken(tk, input.Host, input.ClientID, input.AppName, tm.lookupLogin(ctx, logger, input.Host, tk.AccessToken)), true, nil
 }
 
 // getAccessTokenFromBackend retrieves a still-valid cached access token from the
 // backend, or nil when there is none. For a backend that owns the token lifecycle
 // (the agent) the expiration check runs server-side; otherwise it is checked here
 // against MinExpiration.
-func (tm *TokenManager) getAccessTokenFromBackend(ctx context.Context, logger *slog.Logger, input *inputGetOrCreateToken) (*pubapi.AccessToken, error) {
+//
+// When a client-side backend holds a token that is expiring, that token is returned
+// as the second value (with a nil first value) so the caller can renew it from its
+// refresh token. The agent refreshes server-side, so it never returns one.
+func (tm *TokenManager) getAccessTokenFromBackend(ctx context.Context, logger *slog.Logger, input *inputGetOrCreateToken) (*pubapi.AccessToken, *pubapi.AccessToken, error) {
 	if input.Backend.SupportsDeviceFlow() {
-		tk, err := input.Backend.GetActive(ctx, input.App.ClientID, input.MinExpiration)
+		tk, err := input.Backend.GetActive(ctx, input.App.HostName(), input.App.ClientID, input.MinExpiration)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if tk == nil {
 			tm.input.Logger.AccessTokenIsNotFoundInBackend(logger)
 		}
-		return tk, nil
+		return tk, nil, nil
 	}
 	// Get an access token from the backend
-	tk, err := input.Backend.Get(ctx, input.App.ClientID)
+	tk, err := input.Backend.Get(ctx, input.App.HostName(), input.App.ClientID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if tk == nil {
 		tm.input.Logger.AccessTokenIsNotFoundInBackend(logger)
-		return nil, nil
+		return nil, nil, nil
+	}
+	// A cached token may have been renewed by another process since it was cached, and
+	// renewing it again would use a refresh token GitHub has rotated, so an expiring one
+	// is read from the backend again.
+	if cb, ok := input.Backend.(*cachedBackend); ok && tm.checkExpired(tk.ExpirationDate, input.MinExpiration) {
+		tk, err = cb.reload(ctx, input.App.HostName(), input.App.ClientID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tk == nil {
+			tm.input.Logger.AccessTokenIsNotFoundInBackend(logger)
+			return nil, nil, nil
+		}
 	}
 	// Check if the access token expires
 	if tm.checkExpired(tk.ExpirationDate, input.MinExpiration) {
 		tm.input.Logger.Expire(logger, tk.ExpirationDate)
-		return nil, nil
+		return nil, tk, nil
 	}
 	// Not expires
-	return tk, nil
+	return tk, nil, nil
 }
 
 // maxTokenLifetime is the longest a GitHub App user access token can be valid: GitHub
@@ -422,30 +594,68 @@ func (tm *TokenManager) checkExpired(exDate time.Time, minExpiration time.Durati
 	return time.Now().Add(minExpiration).After(exDate)
 }
 
-// loadConfig reads the configuration file and folds the environment overrides into
-// it, so every caller works with the effective (file plus environment) config. The
+// loadConfig reads the configuration files and folds the environment overrides into
+// them, so every caller works with the effective (files plus environment) config. The
 // two steps are always paired: the resolvers downstream (resolveBackendType,
 // resolveMinExpiration, openBrowser, clipboard) read the config alone and would
-// silently ignore the environment if a caller read the file without this.
-func (tm *TokenManager) loadConfig(cfg *pubconfig.Config, configFilePath string) error {
-	if err := tm.readConfig(cfg, configFilePath); err != nil {
-		return err
+// silently ignore the environment if a caller read the files without this. When
+// WatchConfig watches configFilePath, the config it keeps is used instead of reading
+// the files again.
+func (tm *TokenManager) loadConfig(logger *slog.Logger, cfg *pubconfig.Config, configFilePath string) error {
+	if wc := tm.watched.Load(); wc != nil && wc.path == configFilePath {
+		*cfg = *wc.watcher.Config()
+		return nil
+	}
+	_, err := tm.loadConfigFiles(logger, cfg, configFilePath)
+	return err
+}
+
+// loadConfigFiles is loadConfig that always reads the files, and returns what it read.
+func (tm *TokenManager) loadConfigFiles(logger *slog.Logger, cfg *pubconfig.Config, configFilePath string) (*config.LoadResult, error) {
+	result, err := tm.readConfig(logger, cfg, configFilePath)
+	if err != nil {
+		return nil, err
 	}
 	if err := config.ApplyEnvOverrides(cfg, tm.input.Getenv); err != nil {
-		return fmt.Errorf("apply environment overrides: %w", err)
+		return nil, fmt.Errorf("apply environment overrides: %w", err)
 	}
-	return nil
+	return result, nil
 }
 
-// readConfig loads and validates the configuration from the configured file path.
-// It returns an error if the configuration cannot be read or is invalid. It is the
-// plain file read; use loadConfig to get the effective config.
-func (tm *TokenManager) readConfig(cfg *pubconfig.Config, configFilePath string) error {
-	if err := tm.input.ConfigReader.Read(cfg, configFilePath); err != nil {
-		return fmt.Errorf("read config: %w", slogerr.With(err, "config", configFilePath))
+// readConfig loads and validates the layered configuration: the system config file,
+// the user config file at configFilePath, and the trusted project config files (see
+// config.Load). It returns an error if the configuration cannot be read or is invalid.
+// It is the plain file read; use loadConfig to get the effective config.
+func (tm *TokenManager) readConfig(logger *slog.Logger, cfg *pubconfig.Config, configFilePath string) (*config.LoadResult, error) {
+	result, err := config.Load(cfg, &config.InputLoad{
+		Reader:   tm.input.ConfigReader,
+		Getenv:   tm.input.Getenv,
+		GOOS:     tm.input.GOOS,
+		UserPath: configFilePath,
+		Dir:      tm.workingDir(logger),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	for _, p := range result.Untrusted {
+		tm.input.Logger.IgnoredUntrustedProjectConfig(logger, p)
 	}
 	if err := cfg.Validate(); err != nil {
-		return fmt.Errorf("validate config: %w", err)
+		return nil, fmt.Errorf("validate config: %w", config.Locate(err, result.Origins))
+	}
+	return result, nil
+}
+
+// workingDir returns the directory project config files are looked for from, or ""
+// when it is unknown, in which case they aren't read.
+func (tm *TokenManager) workingDir(logger *slog.Logger) string {
+	if tm.input.Getwd == nil {
+		return ""
+	}
+	dir, err := tm.input.Getwd()
+	if err != nil {
+		slogerr.WithError(logger, err).Warn("failed to get the working directory, so project config files are not read")
+		return ""
 	}
-	return nil
+	return dir
 }
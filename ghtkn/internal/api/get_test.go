@@ -14,6 +14,7 @@ import (
 	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
 	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
 	pubdeviceflow "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/deviceflow"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/gitrepo"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/deviceflow"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
 )
@@ -27,12 +28,24 @@ func newMockInput() *Input {
 			},
 		},
 		Backend:      &mockKeyring{},
+		UserClient:   &mockUserClient{login: "octocat"},
 		Logger:       log.NewLogger(),
 		ConfigReader: &mockConfigReader{},
 		Getenv:       func(key string) string { return "" },
 	}
 }
 
+type mockUserClient struct {
+	login   string
+	err     error
+	gotHost string
+}
+
+func (m *mockUserClient) GetLogin(_ context.Context, host, _ string) (string, error) {
+	m.gotHost = host
+	return m.login, m.err
+}
+
 type mockKeyring struct {
 	token   *pubapi.AccessToken
 	err     error
@@ -41,13 +54,17 @@ type mockKeyring struct {
 	// stored is the last token written by Set. Auth returns no token, so this is how a
 	// test sees what it produced.
 	stored *pubapi.AccessToken
+	// hosts records the host of each Get and Set call.
+	hosts []string
 }
 
-func (m *mockKeyring) Get(_ context.Context, _ string) (*pubapi.AccessToken, error) {
+func (m *mockKeyring) Get(_ context.Context, host, _ string) (*pubapi.AccessToken, error) {
+	m.hosts = append(m.hosts, host)
 	return m.token, m.err
 }
 
-func (m *mockKeyring) Set(_ context.Context, _ string, token *pubapi.AccessToken) error {
+func (m *mockKeyring) Set(_ context.Context, host, _ string, token *pubapi.AccessToken) error {
+	m.hosts = append(m.hosts, host)
 	if m.err != nil {
 		return m.err
 	}
@@ -55,7 +72,7 @@ func (m *mockKeyring) Set(_ context.Context, _ string, token *pubapi.AccessToken
 	return nil
 }
 
-func (m *mockKeyring) Delete(_ context.Context, clientID string) error {
+func (m *mockKeyring) Delete(_ context.Context, _, clientID string) error {
 	if m.delErr != nil {
 		return m.delErr
 	}
@@ -68,19 +85,19 @@ func (m *mockKeyring) Delete(_ context.Context, clientID string) error {
 // PollDeviceFlow/RevokeTokens are never called.
 func (m *mockKeyring) SupportsDeviceFlow() bool { return false }
 
-func (m *mockKeyring) GetActive(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
+func (m *mockKeyring) GetActive(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
 	return nil, errors.New("GetActive should not be called")
 }
 
-func (m *mockKeyring) BeginDeviceFlow(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, *pubdeviceflow.DeviceCodeResponse, error) {
+func (m *mockKeyring) BeginDeviceFlow(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, *pubdeviceflow.DeviceCodeResponse, error) {
 	return nil, nil, errors.New("BeginDeviceFlow should not be called")
 }
 
-func (m *mockKeyring) PollDeviceFlow(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
+func (m *mockKeyring) PollDeviceFlow(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
 	return nil, errors.New("PollDeviceFlow should not be called")
 }
 
-func (m *mockKeyring) RevokeTokens(_ context.Context, _ []string) (revokeFailed, cleanupFailed []string, err error) {
+func (m *mockKeyring) RevokeTokens(_ context.Context, _ string, _ []string) (revokeFailed, cleanupFailed []string, err error) {
 	return nil, nil, errors.New("RevokeTokens should not be called")
 }
 
@@ -155,6 +172,9 @@ func TestTokenManager_Get(t *testing.T) {
 			wantToken: &pubapi.AccessToken{
 				AccessToken:    "cached-token",
 				ExpirationDate: futureTime,
+				// A token stored without its app name is returned with the name of the app
+				// it was read for.
+				AppName: "test-app",
 			},
 		},
 		{
@@ -237,6 +257,177 @@ func TestTokenManager_Get(t *testing.T) {
 	}
 }
 
+// TestTokenManager_Get_refresh verifies that on a client-side backend an expiring
+// token is renewed from its stored refresh token instead of failing with
+// ErrDisableDeviceFlow, that the rotated refresh token is stored, and that the token
+// handed to the caller carries no refresh token.
+func TestTokenManager_Get_refresh(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		stored     func() *pubapi.AccessToken
+		refreshErr error
+		wantErrIs  error
+		// wantRefreshedWith is the refresh token the exchange must be called with, or ""
+		// when it must not be called at all.
+		wantRefreshedWith string
+	}{
+		{
+			name: "an expired token is refreshed",
+			stored: func() *pubapi.AccessToken {
+				return &pubapi.AccessToken{
+					AccessToken:                "expired-token",
+					ExpirationDate:             time.Now().Add(-time.Hour),
+					RefreshToken:               "ghr_old",
+					RefreshTokenExpirationDate: time.Now().Add(time.Hour),
+					Login:                      "alice",
+				}
+			},
+			wantRefreshedWith: "ghr_old",
+		},
+		{
+			name: "an expired refresh token is not used",
+			stored: func() *pubapi.AccessToken {
+				return &pubapi.AccessToken{
+					AccessToken:                "expired-token",
+					ExpirationDate:             time.Now().Add(-time.Hour),
+					RefreshToken:               "ghr_old",
+					RefreshTokenExpirationDate: time.Now().Add(-time.Minute),
+				}
+			},
+			wantErrIs: pubapi.ErrDisableDeviceFlow,
+		},
+		{
+			name: "a failed refresh falls back to ErrDisableDeviceFlow",
+			stored: func() *pubapi.AccessToken {
+				return &pubapi.AccessToken{
+					AccessToken:    "expired-token",
+					ExpirationDate: time.Now().Add(-time.Hour),
+					RefreshToken:   "ghr_old",
+				}
+			},
+			refreshErr:        errors.New("bad_refresh_token"),
+			wantErrIs:         pubapi.ErrDisableDeviceFlow,
+			wantRefreshedWith: "ghr_old",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// See TestTokenManager_Get for why this runs under synctest.
+			synctest.Test(t, func(t *testing.T) {
+				refreshed := &deviceflow.AccessToken{
+					AccessToken:                "refreshed-token",
+					IssuedAt:                   time.Now(),
+					ExpirationDate:             time.Now().Add(8 * time.Hour),
+					RefreshToken:               "ghr_new",
+					RefreshTokenExpirationDate: time.Now().Add(24 * time.Hour),
+				}
+				df := &mockDeviceFlow{refreshed: refreshed, refreshErr: tt.refreshErr}
+				backend := &mockKeyring{token: tt.stored()}
+				input := newMockInput()
+				input.DeviceFlow = df
+				input.Backend = backend
+				logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+				token, _, err := New(input).Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: "/path/to/config.yaml"})
+				if df.gotRefreshToken != tt.wantRefreshedWith {
+					t.Errorf("refreshed with %q, want %q", df.gotRefreshToken, tt.wantRefreshedWith)
+				}
+				if tt.wantErrIs != nil {
+					if !errors.Is(err, tt.wantErrIs) {
+						t.Fatalf("error = %v, want it to wrap %v", err, tt.wantErrIs)
+					}
+					if backend.stored != nil {
+						t.Errorf("stored %v, want nothing stored", backend.stored)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				// The refreshed token is the same user's, so the stored login is kept
+				// rather than looked up again.
+				wantStored := &pubapi.AccessToken{
+					AccessToken:                refreshed.AccessToken,
+					ExpirationDate:             refreshed.ExpirationDate,
+					RefreshToken:               refreshed.RefreshToken,
+					RefreshTokenExpirationDate: refreshed.RefreshTokenExpirationDate,
+					IssuedAt:                   refreshed.IssuedAt,
+					AppName:                    "test-app",
+					ClientID:                   "xxx",
+					Login:                      "alice",
+					Host:                       "github.com",
+				}
+				wantToken := *wantStored
+				wantToken.RefreshToken = ""
+				if diff := cmp.Diff(&wantToken, token); diff != "" {
+					t.Errorf("returned token mismatch (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff(wantStored, backend.stored); diff != "" {
+					t.Errorf("stored token mismatch (-want +got):\n%s", diff)
+				}
+			})
+		})
+	}
+}
+
+// TestTokenManager_Get_host verifies that the token of an app on a GitHub Enterprise
+// Server is read, refreshed, looked up, and stored on the app's host, and that the
+// repository host given with the owner selects the app on that host.
+func TestTokenManager_Get_host(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		df := &mockDeviceFlow{refreshed: &deviceflow.AccessToken{
+			AccessToken:    "refreshed-token",
+			ExpirationDate: time.Now().Add(8 * time.Hour),
+		}}
+		backend := &mockKeyring{token: &pubapi.AccessToken{
+			AccessToken:    "expired-token",
+			ExpirationDate: time.Now().Add(-time.Hour),
+			RefreshToken:   "ghr_old",
+		}}
+		userClient := &mockUserClient{login: "octocat"}
+		input := newMockInput()
+		input.DeviceFlow = df
+		input.Backend = backend
+		input.UserClient = userClient
+		input.ConfigReader = &multiConfigReader{apps: []*pubconfig.App{
+			{Name: "dotcom", ClientID: "Iv1.dotcom", GitOwner: "acme"},
+			{Name: "ghes", ClientID: "Iv1.ghes", GitOwner: "acme", Host: "GHES.example.com"},
+		}}
+		logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+		token, app, err := New(input).Get(t.Context(), logger, &pubapi.InputGet{
+			ConfigFilePath: "/path/to/config.yaml",
+			AppOwner:       "acme",
+			Host:           "ghes.example.com",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if app.Name != "ghes" {
+			t.Errorf("app = %q, want ghes", app.Name)
+		}
+		if token.Host != "ghes.example.com" {
+			t.Errorf("token host = %q, want ghes.example.com", token.Host)
+		}
+		if diff := cmp.Diff([]string{"ghes.example.com", "ghes.example.com"}, backend.hosts); diff != "" {
+			t.Errorf("backend hosts mismatch (-want +got):\n%s", diff)
+		}
+		if df.gotHost != "ghes.example.com" {
+			t.Errorf("refreshed on %q, want ghes.example.com", df.gotHost)
+		}
+		if userClient.gotHost != "ghes.example.com" {
+			t.Errorf("looked up the login on %q, want ghes.example.com", userClient.gotHost)
+		}
+	})
+}
+
 func TestTokenManager_Auth(t *testing.T) {
 	t.Parallel()
 
@@ -250,6 +441,10 @@ func TestTokenManager_Auth(t *testing.T) {
 	wantStored := &pubapi.AccessToken{
 		AccessToken:    newToken.AccessToken,
 		ExpirationDate: newToken.ExpirationDate,
+		AppName:        "test-app",
+		ClientID:       "xxx",
+		Login:          "octocat",
+		Host:           "github.com",
 	}
 
 	tests := []struct {
@@ -310,6 +505,44 @@ func TestTokenManager_Auth(t *testing.T) {
 			input:      &pubapi.InputAuth{ConfigFilePath: "/path/to/config.yaml"},
 			wantStored: wantStored,
 		},
+		{
+			// The login is only metadata: failing to look it up must not fail Auth.
+			name: "a failed login lookup leaves the login empty",
+			setupInput: func() *Input {
+				input := newMockInput()
+				input.DeviceFlow = &mockDeviceFlow{token: newToken}
+				input.Backend = &mockKeyring{}
+				input.UserClient = &mockUserClient{err: errors.New("bad credentials")}
+				return input
+			},
+			input: &pubapi.InputAuth{ConfigFilePath: "/path/to/config.yaml"},
+			wantStored: &pubapi.AccessToken{
+				AccessToken:    newToken.AccessToken,
+				ExpirationDate: newToken.ExpirationDate,
+				AppName:        "test-app",
+				ClientID:       "xxx",
+				Host:           "github.com",
+			},
+		},
+		{
+			// Auth is an explicit re-authentication, so it runs the device flow even when
+			// the stored refresh token could renew the token silently.
+			name: "a refresh token is not used",
+			setupInput: func() *Input {
+				input := newMockInput()
+				input.DeviceFlow = &mockDeviceFlow{token: newToken, refreshErr: errors.New("Refresh should not be called")}
+				input.Backend = &mockKeyring{
+					token: &pubapi.AccessToken{
+						AccessToken:    "expired-token",
+						ExpirationDate: time.Now().Add(-time.Hour),
+						RefreshToken:   "ghr_old",
+					},
+				}
+				return input
+			},
+			input:      &pubapi.InputAuth{ConfigFilePath: "/path/to/config.yaml"},
+			wantStored: wantStored,
+		},
 		{
 			name: "token creation error",
 			setupInput: func() *Input {
@@ -408,3 +641,40 @@ func TestClipboard(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveRepository(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   *pubapi.InputGet
+		want    *gitrepo.Repository
+		wantErr bool
+	}{
+		{name: "nothing", input: &pubapi.InputGet{}, want: &gitrepo.Repository{}},
+		{name: "owner and host", input: &pubapi.InputGet{AppOwner: "acme", Host: "ghes.example.com", RepositoryName: "infra"}, want: &gitrepo.Repository{Host: "ghes.example.com", Owner: "acme", Name: "infra"}},
+		{name: "repository URL", input: &pubapi.InputGet{RepositoryURL: "git@ghes.example.com:acme/repo.git"}, want: &gitrepo.Repository{Host: "ghes.example.com", Owner: "acme", Name: "repo"}},
+		{name: "owner wins over repository URL", input: &pubapi.InputGet{AppOwner: "acme", RepositoryURL: "https://github.com/other/repo"}, want: &gitrepo.Repository{Owner: "acme"}},
+		{name: "repository URL wins over repository directory", input: &pubapi.InputGet{RepositoryURL: "https://github.com/acme/repo", RepositoryDir: "/nonexistent"}, want: &gitrepo.Repository{Host: "github.com", Owner: "acme", Name: "repo"}},
+		{name: "invalid repository URL", input: &pubapi.InputGet{RepositoryURL: "https://github.com/acme"}, wantErr: true},
+		{name: "repository directory outside a working tree", input: &pubapi.InputGet{RepositoryDir: "/"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ResolveRepository(tt.input)
+			if err != nil {
+				if !tt.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("expected an error but got nil")
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
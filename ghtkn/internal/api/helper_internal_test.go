@@ -38,6 +38,10 @@ func (m *testDeviceFlow) Show(_ context.Context, _ *slog.Logger, _ *deviceflow.I
 	return m.showErr
 }
 
+func (m *testDeviceFlow) Refresh(_ context.Context, _ *deviceflow.InputRefresh) (*deviceflow.AccessToken, error) {
+	return nil, errors.New("Refresh should not be called")
+}
+
 func (m *testDeviceFlow) SetLogger(_ *publog.Logger) {}
 
 func (m *testDeviceFlow) SetOnetimeCodeUI(_ pubdeviceflow.OnetimeCodeUI) {}
@@ -230,34 +234,34 @@ type agentBackend struct {
 	revoked       []string // client IDs passed to RevokeTokens, in order
 }
 
-func (b *agentBackend) Get(_ context.Context, _ string) (*pubapi.AccessToken, error) {
+func (b *agentBackend) Get(_ context.Context, _, _ string) (*pubapi.AccessToken, error) {
 	return nil, nil
 }
 
-func (b *agentBackend) Set(_ context.Context, _ string, _ *pubapi.AccessToken) error {
+func (b *agentBackend) Set(_ context.Context, _, _ string, _ *pubapi.AccessToken) error {
 	b.setCalled = true
 	return nil
 }
 
-func (b *agentBackend) Delete(_ context.Context, _ string) error { return nil }
+func (b *agentBackend) Delete(_ context.Context, _, _ string) error { return nil }
 
 func (b *agentBackend) SupportsDeviceFlow() bool { return true }
 
-func (b *agentBackend) GetActive(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
+func (b *agentBackend) GetActive(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
 	b.getActiveCalls++
 	return b.active, nil
 }
 
-func (b *agentBackend) BeginDeviceFlow(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, *pubdeviceflow.DeviceCodeResponse, error) {
+func (b *agentBackend) BeginDeviceFlow(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, *pubdeviceflow.DeviceCodeResponse, error) {
 	b.beginCalls++
 	return b.begun, b.deviceCode, nil
 }
 
-func (b *agentBackend) PollDeviceFlow(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
+func (b *agentBackend) PollDeviceFlow(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
 	return b.polled, nil
 }
 
-func (b *agentBackend) RevokeTokens(_ context.Context, clientIDs []string) (revokeFailed, cleanupFailed []string, err error) {
+func (b *agentBackend) RevokeTokens(_ context.Context, _ string, clientIDs []string) (revokeFailed, cleanupFailed []string, err error) {
 	b.revoked = append(b.revoked, clientIDs...)
 	return b.revokeFailed, b.cleanupFailed, b.revokeErr
 }
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+// lister is implemented by a Backend that can enumerate the tokens it stores; it
+// returns backend.ErrListUnsupported when its inner backend can't.
+type lister interface {
+	List(ctx context.Context) ([]pubbackend.TokenKey, error)
+}
+
+// ListTokens returns the tokens stored in the backends the config selects: the global
+// backend and the backends apps override it with. The token of each app using a backend
+// is looked up, and a backend that can enumerate its tokens (the keyring, text, file,
+// and agent backends) lists the others too, such as the tokens of apps no longer in the
+// config.
+//
+// The tokens are read from the backends to describe them, but the returned
+// descriptions never carry the token or the refresh token. A failure for one backend
+// does not stop the others: the tokens listed are returned with the failures
+// aggregated with errors.Join.
+func (tm *TokenManager) ListTokens(ctx context.Context, logger *slog.Logger, input *pubapi.InputListTokens) ([]*pubapi.StoredToken, error) {
+	if input == nil {
+		input = &pubapi.InputListTokens{}
+	}
+	cfg := &pubconfig.Config{}
+	prof, err := tm.profile(input.Profile)
+	if err != nil {
+		return nil, err
+	}
+	configPath, err := tm.resolveConfigPath(input.ConfigFilePath, prof)
+	if err != nil {
+		return nil, err
+	}
+	if err := tm.loadConfig(logger, cfg, configPath); err != nil {
+		return nil, err
+	}
+
+	// The backends are listed in the order they first appear: the global one, then the
+	// ones of the apps.
+	var backendTypes []string
+	backendCfgs := map[string]*pubconfig.Config{}
+	appsByBackend := map[string][]*pubconfig.App{}
+	addBackend := func(typ string, c *pubconfig.Config) {
+		if _, ok := backendCfgs[typ]; !ok {
+			backendTypes = append(backendTypes, typ)
+			backendCfgs[typ] = c
+		}
+	}
+	addBackend(tm.listBackendType(cfg.Backend), cfg)
+	for _, app := range cfg.Apps {
+		appCfg := cfg.ForApp(app)
+		typ := tm.listBackendType(appCfg.Backend)
+		addBackend(typ, appCfg)
+		appsByBackend[typ] = append(appsByBackend[typ], app)
+	}
+
+	var tokens []*pubapi.StoredToken
+	var errs []error
+	for _, typ := range backendTypes {
+		ts, err := tm.listTokens(ctx, logger, backendCfgs[typ], cfg, appsByBackend[typ], typ, prof)
+		for _, token := range ts {
+			// Two backend types, such as auto and the keyring it selected, may be the
+			// same backend.
+			if !slices.ContainsFunc(tokens, func(t *pubapi.StoredToken) bool {
+				return t.Backend == token.Backend && t.Host == token.Host && t.ClientID == token.ClientID
+			}) {
+				tokens = append(tokens, token)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("list tokens: %w", slogerr.With(err, "backend", typ)))
+		}
+	}
+	return tokens, errors.Join(errs...)
+}
+
+// listBackendType returns the backend type the tokens stored with cfg are listed
+// under: backend.type with the default spelled out as keyring, or empty for an
+// injected backend.
+func (tm *TokenManager) listBackendType(cfg *pubconfig.Backend) string {
+	if tm.input.Backend != nil {
+		return ""
+	}
+	if typ := resolveBackendType(cfg); typ != "" {
+		return typ
+	}
+	return "keyring"
+}
+
+// listTokens returns the tokens stored in the backend backendCfg selects, described
+// with the apps of cfg. apps are the apps using the backend, whose tokens are looked up
+// when the backend can't enumerate its tokens.
+func (tm *TokenManager) listTokens(ctx context.Context, logger *slog.Logger, backendCfg, cfg *pubconfig.Config, apps []*pubconfig.App, typ, prof string) ([]*pubapi.StoredToken, error) {
+	b, err := tm.resolveBackend(ctx, logger, backendCfg, prof)
+	if err != nil {
+		return nil, fmt.Errorf("resolve the backend: %w", err)
+	}
+	typ = tm.selectedBackendType(prof, typ)
+	keys, err := listKeys(ctx, b, apps)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []*pubapi.StoredToken
+	var errs []error
+	for _, key := range keys {
+		token, err := b.Get(ctx, key.Host, key.ClientID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get a stored token from the backend: %w", slogerr.With(err, "client_id", key.ClientID, "host", key.Host)))
+			continue
+		}
+		if token == nil {
+			continue
+		}
+		tokens = append(tokens, storedToken(cfg, typ, key, token))
+	}
+	return tokens, errors.Join(errs...)
+}
+
+// selectedBackendType returns the backend type typ resolves to in the profile prof: the
+// backend selected from it when it falls back through backends, such as auto (see
+// selectBackend), and typ itself otherwise.
+func (tm *TokenManager) selectedBackendType(prof, typ string) string {
+	if selected, ok := tm.selectedBackends.Load(backendScope{profile: prof, backend: typ}); ok {
+		return selected.(string) //nolint:forcetypeassert
+	}
+	return typ
+}
+
+// listKeys returns the keys of the tokens b stores followed by the keys the tokens of
+// apps would be stored under, without duplicates. The keys of apps are included even
+// when b can enumerate its tokens, since an enumeration may miss some, such as the
+// tokens the keyring stored before it kept an index of them.
+func listKeys(ctx context.Context, b Backend, apps []*pubconfig.App) ([]pubbackend.TokenKey, error) {
+	var keys []pubbackend.TokenKey
+	if l, ok := b.(lister); ok {
+		listed, err := l.List(ctx)
+		if err != nil && !errors.Is(err, backend.ErrListUnsupported) {
+			return nil, err //nolint:wrapcheck
+		}
+		for _, key := range listed {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, app := range apps {
+		key := pubbackend.TokenKey{Host: app.HostName(), ClientID: app.ClientID}
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// storedToken describes token, stored under key in the backend of the type typ. The app name is
+// the name of the app of cfg with the token's client ID and host, if any.
+func storedToken(cfg *pubconfig.Config, typ string, key pubbackend.TokenKey, token *pubapi.AccessToken) *pubapi.StoredToken {
+	appName := token.AppName
+	for _, app := range cfg.Apps {
+		if app.ClientID == key.ClientID && app.HostName() == key.Host {
+			appName = app.Name
+			break
+		}
+	}
+	return &pubapi.StoredToken{
+		Backend:                    typ,
+		AppName:                    appName,
+		ClientID:                   key.ClientID,
+		Host:                       key.Host,
+		Login:                      token.Login,
+		IssuedAt:                   token.IssuedAt,
+		ExpirationDate:             token.ExpirationDate,
+		HasRefreshToken:            token.RefreshToken != "",
+		RefreshTokenExpirationDate: token.RefreshTokenExpirationDate,
+	}
+}
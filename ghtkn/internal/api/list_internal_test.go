@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
+)
+
+// mapBackend is a backend storing tokens in a map, which can't enumerate them.
+type mapBackend map[string]string
+
+func (m mapBackend) Get(_ context.Context, host, clientID string) ([]byte, error) {
+	s, ok := m[pubapi.StorageKey(host, clientID)]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(s), nil
+}
+
+func (m mapBackend) Set(_ context.Context, host, clientID, token string) error {
+	m[pubapi.StorageKey(host, clientID)] = token
+	return nil
+}
+
+func (m mapBackend) Delete(_ context.Context, host, clientID string) error {
+	delete(m, pubapi.StorageKey(host, clientID))
+	return nil
+}
+
+// indexedBackend is a mapBackend that enumerates only the tokens in index, like the
+// keyring missing the tokens stored before it kept an index.
+type indexedBackend struct {
+	mapBackend
+
+	index []pubbackend.TokenKey
+}
+
+func (b *indexedBackend) List(context.Context) ([]pubbackend.TokenKey, error) {
+	return b.index, nil
+}
+
+func TestTokenManager_ListTokens(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string]string{
+		"Iv1.x": `{"access_token":"gho_x","refresh_token":"ghr_x","expiration_date":"2999-01-01T00:00:00Z","login":"octocat"}`,
+		// The token of an app no longer in the config is listed too.
+		"Iv1.gone@ghes.example.com": `{"access_token":"gho_gone","expiration_date":"2999-02-01T00:00:00Z","app_name":"gone"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	getEnv := func(k string) string {
+		switch k {
+		case "GHTKN_BACKEND":
+			return "text"
+		case "GHTKN_TEXT_BACKEND_DIR":
+			return dir
+		default:
+			return ""
+		}
+	}
+	tm := New(&Input{
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       log.NewLogger(),
+		Getenv:       getEnv,
+		GOOS:         "linux",
+	})
+	got, err := tm.ListTokens(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputListTokens{
+		ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+	})
+	if err != nil {
+		t.Fatalf("ListTokens() error: %v", err)
+	}
+	want := []*pubapi.StoredToken{
+		{
+			Backend:        "text",
+			AppName:        "gone",
+			ClientID:       "Iv1.gone",
+			Host:           "ghes.example.com",
+			ExpirationDate: time.Date(2999, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Backend:         "text",
+			AppName:         "app1",
+			ClientID:        "Iv1.x",
+			Host:            "github.com",
+			Login:           "octocat",
+			ExpirationDate:  time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC),
+			HasRefreshToken: true,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListTokens() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestTokenManager_ListTokens_unlistable verifies that the tokens of a backend that
+// can't enumerate them are looked up by the apps in the config.
+func TestTokenManager_ListTokens_unlistable(t *testing.T) {
+	t.Parallel()
+	m := mapBackend{
+		"Iv1.x":       `{"access_token":"gho_x","expiration_date":"2999-01-01T00:00:00Z"}`,
+		"Iv1.unknown": `{"access_token":"gho_unknown","expiration_date":"2999-01-01T00:00:00Z"}`,
+	}
+	tm := New(&Input{
+		Backend:      backend.Wrap(m),
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       log.NewLogger(),
+		Getenv:       func(string) string { return "" },
+		GOOS:         "linux",
+	})
+	got, err := tm.ListTokens(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputListTokens{
+		ConfigFilePath: "config.yaml",
+	})
+	if err != nil {
+		t.Fatalf("ListTokens() error: %v", err)
+	}
+	want := []*pubapi.StoredToken{{
+		AppName:        "app1",
+		ClientID:       "Iv1.x",
+		Host:           "github.com",
+		ExpirationDate: time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListTokens() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestTokenManager_ListTokens_unindexed verifies that the token of an app in the config
+// is listed even when the backend's enumeration misses it.
+func TestTokenManager_ListTokens_unindexed(t *testing.T) {
+	t.Parallel()
+	b := &indexedBackend{
+		mapBackend: mapBackend{
+			"Iv1.x":     `{"access_token":"gho_x","expiration_date":"2999-01-01T00:00:00Z"}`,
+			"Iv1.other": `{"access_token":"gho_other","expiration_date":"2999-02-01T00:00:00Z","app_name":"other"}`,
+		},
+		index: []pubbackend.TokenKey{{Host: "github.com", ClientID: "Iv1.other"}},
+	}
+	tm := New(&Input{
+		Backend:      backend.Wrap(b),
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       log.NewLogger(),
+		Getenv:       func(string) string { return "" },
+		GOOS:         "linux",
+	})
+	got, err := tm.ListTokens(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputListTokens{
+		ConfigFilePath: "config.yaml",
+	})
+	if err != nil {
+		t.Fatalf("ListTokens() error: %v", err)
+	}
+	want := []*pubapi.StoredToken{
+		{
+			AppName:        "other",
+			ClientID:       "Iv1.other",
+			Host:           "github.com",
+			ExpirationDate: time.Date(2999, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			AppName:        "app1",
+			ClientID:       "Iv1.x",
+			Host:           "github.com",
+			ExpirationDate: time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListTokens() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestTokenManager_ListTokens_selected verifies that the tokens of a backend selected
+// from a list of backends are listed under the type of the backend selected.
+func TestTokenManager_ListTokens_selected(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Iv1.x"), []byte(`{"access_token":"gho_x","expiration_date":"2999-01-01T00:00:00Z"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	getEnv := func(k string) string {
+		switch k {
+		case "GHTKN_BACKEND":
+			// The exec backend is unusable without GHTKN_EXEC_BACKEND_COMMAND.
+			return "exec,text"
+		case "GHTKN_TEXT_BACKEND_DIR":
+			return dir
+		default:
+			return ""
+		}
+	}
+	tm := New(&Input{
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       log.NewLogger(),
+		Getenv:       getEnv,
+		GOOS:         "linux",
+	})
+	got, err := tm.ListTokens(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputListTokens{
+		ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+	})
+	if err != nil {
+		t.Fatalf("ListTokens() error: %v", err)
+	}
+	want := []*pubapi.StoredToken{{
+		Backend:        "text",
+		AppName:        "app1",
+		ClientID:       "Iv1.x",
+		Host:           "github.com",
+		ExpirationDate: time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListTokens() mismatch (-want +got):\n%s", diff)
+	}
+}
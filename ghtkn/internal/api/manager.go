@@ -4,8 +4,12 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
@@ -14,15 +18,27 @@ import (
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/deviceflow"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/github"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
 	publog "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
-	"github.com/suzuki-shunsuke/go-revoke-github-access-token/revoke"
 )
 
 // TokenManager manages the process of retrieving GitHub App access tokens.
 // It coordinates between configuration reading, token caching, and token generation.
 type TokenManager struct {
 	input *Input
+	// watched is the config WatchConfig keeps in memory, nil when none is watched.
+	watched atomic.Pointer[watchedConfig]
+	// cache is the TokenCache tokens of client-side backends are kept in, nil when
+	// they aren't cached.
+	cache atomic.Pointer[TokenCache]
+	// selectedBackends maps a backend type falling back through backends (auto or a
+	// list), in a profile, to the backend selected from it.
+	selectedBackends sync.Map
+	// flights are the acquisitions of tokens in progress, which callers acquiring the
+	// same token join.
+	flights tokenFlights
 }
 
 // New creates a new Controller instance with the provided input configuration.
@@ -36,13 +52,19 @@ func New(input *Input) *TokenManager {
 // It encapsulates file system access, configuration reading, token generation, and output handling.
 // The IsGitCredential flag determines whether to format output for Git's credential helper protocol.
 type Input struct {
-	DeviceFlow   deviceFlow // Client for creating GitHub App tokens
-	Backend      Backend    // Keyring for token storage
-	Revoker      revoker    // Client for revoking credentials
+	DeviceFlow deviceFlow // Client for creating GitHub App tokens
+	Backend    Backend    // Keyring for token storage
+	Revoker    revoker    // Client for revoking credentials
+	// UserClient looks up the GitHub login a newly minted token belongs to, which is
+	// recorded with the token. It is optional: when nil the login is left empty.
+	UserClient   userClient
 	Logger       *publog.Logger
 	ConfigReader configReader
 	Getenv       func(string) string
 	GOOS         string
+	// Getwd returns the directory project config files are looked for from. It is
+	// optional: when nil project config files aren't read.
+	Getwd func() (string, error)
 }
 
 // NewInput creates a new Input instance with default production values.
@@ -54,11 +76,13 @@ type Input struct {
 func NewInput(getEnv func(string) string) (*Input, error) {
 	return &Input{
 		DeviceFlow:   deviceflow.NewClient(deviceflow.NewInput()),
-		Revoker:      revoke.New(nil),
+		Revoker:      github.NewRevoker(nil),
+		UserClient:   github.New(nil),
 		Logger:       log.NewLogger(),
-		ConfigReader: config.NewReader(),
+		ConfigReader: config.NewReader(getEnv),
 		Getenv:       getEnv,
 		GOOS:         runtime.GOOS,
+		Getwd:        os.Getwd,
 	}, nil
 }
 
@@ -67,12 +91,74 @@ func NewInput(getEnv func(string) string) (*Input, error) {
 // Otherwise the backend is built from cfg's backend.type, defaulting to the OS
 // keyring. cfg must be the effective config (see loadConfig): GHTKN_BACKEND is folded
 // into backend.type upstream, so a cfg read straight from the file selects the wrong
-// backend.
-func (tm *TokenManager) resolveBackend(logger *slog.Logger, cfg *pubconfig.Config) (Backend, error) {
+// backend. The backend is served from the token cache when one is set.
+//
+// When backend.type is auto or a list of backends, the first usable one is selected
+// (see backend.Select). The selection is made once per list and kept, so the backends
+// aren't probed on every call.
+func (tm *TokenManager) resolveBackend(ctx context.Context, logger *slog.Logger, cfg *pubconfig.Config, prof string) (Backend, error) {
 	if tm.input.Backend != nil {
-		return tm.input.Backend, nil
+		return tm.withCache(tm.input.Backend, backendScope{profile: prof, backend: tm.input.Backend}), nil
+	}
+	typ := resolveBackendType(cfg.Backend)
+	candidates, err := backend.Candidates(typ)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if len(candidates) > 0 {
+		return tm.selectBackend(ctx, logger, prof, typ, candidates)
+	}
+	b, err := backend.New(typ, tm.getenv(prof), tm.input.Logger, logger)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if typ == "" {
+		typ = "keyring"
+	}
+	return tm.withCache(b, backendScope{profile: prof, backend: typ}), nil
+}
+
+// selectBackend returns the first usable backend of candidates, the backends the
+// backend type list falls back through. A backend selected before for list is used
+// without probing again.
+func (tm *TokenManager) selectBackend(ctx context.Context, logger *slog.Logger, prof, list string, candidates []string) (Backend, error) {
+	key := backendScope{profile: prof, backend: list}
+	if typ, ok := tm.selectedBackends.Load(key); ok {
+		b, err := backend.New(typ.(string), tm.getenv(prof), tm.input.Logger, logger) //nolint:forcetypeassert
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		return tm.withCache(b, backendScope{profile: prof, backend: typ}), nil
+	}
+	b, typ, err := backend.Select(ctx, candidates, tm.getenv(prof), tm.input.Logger, logger)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
 	}
-	return backend.New(resolveBackendType(cfg.Backend), tm.input.Getenv, tm.input.Logger, logger)
+	tm.selectedBackends.Store(key, typ)
+	tm.input.Logger.SelectedBackend(logger, typ)
+	if backend.IsPlaintext(typ) && typ != candidates[0] {
+		tm.input.Logger.FellBackToPlaintextBackend(logger, typ)
+	}
+	return tm.withCache(b, backendScope{profile: prof, backend: typ}), nil
+}
+
+// profile returns the profile an operation runs in: name when it is set, and otherwise
+// the profile GHTKN_PROFILE selects, "" for the default profile.
+func (tm *TokenManager) profile(name string) (string, error) {
+	if name == "" {
+		return profile.Name(tm.input.Getenv) //nolint:wrapcheck
+	}
+	if err := profile.Validate(name); err != nil {
+		return "", fmt.Errorf("the profile is invalid: %w", err)
+	}
+	return name, nil
+}
+
+// getenv returns the environment the paths of the profile prof, such as the token
+// directory of the text backend, are resolved from: the environment of the process with
+// GHTKN_PROFILE set to prof.
+func (tm *TokenManager) getenv(prof string) func(string) string {
+	return profile.Getenv(tm.input.Getenv, prof)
 }
 
 // Validate checks if the Input configuration is valid.
@@ -84,6 +170,7 @@ func (i *Input) Validate() error {
 // deviceFlow defines the interface for creating GitHub App access tokens.
 type deviceFlow interface {
 	Create(ctx context.Context, logger *slog.Logger, input *deviceflow.InputCreate) (*deviceflow.AccessToken, error)
+	Refresh(ctx context.Context, input *deviceflow.InputRefresh) (*deviceflow.AccessToken, error)
 	Show(ctx context.Context, logger *slog.Logger, input *deviceflow.InputCreate, deviceCode *pubdeviceflow.DeviceCodeResponse) error
 	SetLogger(logger *publog.Logger)
 	SetOnetimeCodeUI(ui pubdeviceflow.OnetimeCodeUI)
@@ -92,24 +179,31 @@ type deviceFlow interface {
 }
 
 // Backend defines the interface for storing and retrieving tokens from the system keyring.
+// A token is identified by the GitHub host of the app and its client ID.
 type Backend interface {
-	Get(ctx context.Context, clientID string) (*api.AccessToken, error)
-	Set(ctx context.Context, clientID string, token *api.AccessToken) error
-	Delete(ctx context.Context, clientID string) error
+	Get(ctx context.Context, host, clientID string) (*api.AccessToken, error)
+	Set(ctx context.Context, host, clientID string, token *api.AccessToken) error
+	Delete(ctx context.Context, host, clientID string) error
 	// SupportsDeviceFlow reports whether the backend owns the token lifecycle
 	// server-side (the agent). When true, expiration-aware reads, device-flow token
 	// creation, and revocation are driven through the backend below instead of the
 	// client-side equivalents (GetActive, BeginDeviceFlow/PollDeviceFlow, RevokeToken).
 	SupportsDeviceFlow() bool
-	GetActive(ctx context.Context, clientID string, minExpiration time.Duration) (*api.AccessToken, error)
-	BeginDeviceFlow(ctx context.Context, clientID string, minExpiration time.Duration) (*api.AccessToken, *pubdeviceflow.DeviceCodeResponse, error)
-	PollDeviceFlow(ctx context.Context, clientID string, minExpiration time.Duration) (*api.AccessToken, error)
-	RevokeTokens(ctx context.Context, clientIDs []string) (revokeFailed, cleanupFailed []string, err error)
+	GetActive(ctx context.Context, host, clientID string, minExpiration time.Duration) (*api.AccessToken, error)
+	BeginDeviceFlow(ctx context.Context, host, clientID string, minExpiration time.Duration) (*api.AccessToken, *pubdeviceflow.DeviceCodeResponse, error)
+	PollDeviceFlow(ctx context.Context, host, clientID string, minExpiration time.Duration) (*api.AccessToken, error)
+	RevokeTokens(ctx context.Context, host string, clientIDs []string) (revokeFailed, cleanupFailed []string, err error)
 }
 
-// revoker defines the interface for revoking GitHub credentials.
+// revoker defines the interface for revoking GitHub credentials issued by a host.
 type revoker interface {
-	Revoke(ctx context.Context, tokens []string) error
+	Revoke(ctx context.Context, host string, tokens []string) error
+}
+
+// userClient defines the interface for looking up the user an access token for a
+// host authenticates as.
+type userClient interface {
+	GetLogin(ctx context.Context, host, accessToken string) (string, error)
 }
 
 // configReader defines the interface for reading configuration files.
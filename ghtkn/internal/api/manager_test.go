@@ -17,6 +17,22 @@ type mockDeviceFlow struct {
 	err        error
 	showCalled bool
 	showErr    error
+	// refreshed is the token Refresh returns, and refreshErr its error.
+	refreshed  *deviceflow.AccessToken
+	refreshErr error
+	// gotRefreshToken is the refresh token Refresh was called with.
+	gotRefreshToken string
+	// gotHost is the host Create or Refresh was called with.
+	gotHost string
+}
+
+func (m *mockDeviceFlow) Refresh(_ context.Context, input *deviceflow.InputRefresh) (*deviceflow.AccessToken, error) {
+	m.gotRefreshToken = input.RefreshToken
+	m.gotHost = input.Host
+	if m.refreshErr != nil {
+		return nil, m.refreshErr
+	}
+	return m.refreshed, nil
 }
 
 func (m *mockDeviceFlow) Show(_ context.Context, _ *slog.Logger, _ *deviceflow.InputCreate, _ *pubdeviceflow.DeviceCodeResponse) error {
@@ -33,6 +49,7 @@ func (m *mockDeviceFlow) SetBrowser(_ pubdeviceflow.Browser) {}
 func (m *mockDeviceFlow) SetCopyOnetimeCodeToClipboard(_ pubdeviceflow.CopyTextToClipboard) {}
 
 func (m *mockDeviceFlow) Create(_ context.Context, logger *slog.Logger, input *deviceflow.InputCreate) (*deviceflow.AccessToken, error) {
+	m.gotHost = input.Host
 	if m.err != nil {
 		return nil, m.err
 	}
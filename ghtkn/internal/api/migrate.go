@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+// Migrate moves the stored tokens of apps from the backend input.From to the backend
+// input.To, such as from the text backend to the OS keyring after it becomes
+// available, so that the apps don't have to be authenticated again.
+//
+// The apps are input.AppNames, or every app in the config when it is empty. Each
+// app's token is read from the source as stored, with its refresh token if any, and
+// written to the destination, with Import for a backend that doesn't take tokens with
+// Set (the agent). It is then read back from the destination, and only once it is
+// there is it deleted from the source, so a failure never loses a token. Apps with no
+// token stored in the source are skipped. Each app's token is moved under the lock
+// its acquisition takes (see lockToken), so a caller refreshing the token meanwhile
+// can't rotate its refresh token between the copy and the deletion.
+//
+// The agent can't be the source: it doesn't hand out the refresh tokens it keeps.
+//
+// A failure for one app does not stop the others, and all failures are aggregated
+// with errors.Join.
+func (tm *TokenManager) Migrate(ctx context.Context, logger *slog.Logger, input *pubapi.InputMigrate) error {
+	if input == nil {
+		input = &pubapi.InputMigrate{}
+	}
+	from, err := migrateBackendType(input.From)
+	if err != nil {
+		return fmt.Errorf("the source backend is invalid: %w", err)
+	}
+	to, err := migrateBackendType(input.To)
+	if err != nil {
+		return fmt.Errorf("the destination backend is invalid: %w", err)
+	}
+	if from == to {
+		return fmt.Errorf("the source and destination backends are the same: %s", from)
+	}
+
+	cfg := &pubconfig.Config{}
+	prof, err := tm.profile(input.Profile)
+	if err != nil {
+		return err
+	}
+	configPath, err := tm.resolveConfigPath(input.ConfigFilePath, prof)
+	if err != nil {
+		return err
+	}
+	if err := tm.loadConfig(logger, cfg, configPath); err != nil {
+		return err
+	}
+
+	src, err := backend.New(from, tm.getenv(prof), tm.input.Logger, logger)
+	if err != nil {
+		return fmt.Errorf("create the source backend: %w", slogerr.With(err, "backend", from))
+	}
+	if src.SupportsDeviceFlow() {
+		// Such a backend (the agent) hands out the access token without the refresh
+		// token it keeps, so migrating from it would delete the only copy of the
+		// refresh token.
+		return fmt.Errorf("tokens can't be migrated from a backend that owns their lifecycle, since it doesn't hand out their refresh tokens: %s", from)
+	}
+	dst, err := backend.New(to, tm.getenv(prof), tm.input.Logger, logger)
+	if err != nil {
+		return fmt.Errorf("create the destination backend: %w", slogerr.With(err, "backend", to))
+	}
+
+	appNames := input.AppNames
+	if len(appNames) == 0 {
+		for _, app := range cfg.Apps {
+			appNames = append(appNames, app.Name)
+		}
+	}
+	var errs []error
+	for _, name := range appNames {
+		app := pubconfig.ResolveApp(cfg, name, "")
+		if app == nil {
+			errs = append(errs, fmt.Errorf("app is not found in the config: %s", name))
+			continue
+		}
+		if err := tm.migrateToken(ctx, logger, src, dst, app, backendScope{profile: prof, backend: from}, backendScope{profile: prof, backend: to}); err != nil {
+			errs = append(errs, fmt.Errorf("migrate a token: %w", slogerr.With(err, "app_name", app.Name)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// migrateBackendType validates the backend type a token is migrated from or to, and
+// normalizes the default to keyring. Auto and lists of backends are rejected, since
+// which backend holds the token must be unambiguous.
+func migrateBackendType(typ string) (string, error) {
+	if typ == "" {
+		return "keyring", nil
+	}
+	candidates, err := backend.Candidates(typ)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+	if len(candidates) > 0 {
+		return "", fmt.Errorf("a single backend type is required: %s", typ)
+	}
+	return typ, nil
+}
+
+// migrateToken moves the token of app from src to dst, verifying the copy before it
+// deletes the source. It holds the lock of the token throughout.
+func (tm *TokenManager) migrateToken(ctx context.Context, logger *slog.Logger, src, dst *backend.Backend, app *pubconfig.App, from, to backendScope) error {
+	host := app.HostName()
+	unlock, err := tm.lockToken(ctx, logger, from.profile, host, app.ClientID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	token, err := src.Get(ctx, host, app.ClientID)
+	if err != nil {
+		return fmt.Errorf("get a token from the source backend: %w", err)
+	}
+	if token == nil {
+		logger.Debug("no stored token to migrate", "app_name", app.Name)
+		return nil
+	}
+	// The destination's token changes outside the cache, and the source's is deleted.
+	defer tm.uncache(to, host, app.ClientID)
+	defer tm.uncache(from, host, app.ClientID)
+	if err := dst.Import(ctx, host, app.ClientID, token); err != nil {
+		return fmt.Errorf("store the token to the destination backend: %w", err)
+	}
+	got, err := dst.Get(ctx, host, app.ClientID)
+	if err != nil {
+		return fmt.Errorf("get the migrated token from the destination backend: %w", err)
+	}
+	if err := verifyMigratedToken(got, token, dst.SupportsDeviceFlow()); err != nil {
+		return err
+	}
+	if err := src.Delete(ctx, host, app.ClientID); err != nil {
+		return fmt.Errorf("delete the migrated token from the source backend: %w", err)
+	}
+	logger.Info("migrated the token", "app_name", app.Name, "profile", from.profile, "from", from.backend, "to", to.backend)
+	return nil
+}
+
+// verifyMigratedToken checks that got, the token read back from the destination
+// backend, is the migrated token want. A backend that owns the token lifecycle (the
+// agent) may have refreshed the token on import, so only that it holds one is checked.
+func verifyMigratedToken(got, want *pubapi.AccessToken, ownsLifecycle bool) error {
+	if got == nil {
+		return errors.New("the migrated token isn't found in the destination backend")
+	}
+	if ownsLifecycle {
+		return nil
+	}
+	if got.AccessToken != want.AccessToken || got.RefreshToken != want.RefreshToken {
+		return errors.New("the token read back from the destination backend differs from the migrated token")
+	}
+	return nil
+}
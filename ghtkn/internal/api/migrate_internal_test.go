@@ -0,0 +1,197 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/flock"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
+)
+
+// lossyBackend accepts tokens but never stores them, so a migration to it can't be
+// verified.
+type lossyBackend struct{}
+
+func (lossyBackend) Get(context.Context, string, string) ([]byte, error) { return nil, nil }
+func (lossyBackend) Set(context.Context, string, string, string) error   { return nil }
+func (lossyBackend) Delete(context.Context, string, string) error        { return nil }
+
+func newMigrateTM(t *testing.T) (*TokenManager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	const token = `{"access_token":"gho_migrated","refresh_token":"ghr_migrated","expiration_date":"2999-01-01T00:00:00Z"}`
+	if err := os.MkdirAll(filepath.Join(dir, "text"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "text", "Iv1.x"), []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	getEnv := func(k string) string {
+		switch k {
+		case "GHTKN_TEXT_BACKEND_DIR":
+			return filepath.Join(dir, "text")
+		case "GHTKN_FILE_BACKEND_DIR":
+			return filepath.Join(dir, "file")
+		case "GHTKN_FILE_BACKEND_KEY":
+			return key
+		case "GHTKN_LOCK_DIR":
+			return filepath.Join(dir, "locks")
+		case "GHTKN_AGENT_SOCKET":
+			return filepath.Join(dir, "agent.sock")
+		default:
+			return ""
+		}
+	}
+	return New(&Input{
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       log.NewLogger(),
+		Getenv:       getEnv,
+		GOOS:         "linux",
+	}), dir
+}
+
+func TestTokenManager_Migrate(t *testing.T) {
+	t.Parallel()
+	tm, dir := newMigrateTM(t)
+	var logs bytes.Buffer
+	if err := tm.Migrate(t.Context(), slog.New(slog.NewTextHandler(&logs, nil)), &pubapi.InputMigrate{
+		From:           "text",
+		To:             "file",
+		ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+	}); err != nil {
+		t.Fatalf("Migrate() error: %v", err)
+	}
+	if !strings.Contains(logs.String(), "from=text to=file") {
+		t.Errorf("the log doesn't name the backends: %s", logs.String())
+	}
+	dst, err := backend.New("file", tm.input.Getenv, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := dst.Get(t.Context(), "github.com", "Iv1.x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == nil || token.AccessToken != "gho_migrated" || token.RefreshToken != "ghr_migrated" {
+		t.Fatalf("the destination holds %+v, want the migrated token with its refresh token", token)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "text", "Iv1.x")); !os.IsNotExist(err) {
+		t.Fatalf("the migrated token must be deleted from the source, stat err = %v", err)
+	}
+}
+
+// TestTokenManager_Migrate_lock verifies that a token is migrated under the lock a
+// caller renewing it holds, so the migration copies the token that caller stored rather
+// than the one whose refresh token the renewal used up.
+func TestTokenManager_Migrate_lock(t *testing.T) {
+	t.Parallel()
+	tm, dir := newMigrateTM(t)
+	unlock, err := flock.Lock(t.Context(), filepath.Join(dir, "locks", "Iv1.x.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- tm.Migrate(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputMigrate{
+			From:           "text",
+			To:             "file",
+			ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+		})
+	}()
+	select {
+	case err := <-done:
+		unlock()
+		t.Fatalf("Migrate() returned while the token was locked, error: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	// The caller holding the lock renews the token, rotating its refresh token.
+	if err := os.WriteFile(filepath.Join(dir, "text", "Iv1.x"), []byte(`{"access_token":"gho_renewed","refresh_token":"ghr_renewed","expiration_date":"2999-01-01T00:00:00Z"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("Migrate() error: %v", err)
+	}
+	dst, err := backend.New("file", tm.input.Getenv, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := dst.Get(t.Context(), "github.com", "Iv1.x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == nil || token.RefreshToken != "ghr_renewed" {
+		t.Fatalf("the destination holds %+v, want the renewed token", token)
+	}
+}
+
+// TestTokenManager_Migrate_fromAgent verifies that tokens aren't migrated from the
+// agent, which returns them without their refresh tokens, so the migration would lose
+// them.
+func TestTokenManager_Migrate_fromAgent(t *testing.T) {
+	t.Parallel()
+	tm, dir := newMigrateTM(t)
+	err := tm.Migrate(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputMigrate{
+		From:           "agent",
+		To:             "keyring",
+		ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "refresh tokens") {
+		t.Fatalf("Migrate() error = %v, want a refusal to migrate from the agent", err)
+	}
+}
+
+func TestTokenManager_Migrate_unverified(t *testing.T) {
+	t.Parallel()
+	if err := pubbackend.Register("migrate-test-lossy", func(*pubbackend.Input) (pubbackend.Backend, error) {
+		return lossyBackend{}, nil
+	}); err != nil && !errors.Is(err, pubbackend.ErrAlreadyRegistered) {
+		t.Fatal(err)
+	}
+	tm, dir := newMigrateTM(t)
+	err := tm.Migrate(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputMigrate{
+		From:           "text",
+		To:             "migrate-test-lossy",
+		ConfigFilePath: filepath.Join(dir, "ghtkn.yaml"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "isn't found in the destination backend") {
+		t.Fatalf("Migrate() error = %v, want a verification failure", err)
+	}
+	// The token the destination lost must be kept in the source.
+	if _, err := os.Stat(filepath.Join(dir, "text", "Iv1.x")); err != nil {
+		t.Fatalf("the source token must be kept: %v", err)
+	}
+}
+
+func TestTokenManager_Migrate_invalidBackends(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		from, to string
+	}{
+		{name: "same", from: "", to: "keyring"},
+		{name: "auto", from: "auto", to: "text"},
+		{name: "list", from: "text", to: "keyring,file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tm, _ := newMigrateTM(t)
+			if err := tm.Migrate(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputMigrate{From: tt.from, To: tt.to}); err == nil {
+				t.Fatal("Migrate() must fail")
+			}
+		})
+	}
+}
@@ -2,18 +2,29 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"sync"
 	"time"
 
 	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
 	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
 	"golang.org/x/oauth2"
 )
 
+// backgroundRefreshRetryInterval is how long a failed background refresh waits before
+// it is tried again. The cached token stays in use meanwhile. It is also the least time
+// between two background refreshes, so a token the backend renews into the refresh
+// window again is never renewed in a tight loop.
+const backgroundRefreshRetryInterval = time.Minute
+
 // TokenSource creates an OAuth2 token source for the token manager.
 // It returns a token source that can be used with OAuth2 clients to automatically
 // handle token retrieval and caching through the internal token management system.
+// Tokens are read with context.Background() and never refreshed in the background;
+// use TokenSourceWithContext for either.
 func (tm *TokenManager) TokenSource(logger *slog.Logger, input *pubapi.InputGet) *TokenSource {
 	return &TokenSource{
 		mutex:  &sync.Mutex{},
@@ -23,6 +34,49 @@ func (tm *TokenManager) TokenSource(logger *slog.Logger, input *pubapi.InputGet)
 	}
 }
 
+// TokenSourceWithContext creates an OAuth2 token source like TokenSource, except that
+// ctx bounds every backend read, and, when input.RefreshAhead is set, the token is
+// renewed in the background before it enters the min expiration margin. The background
+// refresh stops when ctx is canceled.
+//
+// A negative RefreshAhead or RefreshJitter, or a refresh window no token can be valid
+// beyond, is rejected: every Token call of the token source fails with the error.
+func (tm *TokenManager) TokenSourceWithContext(ctx context.Context, logger *slog.Logger, input *pubapi.InputTokenSource) *TokenSource {
+	if input == nil {
+		input = &pubapi.InputTokenSource{}
+	}
+	ts := &TokenSource{
+		mutex:         &sync.Mutex{},
+		tm:            tm,
+		logger:        logger,
+		input:         &input.InputGet,
+		ctx:           ctx,
+		refreshAhead:  input.RefreshAhead,
+		refreshJitter: input.RefreshJitter,
+		err:           validateRefreshWindow(input.RefreshAhead, input.RefreshJitter),
+	}
+	if ts.err == nil && ts.refreshAhead > 0 {
+		context.AfterFunc(ctx, ts.stop)
+	}
+	return ts
+}
+
+// validateRefreshWindow checks the background refresh settings of a token source. The
+// background refresh asks for a token valid for the margin, refreshAhead, and
+// refreshJitter, so a window of maxTokenLifetime or more could never be satisfied.
+func validateRefreshWindow(refreshAhead, refreshJitter time.Duration) error {
+	if refreshAhead < 0 {
+		return fmt.Errorf("RefreshAhead must not be negative: %s", refreshAhead)
+	}
+	if refreshJitter < 0 {
+		return fmt.Errorf("RefreshJitter must not be negative: %s", refreshJitter)
+	}
+	if refreshAhead+refreshJitter >= maxTokenLifetime {
+		return fmt.Errorf("RefreshAhead plus RefreshJitter must be less than the token lifetime %s: %s", maxTokenLifetime, refreshAhead+refreshJitter)
+	}
+	return nil
+}
+
 // TokenSource implements oauth2.TokenSource interface for GitHub access tokens.
 // It provides thread-safe caching of tokens and retrieves them from a client when needed.
 type TokenSource struct {
@@ -31,38 +85,204 @@ type TokenSource struct {
 	tm     tokenSourceClient // Token manager instance for retrieving tokens
 	logger *slog.Logger      // Logger for debugging and error reporting
 	input  *pubapi.InputGet  // Input parameters for token retrieval
+	// ctx bounds the backend reads and the background refresh. nil means
+	// context.Background().
+	ctx context.Context //nolint:containedctx
+	// margin is the min expiration resolved along with the cached token. The cached
+	// token is treated as expired once it is within margin of its expiry, the same rule
+	// Get applies to the stored token.
+	margin time.Duration
+	// refreshAhead is how long before the margin the token is renewed in the
+	// background. Zero disables the background refresh.
+	refreshAhead time.Duration
+	// refreshJitter is the upper bound of the random time subtracted from each
+	// scheduled background refresh.
+	refreshJitter time.Duration
+	timer         *time.Timer // Scheduled background refresh, nil when none is scheduled
+	stopped       bool        // Set once ctx is canceled; no refresh is scheduled after that
+	// err is why the input of the token source was rejected, which Token returns.
+	err error
 }
 
 type tokenSourceClient interface {
-	Get(ctx context.Context, logger *slog.Logger, input *pubapi.InputGet) (*pubapi.AccessToken, *pubconfig.App, error)
+	getToken(ctx context.Context, logger *slog.Logger, input *pubapi.InputGet) (*pubapi.AccessToken, *pubconfig.App, time.Duration, error)
+	invalidateToken(accessToken string)
 }
 
 // Token implements oauth2.TokenSource.Token() interface.
 // It returns a cached token if available, otherwise retrieves a new one from the client.
 // The token retrieval is thread-safe and caches the result for subsequent calls.
+// A cached token within the min expiration margin of its expiry is not reused.
 func (ks *TokenSource) Token() (*oauth2.Token, error) {
-	// Check if we have a cached token (read lock)
+	return ks.tokenContext(ks.context())
+}
+
+// TokenContext is Token whose backend read is also bound to ctx, such as the context
+// of the request the token authenticates, so that a canceled request doesn't wait on a
+// slow backend or a renewal.
+func (ks *TokenSource) TokenContext(ctx context.Context) (*oauth2.Token, error) {
+	if ks.ctx != nil {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		stop := context.AfterFunc(ks.ctx, func() { cancel(context.Cause(ks.ctx)) })
+		defer stop()
+	}
+	return ks.tokenContext(ctx)
+}
+
+// tokenContext is Token reading the backend with ctx.
+func (ks *TokenSource) tokenContext(ctx context.Context) (*oauth2.Token, error) {
+	if ks.err != nil {
+		return nil, ks.err
+	}
 	ks.mutex.Lock()
 	defer ks.mutex.Unlock()
 	token := ks.token
-	if token != nil && !isExpired(token, time.Now()) {
+	if token != nil && !isExpired(token, time.Now(), ks.margin) {
 		return token, nil
 	}
 
-	// Get new token from client
-
-	t, _, err := ks.tm.Get(context.Background(), ks.logger, ks.input)
+	token, margin, err := ks.fetch(ctx, ks.input)
 	if err != nil {
 		return nil, err
 	}
-	ks.token = &oauth2.Token{
+	ks.token = token
+	ks.margin = margin
+	ks.schedule(0)
+	return ks.token, nil
+}
+
+// fetch gets a token from the client with the margin it is cached with: the min
+// expiration Get checked it against, resolved from the config Get read. A token that
+// never expires has no margin.
+func (ks *TokenSource) fetch(ctx context.Context, input *pubapi.InputGet) (*oauth2.Token, time.Duration, error) {
+	t, _, margin, err := ks.tm.getToken(ctx, ks.logger, input)
+	if err != nil {
+		return nil, 0, err
+	}
+	token := &oauth2.Token{
 		AccessToken: t.AccessToken,
 		Expiry:      t.ExpirationDate,
 	}
-	return ks.token, nil
+	if token.Expiry.IsZero() {
+		return token, 0, nil
+	}
+	return token, margin, nil
+}
+
+func (ks *TokenSource) context() context.Context {
+	if ks.ctx == nil {
+		return context.Background()
+	}
+	return ks.ctx
+}
+
+// schedule arranges the background refresh of the cached token at its expiry minus
+// the margin, refreshAhead, and a random jitter, so that many processes sharing a
+// backend don't all renew at once, but no sooner than minDelay. The refresh isn't
+// scheduled when the margin makes the refresh window one no token can be valid beyond.
+// The caller must hold the mutex.
+func (ks *TokenSource) schedule(minDelay time.Duration) {
+	if ks.refreshAhead <= 0 || ks.stopped || ks.token == nil || ks.token.Expiry.IsZero() {
+		return
+	}
+	if ks.margin+ks.refreshAhead+ks.refreshJitter >= maxTokenLifetime {
+		ks.logger.Warn("the access token isn't refreshed in the background, since no token is valid beyond the min expiration plus RefreshAhead and RefreshJitter",
+			"min_expiration", ks.margin, "refresh_ahead", ks.refreshAhead, "refresh_jitter", ks.refreshJitter)
+		return
+	}
+	at := ks.token.Expiry.Add(-ks.margin - ks.refreshAhead)
+	if ks.refreshJitter > 0 {
+		at = at.Add(-rand.N(ks.refreshJitter)) //nolint:gosec
+	}
+	ks.reschedule(max(time.Until(at), minDelay))
+}
+
+// reschedule replaces the scheduled background refresh with one after d.
+// The caller must hold the mutex.
+func (ks *TokenSource) reschedule(d time.Duration) {
+	if ks.timer != nil {
+		ks.timer.Stop()
+	}
+	ks.timer = time.AfterFunc(max(d, 0), ks.refresh)
+}
+
+// refresh renews the cached token in the background. The backend is read without
+// holding the mutex, so Token keeps returning the cached token meanwhile. It asks Get
+// for a token valid beyond the refresh window, so the backend renews a token that
+// another process hasn't renewed already. When that fails, or the backend returns no
+// newer token, the failure is logged and retried later while the cached token is still
+// usable; once it isn't, the next Token call fetches in the foreground.
+func (ks *TokenSource) refresh() {
+	ks.mutex.Lock()
+	old := ks.token
+	margin := ks.margin
+	stopped := ks.stopped
+	ks.mutex.Unlock()
+	if stopped || old == nil {
+		return
+	}
+
+	input := pubapi.InputGet{}
+	if ks.input != nil {
+		input = *ks.input
+	}
+	minExpiration := margin + ks.refreshAhead + ks.refreshJitter
+	input.MinExpiration = &minExpiration
+	token, newMargin, err := ks.fetch(ks.context(), &input)
+
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	if ks.stopped || ks.token != old {
+		// Canceled, or Token already replaced the token in the foreground, which also
+		// scheduled the next refresh.
+		return
+	}
+	if err == nil && token.Expiry.After(old.Expiry) {
+		ks.logger.Debug("refreshed the access token in the background", "expiration_date", token.Expiry)
+		ks.token = token
+		ks.margin = newMargin
+		ks.schedule(backgroundRefreshRetryInterval)
+		return
+	}
+	if err != nil {
+		slogerr.WithError(ks.logger, err).Warn("failed to refresh the access token in the background")
+	} else {
+		ks.logger.Debug("the backend returned no newer access token in the background refresh")
+	}
+	if isExpired(old, time.Now().Add(backgroundRefreshRetryInterval), margin) {
+		return
+	}
+	ks.reschedule(backgroundRefreshRetryInterval)
+}
+
+// invalidate drops the cached token if it is still token, so that the next Token call
+// reads the backend again. Comparing with token keeps concurrent callers that saw the
+// same rejected token from dropping a token another caller has already read again.
+// The token is dropped from the token cache of the client as well.
+func (ks *TokenSource) invalidate(token *oauth2.Token) {
+	ks.tm.invalidateToken(token.AccessToken)
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	if ks.token == token {
+		ks.token = nil
+	}
+}
+
+// stop cancels the scheduled background refresh and prevents new ones.
+func (ks *TokenSource) stop() {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	ks.stopped = true
+	if ks.timer != nil {
+		ks.timer.Stop()
+		ks.timer = nil
+	}
 }
 
-func isExpired(token *oauth2.Token, now time.Time) bool {
-	// TODO consider min expiration
-	return !token.Expiry.IsZero() && token.Expiry.Before(now)
+// isExpired reports whether token is expired or within margin of its expiry at now.
+// A token without an expiry never expires.
+func isExpired(token *oauth2.Token, now time.Time, margin time.Duration) bool {
+	return !token.Expiry.IsZero() && now.Add(margin).After(token.Expiry)
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"sync"
@@ -12,21 +13,47 @@ import (
 
 	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
 	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
 	"golang.org/x/oauth2"
 )
 
 type mockTokenSourceClient struct {
-	token *pubapi.AccessToken
-	err   error
-	calls int
+	token  *pubapi.AccessToken
+	err    error
+	margin time.Duration
+	// get, when set, replaces token and err. It is passed the number of the call,
+	// starting at 1, so a test can return a different result for each call.
+	get    func(call int) (*pubapi.AccessToken, error)
+	calls  int
+	inputs []*pubapi.InputGet
+	ctxs   []context.Context
+	mutex  sync.Mutex
 }
 
-func (m *mockTokenSourceClient) Get(_ context.Context, _ *slog.Logger, _ *pubapi.InputGet) (*pubapi.AccessToken, *pubconfig.App, error) {
+func (m *mockTokenSourceClient) getToken(ctx context.Context, _ *slog.Logger, input *pubapi.InputGet) (*pubapi.AccessToken, *pubconfig.App, time.Duration, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.calls++
+	m.inputs = append(m.inputs, input)
+	m.ctxs = append(m.ctxs, ctx)
+	if m.get != nil {
+		token, err := m.get(m.calls)
+		return token, nil, m.margin, err
+	}
 	if m.err != nil {
-		return nil, nil, m.err
+		return nil, nil, 0, m.err
 	}
-	return m.token, nil, nil
+	return m.token, nil, m.margin, nil
+}
+
+func (m *mockTokenSourceClient) invalidateToken(string) {}
+
+func (m *mockTokenSourceClient) getCalls() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.calls
 }
 
 func newTestLogger() *slog.Logger {
@@ -150,6 +177,58 @@ func TestTokenSource_Token(t *testing.T) {
 		})
 	})
 
+	t.Run("cached token within the margin triggers refetch", func(t *testing.T) {
+		t.Parallel()
+
+		synctest.Test(t, func(t *testing.T) {
+			future := time.Now().Add(time.Hour)
+			expiring := &oauth2.Token{AccessToken: "old", Expiry: time.Now().Add(5 * time.Minute)}
+			client := &mockTokenSourceClient{
+				token:  &pubapi.AccessToken{AccessToken: "new", ExpirationDate: future},
+				margin: 10 * time.Minute,
+			}
+			ts := &TokenSource{
+				token:  expiring,
+				margin: 10 * time.Minute,
+				mutex:  &sync.Mutex{},
+				tm:     client,
+				logger: newTestLogger(),
+			}
+
+			got, err := ts.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AccessToken != "new" {
+				t.Errorf("got AccessToken=%q, want %q", got.AccessToken, "new")
+			}
+			if ts.margin != 10*time.Minute {
+				t.Errorf("margin = %v, want %v", ts.margin, 10*time.Minute)
+			}
+		})
+	})
+
+	t.Run("token without an expiry doesn't resolve the margin", func(t *testing.T) {
+		t.Parallel()
+
+		client := &mockTokenSourceClient{
+			token:  &pubapi.AccessToken{AccessToken: "pat"},
+			margin: 10 * time.Minute,
+		}
+		ts := &TokenSource{
+			mutex:  &sync.Mutex{},
+			tm:     client,
+			logger: newTestLogger(),
+		}
+
+		if _, err := ts.Token(); err != nil {
+			t.Fatal(err)
+		}
+		if ts.margin != 0 {
+			t.Errorf("margin = %v, want 0", ts.margin)
+		}
+	})
+
 	t.Run("client error is returned and token stays nil", func(t *testing.T) {
 		t.Parallel()
 
@@ -203,15 +282,327 @@ func TestTokenSource_Token(t *testing.T) {
 	})
 }
 
+func TestTokenManager_TokenSourceWithContext(t *testing.T) {
+	t.Parallel()
+
+	tm := &TokenManager{}
+	logger := newTestLogger()
+	ctx := t.Context()
+	input := &pubapi.InputTokenSource{
+		InputGet:      pubapi.InputGet{ConfigFilePath: "/path/to/config.yaml"},
+		RefreshAhead:  10 * time.Minute,
+		RefreshJitter: time.Minute,
+	}
+
+	ts := tm.TokenSourceWithContext(ctx, logger, input)
+	if ts.ctx != ctx {
+		t.Error("ctx was not propagated")
+	}
+	if ts.input != &input.InputGet {
+		t.Error("input was not propagated")
+	}
+	if ts.refreshAhead != 10*time.Minute || ts.refreshJitter != time.Minute {
+		t.Errorf("refreshAhead = %v, refreshJitter = %v, want 10m0s, 1m0s", ts.refreshAhead, ts.refreshJitter)
+	}
+}
+
+func TestTokenManager_TokenSourceWithContext_invalidRefreshWindow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input *pubapi.InputTokenSource
+	}{
+		{name: "negative RefreshAhead", input: &pubapi.InputTokenSource{RefreshAhead: -time.Minute}},
+		{name: "negative RefreshJitter", input: &pubapi.InputTokenSource{RefreshAhead: time.Minute, RefreshJitter: -time.Minute}},
+		{name: "RefreshAhead beyond the token lifetime", input: &pubapi.InputTokenSource{RefreshAhead: 8 * time.Hour}},
+		{name: "RefreshAhead and RefreshJitter beyond the token lifetime", input: &pubapi.InputTokenSource{RefreshAhead: 7 * time.Hour, RefreshJitter: time.Hour}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client := &mockTokenSourceClient{
+				token: &pubapi.AccessToken{AccessToken: "first", ExpirationDate: time.Now().Add(time.Hour)},
+			}
+			ts := (&TokenManager{}).TokenSourceWithContext(t.Context(), newTestLogger(), tt.input)
+			ts.tm = client
+			if _, err := ts.Token(); err == nil {
+				t.Fatal("Token() must fail")
+			}
+			if calls := client.getCalls(); calls != 0 {
+				t.Errorf("calls = %d, want 0", calls)
+			}
+		})
+	}
+}
+
+func TestTokenSource_backgroundRefresh(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the token is renewed before it enters the margin", func(t *testing.T) {
+		t.Parallel()
+
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			now := time.Now()
+			client := &mockTokenSourceClient{
+				margin: 5 * time.Minute,
+				get: func(call int) (*pubapi.AccessToken, error) {
+					return &pubapi.AccessToken{
+						AccessToken:    fmt.Sprintf("token-%d", call),
+						ExpirationDate: now.Add(time.Duration(call) * time.Hour),
+					}, nil
+				},
+			}
+			ts := (&TokenManager{}).TokenSourceWithContext(ctx, newTestLogger(), &pubapi.InputTokenSource{
+				RefreshAhead: 10 * time.Minute,
+			})
+			ts.tm = client
+
+			if _, err := ts.Token(); err != nil {
+				t.Fatal(err)
+			}
+			// The refresh is scheduled at expiry - margin - RefreshAhead = 45 minutes.
+			time.Sleep(44 * time.Minute)
+			synctest.Wait()
+			if calls := client.getCalls(); calls != 1 {
+				t.Fatalf("calls = %d before the refresh time, want 1", calls)
+			}
+			time.Sleep(2 * time.Minute)
+			synctest.Wait()
+
+			got, err := ts.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AccessToken != "token-2" {
+				t.Errorf("AccessToken = %q, want %q", got.AccessToken, "token-2")
+			}
+			if calls := client.getCalls(); calls != 2 {
+				t.Errorf("calls = %d, want 2", calls)
+			}
+			if minExp := client.inputs[1].MinExpiration; minExp == nil || *minExp != 15*time.Minute {
+				t.Errorf("MinExpiration of the background refresh = %v, want 15m0s", minExp)
+			}
+		})
+	})
+
+	t.Run("a failed refresh keeps the cached token and is retried", func(t *testing.T) {
+		t.Parallel()
+
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			now := time.Now()
+			client := &mockTokenSourceClient{
+				get: func(call int) (*pubapi.AccessToken, error) {
+					switch call {
+					case 1:
+						return &pubapi.AccessToken{AccessToken: "first", ExpirationDate: now.Add(time.Hour)}, nil
+					case 2:
+						return nil, errors.New("boom")
+					default:
+						return &pubapi.AccessToken{AccessToken: "second", ExpirationDate: now.Add(2 * time.Hour)}, nil
+					}
+				},
+			}
+			ts := (&TokenManager{}).TokenSourceWithContext(ctx, newTestLogger(), &pubapi.InputTokenSource{
+				RefreshAhead: 10 * time.Minute,
+			})
+			ts.tm = client
+
+			if _, err := ts.Token(); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(50*time.Minute + time.Second)
+			synctest.Wait()
+			got, err := ts.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AccessToken != "first" {
+				t.Errorf("AccessToken after the failed refresh = %q, want %q", got.AccessToken, "first")
+			}
+
+			time.Sleep(backgroundRefreshRetryInterval)
+			synctest.Wait()
+			got, err = ts.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AccessToken != "second" {
+				t.Errorf("AccessToken after the retry = %q, want %q", got.AccessToken, "second")
+			}
+			if calls := client.getCalls(); calls != 3 {
+				t.Errorf("calls = %d, want 3", calls)
+			}
+		})
+	})
+
+	t.Run("canceling the context stops the refresh", func(t *testing.T) {
+		t.Parallel()
+
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			client := &mockTokenSourceClient{
+				token: &pubapi.AccessToken{AccessToken: "first", ExpirationDate: time.Now().Add(time.Hour)},
+			}
+			ts := (&TokenManager{}).TokenSourceWithContext(ctx, newTestLogger(), &pubapi.InputTokenSource{
+				RefreshAhead: 10 * time.Minute,
+			})
+			ts.tm = client
+
+			if _, err := ts.Token(); err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+			synctest.Wait()
+			time.Sleep(time.Hour)
+			synctest.Wait()
+			if calls := client.getCalls(); calls != 1 {
+				t.Errorf("calls = %d, want 1", calls)
+			}
+		})
+	})
+
+	t.Run("a token renewed into the refresh window isn't renewed in a loop", func(t *testing.T) {
+		t.Parallel()
+
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			now := time.Now()
+			client := &mockTokenSourceClient{
+				get: func(call int) (*pubapi.AccessToken, error) {
+					// Each renewed token expires later than the last, but still within
+					// RefreshAhead.
+					return &pubapi.AccessToken{
+						AccessToken:    fmt.Sprintf("token-%d", call),
+						ExpirationDate: now.Add(time.Hour + time.Duration(call)*time.Second),
+					}, nil
+				},
+			}
+			ts := (&TokenManager{}).TokenSourceWithContext(ctx, newTestLogger(), &pubapi.InputTokenSource{
+				RefreshAhead: 2 * time.Hour,
+			})
+			ts.tm = client
+
+			if _, err := ts.Token(); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(10*time.Minute + time.Second)
+			synctest.Wait()
+			if calls := client.getCalls(); calls > 12 {
+				t.Errorf("calls = %d in 10 minutes, want at most one refresh per %s", calls, backgroundRefreshRetryInterval)
+			}
+		})
+	})
+
+	t.Run("a margin that leaves no refresh window disables the refresh", func(t *testing.T) {
+		t.Parallel()
+
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			client := &mockTokenSourceClient{
+				margin: 7 * time.Hour,
+				token:  &pubapi.AccessToken{AccessToken: "first", ExpirationDate: time.Now().Add(8 * time.Hour)},
+			}
+			ts := (&TokenManager{}).TokenSourceWithContext(ctx, newTestLogger(), &pubapi.InputTokenSource{
+				RefreshAhead: 2 * time.Hour,
+			})
+			ts.tm = client
+
+			if _, err := ts.Token(); err != nil {
+				t.Fatal(err)
+			}
+			if ts.timer != nil {
+				t.Error("a background refresh was scheduled")
+			}
+		})
+	})
+
+	t.Run("without RefreshAhead nothing is scheduled", func(t *testing.T) {
+		t.Parallel()
+
+		synctest.Test(t, func(t *testing.T) {
+			client := &mockTokenSourceClient{
+				token: &pubapi.AccessToken{AccessToken: "first", ExpirationDate: time.Now().Add(time.Hour)},
+			}
+			ts := (&TokenManager{}).TokenSourceWithContext(t.Context(), newTestLogger(), nil)
+			ts.tm = client
+
+			if _, err := ts.Token(); err != nil {
+				t.Fatal(err)
+			}
+			if ts.timer != nil {
+				t.Error("a background refresh was scheduled")
+			}
+		})
+	})
+}
+
+func TestTokenManager_getToken_minExpiration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		input  *pubapi.InputGet
+		getenv func(string) string
+		want   time.Duration
+	}{
+		{
+			name:   "the input takes precedence",
+			input:  &pubapi.InputGet{ConfigFilePath: "config.yaml", MinExpiration: new(time.Minute)},
+			getenv: func(key string) string { return map[string]string{env.MinExpiration: "10m"}[key] },
+			want:   time.Minute,
+		},
+		{
+			name:   "the environment variable",
+			input:  &pubapi.InputGet{ConfigFilePath: "config.yaml"},
+			getenv: func(key string) string { return map[string]string{env.MinExpiration: "10m"}[key] },
+			want:   10 * time.Minute,
+		},
+		{
+			name:   "defaults to zero",
+			input:  &pubapi.InputGet{ConfigFilePath: "config.yaml"},
+			getenv: func(string) string { return "" },
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tm := New(&Input{
+				Backend:      backend.Wrap(mapBackend{"xxx": `{"access_token":"gho_x","expiration_date":"2999-01-01T00:00:00Z"}`}),
+				ConfigReader: &mockConfigReader{},
+				Logger:       log.NewLogger(),
+				Getenv:       tt.getenv,
+			})
+			_, _, got, err := tm.getToken(t.Context(), newTestLogger(), tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("getToken() min expiration = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsExpired(t *testing.T) {
 	t.Parallel()
 
 	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
 
 	tests := []struct {
-		name  string
-		token *oauth2.Token
-		want  bool
+		name   string
+		token  *oauth2.Token
+		margin time.Duration
+		want   bool
 	}{
 		{
 			name:  "zero expiry never expires",
@@ -228,13 +619,31 @@ func TestIsExpired(t *testing.T) {
 			token: &oauth2.Token{AccessToken: "x", Expiry: now.Add(time.Hour)},
 			want:  false,
 		},
+		{
+			name:   "expiry within the margin is expired",
+			token:  &oauth2.Token{AccessToken: "x", Expiry: now.Add(5 * time.Minute)},
+			margin: 10 * time.Minute,
+			want:   true,
+		},
+		{
+			name:   "expiry beyond the margin is not expired",
+			token:  &oauth2.Token{AccessToken: "x", Expiry: now.Add(time.Hour)},
+			margin: 10 * time.Minute,
+			want:   false,
+		},
+		{
+			name:   "zero expiry never expires regardless of the margin",
+			token:  &oauth2.Token{AccessToken: "x"},
+			margin: 10 * time.Minute,
+			want:   false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			if got := isExpired(tt.token, now); got != tt.want {
+			if got := isExpired(tt.token, now, tt.margin); got != tt.want {
 				t.Errorf("isExpired() = %v, want %v", got, tt.want)
 			}
 		})
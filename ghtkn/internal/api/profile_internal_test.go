@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
+)
+
+// TestTokenManager_Get_profile verifies that the token stored in a profile is only read
+// in that profile: the text backend reads it from the token directory of the profile,
+// and neither the default profile nor another one sees it.
+func TestTokenManager_Get_profile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	const token = "gho_work" //nolint:gosec // G101: a fake token for the test
+	tokenDir := filepath.Join(dir, "ghtkn", "profiles", "work", "tokens")
+	if err := os.MkdirAll(tokenDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tokenDir, "Iv1.x"), []byte(`{"access_token":"`+token+`","expiration_date":"2999-01-01T00:00:00Z"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	getEnv := func(k string) string {
+		switch k {
+		case "GHTKN_BACKEND":
+			return "text"
+		case "XDG_CACHE_HOME":
+			return dir
+		default:
+			return ""
+		}
+	}
+	tm := New(&Input{
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       log.NewLogger(),
+		Getenv:       getEnv,
+		GOOS:         "linux",
+	})
+	logger := slog.New(slog.DiscardHandler)
+	configPath := filepath.Join(dir, "ghtkn.yaml")
+
+	got, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: configPath, Profile: "work"})
+	if err != nil {
+		t.Fatalf("Get() in the profile error: %v", err)
+	}
+	if got.AccessToken != token {
+		t.Fatalf("Get() in the profile = %q, want %q", got.AccessToken, token)
+	}
+	for _, profile := range []string{"", "personal"} {
+		if _, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: configPath, Profile: profile}); !errors.Is(err, pubapi.ErrDisableDeviceFlow) {
+			t.Errorf("Get() in the profile %q error = %v, want ErrDisableDeviceFlow", profile, err)
+		}
+	}
+	if _, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: configPath, Profile: "../work"}); err == nil {
+		t.Error("Get() with an invalid profile expected an error")
+	}
+}
+
+// TestTokenManager_Get_profileTextBackendDir verifies that the profiles keep their
+// tokens apart when GHTKN_TEXT_BACKEND_DIR sets the token directory for all of them.
+func TestTokenManager_Get_profileTextBackendDir(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Iv1.x"), []byte(`{"access_token":"gho_default","expiration_date":"2999-01-01T00:00:00Z"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	getEnv := func(k string) string {
+		switch k {
+		case "GHTKN_BACKEND":
+			return "text"
+		case "GHTKN_TEXT_BACKEND_DIR":
+			return dir
+		default:
+			return ""
+		}
+	}
+	tm := New(&Input{
+		ConfigReader: &oneAppConfigReader{},
+		Logger:       log.NewLogger(),
+		Getenv:       getEnv,
+		GOOS:         "linux",
+	})
+	logger := slog.New(slog.DiscardHandler)
+	configPath := filepath.Join(dir, "ghtkn.yaml")
+
+	got, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: configPath})
+	if err != nil {
+		t.Fatalf("Get() in the default profile error: %v", err)
+	}
+	if got.AccessToken != "gho_default" {
+		t.Fatalf("Get() in the default profile = %q, want gho_default", got.AccessToken)
+	}
+	if _, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: configPath, Profile: "work"}); !errors.Is(err, pubapi.ErrDisableDeviceFlow) {
+		t.Errorf("Get() in the profile work error = %v, want ErrDisableDeviceFlow", err)
+	}
+	if err := tm.Revoke(t.Context(), logger, &pubapi.InputRevoke{ConfigFilePath: configPath, Profile: "work", All: true}); err != nil {
+		t.Fatalf("Revoke() in the profile work error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Iv1.x")); err != nil {
+		t.Errorf("the token of the default profile must be kept: %v", err)
+	}
+}
@@ -14,11 +14,11 @@ import (
 
 // resolveConfigPath returns the config file path to read. When p is empty the
 // default path is auto-detected from the environment.
-func (tm *TokenManager) resolveConfigPath(p string) (string, error) {
+func (tm *TokenManager) resolveConfigPath(p, prof string) (string, error) {
 	if p != "" {
 		return p, nil
 	}
-	path, err := config.GetPath(tm.input.Getenv, tm.input.GOOS)
+	path, err := config.GetPath(tm.getenv(prof), tm.input.GOOS)
 	if err != nil {
 		return "", fmt.Errorf("get config path: %w", err)
 	}
@@ -53,10 +53,12 @@ func (tm *TokenManager) revokeAppNames(cfg *pubconfig.Config, input *pubapi.Inpu
 //
 // The tokens to revoke are the tokens stored in the backend for the apps selected
 // by input (see revokeAppNames): every app when input.All is set, the apps in
-// input.AppNames, or the GHTKN_APP / default app as a fallback.
+// input.AppNames, or the GHTKN_APP / default app as a fallback. Each app's token is
+// revoked from the backend the app selects, which its own backend.type may override.
 //
 // Reading the backend never triggers the device flow and ignores expiration: a
-// stored token is revoked regardless of whether it has expired. Apps with no
+// stored token is revoked regardless of whether it has expired, together with the
+// refresh token stored with it, if any. Apps with no
 // stored token are skipped. Tokens read from the backend are deleted from it
 // after they are revoked. When there is nothing to revoke, Revoke is a no-op.
 //
@@ -71,14 +73,18 @@ func (tm *TokenManager) Revoke(ctx context.Context, logger *slog.Logger, input *
 	}
 
 	cfg := &pubconfig.Config{}
-	configPath, err := tm.resolveConfigPath(input.ConfigFilePath)
+	prof, err := tm.profile(input.Profile)
+	if err != nil {
+		return err
+	}
+	configPath, err := tm.resolveConfigPath(input.ConfigFilePath, prof)
 	if err != nil {
 		return err
 	}
 	// The effective config: the file plus the environment overrides. GHTKN_BACKEND
 	// selects the backend to revoke from, so reading the file alone would revoke from
 	// the wrong backend and silently leave the real token live.
-	if err := tm.loadConfig(cfg, configPath); err != nil {
+	if err := tm.loadConfig(logger, cfg, configPath); err != nil {
 		return err
 	}
 
@@ -87,22 +93,11 @@ func (tm *TokenManager) Revoke(ctx context.Context, logger *slog.Logger, input *
 		return err
 	}
 
-	b, err := tm.resolveBackend(logger, cfg)
-	if err != nil {
-		return fmt.Errorf("resolve the backend: %w", err)
-	}
-
-	// The agent owns the token lifecycle, so it revokes and deletes each stored token
-	// itself; the client only tells it which apps to revoke.
-	if b.SupportsDeviceFlow() {
-		return tm.revokeViaBackend(ctx, logger, b, cfg, appNames)
-	}
-
-	tokens := make([]string, 0, len(appNames))
-	// clientIDs of tokens read from the backend, to delete after revocation.
-	var clientIDs []string
-	// errs aggregates per-app failures so one bad app doesn't block the rest.
+	// Each app's token is in the backend the app selects, so the apps are grouped by
+	// backend, in the order the backends first appear in appNames.
 	var errs []error
+	var backendTypes []string
+	appsByBackend := map[string][]*pubconfig.App{}
 	for _, name := range appNames {
 		app := pubconfig.ResolveApp(cfg, name, "")
 		if app == nil {
@@ -110,7 +105,46 @@ func (tm *TokenManager) Revoke(ctx context.Context, logger *slog.Logger, input *
 			errs = append(errs, fmt.Errorf("app is not found in the config: %s: %w", name, pubapi.ErrRevoke))
 			continue
 		}
-		tk, err := b.Get(ctx, app.ClientID)
+		backendType := ""
+		if tm.input.Backend == nil {
+			backendType = resolveBackendType(cfg.ForApp(app).Backend)
+		}
+		if _, ok := appsByBackend[backendType]; !ok {
+			backendTypes = append(backendTypes, backendType)
+		}
+		appsByBackend[backendType] = append(appsByBackend[backendType], app)
+	}
+
+	for _, backendType := range backendTypes {
+		apps := appsByBackend[backendType]
+		b, err := tm.resolveBackend(ctx, logger, cfg.ForApp(apps[0]), prof)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolve the backend: %w: %w", err, pubapi.ErrRevoke))
+			continue
+		}
+		// The agent owns the token lifecycle, so it revokes and deletes each stored token
+		// itself; the client only tells it which apps to revoke.
+		if b.SupportsDeviceFlow() {
+			errs = append(errs, tm.revokeViaBackend(ctx, logger, b, apps))
+			continue
+		}
+		errs = append(errs, tm.revokeFromBackend(ctx, logger, b, apps))
+	}
+	return errors.Join(errs...)
+}
+
+// revokeFromBackend revokes the apps' tokens stored in a client-side backend through
+// the API of the host that issued them, and deletes the revoked tokens from the backend.
+func (tm *TokenManager) revokeFromBackend(ctx context.Context, logger *slog.Logger, b Backend, apps []*pubconfig.App) error {
+	// Tokens are revoked through the API of the host that issued them, so they are
+	// grouped by host, in the order the hosts first appear in apps.
+	var hosts []string
+	groups := map[string]*revokeGroup{}
+	// errs aggregates per-app failures so one bad app doesn't block the rest.
+	var errs []error
+	for _, app := range apps {
+		host := app.HostName()
+		tk, err := b.Get(ctx, host, app.ClientID)
 		if err != nil {
 			// The token couldn't be read, so it can't be revoked: it may still be live.
 			errs = append(errs, fmt.Errorf("get a stored token from the backend: app_name=%s: %w: %w", app.Name, err, pubapi.ErrRevoke))
@@ -121,66 +155,83 @@ func (tm *TokenManager) Revoke(ctx context.Context, logger *slog.Logger, input *
 			logger.Debug("no stored token to revoke", "app_name", app.Name)
 			continue
 		}
-		clientIDs = append(clientIDs, app.ClientID)
-		tokens = append(tokens, tk.AccessToken)
-	}
-
-	if len(tokens) == 0 {
-		return errors.Join(errs...)
+		group, ok := groups[host]
+		if !ok {
+			group = &revokeGroup{}
+			groups[host] = group
+			hosts = append(hosts, host)
+		}
+		group.clientIDs = append(group.clientIDs, app.ClientID)
+		group.tokens = append(group.tokens, tk.AccessToken)
+		// The refresh token outlives the access token and can mint a new one, so it is
+		// revoked along with it rather than merely dropped with the stored entry.
+		if tk.RefreshToken != "" {
+			group.tokens = append(group.tokens, tk.RefreshToken)
+		}
 	}
 
-	if err := tm.input.Revoker.Revoke(ctx, tokens); err != nil {
-		// The revocation API call failed: the credentials may still be live, so they
-		// must NOT be deleted from the backend.
-		errs = append(errs, fmt.Errorf("revoke credentials: %w: %w", err, pubapi.ErrRevoke))
-		return errors.Join(errs...)
-	}
+	for _, host := range hosts {
+		group := groups[host]
+		if err := tm.input.Revoker.Revoke(ctx, host, group.tokens); err != nil {
+			// The revocation API call failed: the credentials may still be live, so they
+			// must NOT be deleted from the backend.
+			errs = append(errs, fmt.Errorf("revoke credentials: host=%s: %w: %w", host, err, pubapi.ErrRevoke))
+			continue
+		}
 
-	// Remove the revoked tokens from the backend (best-effort). These tokens are
-	// already revoked, so a failure here is a cleanup/UX issue, not a security one.
-	for _, clientID := range clientIDs {
-		if err := b.Delete(ctx, clientID); err != nil {
-			errs = append(errs, fmt.Errorf("delete a revoked token from the backend: client_id=%s: %w: %w", clientID, err, pubapi.ErrBackendCleanup))
+		// Remove the revoked tokens from the backend (best-effort). These tokens are
+		// already revoked, so a failure here is a cleanup/UX issue, not a security one.
+		for _, clientID := range group.clientIDs {
+			if err := b.Delete(ctx, host, clientID); err != nil {
+				errs = append(errs, fmt.Errorf("delete a revoked token from the backend: client_id=%s: %w: %w", clientID, err, pubapi.ErrBackendCleanup))
+			}
 		}
 	}
 	return errors.Join(errs...)
 }
 
+// revokeGroup is the stored tokens of the apps on one GitHub host, revoked together.
+type revokeGroup struct {
+	tokens    []string // Access and refresh tokens to revoke
+	clientIDs []string // Client IDs of the revoked tokens, to delete from the backend
+}
+
 // revokeViaBackend revokes the apps' stored tokens through a backend that owns the
-// token lifecycle (the agent). It resolves the apps to client IDs and hands them to
-// the backend, which revokes and deletes them in one batch. The backend reports which
-// client IDs it could not revoke (ErrRevoke, the credential may be live) and which it
-// revoked but could not delete (ErrBackendCleanup), which are mapped back to app names.
-func (tm *TokenManager) revokeViaBackend(ctx context.Context, logger *slog.Logger, b Backend, cfg *pubconfig.Config, appNames []string) error {
+// token lifecycle (the agent). It hands the apps' client IDs to the backend, which
+// revokes and deletes them in one batch per host. The backend reports which client IDs
+// it could not revoke (ErrRevoke, the credential may be live) and which it revoked but
+// could not delete (ErrBackendCleanup), which are mapped back to app names.
+func (tm *TokenManager) revokeViaBackend(ctx context.Context, logger *slog.Logger, b Backend, apps []*pubconfig.App) error {
 	var errs []error
-	clientIDs := make([]string, 0, len(appNames))
-	appByClientID := make(map[string]string, len(appNames))
-	for _, name := range appNames {
-		app := pubconfig.ResolveApp(cfg, name, "")
-		if app == nil {
-			errs = append(errs, fmt.Errorf("app is not found in the config: %s: %w", name, pubapi.ErrRevoke))
-			continue
+	var hosts []string
+	clientIDsByHost := map[string][]string{}
+	// appByKey maps the storage key of each client ID back to its app name.
+	appByKey := make(map[string]string, len(apps))
+	for _, app := range apps {
+		host := app.HostName()
+		if _, ok := clientIDsByHost[host]; !ok {
+			hosts = append(hosts, host)
 		}
-		clientIDs = append(clientIDs, app.ClientID)
-		appByClientID[app.ClientID] = app.Name
-	}
-	if len(clientIDs) == 0 {
-		return errors.Join(errs...)
+		clientIDsByHost[host] = append(clientIDsByHost[host], app.ClientID)
+		appByKey[pubapi.StorageKey(host, app.ClientID)] = app.Name
 	}
 
-	revokeFailed, cleanupFailed, err := b.RevokeTokens(ctx, clientIDs)
-	if err != nil {
-		// The request itself failed (e.g. the agent is not running or locked), so no
-		// credential was revoked.
-		errs = append(errs, fmt.Errorf("revoke tokens through the backend: %w: %w", err, pubapi.ErrRevoke))
-		return errors.Join(errs...)
-	}
-	logger.Debug("revoked tokens through the agent", "count", len(clientIDs)-len(revokeFailed))
-	for _, clientID := range revokeFailed {
-		errs = append(errs, fmt.Errorf("revoke a token through the backend: app_name=%s: %w", appByClientID[clientID], pubapi.ErrRevoke))
-	}
-	for _, clientID := range cleanupFailed {
-		errs = append(errs, fmt.Errorf("delete a revoked token from the backend: app_name=%s: %w", appByClientID[clientID], pubapi.ErrBackendCleanup))
+	for _, host := range hosts {
+		clientIDs := clientIDsByHost[host]
+		revokeFailed, cleanupFailed, err := b.RevokeTokens(ctx, host, clientIDs)
+		if err != nil {
+			// The request itself failed (e.g. the agent is not running or locked), so no
+			// credential was revoked.
+			errs = append(errs, fmt.Errorf("revoke tokens through the backend: host=%s: %w: %w", host, err, pubapi.ErrRevoke))
+			continue
+		}
+		logger.Debug("revoked tokens through the agent", "host", host, "count", len(clientIDs)-len(revokeFailed))
+		for _, clientID := range revokeFailed {
+			errs = append(errs, fmt.Errorf("revoke a token through the backend: app_name=%s: %w", appByKey[pubapi.StorageKey(host, clientID)], pubapi.ErrRevoke))
+		}
+		for _, clientID := range cleanupFailed {
+			errs = append(errs, fmt.Errorf("delete a revoked token from the backend: app_name=%s: %w", appByKey[pubapi.StorageKey(host, clientID)], pubapi.ErrBackendCleanup))
+		}
 	}
 	return errors.Join(errs...)
 }
@@ -15,14 +15,17 @@ import (
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
 )
 
-// mockRevoker records the batches of tokens passed to Revoke.
+// mockRevoker records the batches of tokens passed to Revoke and the hosts they were
+// revoked on.
 type mockRevoker struct {
 	revoked [][]string
+	hosts   []string
 	err     error
 }
 
-func (m *mockRevoker) Revoke(_ context.Context, tokens []string) error {
+func (m *mockRevoker) Revoke(_ context.Context, host string, tokens []string) error {
 	m.revoked = append(m.revoked, append([]string(nil), tokens...))
+	m.hosts = append(m.hosts, host)
 	return m.err
 }
 
@@ -50,6 +53,17 @@ func TestTokenManager_Revoke(t *testing.T) {
 			wantRevoked: [][]string{{"stored-tok"}},
 			wantDeleted: []string{"xxx"},
 		},
+		{
+			name: "the stored refresh token is revoked with the access token",
+			backend: &mockKeyring{token: &pubapi.AccessToken{
+				AccessToken:    "stored-tok",
+				ExpirationDate: pastTime,
+				RefreshToken:   "stored-refresh-tok",
+			}},
+			input:       &pubapi.InputRevoke{AppNames: []string{"test-app"}},
+			wantRevoked: [][]string{{"stored-tok", "stored-refresh-tok"}},
+			wantDeleted: []string{"xxx"},
+		},
 		{
 			name:        "app with no stored token is a no-op",
 			backend:     &mockKeyring{token: nil},
@@ -143,18 +157,18 @@ type mapKeyring struct {
 	deleted []string
 }
 
-func (m *mapKeyring) Get(_ context.Context, clientID string) (*pubapi.AccessToken, error) {
+func (m *mapKeyring) Get(_ context.Context, _, clientID string) (*pubapi.AccessToken, error) {
 	if err := m.getErr[clientID]; err != nil {
 		return nil, err
 	}
 	return m.tokens[clientID], nil
 }
 
-func (m *mapKeyring) Set(_ context.Context, _ string, _ *pubapi.AccessToken) error {
+func (m *mapKeyring) Set(_ context.Context, _, _ string, _ *pubapi.AccessToken) error {
 	return nil
 }
 
-func (m *mapKeyring) Delete(_ context.Context, clientID string) error {
+func (m *mapKeyring) Delete(_ context.Context, _, clientID string) error {
 	if err := m.delErr[clientID]; err != nil {
 		return err
 	}
@@ -166,19 +180,19 @@ func (m *mapKeyring) Delete(_ context.Context, clientID string) error {
 // does not run the device flow itself.
 func (m *mapKeyring) SupportsDeviceFlow() bool { return false }
 
-func (m *mapKeyring) GetActive(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
+func (m *mapKeyring) GetActive(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
 	return nil, errors.New("GetActive should not be called")
 }
 
-func (m *mapKeyring) BeginDeviceFlow(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, *pubdeviceflow.DeviceCodeResponse, error) {
+func (m *mapKeyring) BeginDeviceFlow(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, *pubdeviceflow.DeviceCodeResponse, error) {
 	return nil, nil, errors.New("BeginDeviceFlow should not be called")
 }
 
-func (m *mapKeyring) PollDeviceFlow(_ context.Context, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
+func (m *mapKeyring) PollDeviceFlow(_ context.Context, _, _ string, _ time.Duration) (*pubapi.AccessToken, error) {
 	return nil, errors.New("PollDeviceFlow should not be called")
 }
 
-func (m *mapKeyring) RevokeTokens(_ context.Context, _ []string) (revokeFailed, cleanupFailed []string, err error) {
+func (m *mapKeyring) RevokeTokens(_ context.Context, _ string, _ []string) (revokeFailed, cleanupFailed []string, err error) {
 	return nil, nil, errors.New("RevokeTokens should not be called")
 }
 
@@ -273,6 +287,44 @@ func TestTokenManager_Revoke_agent(t *testing.T) {
 	}
 }
 
+// TestTokenManager_Revoke_hosts verifies that tokens are revoked through the API of the
+// host that issued them, one call per host in the order the hosts first appear.
+func TestTokenManager_Revoke_hosts(t *testing.T) {
+	t.Parallel()
+
+	backend := &mapKeyring{tokens: map[string]*pubapi.AccessToken{
+		"ca": {AccessToken: "ta"},
+		"cb": {AccessToken: "tb", RefreshToken: "rb"},
+		"cc": {AccessToken: "tc"},
+	}}
+	revoker := &mockRevoker{}
+	input := &Input{
+		Backend: backend,
+		Revoker: revoker,
+		Logger:  log.NewLogger(),
+		ConfigReader: &multiConfigReader{apps: []*pubconfig.App{
+			{Name: "a", ClientID: "ca"},
+			{Name: "b", ClientID: "cb", Host: "ghes.example.com"},
+			{Name: "c", ClientID: "cc"},
+		}},
+		Getenv: func(string) string { return "" },
+	}
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	if err := New(input).Revoke(t.Context(), logger, &pubapi.InputRevoke{All: true, ConfigFilePath: "/path/to/config.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"github.com", "ghes.example.com"}, revoker.hosts); diff != "" {
+		t.Errorf("hosts mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([][]string{{"ta", "tc"}, {"tb", "rb"}}, revoker.revoked); diff != "" {
+		t.Errorf("revoked tokens mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"ca", "cc", "cb"}, backend.deleted); diff != "" {
+		t.Errorf("deleted client ids mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestTokenManager_Revoke_all(t *testing.T) {
 	t.Parallel()
 
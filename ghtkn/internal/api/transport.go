@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+	"golang.org/x/oauth2"
+)
+
+// maxDrainBytes bounds how much of a discarded response body is read so that the
+// connection can be reused.
+const maxDrainBytes = 4 << 10
+
+// Transport creates an http.RoundTripper that authenticates requests with the tokens
+// of a token source created by TokenSourceWithContext. base sends the requests;
+// nil means http.DefaultTransport.
+func (tm *TokenManager) Transport(ctx context.Context, logger *slog.Logger, input *pubapi.InputTokenSource, base http.RoundTripper) *Transport {
+	return &Transport{
+		base:   base,
+		source: tm.TokenSourceWithContext(ctx, logger, input),
+		logger: logger,
+	}
+}
+
+// Transport implements http.RoundTripper. It sets the Authorization header from its
+// token source and recovers from a token that was rotated or revoked by another process:
+// on a 401 it drops the cached token, reads the backend once more, and retries the
+// request with the new token if the request is idempotent. A 403 because the token
+// isn't authorized for SAML SSO is turned into a *pubapi.SSORequiredError.
+type Transport struct {
+	base   http.RoundTripper // Transport that sends the requests (nil means http.DefaultTransport)
+	source transportTokenSource
+	logger *slog.Logger
+}
+
+type transportTokenSource interface {
+	TokenContext(ctx context.Context) (*oauth2.Token, error)
+	invalidate(token *oauth2.Token)
+}
+
+// RoundTrip implements http.RoundTripper. Like every RoundTripper, it doesn't modify
+// req; the Authorization header is set on a clone. The token is read with the context
+// of req, so canceling the request stops waiting for it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.TokenContext(req.Context())
+	if err != nil {
+		closeBody(req)
+		return nil, fmt.Errorf("get a GitHub access token: %w", err)
+	}
+	resp, err := t.roundTrip(req, token)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp, err = t.retry(req, token, resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		if u := ssoURL(resp.Header); u != "" {
+			discard(resp)
+			return nil, &pubapi.SSORequiredError{URL: u}
+		}
+	}
+	return resp, nil
+}
+
+// retry handles a 401. The cached token is dropped and the backend read again in any
+// case, so at least the next request uses a token rotated by another process. The
+// request itself is only retried when it is idempotent, its body can be replayed, and
+// the backend returned a different token; otherwise resp is returned as is.
+func (t *Transport) retry(req *http.Request, token *oauth2.Token, resp *http.Response) (*http.Response, error) {
+	t.source.invalidate(token)
+	newToken, err := t.source.TokenContext(req.Context())
+	if err != nil {
+		slogerr.WithError(t.logger, err).Debug("failed to read the access token again after 401")
+		return resp, nil
+	}
+	if newToken.AccessToken == token.AccessToken {
+		t.logger.Debug("the backend has no other access token to retry the request with after 401")
+		return resp, nil
+	}
+	if !isReplayable(req) {
+		return resp, nil
+	}
+	retryReq := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil //nolint:nilerr
+		}
+		retryReq.Body = body
+	}
+	discard(resp)
+	t.logger.Debug("retrying the request with the access token read again after 401")
+	return t.roundTrip(retryReq, newToken)
+}
+
+func (t *Transport) roundTrip(req *http.Request, token *oauth2.Token) (*http.Response, error) {
+	req2 := req.Clone(req.Context())
+	token.SetAuthHeader(req2)
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req2) //nolint:wrapcheck
+}
+
+// isReplayable reports whether req may be sent again: its method is idempotent and
+// its body, if any, can be obtained again with GetBody.
+func isReplayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// ssoURL returns the authorization URL of an X-GitHub-SSO header of the form
+// "required; url=<url>", or an empty string if the header doesn't require SSO.
+func ssoURL(header http.Header) string {
+	v := header.Get("X-GitHub-SSO")
+	rest, ok := strings.CutPrefix(v, "required;")
+	if !ok {
+		return ""
+	}
+	u, ok := strings.CutPrefix(strings.TrimSpace(rest), "url=")
+	if !ok {
+		return ""
+	}
+	return u
+}
+
+// discard drains a bit of the body of a response that isn't returned, so the
+// connection can be reused, and closes it.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	_ = resp.Body.Close()
+}
+
+// closeBody closes the body of a request that is never sent, as RoundTrip must.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+)
+
+// fakeGitHub answers each request with the next of statuses and records the
+// Authorization header and body of every request.
+type fakeGitHub struct {
+	statuses []int
+	header   http.Header
+	auths    []string
+	bodies   []string
+}
+
+func (f *fakeGitHub) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.auths = append(f.auths, req.Header.Get("Authorization"))
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		f.bodies = append(f.bodies, string(b))
+	}
+	status := f.statuses[0]
+	if len(f.statuses) > 1 {
+		f.statuses = f.statuses[1:]
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     f.header.Clone(),
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func newTestTransport(client *mockTokenSourceClient, base http.RoundTripper) *Transport {
+	return &Transport{
+		base: base,
+		source: &TokenSource{
+			mutex:  &sync.Mutex{},
+			tm:     client,
+			logger: newTestLogger(),
+		},
+		logger: newTestLogger(),
+	}
+}
+
+// rotatingTokens returns "token-1", "token-2", ... one per call, like a backend whose
+// token another process keeps rotating.
+func rotatingTokens(call int) (*pubapi.AccessToken, error) {
+	return &pubapi.AccessToken{AccessToken: "token-" + strconv.Itoa(call)}, nil
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		noGetBody  bool
+		get        func(call int) (*pubapi.AccessToken, error)
+		statuses   []int
+		header     http.Header
+		wantStatus int
+		wantAuths  []string
+		wantBodies []string
+		wantSSO    string
+		wantErr    bool
+	}{
+		{
+			name:       "the Authorization header is set",
+			method:     http.MethodGet,
+			get:        rotatingTokens,
+			statuses:   []int{http.StatusOK},
+			wantStatus: http.StatusOK,
+			wantAuths:  []string{"Bearer token-1"},
+		},
+		{
+			name:       "an idempotent request is retried with the token read again after 401",
+			method:     http.MethodGet,
+			get:        rotatingTokens,
+			statuses:   []int{http.StatusUnauthorized, http.StatusOK},
+			wantStatus: http.StatusOK,
+			wantAuths:  []string{"Bearer token-1", "Bearer token-2"},
+		},
+		{
+			name:       "a replayable body is sent again",
+			method:     http.MethodPut,
+			body:       "payload",
+			get:        rotatingTokens,
+			statuses:   []int{http.StatusUnauthorized, http.StatusOK},
+			wantStatus: http.StatusOK,
+			wantAuths:  []string{"Bearer token-1", "Bearer token-2"},
+			wantBodies: []string{"payload", "payload"},
+		},
+		{
+			name:       "a body that can't be replayed isn't retried",
+			method:     http.MethodPut,
+			body:       "payload",
+			noGetBody:  true,
+			get:        rotatingTokens,
+			statuses:   []int{http.StatusUnauthorized, http.StatusOK},
+			wantStatus: http.StatusUnauthorized,
+			wantAuths:  []string{"Bearer token-1"},
+			wantBodies: []string{"payload"},
+		},
+		{
+			name:       "a non-idempotent request isn't retried",
+			method:     http.MethodPost,
+			body:       "payload",
+			get:        rotatingTokens,
+			statuses:   []int{http.StatusUnauthorized, http.StatusOK},
+			wantStatus: http.StatusUnauthorized,
+			wantAuths:  []string{"Bearer token-1"},
+			wantBodies: []string{"payload"},
+		},
+		{
+			name:   "the request isn't retried with the same token",
+			method: http.MethodGet,
+			get: func(int) (*pubapi.AccessToken, error) {
+				return &pubapi.AccessToken{AccessToken: "revoked"}, nil
+			},
+			statuses:   []int{http.StatusUnauthorized, http.StatusOK},
+			wantStatus: http.StatusUnauthorized,
+			wantAuths:  []string{"Bearer revoked"},
+		},
+		{
+			name:   "a failure to read the token again returns the 401",
+			method: http.MethodGet,
+			get: func(call int) (*pubapi.AccessToken, error) {
+				if call > 1 {
+					return nil, pubapi.ErrDisableDeviceFlow
+				}
+				return &pubapi.AccessToken{AccessToken: "token-1"}, nil
+			},
+			statuses:   []int{http.StatusUnauthorized},
+			wantStatus: http.StatusUnauthorized,
+			wantAuths:  []string{"Bearer token-1"},
+		},
+		{
+			name:     "SAML SSO 403 is turned into SSORequiredError",
+			method:   http.MethodGet,
+			get:      rotatingTokens,
+			statuses: []int{http.StatusForbidden},
+			header: http.Header{
+				"X-Github-Sso": []string{"required; url=https://github.com/orgs/acme/sso?authorization_request=xxx"},
+			},
+			wantAuths: []string{"Bearer token-1"},
+			wantSSO:   "https://github.com/orgs/acme/sso?authorization_request=xxx",
+			wantErr:   true,
+		},
+		{
+			name:       "403 without SSO is returned as is",
+			method:     http.MethodGet,
+			get:        rotatingTokens,
+			statuses:   []int{http.StatusForbidden},
+			header:     http.Header{"X-Github-Sso": []string{"partial-results; organizations=1,2"}},
+			wantStatus: http.StatusForbidden,
+			wantAuths:  []string{"Bearer token-1"},
+		},
+		{
+			name:   "a token error fails without sending the request",
+			method: http.MethodGet,
+			get: func(int) (*pubapi.AccessToken, error) {
+				return nil, pubapi.ErrDisableDeviceFlow
+			},
+			statuses: []int{http.StatusOK},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			github := &fakeGitHub{statuses: tt.statuses, header: tt.header}
+			tr := newTestTransport(&mockTokenSourceClient{get: tt.get}, github)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequestWithContext(t.Context(), tt.method, "https://api.github.com/user", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.noGetBody {
+				req.GetBody = nil
+			}
+
+			resp, err := tr.RoundTrip(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				var ssoErr *pubapi.SSORequiredError
+				if tt.wantSSO != "" && (!errors.As(err, &ssoErr) || ssoErr.URL != tt.wantSSO) {
+					t.Errorf("error = %v, want SSORequiredError with URL %q", err, tt.wantSSO)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+			if diff := cmp.Diff(tt.wantAuths, github.auths); diff != "" {
+				t.Errorf("Authorization headers mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantBodies, github.bodies); diff != "" {
+				t.Errorf("bodies mismatch (-want +got):\n%s", diff)
+			}
+			if req.Header.Get("Authorization") != "" {
+				t.Error("the original request was modified")
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_rereadsAfterUnretried401(t *testing.T) {
+	t.Parallel()
+
+	// A POST isn't retried, but the token is read again so the next request uses the
+	// rotated one.
+	github := &fakeGitHub{statuses: []int{http.StatusUnauthorized, http.StatusOK}}
+	client := &mockTokenSourceClient{get: rotatingTokens}
+	tr := newTestTransport(client, github)
+
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		req, err := http.NewRequestWithContext(t.Context(), method, "https://api.github.com/user", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if diff := cmp.Diff([]string{"Bearer token-1", "Bearer token-2"}, github.auths); diff != "" {
+		t.Errorf("Authorization headers mismatch (-want +got):\n%s", diff)
+	}
+	if client.calls != 2 {
+		t.Errorf("calls = %d, want 2", client.calls)
+	}
+}
+
+func TestTransport_RoundTrip_requestContext(t *testing.T) {
+	t.Parallel()
+
+	// The token is read with the context of the request, so a backend read stops when the
+	// request is canceled.
+	github := &fakeGitHub{statuses: []int{http.StatusOK}}
+	client := &mockTokenSourceClient{get: rotatingTokens}
+	tr := newTestTransport(client, github)
+
+	type key struct{}
+	ctx := context.WithValue(t.Context(), key{}, "request")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/user", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(client.ctxs) != 1 {
+		t.Fatalf("calls = %d, want 1", len(client.ctxs))
+	}
+	if v := client.ctxs[0].Value(key{}); v != "request" {
+		t.Errorf("the token was read with a context other than the request's: %v", v)
+	}
+}
+
+func TestSSOURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "required", header: "required; url=https://github.com/orgs/acme/sso?x=1", want: "https://github.com/orgs/acme/sso?x=1"},
+		{name: "partial results", header: "partial-results; organizations=1,2"},
+		{name: "no header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			if tt.header != "" {
+				h.Set("X-GitHub-SSO", tt.header)
+			}
+			if got := ssoURL(h); got != tt.want {
+				t.Errorf("ssoURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+// watchedConfig is a config file path and the Watcher keeping its config.
+type watchedConfig struct {
+	path    string
+	watcher *config.Watcher
+}
+
+// WatchConfig loads the config and keeps it in memory, reloading it when the config
+// files change, until ctx is canceled. Until then Get, Auth, Revoke, and the token
+// sources use the kept config instead of reading the files on every call, as long as
+// they are given the same config file path. A change that leaves the config invalid is
+// logged and the last good config kept.
+//
+// The environment overrides are folded in at each reload, not on each call, so a
+// change of the environment of the process is only seen after the files change.
+func (tm *TokenManager) WatchConfig(ctx context.Context, logger *slog.Logger, input *pubapi.InputWatchConfig) (*config.Watcher, error) {
+	if input == nil {
+		input = &pubapi.InputWatchConfig{}
+	}
+	prof, err := tm.profile(input.Profile)
+	if err != nil {
+		return nil, err
+	}
+	configPath, err := tm.resolveConfigPath(input.ConfigFilePath, prof)
+	if err != nil {
+		return nil, err
+	}
+	w, err := config.NewWatcher(&config.InputWatch{
+		Load: func() (*pubconfig.Config, []string, []string, error) {
+			cfg := &pubconfig.Config{}
+			result, err := tm.loadConfigFiles(logger, cfg, configPath)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			// The user and system config files are watched even when they don't exist, so
+			// that creating them is noticed, and so are the untrusted project config
+			// files, which are read once they are trusted. The trust store is watched
+			// too, since trusting or untrusting a project only changes it.
+			files := append([]string{config.GetSystemPath(tm.input.Getenv, tm.input.GOOS), configPath}, result.Files...)
+			return cfg, append(files, result.Untrusted...), []string{result.TrustStore}, nil
+		},
+		PollInterval: input.PollInterval,
+		Logger:       logger,
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the loader returns a descriptive error
+	}
+	wc := &watchedConfig{path: configPath, watcher: w}
+	tm.watched.Store(wc)
+	go func() {
+		w.Run(ctx)
+		tm.watched.CompareAndSwap(wc, nil)
+	}()
+	return w, nil
+}
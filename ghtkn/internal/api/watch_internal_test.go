@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pubapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
+)
+
+// countingConfigReader is mockConfigReader that counts the reads.
+type countingConfigReader struct {
+	mockConfigReader
+
+	reads atomic.Int32
+}
+
+func (r *countingConfigReader) Read(cfg *pubconfig.Config, configFilePath string) error {
+	r.reads.Add(1)
+	return r.mockConfigReader.Read(cfg, configFilePath)
+}
+
+func TestTokenManager_WatchConfig(t *testing.T) {
+	t.Parallel()
+	reader := &countingConfigReader{}
+	input := newMockInput()
+	input.ConfigReader = reader
+	input.Backend = &mockKeyring{token: &pubapi.AccessToken{AccessToken: "cached", ExpirationDate: time.Now().Add(time.Hour)}}
+	tm := New(input)
+	logger := slog.New(slog.DiscardHandler)
+	ctx, cancel := context.WithCancel(t.Context())
+
+	if _, err := tm.WatchConfig(ctx, logger, &pubapi.InputWatchConfig{ConfigFilePath: "config.yaml", PollInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	reads := reader.reads.Load()
+	for range 2 {
+		if _, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: "config.yaml"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := reader.reads.Load(); got != reads {
+		t.Errorf("Get read the config %d times while it is watched, want 0", got-reads)
+	}
+	// Another config file isn't watched, so it is read.
+	if _, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: "other.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := reader.reads.Load(); got == reads {
+		t.Error("Get must read a config file that isn't watched")
+	}
+
+	// Once the watch stops, the files are read again.
+	cancel()
+	for tm.watched.Load() != nil {
+		time.Sleep(time.Millisecond)
+	}
+	reads = reader.reads.Load()
+	if _, _, err := tm.Get(t.Context(), logger, &pubapi.InputGet{ConfigFilePath: "config.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if reader.reads.Load() == reads {
+		t.Error("Get must read the config after the watch stops")
+	}
+}
+
+// TestTokenManager_WatchConfig_trust verifies that trusting or untrusting a project
+// while the config is watched reloads it with or without the project config file.
+func TestTokenManager_WatchConfig_trust(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "ghtkn.yaml")
+	files := map[string]string{
+		configPath:                               "apps:\n  - name: user\n    client_id: Iv1.user\n",
+		filepath.Join(projectDir, ".ghtkn.yaml"): "apps:\n  - name: project\n    client_id: Iv1.project\n",
+	}
+	for p, content := range files {
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	envs := map[string]string{
+		"GHTKN_PROJECT_CONFIG": "true",
+		"GHTKN_SYSTEM_CONFIG":  filepath.Join(dir, "system.yaml"),
+		"XDG_DATA_HOME":        filepath.Join(dir, "data"),
+	}
+	getEnv := func(k string) string { return envs[k] }
+	tm := New(&Input{
+		ConfigReader: config.NewReader(getEnv),
+		Logger:       log.NewLogger(),
+		Getenv:       getEnv,
+		GOOS:         "linux",
+		Getwd:        func() (string, error) { return projectDir, nil },
+	})
+	w, err := tm.WatchConfig(t.Context(), slog.New(slog.DiscardHandler), &pubapi.InputWatchConfig{
+		ConfigFilePath: configPath,
+		PollInterval:   10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := config.NewTrustStore(getEnv, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// waitFor waits until the project app is in the watched config or not.
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			got := slices.ContainsFunc(w.Config().Apps, func(app *pubconfig.App) bool { return app.Name == "project" })
+			if got == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("the project app is in the config: %v, want %v", got, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor(false)
+	// Let the watch start before the trust store changes.
+	time.Sleep(50 * time.Millisecond)
+	if err := store.Trust(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(true)
+	if err := store.Untrust(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(false)
+}
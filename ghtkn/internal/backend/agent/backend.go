@@ -7,6 +7,7 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,8 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 	agentapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend/agent"
 	pubdeviceflow "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/deviceflow"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
@@ -76,22 +79,24 @@ func New(getEnv func(string) string, logger *publog.Logger, slogLogger *slog.Log
 	}, nil
 }
 
-// Get probes the agent for a cached token for clientID with no freshness requirement.
+// Get probes the agent for a cached token for clientID on host with no freshness
+// requirement.
 // It exists to satisfy the storage backend interface; the token-lifecycle paths use
 // GetActive/Begin/Poll/Revoke instead. It returns (nil, nil) when the agent has no
 // token for the client ID, and agentapi.ErrAgentNotRunning when no agent is listening.
-func (b *Backend) Get(ctx context.Context, clientID string) ([]byte, error) {
-	return b.GetActive(ctx, clientID, 0)
+func (b *Backend) Get(ctx context.Context, host, clientID string) ([]byte, error) {
+	return b.GetActive(ctx, host, clientID, 0)
 }
 
-// GetActive probes the agent for a token for clientID that is still valid for at least
+// GetActive probes the agent for a token for clientID on host that is still valid for at least
 // minExpiration. The agent checks expiration server-side, so a token expiring within
 // minExpiration is reported as a miss. It is a pure read: it never starts a device
 // flow. It returns (nil, nil) on a miss and agentapi.ErrAgentNotRunning when no agent
 // is listening.
-func (b *Backend) GetActive(ctx context.Context, clientID string, minExpiration time.Duration) ([]byte, error) {
+func (b *Backend) GetActive(ctx context.Context, host, clientID string, minExpiration time.Duration) ([]byte, error) {
 	resp, err := b.get(ctx, &agentapi.Request{
 		ClientID:      clientID,
+		Host:          host,
 		MinExpiration: minExpiration,
 	})
 	if err != nil {
@@ -103,6 +108,16 @@ func (b *Backend) GetActive(ctx context.Context, clientID string, minExpiration
 	return nil, nil
 }
 
+// Probe reports whether an agent able to serve this client is running, by asking for
+// its status.
+func (b *Backend) Probe(ctx context.Context) error {
+	resp, err := agentapi.Send(ctx, b.socket, &agentapi.Request{Command: agentapi.CommandStatus})
+	if err != nil {
+		return err //nolint:wrapcheck // Send returns a descriptive error; callers may use agentapi.IsNotRunning
+	}
+	return checkAgentVersion(resp)
+}
+
 // checkAgentVersion rejects an agent that cannot serve this client. An agent that
 // predates protocol versioning does not know the fields the token lifecycle depends on
 // and silently ignores them: it would answer a GET carrying MinExpiration with
@@ -116,7 +131,7 @@ func checkAgentVersion(resp *agentapi.Response) error {
 	return nil
 }
 
-// get sends a single GET built from the given request (ClientID, StartDeviceFlow,
+// get sends a single GET built from the given request (ClientID, Host, StartDeviceFlow,
 // AwaitDeviceFlow, MinExpiration); only the command is filled in here.
 func (b *Backend) get(ctx context.Context, req *agentapi.Request) (*agentapi.Response, error) {
 	req.Command = agentapi.CommandGet
@@ -148,15 +163,17 @@ func (b *Backend) get(ctx context.Context, req *agentapi.Request) (*agentapi.Res
 	return resp, nil
 }
 
-// Begin asks the agent to start (or join) the server-side device flow for clientID.
+// Begin asks the agent to start (or join) the server-side device flow for clientID on
+// host.
 // The server first checks its store: if a token valid for minExpiration is already
 // there (e.g. minted concurrently by another client), Begin returns it directly (as
 // raw bytes) and no flow is started. Otherwise it returns the one-time code for the
 // started flow, which the client displays before polling with Poll. Exactly one of
 // the returned token and device code is non-nil.
-func (b *Backend) Begin(ctx context.Context, clientID string, minExpiration time.Duration) ([]byte, *pubdeviceflow.DeviceCodeResponse, error) {
+func (b *Backend) Begin(ctx context.Context, host, clientID string, minExpiration time.Duration) ([]byte, *pubdeviceflow.DeviceCodeResponse, error) {
 	resp, err := b.get(ctx, &agentapi.Request{
 		ClientID:        clientID,
+		Host:            host,
 		StartDeviceFlow: true,
 		MinExpiration:   minExpiration,
 	})
@@ -176,14 +193,14 @@ func (b *Backend) Begin(ctx context.Context, clientID string, minExpiration time
 	}, nil
 }
 
-// Poll waits for the agent to finish the server-side device flow for clientID and
+// Poll waits for the agent to finish the server-side device flow for clientID on host and
 // returns the raw token bytes it minted and cached. It polls with AwaitDeviceFlow set,
 // so the agent returns the freshly minted token as is (no freshness check) once the
 // flow completes, and reports Pending while it runs.
-func (b *Backend) Poll(ctx context.Context, clientID string, minExpiration time.Duration) ([]byte, error) {
+func (b *Backend) Poll(ctx context.Context, host, clientID string, minExpiration time.Duration) ([]byte, error) {
 	// Probe immediately so a token that is already available is returned without
 	// waiting for the first tick.
-	if token, err := b.pollOnce(ctx, clientID, minExpiration); err != nil || token != nil {
+	if token, err := b.pollOnce(ctx, host, clientID, minExpiration); err != nil || token != nil {
 		return token, err
 	}
 
@@ -194,7 +211,7 @@ func (b *Backend) Poll(ctx context.Context, clientID string, minExpiration time.
 		case <-ctx.Done():
 			return nil, fmt.Errorf("wait for the device flow to complete: %w", ctx.Err())
 		case <-ticker.C:
-			token, err := b.pollOnce(ctx, clientID, minExpiration)
+			token, err := b.pollOnce(ctx, host, clientID, minExpiration)
 			if err != nil || token != nil {
 				return token, err
 			}
@@ -205,9 +222,10 @@ func (b *Backend) Poll(ctx context.Context, clientID string, minExpiration time.
 // pollOnce sends one GET marked AwaitDeviceFlow while waiting for the device flow to
 // finish. It returns the token bytes when ready, (nil, nil) while the flow is still
 // pending, and an error when the agent reports the flow ended without a token.
-func (b *Backend) pollOnce(ctx context.Context, clientID string, minExpiration time.Duration) ([]byte, error) {
+func (b *Backend) pollOnce(ctx context.Context, host, clientID string, minExpiration time.Duration) ([]byte, error) {
 	resp, err := b.get(ctx, &agentapi.Request{
 		ClientID:        clientID,
+		Host:            host,
 		AwaitDeviceFlow: true,
 		MinExpiration:   minExpiration,
 	})
@@ -223,13 +241,13 @@ func (b *Backend) pollOnce(ctx context.Context, clientID string, minExpiration t
 	return nil, fmt.Errorf("the agent's device flow for %s ended without a token", clientID)
 }
 
-// RevokeTokens asks the agent to revoke the tokens stored for clientIDs in one batch
-// and delete them. It returns the client IDs whose credential could not be revoked
+// RevokeTokens asks the agent to revoke the tokens stored for clientIDs on host in one
+// batch and delete them. It returns the client IDs whose credential could not be revoked
 // (it may still be live) and those revoked but not deleted (a cleanup issue), so the
 // caller can classify each. A non-nil error means the request itself failed (e.g. the
 // agent is not running or locked), not that a particular token could not be revoked.
-func (b *Backend) RevokeTokens(ctx context.Context, clientIDs []string) (revokeFailed, cleanupFailed []string, err error) {
-	resp, err := agentapi.Send(ctx, b.socket, &agentapi.Request{Command: agentapi.CommandRevoke, ClientIDs: clientIDs})
+func (b *Backend) RevokeTokens(ctx context.Context, host string, clientIDs []string) (revokeFailed, cleanupFailed []string, err error) {
+	resp, err := agentapi.Send(ctx, b.socket, &agentapi.Request{Command: agentapi.CommandRevoke, Host: host, ClientIDs: clientIDs})
 	if err != nil {
 		return nil, nil, err //nolint:wrapcheck // Send returns a descriptive error; callers may use agentapi.IsNotRunning
 	}
@@ -249,17 +267,72 @@ func (b *Backend) RevokeTokens(ctx context.Context, clientIDs []string) (revokeF
 
 // Set exists only to satisfy the storage backend interface. The agent mints and
 // stores tokens itself as part of the server-side device flow, so a client never
-// pushes a token to it; this method is never called on the agent path (device-flow
+// pushes a token to it (a token migrated to it is handed over with Import); this method is never called on the agent path (device-flow
 // creation returns changed=false) and always reports that pushing is unsupported.
-func (b *Backend) Set(_ context.Context, _, _ string) error {
+func (b *Backend) Set(_ context.Context, _, _, _ string) error {
 	return errors.New("the ghtkn agent stores tokens itself; pushing a token to it is not supported")
 }
 
-// Delete removes the token stored for clientID from the agent.
+// Import has the agent store token, moved from another backend, for clientID on host.
+// The agent takes over its lifecycle, refreshing it with the refresh token it carries
+// when refresh is enabled. It returns agentapi.ErrAgentLocked when the agent is
+// running but still locked.
+func (b *Backend) Import(ctx context.Context, host, clientID, token string) error {
+	resp, err := agentapi.Send(ctx, b.socket, &agentapi.Request{
+		Command:  agentapi.CommandImport,
+		ClientID: clientID,
+		Host:     host,
+		Token:    json.RawMessage(token),
+	})
+	if err != nil {
+		return err //nolint:wrapcheck // Send returns a descriptive error; callers may use agentapi.IsNotRunning
+	}
+	if err := checkAgentVersion(resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		if resp.Error == agentapi.RespLocked {
+			return agentapi.ErrAgentLocked
+		}
+		// An agent that predates IMPORT answers it as an unknown command, which says why.
+		return fmt.Errorf("import an access token through the agent: %s", resp.Error)
+	}
+	return nil
+}
+
+// List returns the keys of the tokens the agent stores. It returns
+// agentapi.ErrAgentLocked when the agent is running but still locked.
+func (b *Backend) List(ctx context.Context) ([]pubbackend.TokenKey, error) {
+	resp, err := agentapi.Send(ctx, b.socket, &agentapi.Request{Command: agentapi.CommandList})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Send returns a descriptive error; callers may use agentapi.IsNotRunning
+	}
+	if err := checkAgentVersion(resp); err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		if resp.Error == agentapi.RespLocked {
+			return nil, agentapi.ErrAgentLocked
+		}
+		// An agent that predates LIST answers it as an unknown command, which says why.
+		return nil, fmt.Errorf("list access tokens through the agent: %s", resp.Error)
+	}
+	keys := make([]pubbackend.TokenKey, len(resp.Keys))
+	for i, key := range resp.Keys {
+		host := key.Host
+		if host == "" {
+			host = api.DefaultHost
+		}
+		keys[i] = pubbackend.TokenKey{Host: host, ClientID: key.ClientID}
+	}
+	return keys, nil
+}
+
+// Delete removes the token stored for clientID on host from the agent.
 // It is a no-op when the agent has no token for the client ID, and returns
 // agentapi.ErrAgentLocked when the agent is running but still locked.
-func (b *Backend) Delete(ctx context.Context, clientID string) error {
-	resp, err := agentapi.Send(ctx, b.socket, &agentapi.Request{Command: agentapi.CommandDelete, ClientID: clientID})
+func (b *Backend) Delete(ctx context.Context, host, clientID string) error {
+	resp, err := agentapi.Send(ctx, b.socket, &agentapi.Request{Command: agentapi.CommandDelete, ClientID: clientID, Host: host})
 	if err != nil {
 		return err //nolint:wrapcheck // Send returns a descriptive error; callers may use agentapi.IsNotRunning
 	}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 	agentapi "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend/agent"
 	pubdeviceflow "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/deviceflow"
 	publog "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
@@ -114,7 +115,7 @@ func TestBackend_getHit(t *testing.T) {
 		}
 		return &agentapi.Response{OK: true, Token: json.RawMessage(value)}
 	})
-	got, err := (&Backend{socket: f.socket}).Get(t.Context(), "Iv1.x")
+	got, err := (&Backend{socket: f.socket}).Get(t.Context(), "github.com", "Iv1.x")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -128,7 +129,7 @@ func TestBackend_getMiss(t *testing.T) {
 	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 		return &agentapi.Response{Error: agentapi.RespNotFound}
 	})
-	got, err := (&Backend{socket: f.socket}).Get(t.Context(), "Iv1.absent")
+	got, err := (&Backend{socket: f.socket}).Get(t.Context(), "github.com", "Iv1.absent")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -148,7 +149,7 @@ func TestBackend_getWarning(t *testing.T) {
 		return &agentapi.Response{OK: true, Token: json.RawMessage(value), Warning: warning}
 	})
 	var buf bytes.Buffer
-	got, err := (&Backend{socket: f.socket, warn: &buf}).Get(t.Context(), "Iv1.x")
+	got, err := (&Backend{socket: f.socket, warn: &buf}).Get(t.Context(), "github.com", "Iv1.x")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -176,7 +177,7 @@ func TestBackend_getWarning_customHook(t *testing.T) {
 			got = message
 		},
 	}
-	if _, err := (&Backend{socket: f.socket, logger: logger}).Get(t.Context(), "Iv1.x"); err != nil {
+	if _, err := (&Backend{socket: f.socket, logger: logger}).Get(t.Context(), "github.com", "Iv1.x"); err != nil {
 		t.Fatal(err)
 	}
 	if got != warning {
@@ -191,7 +192,7 @@ func TestBackend_getProbeShape(t *testing.T) {
 	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 		return &agentapi.Response{Error: agentapi.RespNotFound}
 	})
-	if _, err := (&Backend{socket: f.socket}).Get(t.Context(), "Iv1.x"); err != nil {
+	if _, err := (&Backend{socket: f.socket}).Get(t.Context(), "github.com", "Iv1.x"); err != nil {
 		t.Fatal(err)
 	}
 	reqs := f.reqs()
@@ -205,7 +206,7 @@ func TestBackend_serverError(t *testing.T) {
 	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 		return &agentapi.Response{Error: "boom"}
 	})
-	if _, err := (&Backend{socket: f.socket}).Get(t.Context(), "Iv1.x"); err == nil {
+	if _, err := (&Backend{socket: f.socket}).Get(t.Context(), "github.com", "Iv1.x"); err == nil {
 		t.Fatal("a server error response must produce an error")
 	}
 }
@@ -213,7 +214,7 @@ func TestBackend_serverError(t *testing.T) {
 func TestBackend_agentNotRunning(t *testing.T) {
 	t.Parallel()
 	socket := filepath.Join(t.TempDir(), "absent.sock")
-	if _, err := (&Backend{socket: socket}).Get(t.Context(), "Iv1.x"); !agentapi.IsNotRunning(err) {
+	if _, err := (&Backend{socket: socket}).Get(t.Context(), "github.com", "Iv1.x"); !agentapi.IsNotRunning(err) {
 		t.Fatalf("Get err = %v, want ErrAgentNotRunning", err)
 	}
 }
@@ -223,7 +224,7 @@ func TestBackend_locked(t *testing.T) {
 	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 		return &agentapi.Response{Error: agentapi.RespLocked}
 	})
-	if _, err := (&Backend{socket: f.socket}).Get(t.Context(), "Iv1.x"); !errors.Is(err, agentapi.ErrAgentLocked) {
+	if _, err := (&Backend{socket: f.socket}).Get(t.Context(), "github.com", "Iv1.x"); !errors.Is(err, agentapi.ErrAgentLocked) {
 		t.Fatalf("Get err = %v, want ErrAgentLocked", err)
 	}
 }
@@ -232,7 +233,7 @@ func TestBackend_locked(t *testing.T) {
 // agent mints and stores tokens itself.
 func TestBackend_setUnsupported(t *testing.T) {
 	t.Parallel()
-	if err := (&Backend{socket: "unused"}).Set(t.Context(), "Iv1.x", "{}"); err == nil {
+	if err := (&Backend{socket: "unused"}).Set(t.Context(), "github.com", "Iv1.x", "{}"); err == nil {
 		t.Fatal("Set must return an error on the agent backend")
 	}
 }
@@ -273,7 +274,7 @@ func TestBackend_beginAndPoll(t *testing.T) {
 		b := &Backend{socket: f.socket}
 		ctx := t.Context()
 
-		token, dc, err := b.Begin(ctx, "Iv1.x", 0)
+		token, dc, err := b.Begin(ctx, "github.com", "Iv1.x", 0)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -285,7 +286,7 @@ func TestBackend_beginAndPoll(t *testing.T) {
 			t.Fatalf("device code (-want +got):\n%s", diff)
 		}
 
-		got, err := b.Poll(ctx, "Iv1.x", 0)
+		got, err := b.Poll(ctx, "github.com", "Iv1.x", 0)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -304,7 +305,7 @@ func TestBackend_pollFlowFailed(t *testing.T) {
 	})
 	synctest.Test(t, func(t *testing.T) {
 		b := &Backend{socket: f.socket}
-		if _, err := b.Poll(t.Context(), "Iv1.x", 0); err == nil {
+		if _, err := b.Poll(t.Context(), "github.com", "Iv1.x", 0); err == nil {
 			t.Fatal("Poll must error when the flow ends without a token")
 		}
 	})
@@ -319,7 +320,7 @@ func TestBackend_getActiveSendsMinExpiration(t *testing.T) {
 		return &agentapi.Response{OK: true, Token: json.RawMessage(value)}
 	})
 	const minExpiration = 30 * time.Minute
-	if _, err := (&Backend{socket: f.socket}).GetActive(t.Context(), "Iv1.x", minExpiration); err != nil {
+	if _, err := (&Backend{socket: f.socket}).GetActive(t.Context(), "github.com", "Iv1.x", minExpiration); err != nil {
 		t.Fatal(err)
 	}
 	reqs := f.reqs()
@@ -347,7 +348,7 @@ func TestBackend_beginReturnsExistingToken(t *testing.T) {
 		return &agentapi.Response{OK: true, Token: json.RawMessage(value)}
 	})
 	b := &Backend{socket: f.socket}
-	token, dc, err := b.Begin(t.Context(), "Iv1.x", 0)
+	token, dc, err := b.Begin(t.Context(), "github.com", "Iv1.x", 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -369,7 +370,7 @@ func TestBackend_revokeTokens(t *testing.T) {
 		f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 			return &agentapi.Response{OK: true, RevokeFailed: []string{"Iv1.b"}, CleanupFailed: []string{"Iv1.c"}}
 		})
-		revokeFailed, cleanupFailed, err := (&Backend{socket: f.socket}).RevokeTokens(t.Context(), []string{"Iv1.a", "Iv1.b", "Iv1.c"})
+		revokeFailed, cleanupFailed, err := (&Backend{socket: f.socket}).RevokeTokens(t.Context(), "github.com", []string{"Iv1.a", "Iv1.b", "Iv1.c"})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -392,7 +393,7 @@ func TestBackend_revokeTokens(t *testing.T) {
 		f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 			return &agentapi.Response{Error: "boom"}
 		})
-		if _, _, err := (&Backend{socket: f.socket}).RevokeTokens(t.Context(), []string{"Iv1.x"}); err == nil {
+		if _, _, err := (&Backend{socket: f.socket}).RevokeTokens(t.Context(), "github.com", []string{"Iv1.x"}); err == nil {
 			t.Fatal("a server error response must produce an error")
 		}
 	})
@@ -401,7 +402,7 @@ func TestBackend_revokeTokens(t *testing.T) {
 		f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 			return &agentapi.Response{Error: agentapi.RespLocked}
 		})
-		if _, _, err := (&Backend{socket: f.socket}).RevokeTokens(t.Context(), []string{"Iv1.x"}); !errors.Is(err, agentapi.ErrAgentLocked) {
+		if _, _, err := (&Backend{socket: f.socket}).RevokeTokens(t.Context(), "github.com", []string{"Iv1.x"}); !errors.Is(err, agentapi.ErrAgentLocked) {
 			t.Fatalf("RevokeTokens err = %v, want ErrAgentLocked", err)
 		}
 	})
@@ -410,7 +411,7 @@ func TestBackend_revokeTokens(t *testing.T) {
 func TestBackend_deleteOK(t *testing.T) {
 	t.Parallel()
 	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response { return &agentapi.Response{OK: true} })
-	if err := (&Backend{socket: f.socket}).Delete(t.Context(), "Iv1.x"); err != nil {
+	if err := (&Backend{socket: f.socket}).Delete(t.Context(), "github.com", "Iv1.x"); err != nil {
 		t.Fatal(err)
 	}
 	reqs := f.reqs()
@@ -419,12 +420,46 @@ func TestBackend_deleteOK(t *testing.T) {
 	}
 }
 
+// TestBackend_sendsHost guards that the host of the app reaches the agent with every
+// request that identifies a stored token, so it serves a GitHub Enterprise Server app
+// against its own host.
+func TestBackend_sendsHost(t *testing.T) {
+	t.Parallel()
+	value := `{"access_token":"abc","expiration_date":"2026-01-01T00:00:00Z"}`
+	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
+		return &agentapi.Response{OK: true, Token: json.RawMessage(value)}
+	})
+	b := &Backend{socket: f.socket}
+	const host = "ghes.example.com"
+	if _, err := b.GetActive(t.Context(), host, "Iv1.x", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := b.Begin(t.Context(), host, "Iv1.x", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Delete(t.Context(), host, "Iv1.x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := b.RevokeTokens(t.Context(), host, []string{"Iv1.x"}); err != nil {
+		t.Fatal(err)
+	}
+	reqs := f.reqs()
+	if len(reqs) != 4 {
+		t.Fatalf("want 4 requests, got %d: %+v", len(reqs), reqs)
+	}
+	for _, req := range reqs {
+		if req.Host != host {
+			t.Errorf("%s Host = %q, want %q", req.Command, req.Host, host)
+		}
+	}
+}
+
 func TestBackend_deleteMiss(t *testing.T) {
 	t.Parallel()
 	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 		return &agentapi.Response{Error: agentapi.RespNotFound}
 	})
-	if err := (&Backend{socket: f.socket}).Delete(t.Context(), "Iv1.absent"); err != nil {
+	if err := (&Backend{socket: f.socket}).Delete(t.Context(), "github.com", "Iv1.absent"); err != nil {
 		t.Fatalf("Delete() on miss must return nil, got %v", err)
 	}
 }
@@ -434,7 +469,7 @@ func TestBackend_deleteLocked(t *testing.T) {
 	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 		return &agentapi.Response{Error: agentapi.RespLocked}
 	})
-	if err := (&Backend{socket: f.socket}).Delete(t.Context(), "Iv1.x"); !errors.Is(err, agentapi.ErrAgentLocked) {
+	if err := (&Backend{socket: f.socket}).Delete(t.Context(), "github.com", "Iv1.x"); !errors.Is(err, agentapi.ErrAgentLocked) {
 		t.Fatalf("Delete err = %v, want ErrAgentLocked", err)
 	}
 }
@@ -442,7 +477,7 @@ func TestBackend_deleteLocked(t *testing.T) {
 func TestBackend_deleteNotRunning(t *testing.T) {
 	t.Parallel()
 	socket := filepath.Join(t.TempDir(), "absent.sock")
-	if err := (&Backend{socket: socket}).Delete(t.Context(), "Iv1.x"); !agentapi.IsNotRunning(err) {
+	if err := (&Backend{socket: socket}).Delete(t.Context(), "github.com", "Iv1.x"); !agentapi.IsNotRunning(err) {
 		t.Fatalf("Delete err = %v, want ErrAgentNotRunning", err)
 	}
 }
@@ -463,16 +498,16 @@ func TestBackend_obsoleteAgent(t *testing.T) {
 	})
 	b := &Backend{socket: f.socket}
 
-	if _, err := b.GetActive(t.Context(), "Iv1.x", time.Hour); !errors.Is(err, agentapi.ErrObsoleteAgent) {
+	if _, err := b.GetActive(t.Context(), "github.com", "Iv1.x", time.Hour); !errors.Is(err, agentapi.ErrObsoleteAgent) {
 		t.Fatalf("GetActive err = %v, want ErrObsoleteAgent", err)
 	}
-	if _, _, err := b.Begin(t.Context(), "Iv1.x", 0); !errors.Is(err, agentapi.ErrObsoleteAgent) {
+	if _, _, err := b.Begin(t.Context(), "github.com", "Iv1.x", 0); !errors.Is(err, agentapi.ErrObsoleteAgent) {
 		t.Fatalf("Begin err = %v, want ErrObsoleteAgent", err)
 	}
-	if _, err := b.Poll(t.Context(), "Iv1.x", 0); !errors.Is(err, agentapi.ErrObsoleteAgent) {
+	if _, err := b.Poll(t.Context(), "github.com", "Iv1.x", 0); !errors.Is(err, agentapi.ErrObsoleteAgent) {
 		t.Fatalf("Poll err = %v, want ErrObsoleteAgent", err)
 	}
-	if _, _, err := b.RevokeTokens(t.Context(), []string{"Iv1.x"}); !errors.Is(err, agentapi.ErrObsoleteAgent) {
+	if _, _, err := b.RevokeTokens(t.Context(), "github.com", []string{"Iv1.x"}); !errors.Is(err, agentapi.ErrObsoleteAgent) {
 		t.Fatalf("RevokeTokens err = %v, want ErrObsoleteAgent", err)
 	}
 }
@@ -484,7 +519,81 @@ func TestBackend_obsoleteAgentReported(t *testing.T) {
 	f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
 		return &agentapi.Response{Error: agentapi.RespObsoleteAgent}
 	})
-	if _, err := (&Backend{socket: f.socket}).GetActive(t.Context(), "Iv1.x", 0); !errors.Is(err, agentapi.ErrObsoleteAgent) {
+	if _, err := (&Backend{socket: f.socket}).GetActive(t.Context(), "github.com", "Iv1.x", 0); !errors.Is(err, agentapi.ErrObsoleteAgent) {
 		t.Fatalf("GetActive err = %v, want ErrObsoleteAgent", err)
 	}
 }
+
+func TestBackend_import(t *testing.T) {
+	t.Parallel()
+	value := `{"access_token":"abc","refresh_token":"ghr_x","expiration_date":"2026-01-01T00:00:00Z"}`
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response { return &agentapi.Response{OK: true} })
+		if err := (&Backend{socket: f.socket}).Import(t.Context(), "ghes.example.com", "Iv1.x", value); err != nil {
+			t.Fatal(err)
+		}
+		reqs := f.reqs()
+		if len(reqs) != 1 {
+			t.Fatalf("want 1 request, got %d: %+v", len(reqs), reqs)
+		}
+		req := reqs[0]
+		if req.Command != agentapi.CommandImport || req.ClientID != "Iv1.x" || req.Host != "ghes.example.com" || string(req.Token) != value {
+			t.Fatalf("unexpected request: %+v", req)
+		}
+	})
+	t.Run("locked", func(t *testing.T) {
+		t.Parallel()
+		f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
+			return &agentapi.Response{Error: agentapi.RespLocked}
+		})
+		if err := (&Backend{socket: f.socket}).Import(t.Context(), "github.com", "Iv1.x", value); !errors.Is(err, agentapi.ErrAgentLocked) {
+			t.Fatalf("Import err = %v, want ErrAgentLocked", err)
+		}
+	})
+	t.Run("unknown command", func(t *testing.T) {
+		t.Parallel()
+		f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
+			return &agentapi.Response{Error: "unknown command: IMPORT"}
+		})
+		if err := (&Backend{socket: f.socket}).Import(t.Context(), "github.com", "Iv1.x", value); err == nil || !strings.Contains(err.Error(), "unknown command") {
+			t.Fatalf("Import err = %v, want the agent's error", err)
+		}
+	})
+}
+
+func TestBackend_list(t *testing.T) {
+	t.Parallel()
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+		f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
+			return &agentapi.Response{OK: true, Keys: []agentapi.TokenKey{
+				{ClientID: "Iv1.x"},
+				{ClientID: "Iv1.y", Host: "ghes.example.com"},
+			}}
+		})
+		got, err := (&Backend{socket: f.socket}).List(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []pubbackend.TokenKey{
+			{Host: "github.com", ClientID: "Iv1.x"},
+			{Host: "ghes.example.com", ClientID: "Iv1.y"},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("List() mismatch (-want +got):\n%s", diff)
+		}
+		if reqs := f.reqs(); len(reqs) != 1 || reqs[0].Command != agentapi.CommandList {
+			t.Fatalf("unexpected request: %+v", reqs)
+		}
+	})
+	t.Run("locked", func(t *testing.T) {
+		t.Parallel()
+		f := startFakeAgent(t, func(*agentapi.Request) *agentapi.Response {
+			return &agentapi.Response{Error: agentapi.RespLocked}
+		})
+		if _, err := (&Backend{socket: f.socket}).List(t.Context()); !errors.Is(err, agentapi.ErrAgentLocked) {
+			t.Fatalf("List err = %v, want ErrAgentLocked", err)
+		}
+	})
+}
@@ -13,11 +13,16 @@ import (
 	"time"
 
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 	pubdeviceflow "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/deviceflow"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend/agent"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend/exec"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend/file"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend/keyring"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend/text"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
 	publog "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
 )
 
 // Backend stores and retrieves access tokens through a pluggable inner backend.
@@ -27,31 +32,22 @@ type Backend struct {
 	backend backend
 }
 
-// backend is the interface implemented by concrete storage backends (keyring, text, ...).
-// Get returns (nil, nil) when no token is stored for the given client ID.
-// Delete removes the token stored for the client ID and is a no-op when none is stored.
-type backend interface {
-	Get(context.Context, string) ([]byte, error)
-	Set(context.Context, string, string) error
-	Delete(context.Context, string) error
-}
+// backend is the interface implemented by concrete storage backends (keyring, text,
+// and the ones registered with pubbackend.Register). See pubbackend.Backend.
+type backend = pubbackend.Backend
 
 // deviceFlowBackend is implemented by backends that own the token lifecycle
 // server-side (the agent): they check expiration, run the device flow, and revoke
 // tokens themselves. The api layer detects it via SupportsDeviceFlow and drives these
 // operations through the wrapper methods instead of the client-side equivalents.
-type deviceFlowBackend interface {
-	GetActive(ctx context.Context, clientID string, minExpiration time.Duration) ([]byte, error)
-	Begin(ctx context.Context, clientID string, minExpiration time.Duration) ([]byte, *pubdeviceflow.DeviceCodeResponse, error)
-	Poll(ctx context.Context, clientID string, minExpiration time.Duration) ([]byte, error)
-	RevokeTokens(ctx context.Context, clientIDs []string) (revokeFailed, cleanupFailed []string, err error)
-}
+type deviceFlowBackend = pubbackend.DeviceFlowBackend
 
 // New creates a Backend based on the GHTKN_BACKEND environment variable.
 // An empty value or "keyring" selects the OS keyring (the default); "agent" selects
-// the ghtkn agent; "text" selects the plaintext file backend. Any other value
-// returns an error. logger and slogLogger are only used by the agent backend to
-// surface its warnings; the keyring and text backends ignore them.
+// the ghtkn agent; "text" selects the plaintext file backend; "file" selects the
+// encrypted file backend; "exec" selects an external program; any other value selects
+// the backend registered under it with pubbackend.Register, and is an error when none
+// is. logger is only used by the agent backend to surface its warnings.
 func New(s string, getEnv func(string) string, logger *publog.Logger, slogLogger *slog.Logger) (*Backend, error) {
 	switch s {
 	case "agent":
@@ -63,42 +59,72 @@ func New(s string, getEnv func(string) string, logger *publog.Logger, slogLogger
 			backend: a,
 		}, nil
 	case "text":
-		t, err := text.New(getEnv)
+		t, err := text.New(getEnv, logger, slogLogger)
 		if err != nil {
 			return nil, err
 		}
 		return &Backend{
 			backend: t,
 		}, nil
+	case "exec":
+		e, err := exec.New(getEnv)
+		if err != nil {
+			return nil, err
+		}
+		return &Backend{
+			backend: e,
+		}, nil
+	case "file":
+		f, err := file.New(getEnv, logger, slogLogger)
+		if err != nil {
+			return nil, err
+		}
+		return &Backend{
+			backend: f,
+		}, nil
 	case "", "keyring":
+		timeout, err := keyring.Timeout(getEnv)
+		if err != nil {
+			return nil, err
+		}
+		name, err := profile.Name(getEnv)
+		if err != nil {
+			return nil, err
+		}
 		return &Backend{
 			backend: keyring.New(&keyring.Input{
-				ServiceKey: keyring.DefaultServiceKey,
+				ServiceKey: keyring.ServiceKey(name),
+				Timeout:    timeout,
+				Logger:     slogLogger,
 			}),
 		}, nil
 	default:
-		return nil, fmt.Errorf("unsupported backend: %s", s)
+		factory, ok := pubbackend.Lookup(s)
+		if !ok {
+			return nil, fmt.Errorf("unsupported backend: %s", s)
+		}
+		b, err := factory(&pubbackend.Input{Getenv: getEnv, Logger: slogLogger})
+		if err != nil {
+			return nil, fmt.Errorf("create a backend: %w", slogerr.With(err, "backend", s))
+		}
+		return Wrap(b), nil
 	}
 }
 
-// Get retrieves and validates the access token stored for clientID.
-// It returns (nil, nil) when no token is stored.
-func (b *Backend) Get(ctx context.Context, clientID string) (*api.AccessToken, error) {
-	bt, err := b.backend.Get(ctx, clientID)
+// Wrap returns the Backend storing tokens in b.
+func Wrap(b pubbackend.Backend) *Backend {
+	return &Backend{backend: b}
+}
+
+// Get retrieves and validates the access token stored for clientID on host.
+// It returns (nil, nil) when no token is stored. A payload stored before the token
+// metadata existed is still readable; see decodeToken.
+func (b *Backend) Get(ctx context.Context, host, clientID string) (*api.AccessToken, error) {
+	bt, err := b.backend.Get(ctx, host, clientID)
 	if err != nil {
 		return nil, fmt.Errorf("get a token from the backend: %w", err)
 	}
-	if bt == nil {
-		return nil, nil
-	}
-	token := &api.AccessToken{}
-	if err := json.Unmarshal(bt, token); err != nil {
-		return nil, fmt.Errorf("unmarshal the token as JSON: %w", err)
-	}
-	if err := token.Validate(); err != nil {
-		return nil, fmt.Errorf("the token in the backend is invalid: %w", err)
-	}
-	return token, nil
+	return decodeToken(bt, host, clientID)
 }
 
 // SupportsDeviceFlow reports whether the inner backend owns the token lifecycle
@@ -110,66 +136,67 @@ func (b *Backend) SupportsDeviceFlow() bool {
 	return ok
 }
 
-// GetActive returns the token stored for clientID that is still valid for at least
+// GetActive returns the token stored for clientID on host that is still valid for at least
 // minExpiration, or nil when there is no such token. The freshness check runs
 // server-side. It is only valid on a backend where SupportsDeviceFlow reports true.
-func (b *Backend) GetActive(ctx context.Context, clientID string, minExpiration time.Duration) (*api.AccessToken, error) {
+func (b *Backend) GetActive(ctx context.Context, host, clientID string, minExpiration time.Duration) (*api.AccessToken, error) {
 	df, ok := b.backend.(deviceFlowBackend)
 	if !ok {
 		return nil, errors.New("the backend does not check expiration itself")
 	}
-	bt, err := df.GetActive(ctx, clientID, minExpiration)
+	bt, err := df.GetActive(ctx, host, clientID, minExpiration)
 	if err != nil {
 		return nil, fmt.Errorf("get an active token from the backend: %w", err)
 	}
-	return decodeToken(bt)
+	return decodeToken(bt, host, clientID)
 }
 
-// BeginDeviceFlow asks the backend to start the server-side device flow for clientID.
+// BeginDeviceFlow asks the backend to start the server-side device flow for clientID
+// on host.
 // If a token valid for minExpiration already exists it is returned directly and the
 // returned device code is nil; otherwise the token is nil and the device code carries
 // the one-time code to display. Exactly one of the two is non-nil.
-func (b *Backend) BeginDeviceFlow(ctx context.Context, clientID string, minExpiration time.Duration) (*api.AccessToken, *pubdeviceflow.DeviceCodeResponse, error) {
+func (b *Backend) BeginDeviceFlow(ctx context.Context, host, clientID string, minExpiration time.Duration) (*api.AccessToken, *pubdeviceflow.DeviceCodeResponse, error) {
 	df, ok := b.backend.(deviceFlowBackend)
 	if !ok {
 		return nil, nil, errors.New("the backend does not run the device flow itself")
 	}
-	bt, dc, err := df.Begin(ctx, clientID, minExpiration)
+	bt, dc, err := df.Begin(ctx, host, clientID, minExpiration)
 	if err != nil {
 		return nil, nil, fmt.Errorf("begin the device flow through the backend: %w", err)
 	}
 	if bt != nil {
-		token, err := decodeToken(bt)
+		token, err := decodeToken(bt, host, clientID)
 		return token, nil, err
 	}
 	return nil, dc, nil
 }
 
 // PollDeviceFlow waits for the backend to finish the server-side device flow for
-// clientID and returns the validated token it minted.
-func (b *Backend) PollDeviceFlow(ctx context.Context, clientID string, minExpiration time.Duration) (*api.AccessToken, error) {
+// clientID on host and returns the validated token it minted.
+func (b *Backend) PollDeviceFlow(ctx context.Context, host, clientID string, minExpiration time.Duration) (*api.AccessToken, error) {
 	df, ok := b.backend.(deviceFlowBackend)
 	if !ok {
 		return nil, errors.New("the backend does not run the device flow itself")
 	}
-	bt, err := df.Poll(ctx, clientID, minExpiration)
+	bt, err := df.Poll(ctx, host, clientID, minExpiration)
 	if err != nil {
 		return nil, fmt.Errorf("wait for the device flow through the backend: %w", err)
 	}
-	return decodeToken(bt)
+	return decodeToken(bt, host, clientID)
 }
 
-// RevokeTokens asks the backend to revoke the tokens stored for clientIDs in one batch
-// and delete them. It returns the client IDs whose credential could not be revoked (it
+// RevokeTokens asks the backend to revoke the tokens stored for clientIDs on host in
+// one batch and delete them. It returns the client IDs whose credential could not be revoked (it
 // may be live) and those revoked but not deleted (a cleanup issue), so the caller can
 // classify each. A non-nil error means the request itself failed. It is only valid on
 // a backend where SupportsDeviceFlow reports true.
-func (b *Backend) RevokeTokens(ctx context.Context, clientIDs []string) (revokeFailed, cleanupFailed []string, err error) {
+func (b *Backend) RevokeTokens(ctx context.Context, host string, clientIDs []string) (revokeFailed, cleanupFailed []string, err error) {
 	df, ok := b.backend.(deviceFlowBackend)
 	if !ok {
 		return nil, nil, errors.New("the backend does not revoke tokens itself")
 	}
-	revokeFailed, cleanupFailed, err = df.RevokeTokens(ctx, clientIDs)
+	revokeFailed, cleanupFailed, err = df.RevokeTokens(ctx, host, clientIDs)
 	if err != nil {
 		return nil, nil, fmt.Errorf("revoke tokens through the backend: %w", err)
 	}
@@ -177,8 +204,15 @@ func (b *Backend) RevokeTokens(ctx context.Context, clientIDs []string) (revokeF
 }
 
 // decodeToken unmarshals and validates raw token bytes, returning (nil, nil) when the
-// bytes are empty.
-func decodeToken(bt []byte) (*api.AccessToken, error) {
+// bytes are empty. host and clientID identify where the token was stored.
+//
+// A payload older than api.AccessTokenSchemaVersion (or one minted by the agent, which
+// does not record the metadata) is read as is, and the metadata that can be inferred is
+// filled in: the client ID and the host are the ones it was stored under (a token
+// stored before the host was recorded is always a github.com one, stored under the
+// bare client ID). Metadata that can't be inferred (issued_at, login, app_name) is left
+// empty rather than guessed.
+func decodeToken(bt []byte, host, clientID string) (*api.AccessToken, error) {
 	if len(bt) == 0 {
 		return nil, nil
 	}
@@ -189,25 +223,84 @@ func decodeToken(bt []byte) (*api.AccessToken, error) {
 	if err := token.Validate(); err != nil {
 		return nil, fmt.Errorf("the token from the backend is invalid: %w", err)
 	}
+	if token.ClientID == "" {
+		token.ClientID = clientID
+	}
+	if token.Host == "" {
+		token.Host = host
+	}
+	if token.Host == "" {
+		token.Host = api.DefaultHost
+	}
 	return token, nil
 }
 
-// Delete removes the token stored for clientID. It is a no-op when no token is stored.
-func (b *Backend) Delete(ctx context.Context, clientID string) error {
-	if err := b.backend.Delete(ctx, clientID); err != nil {
+// ErrListUnsupported is returned by List when the inner backend can't enumerate the
+// tokens it stores.
+var ErrListUnsupported = errors.New("the backend can't list the tokens it stores")
+
+// List returns the keys of the tokens the backend stores. It returns
+// ErrListUnsupported when the inner backend doesn't implement pubbackend.Lister.
+func (b *Backend) List(ctx context.Context) ([]pubbackend.TokenKey, error) {
+	l, ok := b.backend.(pubbackend.Lister)
+	if !ok {
+		return nil, ErrListUnsupported
+	}
+	keys, err := l.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list the tokens of the backend: %w", err)
+	}
+	return keys, nil
+}
+
+// Delete removes the token stored for clientID on host. It is a no-op when no token is
+// stored.
+func (b *Backend) Delete(ctx context.Context, host, clientID string) error {
+	if err := b.backend.Delete(ctx, host, clientID); err != nil {
 		return fmt.Errorf("delete a token from the backend: %w", err)
 	}
 	return nil
 }
 
-// Set marshals token to JSON and stores it for clientID.
-func (b *Backend) Set(ctx context.Context, clientID string, token *api.AccessToken) error {
-	bts, err := json.Marshal(token)
+// Set marshals token to JSON and stores it for clientID on host, stamped with the
+// current api.AccessTokenSchemaVersion.
+func (b *Backend) Set(ctx context.Context, host, clientID string, token *api.AccessToken) error {
+	bts, err := encodeToken(token)
 	if err != nil {
-		return fmt.Errorf("marshal the token as JSON: %w", err)
+		return err
 	}
-	if err := b.backend.Set(ctx, clientID, string(bts)); err != nil {
+	if err := b.backend.Set(ctx, host, clientID, bts); err != nil {
 		return fmt.Errorf("set a token to the backend: %w", err)
 	}
 	return nil
 }
+
+// Import stores token, moved from another backend, for clientID on host. It hands the
+// token to a backend implementing pubbackend.Importer (the agent) with Import, and
+// stores it with Set otherwise.
+func (b *Backend) Import(ctx context.Context, host, clientID string, token *api.AccessToken) error {
+	im, ok := b.backend.(pubbackend.Importer)
+	if !ok {
+		return b.Set(ctx, host, clientID, token)
+	}
+	bts, err := encodeToken(token)
+	if err != nil {
+		return err
+	}
+	if err := im.Import(ctx, host, clientID, bts); err != nil {
+		return fmt.Errorf("import a token to the backend: %w", err)
+	}
+	return nil
+}
+
+// encodeToken marshals token to JSON, stamped with the current
+// api.AccessTokenSchemaVersion.
+func encodeToken(token *api.AccessToken) (string, error) {
+	tk := *token
+	tk.SchemaVersion = api.AccessTokenSchemaVersion
+	bts, err := json.Marshal(&tk)
+	if err != nil {
+		return "", fmt.Errorf("marshal the token as JSON: %w", err)
+	}
+	return string(bts), nil
+}
@@ -8,7 +8,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 )
 
 // mockInner is a stub implementation of the inner backend interface.
@@ -19,18 +21,18 @@ type mockInner struct {
 	del  func(clientID string) error
 }
 
-func (m *mockInner) Get(_ context.Context, _ string) ([]byte, error) {
+func (m *mockInner) Get(_ context.Context, _, _ string) ([]byte, error) {
 	return m.data, m.err
 }
 
-func (m *mockInner) Set(_ context.Context, clientID, token string) error {
+func (m *mockInner) Set(_ context.Context, _, clientID, token string) error {
 	if m.set != nil {
 		return m.set(clientID, token)
 	}
 	return nil
 }
 
-func (m *mockInner) Delete(_ context.Context, clientID string) error {
+func (m *mockInner) Delete(_ context.Context, _, clientID string) error {
 	if m.del != nil {
 		return m.del(clientID)
 	}
@@ -44,6 +46,7 @@ func TestBackend_Get(t *testing.T) {
 	valid := `{"access_token":"tok","expiration_date":"2025-01-15T10:30:00Z"}`
 	tests := []struct {
 		name    string
+		host    string // defaults to github.com
 		inner   *mockInner
 		want    *api.AccessToken
 		wantErr bool
@@ -51,7 +54,7 @@ func TestBackend_Get(t *testing.T) {
 		{
 			name:  "valid token",
 			inner: &mockInner{data: []byte(valid)},
-			want:  &api.AccessToken{AccessToken: "tok", ExpirationDate: exp},
+			want:  &api.AccessToken{AccessToken: "tok", ExpirationDate: exp, ClientID: "client-id", Host: "github.com"},
 		},
 		{
 			name:  "not found returns nil",
@@ -79,15 +82,57 @@ func TestBackend_Get(t *testing.T) {
 			// never-expiring.
 			name:  "never-expiring token round-trips",
 			inner: &mockInner{data: []byte(`{"access_token":"tok"}`)},
-			want:  &api.AccessToken{AccessToken: "tok"},
+			want:  &api.AccessToken{AccessToken: "tok", ClientID: "client-id", Host: "github.com"},
+		},
+		{
+			// The client-side backends store the refresh token so Get can renew an
+			// expiring token without the device flow.
+			name:  "refresh token round-trips",
+			inner: &mockInner{data: []byte(`{"access_token":"tok","expiration_date":"2025-01-15T10:30:00Z","refresh_token":"ghr","refresh_token_expiration_date":"2025-07-15T10:30:00Z"}`)},
+			want: &api.AccessToken{
+				AccessToken:                "tok",
+				ExpirationDate:             exp,
+				RefreshToken:               "ghr",
+				RefreshTokenExpirationDate: time.Date(2025, 7, 15, 10, 30, 0, 0, time.UTC),
+				ClientID:                   "client-id",
+				Host:                       "github.com",
+			},
+		},
+		{
+			// A current payload carries its metadata, which is returned as stored rather
+			// than overwritten by what a legacy payload would have inferred.
+			name:  "metadata round-trips",
+			inner: &mockInner{data: []byte(`{"schema_version":1,"access_token":"tok","expiration_date":"2025-01-15T10:30:00Z","issued_at":"2025-01-15T02:30:00Z","app_name":"app","client_id":"Iv1.other","login":"octocat","host":"ghes.example.com"}`)},
+			want: &api.AccessToken{
+				SchemaVersion:  1,
+				AccessToken:    "tok",
+				ExpirationDate: exp,
+				IssuedAt:       time.Date(2025, 1, 15, 2, 30, 0, 0, time.UTC),
+				AppName:        "app",
+				ClientID:       "Iv1.other",
+				Login:          "octocat",
+				Host:           "ghes.example.com",
+			},
+		},
+		{
+			// A payload that doesn't record its host (e.g. minted by the agent) is for the
+			// host it was stored under.
+			name:  "the host is inferred from where the token was stored",
+			host:  "ghes.example.com",
+			inner: &mockInner{data: []byte(valid)},
+			want:  &api.AccessToken{AccessToken: "tok", ExpirationDate: exp, ClientID: "client-id", Host: "ghes.example.com"},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
+			host := tt.host
+			if host == "" {
+				host = "github.com"
+			}
 			b := &Backend{backend: tt.inner}
-			got, err := b.Get(t.Context(), "client-id")
+			got, err := b.Get(t.Context(), host, "client-id")
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -103,9 +148,8 @@ func TestBackend_Get(t *testing.T) {
 			if got == nil {
 				t.Fatalf("Get() = nil, want %v", tt.want)
 			}
-			if got.AccessToken != tt.want.AccessToken ||
-				!got.ExpirationDate.Equal(tt.want.ExpirationDate) {
-				t.Errorf("Get() = %+v, want %+v", got, tt.want)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Get() mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
@@ -126,7 +170,7 @@ func TestBackend_Set(t *testing.T) {
 			AccessToken:    "tok",
 			ExpirationDate: time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
 		}
-		if err := b.Set(t.Context(), "client-id", token); err != nil {
+		if err := b.Set(t.Context(), "github.com", "client-id", token); err != nil {
 			t.Fatalf("Set() error = %v", err)
 		}
 		got := &api.AccessToken{}
@@ -136,6 +180,12 @@ func TestBackend_Set(t *testing.T) {
 		if got.AccessToken != token.AccessToken {
 			t.Errorf("stored token = %+v, want %+v", got, token)
 		}
+		if got.SchemaVersion != api.AccessTokenSchemaVersion {
+			t.Errorf("stored schema_version = %d, want %d", got.SchemaVersion, api.AccessTokenSchemaVersion)
+		}
+		if token.SchemaVersion != 0 {
+			t.Error("Set() modified the caller's token")
+		}
 	})
 
 	t.Run("propagates inner errors", func(t *testing.T) {
@@ -144,7 +194,7 @@ func TestBackend_Set(t *testing.T) {
 		b := &Backend{backend: &mockInner{set: func(_, _ string) error {
 			return errors.New("boom")
 		}}}
-		if err := b.Set(t.Context(), "client-id", &api.AccessToken{}); err == nil {
+		if err := b.Set(t.Context(), "github.com", "client-id", &api.AccessToken{}); err == nil {
 			t.Error("Set() expected an error, got nil")
 		}
 	})
@@ -161,7 +211,7 @@ func TestBackend_Delete(t *testing.T) {
 			gotClientID = clientID
 			return nil
 		}}}
-		if err := b.Delete(t.Context(), "client-id"); err != nil {
+		if err := b.Delete(t.Context(), "github.com", "client-id"); err != nil {
 			t.Fatalf("Delete() error = %v", err)
 		}
 		if gotClientID != "client-id" {
@@ -175,7 +225,7 @@ func TestBackend_Delete(t *testing.T) {
 		b := &Backend{backend: &mockInner{del: func(string) error {
 			return errors.New("boom")
 		}}}
-		if err := b.Delete(t.Context(), "client-id"); err == nil {
+		if err := b.Delete(t.Context(), "github.com", "client-id"); err == nil {
 			t.Error("Delete() expected an error, got nil")
 		}
 	})
@@ -210,9 +260,66 @@ func TestNew(t *testing.T) {
 		}
 	})
 
+	t.Run("file", func(t *testing.T) {
+		envs := map[string]string{
+			"GHTKN_FILE_BACKEND_DIR":        t.TempDir(),
+			"GHTKN_FILE_BACKEND_PASSPHRASE": "passphrase",
+		}
+		if _, err := New("file", func(s string) string { return envs[s] }, nil, nil); err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		delete(envs, "GHTKN_FILE_BACKEND_PASSPHRASE")
+		if _, err := New("file", func(s string) string { return envs[s] }, nil, nil); err == nil {
+			t.Error("New() expected an error without a key")
+		}
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		envs := map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": "ghtkn-helper"}
+		if _, err := New("exec", func(s string) string { return envs[s] }, nil, nil); err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if _, err := New("exec", func(string) string { return "" }, nil, nil); err == nil {
+			t.Error("New() expected an error without a command")
+		}
+	})
+
 	t.Run("unsupported backend errors", func(t *testing.T) {
 		if _, err := New("bogus", os.Getenv, nil, nil); err == nil {
 			t.Error("New() expected an error for an unsupported backend")
 		}
 	})
+	t.Run("registered backend", func(t *testing.T) {
+		inner := &mockInner{data: []byte(`{"access_token":"tok"}`)}
+		if err := pubbackend.Register("test-new", func(input *pubbackend.Input) (pubbackend.Backend, error) {
+			if input.Getenv("GHTKN_TEST") != "x" {
+				t.Error("the factory must be given getEnv")
+			}
+			return inner, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		b, err := New("test-new", func(string) string { return "x" }, nil, nil)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		tk, err := b.Get(t.Context(), "github.com", "client-id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tk.AccessToken != "tok" {
+			t.Errorf("AccessToken = %q, want the token of the registered backend", tk.AccessToken)
+		}
+	})
+
+	t.Run("registered backend factory errors", func(t *testing.T) {
+		if err := pubbackend.Register("test-new-error", func(*pubbackend.Input) (pubbackend.Backend, error) {
+			return nil, errors.New("boom")
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New("test-new-error", os.Getenv, nil, nil); err == nil {
+			t.Error("New() expected the error of the factory")
+		}
+	})
 }
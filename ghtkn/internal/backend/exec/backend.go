@@ -0,0 +1,258 @@
+// Package exec provides a backend that delegates storing GitHub access tokens to an
+// external program, in the style of git credential helpers and Docker credential
+// helpers. It lets a token be kept in a password manager or a vault, such as pass or
+// the 1Password CLI, through a small wrapper script without the SDK linking them.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+// The program is GHTKN_EXEC_BACKEND_COMMAND, either a JSON array of the program and its
+// arguments, such as ["C:\\Program Files\\helper.exe", "--vault", "ci"], or a command
+// line split into them on white space as a shell does, where single and double quotes
+// and a backslash keep white space in an argument (see splitCommand). Each operation
+// runs it once with the operation appended as the last argument: get, store, or erase.
+// It reads a JSON request from stdin:
+//
+//	{"host": "github.com", "client_id": "Iv1.xxx", "token": "..."}
+//
+// token is only given to store; it is the token the SDK serialized, which the program
+// stores as is. profile is given besides host and client_id when a profile other than
+// the default one is selected (see GHTKN_PROFILE), and the program must keep the tokens
+// of each profile apart. For get, the program writes the stored token to stdout as
+//
+//	{"token": "..."}
+//
+// The program exits with 0 on success, and with 3 when no token is stored for get
+// and erase, which erase treats as success. Any other exit status is an error, which
+// includes what the program wrote to stderr. An operation that takes longer than
+// GHTKN_EXEC_BACKEND_TIMEOUT (a Go duration, 10s by default), or whose context is done
+// first, is killed and fails with backend.ErrBackendTimeout.
+
+const (
+	// ExitNotFound is the exit status with which the program reports that no token is
+	// stored.
+	ExitNotFound   = 3
+	defaultTimeout = 10 * time.Second
+	// maxStderr is how much of stderr an error includes, from its end.
+	maxStderr = 4096
+)
+
+// Backend stores access tokens through an external program.
+type Backend struct {
+	command []string
+	timeout time.Duration
+	// profile is the profile the tokens are stored in, "" for the default profile.
+	profile string
+}
+
+// New creates an exec backend running the program GHTKN_EXEC_BACKEND_COMMAND. It returns
+// an error if it is unset or invalid, or GHTKN_EXEC_BACKEND_TIMEOUT is invalid.
+func New(getEnv func(string) string) (*Backend, error) {
+	command, err := splitCommand(getEnv(env.ExecBackendCommand))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", env.ExecBackendCommand, err)
+	}
+	if len(command) == 0 || command[0] == "" {
+		return nil, fmt.Errorf("%s is required to use the exec backend", env.ExecBackendCommand)
+	}
+	name, err := profile.Name(getEnv)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	b := &Backend{command: command, timeout: defaultTimeout, profile: name}
+	if s := getEnv(env.ExecBackendTimeout); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s as a duration: %w", env.ExecBackendTimeout, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%s must be positive", env.ExecBackendTimeout)
+		}
+		b.timeout = d
+	}
+	return b, nil
+}
+
+// splitCommand splits s into the program and its arguments. s is a JSON array of
+// strings when it starts with '['. Otherwise it is split on white space, except within
+// single quotes, which keep everything up to the closing quote as is, and within
+// double quotes, where a backslash only escapes '"' and '\\'. Outside quotes a
+// backslash keeps the next character as is.
+func splitCommand(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		var args []string
+		if err := json.Unmarshal([]byte(s), &args); err != nil {
+			return nil, fmt.Errorf("decode the command as a JSON array of strings: %w", err)
+		}
+		return args, nil
+	}
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+				continue
+			}
+			arg.WriteRune(r)
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("the quote %c isn't closed", quote)
+	}
+	if escaped {
+		return nil, errors.New("the command ends with a backslash")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// request is what the program reads from stdin.
+type request struct {
+	Host     string `json:"host"`
+	ClientID string `json:"client_id"`
+	Profile  string `json:"profile,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// getResponse is what the program writes to stdout for get.
+type getResponse struct {
+	Token string `json:"token"`
+}
+
+// errNotFound is returned by run when the program exits with ExitNotFound.
+var errNotFound = errors.New("no token is stored")
+
+// Get returns the token the program has stored for clientID on host. It returns
+// (nil, nil) when the program reports none is stored.
+func (b *Backend) Get(ctx context.Context, host, clientID string) ([]byte, error) {
+	stdout, err := b.run(ctx, "get", &request{Host: host, ClientID: clientID})
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	resp := &getResponse{}
+	if err := json.Unmarshal(stdout, resp); err != nil {
+		return nil, fmt.Errorf("decode the output of the exec backend as JSON: %w", err)
+	}
+	if resp.Token == "" {
+		return nil, errors.New("the exec backend returned an empty token")
+	}
+	return []byte(resp.Token), nil
+}
+
+// Set has the program store the token for clientID on host.
+func (b *Backend) Set(ctx context.Context, host, clientID, token string) error {
+	if _, err := b.run(ctx, "store", &request{Host: host, ClientID: clientID, Token: token}); err != nil {
+		if errors.Is(err, errNotFound) {
+			return fmt.Errorf("the exec backend exited with status %d for store", ExitNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete has the program erase the token for clientID on host. It is a no-op when the
+// program reports none is stored.
+func (b *Backend) Delete(ctx context.Context, host, clientID string) error {
+	if _, err := b.run(ctx, "erase", &request{Host: host, ClientID: clientID}); err != nil && !errors.Is(err, errNotFound) {
+		return err
+	}
+	return nil
+}
+
+// run runs the program for the operation op with req as stdin, and returns its stdout.
+func (b *Backend) run(ctx context.Context, op string, req *request) ([]byte, error) {
+	req.Profile = b.profile
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encode a request to the exec backend as JSON: %w", err)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, b.command[0], append(b.command[1:], op)...) //nolint:gosec // the command is configured by the user
+	cmd.Stdin = bytes.NewReader(in)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Don't wait long for a child the program left holding stdout or stderr.
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		switch {
+		case ctx.Err() != nil:
+			err = fmt.Errorf("%w: %w", pubbackend.ErrBackendTimeout, context.Cause(ctx))
+		case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+			err = fmt.Errorf("%w: the exec backend timed out after %s", pubbackend.ErrBackendTimeout, b.timeout)
+		case errors.As(err, &exitErr) && exitErr.ExitCode() == ExitNotFound:
+			return nil, errNotFound
+		}
+		if msg := tail(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("run the exec backend: %w", slogerr.With(err, "operation", op))
+	}
+	return stdout.Bytes(), nil
+}
+
+// tail returns the end of the program's stderr, which an error includes.
+func tail(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxStderr {
+		s = "..." + s[len(s)-maxStderr:]
+	}
+	return s
+}
@@ -0,0 +1,233 @@
+package exec
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+)
+
+// fakeHelper is a credential helper storing tokens as files in the directory it is
+// given, keyed by the client ID. It logs each operation to stderr.
+const fakeHelper = `#!/bin/sh
+set -eu
+dir=$1
+op=$2
+req=$(cat)
+id=$(printf '%s' "$req" | sed 's/.*"client_id":"\([^"]*\)".*/\1/')
+case $op in
+get)
+  [ -f "$dir/$id" ] || exit 3
+  cat "$dir/$id"
+  ;;
+store)
+  printf '%s' "$req" | sed 's/.*"token":"\(.*\)"}$/{"token":"\1"}/' > "$dir/$id"
+  ;;
+erase)
+  [ -f "$dir/$id" ] || exit 3
+  rm "$dir/$id"
+  ;;
+*)
+  echo "unknown operation: $op" >&2
+  exit 1
+  ;;
+esac
+`
+
+func writeScript(t *testing.T, content string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake helper is a shell script")
+	}
+	p := filepath.Join(t.TempDir(), "helper")
+	if err := os.WriteFile(p, []byte(content), 0o700); err != nil { //nolint:gosec // the script must be executable
+		t.Fatal(err)
+	}
+	return p
+}
+
+// The tests running a script aren't parallel, since running a script while another
+// test still has its script open for writing can fail with ETXTBSY.
+
+func TestBackend(t *testing.T) {
+	script := writeScript(t, fakeHelper)
+	b, err := New(func(k string) string {
+		if k == "GHTKN_EXEC_BACKEND_COMMAND" {
+			return script + " " + t.TempDir()
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := t.Context()
+
+	got, err := b.Get(ctx, "github.com", "client-id")
+	if err != nil {
+		t.Fatalf("Get() before Set error = %v", err)
+	}
+	if got != nil {
+		t.Fatalf("Get() before Set = %q, want nil", got)
+	}
+	if err := b.Delete(ctx, "github.com", "client-id"); err != nil {
+		t.Fatalf("Delete() before Set error = %v", err)
+	}
+
+	if err := b.Set(ctx, "github.com", "client-id", "token"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, err = b.Get(ctx, "github.com", "client-id")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if diff := cmp.Diff([]byte("token"), got); diff != "" {
+		t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := b.Delete(ctx, "github.com", "client-id"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	got, err = b.Get(ctx, "github.com", "client-id")
+	if err != nil {
+		t.Fatalf("Get() after Delete error = %v", err)
+	}
+	if got != nil {
+		t.Errorf("Get() after Delete = %q, want nil", got)
+	}
+}
+
+func TestBackend_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		wantErr string
+		isErr   error
+	}{
+		{
+			name:    "stderr is included",
+			script:  "#!/bin/sh\necho 'vault is sealed' >&2\nexit 1\n",
+			wantErr: "vault is sealed",
+		},
+		{
+			name:    "invalid output",
+			script:  "#!/bin/sh\necho not json\n",
+			wantErr: "decode the output of the exec backend as JSON",
+		},
+		{
+			name:    "empty token",
+			script:  "#!/bin/sh\necho '{}'\n",
+			wantErr: "empty token",
+		},
+		{
+			name:    "timeout",
+			script:  "#!/bin/sh\nexec sleep 10\n",
+			timeout: 100 * time.Millisecond,
+			wantErr: "timed out after 100ms",
+			isErr:   pubbackend.ErrBackendTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Backend{command: []string{writeScript(t, tt.script)}, timeout: defaultTimeout}
+			if tt.timeout != 0 {
+				b.timeout = tt.timeout
+			}
+			_, err := b.Get(t.Context(), "github.com", "client-id")
+			if err == nil {
+				t.Fatal("Get() expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Get() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+			if tt.isErr != nil && !errors.Is(err, tt.isErr) {
+				t.Errorf("Get() error = %q, want it to wrap %q", err, tt.isErr)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		envs    map[string]string
+		want    *Backend
+		wantErr bool
+	}{
+		{
+			name: "command with arguments",
+			envs: map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": "ghtkn-helper --vault ci"},
+			want: &Backend{command: []string{"ghtkn-helper", "--vault", "ci"}, timeout: defaultTimeout},
+		},
+		{
+			name: "quoted program path",
+			envs: map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": `"/opt/my tools/ghtkn-helper" --vault 'team ci' a\ b "say \"hi\""`},
+			want: &Backend{command: []string{"/opt/my tools/ghtkn-helper", "--vault", "team ci", "a b", `say "hi"`}, timeout: defaultTimeout},
+		},
+		{
+			name: "JSON array",
+			envs: map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": `["C:\\Program Files\\helper.exe", "--vault", "ci"]`},
+			want: &Backend{command: []string{`C:\Program Files\helper.exe`, "--vault", "ci"}, timeout: defaultTimeout},
+		},
+		{
+			name: "empty quoted argument",
+			envs: map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": `ghtkn-helper ""`},
+			want: &Backend{command: []string{"ghtkn-helper", ""}, timeout: defaultTimeout},
+		},
+		{
+			name:    "unclosed quote",
+			envs:    map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": `"/opt/my tools/ghtkn-helper`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON array",
+			envs:    map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": `["ghtkn-helper", 1]`},
+			wantErr: true,
+		},
+		{
+			name:    "empty JSON array",
+			envs:    map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": `[]`},
+			wantErr: true,
+		},
+		{
+			name: "timeout",
+			envs: map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": "ghtkn-helper", "GHTKN_EXEC_BACKEND_TIMEOUT": "30s"},
+			want: &Backend{command: []string{"ghtkn-helper"}, timeout: 30 * time.Second},
+		},
+		{
+			name:    "no command",
+			envs:    map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid timeout",
+			envs:    map[string]string{"GHTKN_EXEC_BACKEND_COMMAND": "ghtkn-helper", "GHTKN_EXEC_BACKEND_TIMEOUT": "0s"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := New(func(k string) string { return tt.envs[k] })
+			if err != nil {
+				if !tt.wantErr {
+					t.Fatalf("New() error = %v", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("New() expected an error")
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(Backend{})); diff != "" {
+				t.Errorf("New() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package file provides an encrypted file backend for GitHub access tokens.
+// Tokens are stored like the text backend's, one file per token under the user's
+// cache directory, but each file is encrypted, so a token doesn't leak through a copy
+// of the directory such as a backup. It targets environments where the OS keyring is
+// unavailable but plaintext files are unacceptable.
+package file
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/backend/text"
+	publog "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The access token is saved to ${XDG_CACHE_HOME}/ghtkn/encrypted-tokens/<client-id>
+// (%LocalAppData%\cache\ghtkn\encrypted-tokens\<client-id> on Windows), named as the
+// text backend names it. The directory can be overridden with the GHTKN_FILE_BACKEND_DIR
+// environment variable. The files are written as the text backend writes them.
+//
+// Each file is a JSON envelope holding the token encrypted with XChaCha20-Poly1305. The
+// storage key of the token is the additional data, so a file renamed to another token's
+// name fails to decrypt. The key is taken from the first of these that is set:
+//
+//   - GHTKN_FILE_BACKEND_KEY: the key encoded in base64.
+//   - GHTKN_FILE_BACKEND_KEY_FILE: the path of a file holding the key encoded in base64.
+//     It must be a regular file owned by the current user that other users can't read
+//     or write, as the key would otherwise be as exposed as a plaintext token.
+//   - GHTKN_FILE_BACKEND_PASSPHRASE: a passphrase the key is derived from with Argon2id,
+//     with a random salt per file.
+
+const (
+	// envelopeVersion is the version of the format of the envelope.
+	envelopeVersion = 1
+	// kdfNone marks a file encrypted with the key as is.
+	kdfNone = "none"
+	// kdfArgon2id marks a file encrypted with a key derived from a passphrase.
+	kdfArgon2id = "argon2id"
+	saltSize    = 16
+)
+
+// defaultArgon2Params are the Argon2id parameters a new file is written with. They are
+// recorded in the envelope, so they can be raised without breaking existing files.
+var defaultArgon2Params = &argon2Params{Time: 1, Memory: 64 * 1024, Threads: 4}
+
+// The bounds of the Argon2id parameters a token file may specify. Since the parameters
+// are read from the file, they are checked before the key is derived, so a corrupt or
+// crafted file can't make argon2.IDKey panic, allocate gigabytes, or run for hours.
+const (
+	maxArgon2Time = 64
+	// maxArgon2Memory is 1 GiB in KiB.
+	maxArgon2Memory = 1024 * 1024
+)
+
+type argon2Params struct {
+	Time uint32 `json:"time"`
+	// Memory is in KiB.
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// validate checks p is within the bounds Argon2id accepts and ghtkn allows: at least
+// one pass and one thread, and at least 8 KiB per thread up to 1 GiB of memory.
+func (p *argon2Params) validate() error {
+	if p.Time < 1 || p.Time > maxArgon2Time {
+		return slogerr.With(errors.New("the Argon2 time is out of range"), "time", p.Time, "max", maxArgon2Time) //nolint:wrapcheck
+	}
+	if p.Threads < 1 {
+		return errors.New("the Argon2 threads must be at least 1")
+	}
+	if p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory {
+		return slogerr.With(errors.New("the Argon2 memory is out of range"), //nolint:wrapcheck
+			"memory_kib", p.Memory, "min_kib", 8*uint32(p.Threads), "max_kib", maxArgon2Memory)
+	}
+	return nil
+}
+
+// envelope is the content of a token file.
+type envelope struct {
+	Version    int           `json:"version"`
+	KDF        string        `json:"kdf"`
+	Argon2     *argon2Params `json:"argon2,omitempty"`
+	Salt       []byte        `json:"salt,omitempty"`
+	Nonce      []byte        `json:"nonce"`
+	Ciphertext []byte        `json:"ciphertext"`
+}
+
+// Backend stores access tokens as encrypted files under a directory.
+type Backend struct {
+	files *text.Backend
+	// key is the encryption key, nil when it is derived from passphrase.
+	key        []byte
+	passphrase string
+}
+
+// New creates a file backend. The storage directory is GHTKN_FILE_BACKEND_DIR if set,
+// otherwise ${XDG_CACHE_HOME}/ghtkn/encrypted-tokens (falling back to $HOME/.cache), or
+// %LocalAppData%\cache\ghtkn\encrypted-tokens on Windows. It returns an error if none
+// of these are set, or if no key is configured. logger and slogLogger are used to
+// report insecure permissions of the files, as the text backend does.
+func New(getEnv func(string) string, logger *publog.Logger, slogLogger *slog.Logger) (*Backend, error) {
+	dir, err := text.Dir(getEnv, runtime.GOOS, env.FileBackendDir, "encrypted-tokens")
+	if err != nil {
+		return nil, err
+	}
+	b := &Backend{files: text.NewWithDir(dir, logger, slogLogger)}
+	switch {
+	case getEnv(env.FileBackendKey) != "":
+		key, err := decodeKey(getEnv(env.FileBackendKey))
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", env.FileBackendKey, err)
+		}
+		b.key = key
+	case getEnv(env.FileBackendKeyFile) != "":
+		p := getEnv(env.FileBackendKeyFile)
+		bt, err := text.ReadSecretFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("read the key file of the file backend: %w", err)
+		}
+		key, err := decodeKey(string(bt))
+		if err != nil {
+			return nil, fmt.Errorf("decode the key file of the file backend %s: %w", p, err)
+		}
+		b.key = key
+	case getEnv(env.FileBackendPassphrase) != "":
+		b.passphrase = getEnv(env.FileBackendPassphrase)
+	default:
+		return nil, fmt.Errorf("the file backend requires a key: set %s, %s, or %s", env.FileBackendKey, env.FileBackendKeyFile, env.FileBackendPassphrase)
+	}
+	return b, nil
+}
+
+// decodeKey decodes a base64-encoded key, ignoring the surrounding whitespace.
+func decodeKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode the key as base64: %w", err)
+	}
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("the key must be %d bytes, but it is %d bytes", chacha20poly1305.KeySize, len(key))
+	}
+	return key, nil
+}
+
+// Get decrypts the token stored for clientID on host. It returns (nil, nil) when no
+// token file exists, and an error when the file can't be decrypted with the key, such
+// as when the key was changed.
+func (b *Backend) Get(ctx context.Context, host, clientID string) ([]byte, error) {
+	bt, err := b.files.Get(ctx, host, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if bt == nil {
+		return nil, nil
+	}
+	e := &envelope{}
+	if err := json.Unmarshal(bt, e); err != nil {
+		return nil, fmt.Errorf("decode a token file as JSON: %w", err)
+	}
+	if e.Version != envelopeVersion {
+		return nil, fmt.Errorf("unsupported token file version: %d", e.Version)
+	}
+	key, err := b.fileKey(e)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("create a cipher: %w", err)
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, errors.New("the nonce of a token file is invalid")
+	}
+	token, err := aead.Open(nil, e.Nonce, e.Ciphertext, []byte(api.StorageKey(host, clientID)))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt a token file, possibly with a wrong key: %w", err)
+	}
+	return token, nil
+}
+
+// fileKey returns the key e was encrypted with.
+func (b *Backend) fileKey(e *envelope) ([]byte, error) {
+	switch e.KDF {
+	case kdfNone:
+		if b.key == nil {
+			return nil, errors.New("the token file was encrypted with a key, but a passphrase is configured")
+		}
+		return b.key, nil
+	case kdfArgon2id:
+		if b.key != nil {
+			return nil, errors.New("the token file was encrypted with a passphrase, but a key is configured")
+		}
+		if e.Argon2 == nil || len(e.Salt) != saltSize {
+			return nil, errors.New("the key derivation parameters of a token file are invalid")
+		}
+		if err := e.Argon2.validate(); err != nil {
+			return nil, fmt.Errorf("validate the Argon2 parameters of a token file: %w", err)
+		}
+		return deriveKey(b.passphrase, e.Salt, e.Argon2), nil
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function of a token file: %s", e.KDF)
+	}
+}
+
+// Set encrypts the token for clientID on host and writes it atomically with file
+// permission 0600.
+func (b *Backend) Set(ctx context.Context, host, clientID, token string) error {
+	e := &envelope{Version: envelopeVersion, KDF: kdfNone}
+	key := b.key
+	if key == nil {
+		e.KDF = kdfArgon2id
+		e.Argon2 = defaultArgon2Params
+		e.Salt = make([]byte, saltSize)
+		if _, err := rand.Read(e.Salt); err != nil {
+			return fmt.Errorf("generate a salt: %w", err)
+		}
+		key = deriveKey(b.passphrase, e.Salt, e.Argon2)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return fmt.Errorf("create a cipher: %w", err)
+	}
+	e.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return fmt.Errorf("generate a nonce: %w", err)
+	}
+	e.Ciphertext = aead.Seal(nil, e.Nonce, []byte(token), []byte(api.StorageKey(host, clientID)))
+	bt, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode a token file as JSON: %w", err)
+	}
+	return b.files.Set(ctx, host, clientID, string(bt))
+}
+
+// Probe reports whether tokens can be stored, creating the token directory.
+func (b *Backend) Probe(ctx context.Context) error {
+	return b.files.Probe(ctx) //nolint:wrapcheck
+}
+
+// List returns the keys of the tokens stored in the directory, without decrypting them.
+func (b *Backend) List(ctx context.Context) ([]pubbackend.TokenKey, error) {
+	return b.files.List(ctx) //nolint:wrapcheck
+}
+
+// Delete removes the token file for clientID on host. It is a no-op when no file exists.
+func (b *Backend) Delete(ctx context.Context, host, clientID string) error {
+	return b.files.Delete(ctx, host, clientID)
+}
+
+func deriveKey(passphrase string, salt []byte, p *argon2Params) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, chacha20poly1305.KeySize)
+}
@@ -0,0 +1,176 @@
+package file
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBackend_GetSet(t *testing.T) {
+	t.Parallel()
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		envs map[string]string
+	}{
+		{name: "key", envs: map[string]string{"GHTKN_FILE_BACKEND_KEY": key}},
+		{name: "key file", envs: map[string]string{"GHTKN_FILE_BACKEND_KEY_FILE": keyFile}},
+		{name: "passphrase", envs: map[string]string{"GHTKN_FILE_BACKEND_PASSPHRASE": "passphrase"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			tt.envs["GHTKN_FILE_BACKEND_DIR"] = dir
+			b, err := New(func(k string) string { return tt.envs[k] }, nil, nil)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			ctx := t.Context()
+
+			got, err := b.Get(ctx, "github.com", "client-id")
+			if err != nil {
+				t.Fatalf("Get() before Set error = %v", err)
+			}
+			if got != nil {
+				t.Fatalf("Get() before Set = %q, want nil", got)
+			}
+
+			token := `{"access_token":"ghu_secret"}` + "\n"
+			if err := b.Set(ctx, "github.com", "client-id", token); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			got, err = b.Get(ctx, "github.com", "client-id")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if diff := cmp.Diff([]byte(token), got); diff != "" {
+				t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+			}
+
+			// The token isn't stored in plaintext.
+			content, err := os.ReadFile(filepath.Join(dir, "client-id"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(content), "ghu_secret") {
+				t.Errorf("the token file contains the token in plaintext: %s", content)
+			}
+
+			// A token file renamed to another token's name fails to decrypt.
+			if err := os.Rename(filepath.Join(dir, "client-id"), filepath.Join(dir, "other")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.Get(ctx, "github.com", "other"); err == nil {
+				t.Error("Get() of a renamed token file expected an error")
+			}
+		})
+	}
+}
+
+func TestBackend_Get_wrongKey(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	newBackend := func(envs map[string]string) *Backend {
+		t.Helper()
+		envs["GHTKN_FILE_BACKEND_DIR"] = dir
+		b, err := New(func(k string) string { return envs[k] }, nil, nil)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return b
+	}
+	ctx := t.Context()
+	if err := newBackend(map[string]string{"GHTKN_FILE_BACKEND_PASSPHRASE": "right"}).Set(ctx, "github.com", "client-id", "token"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	for name, envs := range map[string]map[string]string{
+		"wrong passphrase":            {"GHTKN_FILE_BACKEND_PASSPHRASE": "wrong"},
+		"key instead of a passphrase": {"GHTKN_FILE_BACKEND_KEY": base64.StdEncoding.EncodeToString(make([]byte, 32))},
+	} {
+		got, err := newBackend(envs).Get(ctx, "github.com", "client-id")
+		if err == nil {
+			t.Errorf("%s: Get() = %q, want an error", name, got)
+		}
+	}
+}
+
+func TestNew_invalidKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		envs map[string]string
+	}{
+		{name: "no key", envs: map[string]string{}},
+		{name: "not base64", envs: map[string]string{"GHTKN_FILE_BACKEND_KEY": "not base64!"}},
+		{name: "short key", envs: map[string]string{"GHTKN_FILE_BACKEND_KEY": base64.StdEncoding.EncodeToString(make([]byte, 16))}},
+		{name: "missing key file", envs: map[string]string{"GHTKN_FILE_BACKEND_KEY_FILE": filepath.Join(t.TempDir(), "missing")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.envs["GHTKN_FILE_BACKEND_DIR"] = t.TempDir()
+			if _, err := New(func(k string) string { return tt.envs[k] }, nil, nil); err == nil {
+				t.Error("New() expected an error")
+			}
+		})
+	}
+}
+
+func TestBackend_Get_invalidArgon2Params(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		params argon2Params
+	}{
+		{name: "no pass", params: argon2Params{Time: 0, Memory: 64 * 1024, Threads: 4}},
+		{name: "too many passes", params: argon2Params{Time: maxArgon2Time + 1, Memory: 64 * 1024, Threads: 4}},
+		{name: "no thread", params: argon2Params{Time: 1, Memory: 64 * 1024, Threads: 0}},
+		{name: "too little memory", params: argon2Params{Time: 1, Memory: 31, Threads: 4}},
+		{name: "too much memory", params: argon2Params{Time: 1, Memory: maxArgon2Memory + 1, Threads: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			envs := map[string]string{"GHTKN_FILE_BACKEND_DIR": dir, "GHTKN_FILE_BACKEND_PASSPHRASE": "passphrase"}
+			b, err := New(func(k string) string { return envs[k] }, nil, nil)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			ctx := t.Context()
+			if err := b.Set(ctx, "github.com", "client-id", "token"); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			p := filepath.Join(dir, "client-id")
+			content, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := &envelope{}
+			if err := json.Unmarshal(content, e); err != nil {
+				t.Fatal(err)
+			}
+			e.Argon2 = &tt.params
+			content, err = json.Marshal(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, content, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := b.Get(ctx, "github.com", "client-id"); err == nil {
+				t.Errorf("Get() = %q, want an error", got)
+			}
+		})
+	}
+}
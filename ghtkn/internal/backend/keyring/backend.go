@@ -1,20 +1,40 @@
 // Package keyring provides secure storage for GitHub access tokens.
 // It wraps the zalando/go-keyring library to store and retrieve tokens from the system keychain.
+// A keyring call that takes longer than GHTKN_KEYRING_TIMEOUT (a Go duration, 30s by
+// default) fails with backend.ErrBackendTimeout.
 package keyring
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
 	"github.com/zalando/go-keyring"
 )
 
+// defaultTimeout is how long a keyring call may take by default. It leaves time to
+// answer a prompt to unlock the keyring, but not to wait for one nobody sees.
+const defaultTimeout = 30 * time.Second
+
 type Backend struct {
 	get     func(service, key string) (string, error)
 	set     func(service, key, token string) error
 	del     func(service, key string) error
 	service string
+	// timeout is how long a keyring call may take, defaultTimeout when it is zero.
+	timeout time.Duration
+	// logger reports the failures to keep the index up to date. It defaults to
+	// slog.Default() when nil.
+	logger *slog.Logger
 }
 
 // New creates a new Keyring instance with the specified service name.
@@ -25,17 +45,120 @@ func New(input *Input) *Backend {
 		set:     keyring.Set,
 		del:     keyring.Delete,
 		service: input.ServiceKey,
+		timeout: input.Timeout,
+		logger:  input.Logger,
 	}
 }
 
 type Input struct {
 	ServiceKey string
+	// Timeout is how long a keyring call may take. Zero means 30 seconds.
+	Timeout time.Duration
+	// Logger reports the failures to update the index. nil means slog.Default().
+	Logger *slog.Logger
+}
+
+func (b *Backend) log() *slog.Logger {
+	if b.logger == nil {
+		return slog.Default()
+	}
+	return b.logger
+}
+
+// Timeout returns the timeout of keyring calls GHTKN_KEYRING_TIMEOUT sets, a Go
+// duration, or zero when it is unset.
+func Timeout(getEnv func(string) string) (time.Duration, error) {
+	s := getEnv(env.KeyringTimeout)
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s as a duration: %w", env.KeyringTimeout, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", env.KeyringTimeout)
+	}
+	return d, nil
+}
+
+// The calls of go-keyring block and take no context, and on Linux a call can wait
+// indefinitely on D-Bus for the Secret Service to be unlocked. So each call runs in a
+// goroutine, and the operation fails with pubbackend.ErrBackendTimeout when the
+// timeout passes or ctx is done first. The call is then left to finish in the
+// background, and its result is discarded.
+
+// call runs f, a keyring call, until the timeout passes or ctx is done.
+func call[T any](ctx context.Context, timeout time.Duration, f func() (T, error)) (T, error) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	var zero T
+	if ctx.Err() != nil {
+		return zero, fmt.Errorf("%w: %w", pubbackend.ErrBackendTimeout, context.Cause(ctx))
+	}
+	type result struct {
+		v   T
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		v, err := f()
+		ch <- result{v: v, err: err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-ctx.Done():
+		return zero, fmt.Errorf("%w: %w", pubbackend.ErrBackendTimeout, context.Cause(ctx))
+	case <-timer.C:
+		return zero, fmt.Errorf("%w: the keyring didn't respond in %s", pubbackend.ErrBackendTimeout, timeout)
+	}
+}
+
+// getSecret gets the secret stored under key in the service.
+func (b *Backend) getSecret(ctx context.Context, key string) (string, error) {
+	return call(ctx, b.timeout, func() (string, error) {
+		return b.get(b.service, key)
+	})
 }
 
-// Get retrieves the raw token stored for clientID.
+// setSecret stores value under key in the service.
+func (b *Backend) setSecret(ctx context.Context, key, value string) error {
+	_, err := call(ctx, b.timeout, func() (struct{}, error) {
+		return struct{}{}, b.set(b.service, key, value)
+	})
+	return err
+}
+
+// delSecret deletes the secret stored under key in the service.
+func (b *Backend) delSecret(ctx context.Context, key string) error {
+	_, err := call(ctx, b.timeout, func() (struct{}, error) {
+		return struct{}{}, b.del(b.service, key)
+	})
+	return err
+}
+
+// storageKey returns the key the token of clientID on host is stored under. It fails
+// when the client ID is invalid, so a token can't be stored under a key of ghtkn's own,
+// such as indexKey.
+func storageKey(host, clientID string) (string, error) {
+	if err := api.ValidateClientID(clientID); err != nil {
+		return "", fmt.Errorf("validate the client id: %w", err)
+	}
+	return api.StorageKey(host, clientID), nil
+}
+
+// Get retrieves the raw token stored for clientID on host.
 // It returns (nil, nil) when no token is stored in the keyring.
-func (b *Backend) Get(_ context.Context, clientID string) ([]byte, error) {
-	s, err := b.get(b.service, clientID)
+func (b *Backend) Get(ctx context.Context, host, clientID string) ([]byte, error) {
+	key, err := storageKey(host, clientID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := b.getSecret(ctx, key)
 	if err != nil {
 		if errors.Is(err, keyring.ErrNotFound) {
 			return nil, nil
@@ -45,22 +168,136 @@ func (b *Backend) Get(_ context.Context, clientID string) ([]byte, error) {
 	return []byte(s), nil
 }
 
-// Set stores the raw token for clientID in the keyring.
-func (b *Backend) Set(_ context.Context, clientID string, token string) error {
-	if err := b.set(b.service, clientID, token); err != nil {
+// Set stores the raw token for clientID on host in the keyring, and adds it to the
+// index. A failure to update the index is only logged, since the token is stored.
+func (b *Backend) Set(ctx context.Context, host, clientID string, token string) error {
+	key, err := storageKey(host, clientID)
+	if err != nil {
+		return err
+	}
+	if err := b.setSecret(ctx, key, token); err != nil {
 		return fmt.Errorf("set a secret to the keyring: %w", err)
 	}
+	b.updateIndex(ctx, func(keys []string) []string {
+		if slices.Contains(keys, key) {
+			return nil
+		}
+		return append(keys, key)
+	})
 	return nil
 }
 
-// Delete removes the token stored for clientID from the keyring.
-// It is a no-op when no token is stored.
-func (b *Backend) Delete(_ context.Context, clientID string) error {
-	if err := b.del(b.service, clientID); err != nil {
-		if errors.Is(err, keyring.ErrNotFound) {
+// Delete removes the token stored for clientID on host from the keyring, and from the
+// index. It is a no-op when no token is stored. A failure to update the index is only
+// logged, since the token is deleted.
+func (b *Backend) Delete(ctx context.Context, host, clientID string) error {
+	key, err := storageKey(host, clientID)
+	if err != nil {
+		return err
+	}
+	if err := b.delSecret(ctx, key); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("delete a secret from the keyring: %w", err)
+	}
+	b.updateIndex(ctx, func(keys []string) []string {
+		if !slices.Contains(keys, key) {
 			return nil
 		}
-		return fmt.Errorf("delete a secret from the keyring: %w", err)
+		return slices.DeleteFunc(keys, func(k string) bool { return k == key })
+	})
+	return nil
+}
+
+// The keyring can't be enumerated, so the keys of the stored tokens are kept in an
+// index: a JSON array of storage keys stored under indexKey in the same service. Set and
+// Delete keep it up to date. A token stored before the index existed isn't in it until
+// it is stored again, such as when it is renewed, so ghtkn.ListTokens also looks up the
+// tokens of the apps in the config.
+//
+// Keeping the index costs each Set and Delete a read of the index, under indexMu, even
+// when List is never used. The index is only written when it changes, so storing a
+// token it already lists, such as a renewed one, costs no write.
+//
+// The keys ghtkn stores itself, indexKey and probeKey, start with ':', which no client
+// ID does (see api.ValidateClientID), so no token can be stored under them.
+
+// indexKey is the key the index is stored under.
+const indexKey = ":ghtkn-index"
+
+// indexMu serializes the updates of the index in the process. Updates from other
+// processes can still race, which at worst leaves out a token until it is stored again.
+var indexMu sync.Mutex
+
+// readIndex returns the storage keys in the index, nil when there is no index. An
+// index that can't be decoded, such as one another program overwrote, is logged and
+// read as empty, so the next update rewrites it.
+func (b *Backend) readIndex(ctx context.Context) ([]string, error) {
+	s, err := b.getSecret(ctx, indexKey)
+	if err != nil {
+		if errors.Is(err, keyring.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get the index from the keyring: %w", err)
+	}
+	var keys []string
+	if err := json.Unmarshal([]byte(s), &keys); err != nil {
+		slogerr.WithError(b.log(), err).Warn("the index of the keyring is broken, so it is rebuilt from the tokens stored from now on")
+		return nil, nil
+	}
+	return keys, nil
+}
+
+// updateIndex replaces the index with what update returns for it. update returns nil
+// to leave it as is. A failure is logged rather than returned: the index only serves
+// List, which also looks up the tokens of the configured apps.
+func (b *Backend) updateIndex(ctx context.Context, update func(keys []string) []string) {
+	if err := b.writeIndex(ctx, update); err != nil {
+		slogerr.WithError(b.log(), err).Warn("failed to update the index of the keyring, so the token may not be listed")
+	}
+}
+
+func (b *Backend) writeIndex(ctx context.Context, update func(keys []string) []string) error {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	keys, err := b.readIndex(ctx)
+	if err != nil {
+		return err
+	}
+	keys = update(keys)
+	if keys == nil {
+		return nil
+	}
+	bt, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("encode the index of the keyring as JSON: %w", err)
+	}
+	if err := b.setSecret(ctx, indexKey, string(bt)); err != nil {
+		return fmt.Errorf("set the index to the keyring: %w", err)
+	}
+	return nil
+}
+
+// List returns the keys of the tokens in the index.
+func (b *Backend) List(ctx context.Context) ([]pubbackend.TokenKey, error) {
+	keys, err := b.readIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokenKeys := make([]pubbackend.TokenKey, len(keys))
+	for i, key := range keys {
+		host, clientID := api.ParseStorageKey(key)
+		tokenKeys[i] = pubbackend.TokenKey{Host: host, ClientID: clientID}
+	}
+	return tokenKeys, nil
+}
+
+// probeKey is the key Probe reads, under which nothing is stored.
+const probeKey = ":ghtkn-probe"
+
+// Probe reports whether the keyring is reachable, such as whether a Secret Service
+// runs on Linux, by reading a key that isn't stored.
+func (b *Backend) Probe(ctx context.Context) error {
+	if _, err := b.getSecret(ctx, probeKey); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("access the keyring: %w", err)
 	}
 	return nil
 }
@@ -68,3 +305,13 @@ func (b *Backend) Delete(_ context.Context, clientID string) error {
 // DefaultServiceKey is the default service identifier used in the system keychain.
 // This key is used to namespace tokens in the keyring to avoid conflicts with other applications.
 const DefaultServiceKey = "github.com/suzuki-shunsuke/ghtkn"
+
+// ServiceKey returns the service identifier the tokens of profile are stored under:
+// DefaultServiceKey for the default profile, and DefaultServiceKey/profiles/<profile>
+// otherwise, so a profile never reads the tokens of another.
+func ServiceKey(profile string) string {
+	if profile == "" {
+		return DefaultServiceKey
+	}
+	return DefaultServiceKey + "/profiles/" + profile
+}
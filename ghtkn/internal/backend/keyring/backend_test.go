@@ -1,10 +1,17 @@
 package keyring
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"log/slog"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 	"github.com/zalando/go-keyring"
 )
 
@@ -39,7 +46,7 @@ func TestBackend_Get(t *testing.T) {
 			t.Parallel()
 
 			b := &Backend{get: tt.get, service: DefaultServiceKey}
-			got, err := b.Get(t.Context(), "client-id")
+			got, err := b.Get(t.Context(), "github.com", "client-id")
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -53,26 +60,83 @@ func TestBackend_Get(t *testing.T) {
 	}
 }
 
+// fakeKeyring is an in-memory keyring of one service.
+type fakeKeyring struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func newFakeKeyring(secrets map[string]string) *fakeKeyring {
+	if secrets == nil {
+		secrets = map[string]string{}
+	}
+	return &fakeKeyring{secrets: secrets}
+}
+
+func (k *fakeKeyring) backend() *Backend {
+	return &Backend{get: k.get, set: k.set, del: k.del, service: DefaultServiceKey}
+}
+
+func (k *fakeKeyring) get(_, key string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	s, ok := k.secrets[key]
+	if !ok {
+		return "", keyring.ErrNotFound
+	}
+	return s, nil
+}
+
+func (k *fakeKeyring) set(_, key, secret string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.secrets[key] = secret
+	return nil
+}
+
+func (k *fakeKeyring) del(_, key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.secrets[key]; !ok {
+		return keyring.ErrNotFound
+	}
+	delete(k.secrets, key)
+	return nil
+}
+
 func TestBackend_Set(t *testing.T) {
 	t.Parallel()
 
 	t.Run("stores the token under the service and client id", func(t *testing.T) {
 		t.Parallel()
 
-		var gotService, gotKey, gotToken string
-		b := &Backend{
-			set: func(service, key, token string) error {
-				gotService, gotKey, gotToken = service, key, token
-				return nil
-			},
-			service: DefaultServiceKey,
+		var gotService string
+		k := newFakeKeyring(nil)
+		b := k.backend()
+		b.set = func(service, key, token string) error {
+			gotService = service
+			return k.set(service, key, token)
+		}
+		if err := b.Set(t.Context(), "github.com", "client-id", "token"); err != nil {
+			t.Fatalf("Set() error = %v", err)
 		}
-		if err := b.Set(t.Context(), "client-id", "token"); err != nil {
+		if gotService != DefaultServiceKey {
+			t.Errorf("Set() service = %q, want %q", gotService, DefaultServiceKey)
+		}
+		if got := k.secrets["client-id"]; got != "token" {
+			t.Errorf("Set() stored %q under %q, want %q", got, "client-id", "token")
+		}
+	})
+
+	t.Run("a token for another host is stored under the client id and host", func(t *testing.T) {
+		t.Parallel()
+
+		k := newFakeKeyring(nil)
+		if err := k.backend().Set(t.Context(), "ghes.example.com", "client-id", "token"); err != nil {
 			t.Fatalf("Set() error = %v", err)
 		}
-		if gotService != DefaultServiceKey || gotKey != "client-id" || gotToken != "token" {
-			t.Errorf("Set() stored (%q, %q, %q), want (%q, %q, %q)",
-				gotService, gotKey, gotToken, DefaultServiceKey, "client-id", "token")
+		if _, ok := k.secrets["client-id@ghes.example.com"]; !ok {
+			t.Errorf("Set() stored %v, want the key %q", k.secrets, "client-id@ghes.example.com")
 		}
 	})
 
@@ -83,7 +147,7 @@ func TestBackend_Set(t *testing.T) {
 			set:     func(_, _, _ string) error { return errors.New("boom") },
 			service: DefaultServiceKey,
 		}
-		if err := b.Set(t.Context(), "client-id", "token"); err == nil {
+		if err := b.Set(t.Context(), "github.com", "client-id", "token"); err == nil {
 			t.Error("Set() expected an error, got nil")
 		}
 	})
@@ -116,8 +180,9 @@ func TestBackend_Delete(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			b := &Backend{del: tt.del, service: DefaultServiceKey}
-			err := b.Delete(t.Context(), "client-id")
+			b := newFakeKeyring(nil).backend()
+			b.del = tt.del
+			err := b.Delete(t.Context(), "github.com", "client-id")
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("Delete() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -125,6 +190,104 @@ func TestBackend_Delete(t *testing.T) {
 	}
 }
 
+func TestBackend_List(t *testing.T) {
+	t.Parallel()
+
+	k := newFakeKeyring(map[string]string{
+		// A token stored before the index existed isn't listed.
+		"legacy": "token",
+	})
+	b := k.backend()
+	ctx := t.Context()
+	for _, host := range []string{"github.com", "ghes.example.com", "github.com"} {
+		if err := b.Set(ctx, host, "client-id", "token"); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	if err := b.Set(ctx, "github.com", "other", "token"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := b.Delete(ctx, "github.com", "other"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	got, err := b.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []pubbackend.TokenKey{
+		{Host: "github.com", ClientID: "client-id"},
+		{Host: "ghes.example.com", ClientID: "client-id"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBackend_index(t *testing.T) {
+	t.Parallel()
+
+	t.Run("a broken index is rewritten", func(t *testing.T) {
+		t.Parallel()
+
+		k := newFakeKeyring(map[string]string{indexKey: "not json"})
+		b := k.backend()
+		b.logger = slog.New(slog.DiscardHandler)
+		if err := b.Set(t.Context(), "github.com", "client-id", "token"); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		if got := k.secrets[indexKey]; got != `["client-id"]` {
+			t.Errorf("index = %s, want %s", got, `["client-id"]`)
+		}
+	})
+
+	t.Run("a token can't be stored under the keys of the index", func(t *testing.T) {
+		t.Parallel()
+
+		k := newFakeKeyring(nil)
+		b := k.backend()
+		for _, clientID := range []string{"ghtkn-index", "ghtkn-probe"} {
+			if err := b.Set(t.Context(), "github.com", clientID, "token"); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+		}
+		if err := b.Set(t.Context(), "github.com", indexKey, "token"); err == nil {
+			t.Error("Set() under the key of the index must fail")
+		}
+		keys, err := b.List(t.Context())
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		want := []pubbackend.TokenKey{{Host: "github.com", ClientID: "ghtkn-index"}, {Host: "github.com", ClientID: "ghtkn-probe"}}
+		if diff := cmp.Diff(want, keys); diff != "" {
+			t.Errorf("List() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("a failure to update the index is only logged", func(t *testing.T) {
+		t.Parallel()
+
+		k := newFakeKeyring(nil)
+		b := k.backend()
+		var logs bytes.Buffer
+		b.logger = slog.New(slog.NewTextHandler(&logs, nil))
+		b.set = func(service, key, secret string) error {
+			if key == indexKey {
+				return errors.New("boom")
+			}
+			return k.set(service, key, secret)
+		}
+		if err := b.Set(t.Context(), "github.com", "client-id", "token"); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		if got := k.secrets["client-id"]; got != "token" {
+			t.Errorf("Set() stored %q, want %q", got, "token")
+		}
+		if !strings.Contains(logs.String(), "boom") {
+			t.Errorf("the failure isn't logged: %s", logs.String())
+		}
+	})
+}
+
 func TestNew(t *testing.T) {
 	t.Parallel()
 
@@ -136,3 +299,118 @@ func TestNew(t *testing.T) {
 		t.Errorf("service = %q, want %q", b.service, DefaultServiceKey)
 	}
 }
+
+func TestBackend_Probe(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		get     func(service, key string) (string, error)
+		wantErr bool
+	}{
+		{
+			name: "reachable",
+			get:  func(_, _ string) (string, error) { return "", keyring.ErrNotFound },
+		},
+		{
+			name: "unreachable",
+			get: func(_, _ string) (string, error) {
+				return "", errors.New("the name org.freedesktop.secrets was not provided by any .service files")
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b := &Backend{get: tt.get, service: DefaultServiceKey}
+			err := b.Probe(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackend_timeout(t *testing.T) {
+	t.Parallel()
+
+	// unblock releases the keyring calls, which block like a call waiting on a prompt
+	// to unlock the keyring that nobody answers, once the test ends.
+	unblock := make(chan struct{})
+	t.Cleanup(func() { close(unblock) })
+	b := &Backend{
+		get: func(_, _ string) (string, error) {
+			<-unblock
+			return "", nil
+		},
+		set: func(_, _, _ string) error {
+			<-unblock
+			return nil
+		},
+		del: func(_, _ string) error {
+			<-unblock
+			return nil
+		},
+		service: DefaultServiceKey,
+	}
+	canceled, cancel := context.WithCancel(t.Context())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context //nolint:containedctx
+		timeout time.Duration
+		// cause is the error the timeout is caused by, nil for the timeout of the backend.
+		cause error
+	}{
+		{name: "timeout", ctx: t.Context(), timeout: 10 * time.Millisecond},
+		{name: "canceled", ctx: canceled, timeout: time.Hour, cause: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b := *b
+			b.timeout = tt.timeout
+			_, getErr := b.Get(tt.ctx, "github.com", "client-id")
+			errs := map[string]error{
+				"Get":    getErr,
+				"Set":    b.Set(tt.ctx, "github.com", "client-id", "token"),
+				"Delete": b.Delete(tt.ctx, "github.com", "client-id"),
+			}
+			for op, err := range errs {
+				if !errors.Is(err, pubbackend.ErrBackendTimeout) {
+					t.Errorf("%s() error = %v, want ErrBackendTimeout", op, err)
+				}
+				if tt.cause != nil && !errors.Is(err, tt.cause) {
+					t.Errorf("%s() error = %v, want %v", op, err, tt.cause)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "set", value: "5s", want: 5 * time.Second},
+		{name: "invalid", value: "5", wantErr: true},
+		{name: "not positive", value: "0s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Timeout(func(string) string { return tt.value })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Timeout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Timeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
+	publog "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+// Auto is the backend type that falls back through autoTypes.
+const Auto = "auto"
+
+// autoTypes are the backends Auto falls back through, in order: the OS keyring, a
+// running agent, the encrypted file backend when a key is configured, and last the
+// plaintext text backend.
+var autoTypes = []string{"keyring", "agent", "file", "text"}
+
+// probeTimeout bounds how long a backend may take to tell whether it is usable, since
+// an unreachable service can hang rather than fail.
+const probeTimeout = 5 * time.Second
+
+// Candidates returns the backend types s falls back through, in order: autoTypes for
+// Auto and the types of a comma-separated list. It returns nil for a single type,
+// which is used without probing.
+func Candidates(s string) ([]string, error) {
+	if s == Auto {
+		return autoTypes, nil
+	}
+	if !strings.Contains(s, ",") {
+		return nil, nil
+	}
+	types := strings.Split(s, ",")
+	for i, t := range types {
+		t = strings.TrimSpace(t)
+		switch t {
+		case "":
+			return nil, fmt.Errorf("the list of backends has an empty entry: %s", s)
+		case Auto:
+			return nil, fmt.Errorf("auto can't be in a list of backends: %s", s)
+		}
+		types[i] = t
+	}
+	return slices.Compact(types), nil
+}
+
+// IsPlaintext reports whether the backend type stores tokens unencrypted.
+func IsPlaintext(typ string) bool {
+	return typ == "text"
+}
+
+// Probe reports whether the backend is usable in the environment. A backend that can't
+// tell is assumed usable.
+func (b *Backend) Probe(ctx context.Context) error {
+	p, ok := b.backend.(pubbackend.Prober)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	return p.Probe(ctx) //nolint:wrapcheck
+}
+
+// Select returns the first of types that can be built and is usable, with its type.
+// The backends skipped are logged at debug level with the reason. It fails with the
+// reasons of all of them when none is usable.
+func Select(ctx context.Context, types []string, getEnv func(string) string, logger *publog.Logger, slogLogger *slog.Logger) (*Backend, string, error) {
+	errs := make([]error, 0, len(types))
+	for _, typ := range types {
+		b, err := New(typ, getEnv, logger, slogLogger)
+		if err == nil {
+			err = b.Probe(ctx)
+		}
+		if err == nil {
+			return b, typ, nil
+		}
+		if slogLogger != nil {
+			slogerr.WithError(slogLogger, err).Debug("the backend is unavailable", "backend", typ)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", typ, err))
+	}
+	return nil, "", fmt.Errorf("no backend is available: %w", errors.Join(errs...))
+}
@@ -0,0 +1,60 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCandidates(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single", s: "keyring"},
+		{name: "default", s: ""},
+		{name: "auto", s: "auto", want: []string{"keyring", "agent", "file", "text"}},
+		{name: "list", s: "keyring, agent,text", want: []string{"keyring", "agent", "text"}},
+		{name: "empty entry", s: "keyring,,text", wantErr: true},
+		{name: "auto in a list", s: "keyring,auto", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Candidates(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Candidates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Candidates() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	getEnv := func(k string) string {
+		if k == "GHTKN_TEXT_BACKEND_DIR" {
+			return dir
+		}
+		return ""
+	}
+	// The exec backend can't be built without a command, so the text backend is
+	// selected.
+	b, typ, err := Select(t.Context(), []string{"exec", "text"}, getEnv, nil, nil)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if typ != "text" || b == nil {
+		t.Errorf("Select() = %v, %q, want the text backend", b, typ)
+	}
+
+	if _, _, err := Select(t.Context(), []string{"exec", "file"}, getEnv, nil, nil); err == nil {
+		t.Error("Select() expected an error when no backend is usable")
+	}
+}
@@ -9,52 +9,93 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
+	publog "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
 )
 
 // The access token is saved in plaintext to ${XDG_CACHE_HOME}/ghtkn/tokens/<client-id>
-// (%LocalAppData%\cache\ghtkn\tokens\<client-id> on Windows).
-// The directory can be overridden with the GHTKN_TEXT_BACKEND_DIR environment variable.
+// (%LocalAppData%\cache\ghtkn\tokens\<client-id> on Windows). The file of a token for a
+// host other than github.com is named <client-id>@<host> (see api.StorageKey).
+// The directory can be overridden with the GHTKN_TEXT_BACKEND_DIR environment variable,
+// under which a profile keeps its files in profiles/<profile>.
 // The file permissions are 0600. No encryption is performed.
+//
+// Since the permissions are all that protects the tokens, a token file is only read
+// when it is a regular file owned by the current user that other users can't write,
+// and the directory likewise. A file or a directory other users can read is used, but
+// reported through the InsecureTokenFile and InsecureTokenDir log hooks.
 // See https://github.com/suzuki-shunsuke/design-docs/blob/main/ghtkn/backend/README.md
 
 // Backend stores access tokens as plaintext files under dir.
 type Backend struct {
 	dir string
+	// logger holds the customizable log hooks; InsecureTokenFile and InsecureTokenDir
+	// report permissions other users can read through. It defaults to the internal
+	// defaults when nil.
+	logger *publog.Logger
+	// slogLogger is the per-request structured logger passed to the log hooks. It
+	// defaults to slog.Default() when nil.
+	slogLogger *slog.Logger
 }
 
 // New creates a text backend. The storage directory is GHTKN_TEXT_BACKEND_DIR
 // if set, otherwise ${XDG_CACHE_HOME}/ghtkn/tokens (falling back to $HOME/.cache),
 // or %LocalAppData%\cache\ghtkn\tokens on Windows.
-// It returns an error if none of these are set.
-func New(getEnv func(string) string) (*Backend, error) {
+// It returns an error if none of these are set. logger and slogLogger are used to
+// report insecure permissions, and may be nil as for the agent backend.
+func New(getEnv func(string) string, logger *publog.Logger, slogLogger *slog.Logger) (*Backend, error) {
 	dir, err := tokenDir(getEnv, runtime.GOOS)
 	if err != nil {
 		return nil, err
 	}
+	return NewWithDir(dir, logger, slogLogger), nil
+}
+
+// NewWithDir creates a text backend storing the token files in dir. The encrypted file
+// backend stores its files through it.
+func NewWithDir(dir string, logger *publog.Logger, slogLogger *slog.Logger) *Backend {
 	return &Backend{
-		dir: dir,
-	}, nil
+		dir:        dir,
+		logger:     logger,
+		slogLogger: slogLogger,
+	}
 }
 
 // tokenDir resolves the directory that stores token files. GHTKN_TEXT_BACKEND_DIR
 // takes precedence; otherwise it is ${cache dir}/ghtkn/tokens.
 func tokenDir(getEnv func(string) string, goos string) (string, error) {
-	dir := getEnv(env.TextBackendDir)
-	if dir != "" {
-		return dir, nil
+	return Dir(getEnv, goos, env.TextBackendDir, "tokens")
+}
+
+// Dir resolves a directory ghtkn keeps files in, such as the one a file backend stores
+// token files in: the environment variable dirEnv if set, otherwise
+// ${cache dir}/ghtkn/<name>. For the profile GHTKN_PROFILE selects, it is
+// ${dirEnv}/profiles/<profile> or ${cache dir}/ghtkn/profiles/<profile>/<name>.
+func Dir(getEnv func(string) string, goos, dirEnv, name string) (string, error) {
+	if dir := getEnv(dirEnv); dir != "" {
+		return profile.OverrideDir(getEnv, dir) //nolint:wrapcheck
 	}
 	cacheDir, err := cacheDir(getEnv, goos)
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(cacheDir, "ghtkn", "tokens"), nil
+	profileDir, err := profile.Dir(getEnv)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+	return filepath.Join(cacheDir, "ghtkn", profileDir, name), nil
 }
 
 // cacheDir resolves the base cache directory. On Windows it is %LocalAppData%\cache;
@@ -65,7 +106,7 @@ func cacheDir(getEnv func(string) string, goos string) (string, error) {
 		if d := getEnv(env.LocalAppData); d != "" {
 			return filepath.Join(d, "cache"), nil
 		}
-		return "", errors.New("LocalAppData is required to use the text backend on Windows")
+		return "", errors.New("LocalAppData is required to store tokens in files on Windows")
 	}
 	if d := getEnv(env.XDGCacheHome); d != "" {
 		return d, nil
@@ -73,31 +114,97 @@ func cacheDir(getEnv func(string) string, goos string) (string, error) {
 	if home := getEnv(env.Home); home != "" {
 		return filepath.Join(home, ".cache"), nil
 	}
-	return "", errors.New("XDG_CACHE_HOME or HOME is required to use the text backend")
+	return "", errors.New("XDG_CACHE_HOME or HOME is required to store tokens in files")
 }
 
-// Get reads the token stored for clientID, trimming the trailing newline
-// appended by Set. It returns (nil, nil) when no token file exists.
-func (b *Backend) Get(_ context.Context, clientID string) ([]byte, error) {
-	bt, err := os.ReadFile(filepath.Join(b.dir, clientID))
+// Probe reports whether tokens can be stored, creating the token directory.
+func (b *Backend) Probe(_ context.Context) error {
+	return b.mkdir()
+}
+
+// mkdir creates the token directory with permission 0700 and verifies the permission
+// of the directory, which may have existed already.
+func (b *Backend) mkdir() error {
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return fmt.Errorf("create the token directory: %w", err)
+	}
+	return b.checkDir()
+}
+
+// path returns the path of the token file of clientID on host. It fails when the
+// client ID or the host could make the path point outside of the directory.
+func (b *Backend) path(host, clientID string) (string, error) {
+	if err := api.ValidateClientID(clientID); err != nil {
+		return "", fmt.Errorf("validate the client id: %w", err)
+	}
+	if strings.ContainsAny(host, "/\\\x00") {
+		return "", slogerr.With(errors.New("the host must not contain a path separator"), "host", host) //nolint:wrapcheck
+	}
+	return filepath.Join(b.dir, api.StorageKey(host, clientID)), nil
+}
+
+// Get reads the token stored for clientID on host, trimming the trailing newline
+// appended by Set. It returns (nil, nil) when no token file exists. It fails without
+// reading the file when the file or the directory could have been written by another
+// user (see checkFile).
+func (b *Backend) Get(_ context.Context, host, clientID string) ([]byte, error) {
+	p, err := b.path(host, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.checkDir(); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	info, err := os.Lstat(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get the status of a token file: %w", err)
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return nil, slogerr.With(errors.New("the token file is a symbolic link"), "path", p) //nolint:wrapcheck
+	}
+	f, err := openNoFollow(p)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			return nil, nil
 		}
+		return nil, fmt.Errorf("open a token file: %w", err)
+	}
+	defer f.Close()
+	// The file is checked through the opened descriptor, so it can't be replaced between
+	// the check and the read.
+	info, err = f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("get the status of a token file: %w", err)
+	}
+	if err := b.checkFile(p, info); err != nil {
+		return nil, err
+	}
+	bt, err := io.ReadAll(f)
+	if err != nil {
 		return nil, fmt.Errorf("read a token file: %w", err)
 	}
 	return bytes.TrimSuffix(bt, []byte("\n")), nil
 }
 
-// Set writes the token for clientID atomically with file permission 0600.
+// Set writes the token for clientID on host atomically with file permission 0600.
 // A trailing newline is appended if the token doesn't already end with one.
 // It writes to a temporary file in the same directory and renames it into place,
 // so a concurrent writer can at worst lose its write but never corrupt the file.
-func (b *Backend) Set(_ context.Context, clientID, token string) error {
-	if err := os.MkdirAll(b.dir, 0o700); err != nil {
-		return fmt.Errorf("create the token directory: %w", err)
+func (b *Backend) Set(_ context.Context, host, clientID, token string) error {
+	p, err := b.path(host, clientID)
+	if err != nil {
+		return err
 	}
-	tmp, err := os.CreateTemp(b.dir, clientID+"-*.tmp")
+	if err := b.mkdir(); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(b.dir, filepath.Base(p)+"-*.tmp")
 	if err != nil {
 		return fmt.Errorf("create a temporary file: %w", err)
 	}
@@ -114,16 +221,20 @@ func (b *Backend) Set(_ context.Context, clientID, token string) error {
 		_ = os.Remove(tmpName)
 		return fmt.Errorf("close the temporary file: %w", err)
 	}
-	if err := os.Rename(tmpName, filepath.Join(b.dir, clientID)); err != nil {
+	if err := os.Rename(tmpName, p); err != nil {
 		_ = os.Remove(tmpName)
 		return fmt.Errorf("rename the temporary file: %w", err)
 	}
 	return nil
 }
 
-// Delete removes the token file for clientID. It is a no-op when no file exists.
-func (b *Backend) Delete(_ context.Context, clientID string) error {
-	if err := os.Remove(filepath.Join(b.dir, clientID)); err != nil {
+// Delete removes the token file for clientID on host. It is a no-op when no file exists.
+func (b *Backend) Delete(_ context.Context, host, clientID string) error {
+	p, err := b.path(host, clientID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			return nil
 		}
@@ -131,3 +242,32 @@ func (b *Backend) Delete(_ context.Context, clientID string) error {
 	}
 	return nil
 }
+
+// List returns the keys of the tokens stored in the directory, one per token file. The
+// temporary files of an interrupted Set are skipped. It returns nil when the directory
+// doesn't exist.
+func (b *Backend) List(_ context.Context) ([]pubbackend.TokenKey, error) {
+	if err := b.checkDir(); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read the token directory: %w", err)
+	}
+	keys := make([]pubbackend.TokenKey, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		host, clientID := api.ParseStorageKey(name)
+		keys = append(keys, pubbackend.TokenKey{Host: host, ClientID: clientID})
+	}
+	return keys, nil
+}
@@ -3,9 +3,12 @@ package text
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	pubbackend "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/backend"
 )
 
 func TestBackend_GetSet(t *testing.T) {
@@ -15,7 +18,7 @@ func TestBackend_GetSet(t *testing.T) {
 	ctx := t.Context()
 
 	// Get before Set returns (nil, nil).
-	got, err := b.Get(ctx, "client-id")
+	got, err := b.Get(ctx, "github.com", "client-id")
 	if err != nil {
 		t.Fatalf("Get() before Set error = %v", err)
 	}
@@ -24,10 +27,10 @@ func TestBackend_GetSet(t *testing.T) {
 	}
 
 	// Set then Get round-trips. Set appends a trailing newline that Get trims.
-	if err := b.Set(ctx, "client-id", "token"); err != nil {
+	if err := b.Set(ctx, "github.com", "client-id", "token"); err != nil {
 		t.Fatalf("Set() error = %v", err)
 	}
-	got, err = b.Get(ctx, "client-id")
+	got, err = b.Get(ctx, "github.com", "client-id")
 	if err != nil {
 		t.Fatalf("Get() error = %v", err)
 	}
@@ -46,10 +49,10 @@ func TestBackend_GetSet(t *testing.T) {
 
 	// Set overwrites an existing token; a token that already ends with a newline
 	// is not given a second one, so Get still round-trips it.
-	if err := b.Set(ctx, "client-id", "token2\n"); err != nil {
+	if err := b.Set(ctx, "github.com", "client-id", "token2\n"); err != nil {
 		t.Fatalf("Set() overwrite error = %v", err)
 	}
-	got, err = b.Get(ctx, "client-id")
+	got, err = b.Get(ctx, "github.com", "client-id")
 	if err != nil {
 		t.Fatalf("Get() after overwrite error = %v", err)
 	}
@@ -65,18 +68,18 @@ func TestBackend_Delete(t *testing.T) {
 	ctx := t.Context()
 
 	// Delete before Set is a no-op (no file exists yet).
-	if err := b.Delete(ctx, "client-id"); err != nil {
+	if err := b.Delete(ctx, "github.com", "client-id"); err != nil {
 		t.Fatalf("Delete() before Set error = %v", err)
 	}
 
 	// After Set, Delete removes the file so a subsequent Get returns (nil, nil).
-	if err := b.Set(ctx, "client-id", "token"); err != nil {
+	if err := b.Set(ctx, "github.com", "client-id", "token"); err != nil {
 		t.Fatalf("Set() error = %v", err)
 	}
-	if err := b.Delete(ctx, "client-id"); err != nil {
+	if err := b.Delete(ctx, "github.com", "client-id"); err != nil {
 		t.Fatalf("Delete() error = %v", err)
 	}
-	got, err := b.Get(ctx, "client-id")
+	got, err := b.Get(ctx, "github.com", "client-id")
 	if err != nil {
 		t.Fatalf("Get() after Delete error = %v", err)
 	}
@@ -85,6 +88,71 @@ func TestBackend_Delete(t *testing.T) {
 	}
 }
 
+func TestBackend_hosts(t *testing.T) {
+	t.Parallel()
+
+	b := &Backend{dir: filepath.Join(t.TempDir(), "ghtkn", "tokens")}
+	ctx := t.Context()
+
+	// The same client ID on two hosts is stored in two files.
+	if err := b.Set(ctx, "github.com", "client-id", "dotcom"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := b.Set(ctx, "ghes.example.com", "client-id", "ghes"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	for host, want := range map[string]string{"github.com": "dotcom", "ghes.example.com": "ghes"} {
+		got, err := b.Get(ctx, host, "client-id")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("Get(%s) = %q, want %q", host, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(b.dir, "client-id@ghes.example.com")); err != nil {
+		t.Errorf("the token file of the host: %v", err)
+	}
+}
+
+func TestBackend_List(t *testing.T) {
+	t.Parallel()
+
+	b := &Backend{dir: filepath.Join(t.TempDir(), "ghtkn", "tokens")}
+	ctx := t.Context()
+
+	// A directory that doesn't exist yet holds no tokens.
+	keys, err := b.List(ctx)
+	if err != nil {
+		t.Fatalf("List() before Set error = %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("List() before Set = %v, want none", keys)
+	}
+
+	for _, host := range []string{"github.com", "ghes.example.com"} {
+		if err := b.Set(ctx, host, "client-id", "token"); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	// The temporary file of an interrupted Set isn't a token.
+	if err := os.WriteFile(filepath.Join(b.dir, "client-id-123.tmp"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = b.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	slices.SortFunc(keys, func(a, b pubbackend.TokenKey) int { return strings.Compare(a.Host, b.Host) })
+	want := []pubbackend.TokenKey{
+		{Host: "ghes.example.com", ClientID: "client-id"},
+		{Host: "github.com", ClientID: "client-id"},
+	}
+	if diff := cmp.Diff(want, keys); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func Test_cacheDir(t *testing.T) {
 	t.Parallel()
 
@@ -184,6 +252,18 @@ func Test_tokenDir(t *testing.T) {
 			env:  map[string]string{"XDG_CACHE_HOME": "/tmp/xdg-cache"},
 			want: filepath.Join("/tmp/xdg-cache", "ghtkn", "tokens"),
 		},
+		{
+			name: "namespaces the directory by GHTKN_PROFILE",
+			goos: "linux",
+			env:  map[string]string{"GHTKN_PROFILE": "work", "XDG_CACHE_HOME": "/tmp/xdg-cache"},
+			want: filepath.Join("/tmp/xdg-cache", "ghtkn", "profiles", "work", "tokens"),
+		},
+		{
+			name:    "errors on an invalid GHTKN_PROFILE",
+			goos:    "linux",
+			env:     map[string]string{"GHTKN_PROFILE": "..", "XDG_CACHE_HOME": "/tmp/xdg-cache"},
+			wantErr: true,
+		},
 		{
 			name: "falls back to HOME/.cache/ghtkn/tokens",
 			goos: "linux",
@@ -220,3 +300,37 @@ func Test_tokenDir(t *testing.T) {
 		})
 	}
 }
+
+func TestBackend_invalidKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		host     string
+		clientID string
+	}{
+		{name: "client id escaping the directory", host: "github.com", clientID: "../client-id"},
+		{name: "client id with a path separator", host: "github.com", clientID: "a/client-id"},
+		{name: "hidden client id", host: "github.com", clientID: ".client-id"},
+		{name: "host with a path separator", host: "ghe.example.com/../..", clientID: "client-id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			parent := t.TempDir()
+			b := &Backend{dir: filepath.Join(parent, "tokens")}
+			ctx := t.Context()
+			if err := b.Set(ctx, tt.host, tt.clientID, "token"); err == nil {
+				t.Error("Set() expected an error")
+			}
+			if _, err := b.Get(ctx, tt.host, tt.clientID); err == nil {
+				t.Error("Get() expected an error")
+			}
+			if err := b.Delete(ctx, tt.host, tt.clientID); err == nil {
+				t.Error("Delete() expected an error")
+			}
+			if entries, err := os.ReadDir(parent); err != nil || len(entries) != 0 {
+				t.Errorf("ReadDir() = %v, %v, want nothing to be written", entries, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package text
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
+	publog "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+// warned holds the paths an insecure permission was reported for, so each is reported
+// once per process rather than on every token operation.
+var warned sync.Map
+
+// checkDir verifies that the token directory is a directory owned by the current user
+// that other users can't write, since they could otherwise replace the token files. It
+// reports the directory through the InsecureTokenDir log hook when other users can
+// read or list it.
+func (b *Backend) checkDir() error {
+	info, err := os.Stat(b.dir)
+	if err != nil {
+		return fmt.Errorf("get the status of the token directory: %w", err)
+	}
+	if !info.IsDir() {
+		return slogerr.With(errors.New("the token directory isn't a directory"), "path", b.dir) //nolint:wrapcheck
+	}
+	return b.checkPerm("the token directory", b.dir, info, 0o077, b.hooks().InsecureTokenDir)
+}
+
+// checkFile verifies that a token file is a regular file owned by the current user
+// that other users can't write. It reports the file through the InsecureTokenFile log
+// hook when other users can read it.
+func (b *Backend) checkFile(path string, info fs.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return slogerr.With(errors.New("the token file isn't a regular file"), "path", path) //nolint:wrapcheck
+	}
+	return b.checkPerm("the token file", path, info, 0o044, b.hooks().InsecureTokenFile)
+}
+
+// checkPerm fails when path isn't owned by the current user or other users can write
+// it, and reports it through warn once when any of the permission bits in readable are
+// set. It is a no-op on platforms without Unix permissions.
+func (b *Backend) checkPerm(name, path string, info fs.FileInfo, readable fs.FileMode, warn func(*slog.Logger, string, fs.FileMode)) error {
+	if !checkPermissions {
+		return nil
+	}
+	if !ownedByCurrentUser(info) {
+		return slogerr.With(fmt.Errorf("%s is owned by another user", name), "path", path) //nolint:wrapcheck
+	}
+	mode := info.Mode().Perm()
+	if mode&0o022 != 0 {
+		return slogerr.With(fmt.Errorf("%s can be written by other users", name), //nolint:wrapcheck
+			"path", path, "mode", mode.String())
+	}
+	if mode&readable == 0 {
+		return nil
+	}
+	if _, loaded := warned.LoadOrStore(path, struct{}{}); !loaded {
+		warn(b.slog(), path, mode)
+	}
+	return nil
+}
+
+// ReadSecretFile reads a file holding a secret other than a token, such as the key of
+// the encrypted file backend. Since the secret protects the tokens, it is read only when
+// the file is a regular file, not a symbolic link, owned by the current user that other
+// users can neither read nor write. The checks are skipped on platforms without Unix
+// permissions.
+func ReadSecretFile(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, fmt.Errorf("get the status of the file: %w", err)
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return nil, slogerr.With(errors.New("the file is a symbolic link"), "path", path) //nolint:wrapcheck
+	}
+	f, err := openNoFollow(path)
+	if err != nil {
+		return nil, fmt.Errorf("open the file: %w", err)
+	}
+	defer f.Close()
+	// The file is checked through the opened descriptor, so it can't be replaced between
+	// the check and the read.
+	info, err = f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("get the status of the file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, slogerr.With(errors.New("the file isn't a regular file"), "path", path) //nolint:wrapcheck
+	}
+	if checkPermissions {
+		if !ownedByCurrentUser(info) {
+			return nil, slogerr.With(errors.New("the file is owned by another user"), "path", path) //nolint:wrapcheck
+		}
+		if mode := info.Mode().Perm(); mode&0o077 != 0 {
+			return nil, slogerr.With(errors.New("the file can be accessed by other users; restrict its permission to 0600"), //nolint:wrapcheck
+				"path", path, "mode", mode.String())
+		}
+	}
+	bt, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read the file: %w", err)
+	}
+	return bt, nil
+}
+
+// hooks returns the log hooks, defaulting to the internal defaults when they are unset
+// (e.g. a Backend built as a struct literal in a test).
+func (b *Backend) hooks() *publog.Logger {
+	if b.logger == nil || b.logger.InsecureTokenFile == nil || b.logger.InsecureTokenDir == nil {
+		return log.NewLogger()
+	}
+	return b.logger
+}
+
+// slog returns the structured logger passed to the log hooks, defaulting to
+// slog.Default().
+func (b *Backend) slog() *slog.Logger {
+	if b.slogLogger == nil {
+		return slog.Default()
+	}
+	return b.slogLogger
+}
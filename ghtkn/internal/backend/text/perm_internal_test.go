@@ -0,0 +1,162 @@
+//go:build unix
+
+package text
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	publog "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/log"
+)
+
+// warnings records the paths the log hooks report.
+type warnings struct {
+	mu    sync.Mutex
+	files []string
+	dirs  []string
+}
+
+func (w *warnings) logger() *publog.Logger {
+	return &publog.Logger{
+		InsecureTokenFile: func(_ *slog.Logger, path string, _ fs.FileMode) {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.files = append(w.files, path)
+		},
+		InsecureTokenDir: func(_ *slog.Logger, path string, _ fs.FileMode) {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.dirs = append(w.dirs, path)
+		},
+	}
+}
+
+func TestBackend_Get_permissions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		dirMode   fs.FileMode
+		fileMode  fs.FileMode
+		isErr     bool
+		wantFiles bool
+		wantDirs  bool
+	}{
+		{name: "private", dirMode: 0o700, fileMode: 0o600},
+		{name: "readable file", dirMode: 0o700, fileMode: 0o644, wantFiles: true},
+		{name: "writable file", dirMode: 0o700, fileMode: 0o620, isErr: true},
+		{name: "listable directory", dirMode: 0o755, fileMode: 0o600, wantDirs: true},
+		{name: "writable directory", dirMode: 0o777, fileMode: 0o600, isErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &warnings{}
+			b := NewWithDir(filepath.Join(t.TempDir(), "tokens"), w.logger(), nil)
+			ctx := t.Context()
+			if err := b.Set(ctx, "github.com", "client-id", "token"); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			p := filepath.Join(b.dir, "client-id")
+			if err := os.Chmod(p, tt.fileMode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(b.dir, tt.dirMode); err != nil {
+				t.Fatal(err)
+			}
+			got, err := b.Get(ctx, "github.com", "client-id")
+			if err != nil {
+				if !tt.isErr {
+					t.Fatalf("Get() error = %v", err)
+				}
+				return
+			}
+			if tt.isErr {
+				t.Fatal("Get() expected an error")
+			}
+			if diff := cmp.Diff([]byte("token"), got); diff != "" {
+				t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+			}
+			if (len(w.files) != 0) != tt.wantFiles {
+				t.Errorf("InsecureTokenFile reported %v", w.files)
+			}
+			if (len(w.dirs) != 0) != tt.wantDirs {
+				t.Errorf("InsecureTokenDir reported %v", w.dirs)
+			}
+			// The same path is reported only once.
+			if _, err := b.Get(ctx, "github.com", "client-id"); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if len(w.files) > 1 || len(w.dirs) > 1 {
+				t.Errorf("the log hooks were called more than once: %v, %v", w.files, w.dirs)
+			}
+		})
+	}
+}
+
+func TestBackend_Get_symlink(t *testing.T) {
+	t.Parallel()
+	b := &Backend{dir: filepath.Join(t.TempDir(), "tokens")}
+	ctx := t.Context()
+	if err := b.Set(ctx, "github.com", "other", "token"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := os.Symlink(filepath.Join(b.dir, "other"), filepath.Join(b.dir, "client-id")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Get(ctx, "github.com", "client-id"); err == nil {
+		t.Error("Get() expected an error for a symbolic link")
+	}
+}
+
+func TestReadSecretFile(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		mode    fs.FileMode
+		symlink bool
+		isErr   bool
+	}{
+		{name: "private", mode: 0o600},
+		{name: "readable by the group", mode: 0o640, isErr: true},
+		{name: "readable by others", mode: 0o604, isErr: true},
+		{name: "symbolic link", mode: 0o600, symlink: true, isErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			p := filepath.Join(dir, "key")
+			if err := os.WriteFile(p, []byte("secret"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(p, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			if tt.symlink {
+				link := filepath.Join(dir, "link")
+				if err := os.Symlink(p, link); err != nil {
+					t.Fatal(err)
+				}
+				p = link
+			}
+			got, err := ReadSecretFile(p)
+			if err != nil {
+				if !tt.isErr {
+					t.Fatalf("ReadSecretFile() error = %v", err)
+				}
+				return
+			}
+			if tt.isErr {
+				t.Fatal("ReadSecretFile() must fail")
+			}
+			if string(got) != "secret" {
+				t.Errorf("ReadSecretFile() = %q, want %q", got, "secret")
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package text
+
+import (
+	"io/fs"
+	"os"
+)
+
+// checkPermissions is whether the owner and the mode of the token files are verified.
+// Windows has no Unix permissions, so the ACLs of the cache directory protect them.
+const checkPermissions = false
+
+func ownedByCurrentUser(fs.FileInfo) bool {
+	return true
+}
+
+// openNoFollow opens path for reading. Get has refused a symbolic link already.
+func openNoFollow(path string) (*os.File, error) {
+	return os.Open(path) //nolint:wrapcheck
+}
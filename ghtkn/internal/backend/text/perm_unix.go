@@ -0,0 +1,27 @@
+//go:build unix
+
+package text
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// checkPermissions is whether the owner and the mode of the token files are verified.
+const checkPermissions = true
+
+// ownedByCurrentUser reports whether the file of info is owned by the user running the
+// process.
+func ownedByCurrentUser(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	return int(st.Uid) == os.Getuid()
+}
+
+// openNoFollow opens path for reading, failing when it is a symbolic link.
+func openNoFollow(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0) //nolint:wrapcheck
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// maxSymlinks bounds the symbolic links resolveSymlink follows, so a loop fails.
+const maxSymlinks = 255
+
+// writeFileAtomic writes b to path through a temporary file in the same directory
+// renamed over path, so a reader never sees a partially written file. The file gets
+// perm and a missing directory dirPerm. When path is a symbolic link, such as a config
+// file kept in a dotfiles repository, the file it points to is written instead so the
+// link is kept.
+func writeFileAtomic(path string, b []byte, perm, dirPerm fs.FileMode) error {
+	path, err := resolveSymlink(path)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return fmt.Errorf("create a directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create a temporary file: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("write to a temporary file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("change the permission of a temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("close the temporary file: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("rename the temporary file: %w", err)
+	}
+	return nil
+}
+
+// resolveSymlink returns the file path points to when it is a symbolic link, following
+// a chain of links. Unlike filepath.EvalSymlinks it resolves a link to a missing file,
+// which the write then creates, and it doesn't resolve the links in the directories,
+// which a rename goes through anyway.
+func resolveSymlink(path string) (string, error) {
+	for range maxSymlinks {
+		fi, err := os.Lstat(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return path, nil
+			}
+			return "", fmt.Errorf("get the status of a file: %w", err)
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			return path, nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", fmt.Errorf("read a symbolic link: %w", err)
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return "", errors.New("too many levels of symbolic links")
+}
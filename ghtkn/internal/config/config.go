@@ -4,35 +4,110 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
 	"gopkg.in/yaml.v3"
 )
 
 // Reader handles reading configuration files from the filesystem.
-type Reader struct{}
+type Reader struct {
+	getEnv func(string) string
+}
 
-// NewReader creates a new configuration Reader.
-func NewReader() *Reader {
-	return &Reader{}
+// NewReader creates a new configuration Reader. The references to the environment in
+// the values of the files it reads (see expander) are resolved with getEnv.
+func NewReader(getEnv func(string) string) *Reader {
+	return &Reader{getEnv: getEnv}
 }
 
 // Read reads and parses a configuration file from the given path.
-// It decodes the YAML content into the provided Config struct.
+// It decodes the YAML content into the provided Config struct, rejecting a key that
+// isn't a field of Config with its position and a suggestion, and resolving the
+// references to the environment and to files in the values.
 // If configFilePath is empty, it returns nil without reading anything.
 func (r *Reader) Read(cfg *pubconfig.Config, configFilePath string) error {
+	_, err := r.ReadWithPositions(cfg, configFilePath)
+	return err
+}
+
+// ReadWithPositions is Read that also returns the position each field is set at, keyed
+// by its YAML path such as "backend.type" and "apps.0.client_id". Load reports the
+// positions in the origins of the settings.
+func (r *Reader) ReadWithPositions(cfg *pubconfig.Config, configFilePath string) (map[string]Position, error) {
 	if configFilePath == "" {
-		return nil
+		return nil, nil //nolint:nilnil // no file sets no field
 	}
-	f, err := os.Open(configFilePath)
+	b, err := os.ReadFile(configFilePath)
 	if err != nil {
-		return fmt.Errorf("open a configuration file: %w", err)
+		return nil, fmt.Errorf("open a configuration file: %w", err)
+	}
+	return decode(cfg, configFilePath, b, &expander{getEnv: r.getEnv, dir: filepath.Dir(configFilePath)})
+}
+
+// decode parses the content b of the configuration file path into cfg and returns the
+// position each field is set at. A key that isn't a field of Config is an error (see
+// checkFields). x resolves the references in the values; nil leaves them as they are.
+func decode(cfg *pubconfig.Config, path string, b []byte, x *expander) (map[string]Position, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("decode a configuration file as YAML: %w", err)
+	}
+	if err := checkFields(&doc, path); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		// An empty file fails with io.EOF as a yaml.Decoder reports it.
+		if err := yaml.NewDecoder(bytes.NewReader(b)).Decode(cfg); err != nil {
+			return nil, fmt.Errorf("decode a configuration file as YAML: %w", err)
+		}
+		return nil, nil //nolint:nilnil // no field is set
+	}
+	if x != nil {
+		if err := x.expand(&doc, path); err != nil {
+			return nil, err
+		}
+	}
+	joinBackendTypes(&doc)
+	if err := doc.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("decode a configuration file as YAML: %w", err)
+	}
+	return fieldPositions(&doc), nil
+}
+
+// joinBackendTypes replaces a backend.type given as a list of backends to fall back
+// through, at the top level or of an app, with the comma-separated string Backend.Type
+// holds it as.
+func joinBackendTypes(doc *yaml.Node) {
+	join := func(m *yaml.Node) {
+		b := mappingValue(m, "backend")
+		if b == nil || b.Kind != yaml.MappingNode {
+			return
+		}
+		t := mappingValue(b, "type")
+		if t == nil || t.Kind != yaml.SequenceNode {
+			return
+		}
+		types := make([]string, len(t.Content))
+		for i, n := range t.Content {
+			types[i] = n.Value
+		}
+		t.Kind, t.Tag, t.Style, t.Value, t.Content = yaml.ScalarNode, "!!str", 0, strings.Join(types, ","), nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return
 	}
-	defer f.Close() //nolint:errcheck
-	if err := yaml.NewDecoder(f).Decode(cfg); err != nil {
-		return fmt.Errorf("decode a configuration file as YAML: %w", err)
+	join(root)
+	if apps := mappingValue(root, "apps"); apps != nil && apps.Kind == yaml.SequenceNode {
+		for _, app := range apps.Content {
+			if app.Kind == yaml.MappingNode {
+				join(app)
+			}
+		}
 	}
-	return nil
 }
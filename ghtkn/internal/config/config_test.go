@@ -3,8 +3,10 @@ package config_test
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
 )
@@ -12,7 +14,7 @@ import (
 func TestNewReader(t *testing.T) {
 	t.Parallel()
 
-	reader := config.NewReader()
+	reader := config.NewReader(os.Getenv)
 
 	if reader == nil {
 		t.Error("NewReader() returned nil")
@@ -55,6 +57,30 @@ func TestReader_Read(t *testing.T) { //nolint:funlen
 			},
 			wantErr: false,
 		},
+		{
+			name:       "backends to fall back through",
+			configPath: "config.yaml",
+			configContent: `backend:
+  type: [keyring, agent, text]
+apps:
+  - name: test-app
+    client_id: xxx
+    backend:
+      type:
+        - keyring
+        - file`,
+			fileExists: true,
+			expectedConfig: &pubconfig.Config{
+				Backend: &pubconfig.Backend{Type: "keyring,agent,text"},
+				Apps: []*pubconfig.App{
+					{
+						Name:     "test-app",
+						ClientID: "xxx",
+						Backend:  &pubconfig.Backend{Type: "keyring,file"},
+					},
+				},
+			},
+		},
 		{
 			name:       "multiple apps config",
 			configPath: "config.yaml",
@@ -120,7 +146,7 @@ func TestReader_Read(t *testing.T) { //nolint:funlen
 				}
 			}
 
-			reader := config.NewReader()
+			reader := config.NewReader(os.Getenv)
 			cfg := &pubconfig.Config{}
 
 			err := reader.Read(cfg, configPath)
@@ -148,3 +174,146 @@ func TestReader_Read(t *testing.T) { //nolint:funlen
 		})
 	}
 }
+
+func TestReader_Read_unknownFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "misspelled top-level field",
+			content: "min_expiraton: 1h\n",
+			want:    `ghtkn.yaml:1:1: unknown field "min_expiraton", did you mean "min_expiration"?`,
+		},
+		{
+			name:    "misspelled nested field",
+			content: "backend:\n  typ: text\n",
+			want:    `ghtkn.yaml:2:3: unknown field "backend.typ", did you mean "type"?`,
+		},
+		{
+			name:    "field of another mapping",
+			content: "apps:\n  - name: a\n    client_id: Iv1.a\ngit_owner: acme\n",
+			want:    `ghtkn.yaml:4:1: unknown field "git_owner", which is a field of apps[]`,
+		},
+		{
+			name:    "misspelled app field",
+			content: "apps:\n  - name: a\n    clientid: Iv1.a\n",
+			want:    `ghtkn.yaml:3:5: unknown field "apps[].clientid", did you mean "client_id"?`,
+		},
+		{
+			name:    "every unknown field is reported",
+			content: "foo: 1\nbackend:\n  typ: text\n",
+			want: `ghtkn.yaml:1:1: unknown field "foo"
+ghtkn.yaml:3:3: unknown field "backend.typ", did you mean "type"?`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			path := filepath.Join(dir, "ghtkn.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := config.NewReader(os.Getenv).Read(&pubconfig.Config{}, path)
+			if err == nil {
+				t.Fatal("expected an error but got nil")
+			}
+			if want := strings.ReplaceAll(tt.want, "ghtkn.yaml", path); err.Error() != want {
+				t.Errorf("error = %q, want %q", err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestReader_Read_expand(t *testing.T) {
+	t.Parallel()
+	envs := map[string]string{"CLIENT_ID": "Iv1.work", "HOST": "ghes.example.com", "CLIPBOARD": "true"}
+	getEnv := func(k string) string { return envs[k] }
+
+	tests := []struct {
+		name    string
+		content string
+		want    *pubconfig.Config
+		wantErr string
+	}{
+		{
+			name: "references",
+			content: `min_expiration: ${MIN_EXPIRATION:-30m}
+clipboard:
+  enable: ${CLIPBOARD}
+apps:
+  - name: work
+    client_id: ${CLIENT_ID}
+    host: env:HOST
+  - name: secret
+    client_id: file:client_id.txt
+  - name: literal
+    client_id: Iv1.$${CLIENT_ID}
+`,
+			want: &pubconfig.Config{
+				MinExpiration: "30m",
+				Clipboard:     &pubconfig.Clipboard{Enable: new(true)},
+				Apps: []*pubconfig.App{
+					{Name: "work", ClientID: "Iv1.work", Host: "ghes.example.com"},
+					{Name: "secret", ClientID: "Iv1.secret"},
+					{Name: "literal", ClientID: "Iv1.${CLIENT_ID}"},
+				},
+			},
+		},
+		{
+			name:    "unresolved references",
+			content: "apps:\n  - name: a\n    client_id: ${MISSING}\n    host: env:MISSING\n",
+			wantErr: `ghtkn.yaml:3:16: apps[].client_id: the environment variable MISSING in ${MISSING} is unset or empty; give it a default with ${MISSING:-default}
+ghtkn.yaml:4:11: apps[].host: the environment variable MISSING is unset or empty`,
+		},
+		{
+			name:    "invalid reference",
+			content: "min_expiration: ${1H}\n",
+			wantErr: `ghtkn.yaml:1:17: min_expiration: the reference ${1H} must be ${VAR} or ${VAR:-default}`,
+		},
+		{
+			name:    "nested reference with the variable unset",
+			content: "min_expiration: ${MIN_EXPIRATION:-${DEFAULT_MIN_EXPIRATION}}\n",
+			wantErr: `ghtkn.yaml:1:17: min_expiration: the reference "${MIN_EXPIRATION:-${DEFAULT_MIN_EXPIRATION}}" contains another reference, which isn't supported`,
+		},
+		{
+			name:    "nested reference with the variable set",
+			content: "apps:\n  - name: a\n    client_id: ${CLIENT_ID:-${DEFAULT_CLIENT_ID}}\n",
+			wantErr: `ghtkn.yaml:3:16: apps[].client_id: the reference "${CLIENT_ID:-${DEFAULT_CLIENT_ID}}" contains another reference, which isn't supported`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			path := filepath.Join(dir, "ghtkn.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "client_id.txt"), []byte("Iv1.secret\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg := &pubconfig.Config{}
+			err := config.NewReader(getEnv).Read(cfg, path)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatal("expected an error but got nil")
+				}
+				if want := strings.ReplaceAll(tt.wantErr, "ghtkn.yaml", path); err.Error() != want {
+					t.Errorf("error = %q, want %q", err.Error(), want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, cfg); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
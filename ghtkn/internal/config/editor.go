@@ -0,0 +1,417 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Editor edits a config file through its YAML node tree rather than through Config, so
+// the comments, the order of the keys, and the quoting of values are kept. Save splices
+// the nodes that changed into the original file (see splice), so the lines no edit
+// touched, with their blank lines, are kept as they were, and the lines it writes are
+// indented with the indentation detectIndent found. Changes are made in memory and
+// written by Save.
+type Editor struct {
+	path   string
+	doc    *yaml.Node
+	perm   fs.FileMode
+	indent int
+	// src is the original file and orig its node tree, which doc is compared with.
+	src    []byte
+	orig   *yaml.Node
+	getEnv func(string) string
+	goos   string
+}
+
+// NewEditor returns an Editor of the config file path. A missing file is edited as an
+// empty one and created by Save. The references to the environment in the values, and
+// the system and user config files Save validates the file with, are resolved with
+// getEnv and goos.
+func NewEditor(path string, getEnv func(string) string, goos string) (*Editor, error) {
+	e := &Editor{path: path, doc: &yaml.Node{Kind: yaml.DocumentNode}, perm: 0o644, indent: 2, getEnv: getEnv, goos: goos}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read a configuration file: %w", err)
+	}
+	if err == nil {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("get the file mode of a configuration file: %w", err)
+		}
+		e.perm = fi.Mode().Perm()
+		if err := yaml.Unmarshal(b, e.doc); err != nil {
+			return nil, fmt.Errorf("decode a configuration file as YAML: %w", err)
+		}
+		e.src = b
+		e.orig = &yaml.Node{}
+		if err := yaml.Unmarshal(b, e.orig); err != nil {
+			return nil, fmt.Errorf("decode a configuration file as YAML: %w", err)
+		}
+	}
+	if e.doc.Kind != yaml.DocumentNode {
+		// An empty file decodes to the zero node.
+		e.doc = &yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(e.doc.Content) == 0 {
+		e.doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	if e.root().Kind != yaml.MappingNode {
+		return nil, errors.New("the top level of a configuration file must be a mapping")
+	}
+	e.indent = detectIndent(e.root())
+	return e, nil
+}
+
+// Path returns the path of the config file.
+func (e *Editor) Path() string {
+	return e.path
+}
+
+func (e *Editor) root() *yaml.Node {
+	return e.doc.Content[0]
+}
+
+// Config returns the config the edited file holds, without validating it.
+func (e *Editor) Config() (*pubconfig.Config, error) {
+	b, err := e.render()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &pubconfig.Config{}
+	if _, err := decode(cfg, e.path, b, &expander{getEnv: e.getEnv, dir: filepath.Dir(e.path)}); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// SetApp adds app, or updates the app with the same name in place, including the
+// settings it overrides. The fields of an updated app keep their comments and order; a
+// field app doesn't set is removed, and a field the app didn't have is appended.
+func (e *Editor) SetApp(app *pubconfig.App) error {
+	if app.Name == "" {
+		return errors.New("name is required")
+	}
+	apps := mappingValue(e.root(), "apps")
+	if apps == nil {
+		apps = &yaml.Node{Kind: yaml.SequenceNode}
+		setMappingValue(e.root(), "apps", apps)
+	}
+	if apps.Kind != yaml.SequenceNode {
+		return errors.New("apps must be a sequence")
+	}
+	node := findApp(apps, app.Name)
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode}
+		apps.Content = append(apps.Content, node)
+	}
+	setMappingValue(node, "name", strNode(app.Name))
+	setMappingValue(node, "client_id", strNode(app.ClientID))
+	setOrRemove(node, "host", app.Host != "", func() *yaml.Node { return strNode(app.Host) })
+	setOrRemove(node, "git_owner", app.GitOwner != "", func() *yaml.Node { return strNode(app.GitOwner) })
+	setOrRemove(node, "git_owners", len(app.GitOwners) > 0, func() *yaml.Node {
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		if old := mappingValue(node, "git_owners"); old != nil && old.Kind == yaml.SequenceNode {
+			seq.Style = old.Style
+		}
+		for _, owner := range app.GitOwners {
+			seq.Content = append(seq.Content, strNode(owner))
+		}
+		return seq
+	})
+	setOrRemove(node, "default", app.Default, func() *yaml.Node { return boolNode(true) })
+	overrides := appOverrides(app)
+	for _, s := range settings {
+		if !s.app {
+			continue
+		}
+		v, ok := s.value(overrides)
+		if !ok {
+			unsetPath(node, s.name)
+			continue
+		}
+		n, err := settingNode(s.name, v)
+		if err != nil {
+			return fmt.Errorf("%s is invalid: %w", s.name, err)
+		}
+		setPath(node, s.name, n)
+	}
+	return nil
+}
+
+// RemoveApp removes the app named name and reports whether it was there.
+func (e *Editor) RemoveApp(name string) bool {
+	apps := mappingValue(e.root(), "apps")
+	if apps == nil || apps.Kind != yaml.SequenceNode {
+		return false
+	}
+	for i, node := range apps.Content {
+		if appName(node) == name {
+			apps.Content = slices.Delete(apps.Content, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// Set sets the config field other than apps named by its YAML path, such as
+// "backend.type" or "min_expiration", to value. A boolean field takes a value
+// strconv.ParseBool accepts and min_expiration a Go duration.
+func (e *Editor) Set(name, value string) error {
+	if !slices.ContainsFunc(settings, func(s setting) bool { return s.name == name }) {
+		return fmt.Errorf("unknown setting: %s", name)
+	}
+	node, err := settingNode(name, value)
+	if err != nil {
+		return err
+	}
+	setPath(e.root(), name, node)
+	return nil
+}
+
+// Unset removes the config field other than apps named by its YAML path, so it has its
+// default, and the mapping it was in when that is left empty.
+func (e *Editor) Unset(name string) error {
+	if !slices.ContainsFunc(settings, func(s setting) bool { return s.name == name }) {
+		return fmt.Errorf("unknown setting: %s", name)
+	}
+	unsetPath(e.root(), name)
+	return nil
+}
+
+// settingNode returns the node of value for the setting name, checking value.
+func settingNode(name, value string) (*yaml.Node, error) {
+	switch name {
+	case "min_expiration":
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("parse min_expiration as a duration: %w", err)
+		}
+		return strNode(value), nil
+	case "backend.type":
+		if value == "" {
+			return nil, errors.New("backend.type must not be empty")
+		}
+		return strNode(value), nil
+	default:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s as a boolean: %w", name, err)
+		}
+		return boolNode(b), nil
+	}
+}
+
+// setPath sets the field of the mapping m named by the YAML path name to node,
+// creating the mappings on the way.
+func setPath(m *yaml.Node, name string, node *yaml.Node) {
+	keys := strings.Split(name, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child := mappingValue(m, key)
+		if child == nil || child.Kind != yaml.MappingNode {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(m, key, child)
+		}
+		m = child
+	}
+	setMappingValue(m, keys[len(keys)-1], node)
+}
+
+// unsetPath removes the field of the mapping m named by the YAML path name, and the
+// mapping it was in when that is left empty.
+func unsetPath(m *yaml.Node, name string) {
+	parent, key, ok := strings.Cut(name, ".")
+	if !ok {
+		removeMappingKey(m, name)
+		return
+	}
+	child := mappingValue(m, parent)
+	if child == nil || child.Kind != yaml.MappingNode {
+		return
+	}
+	removeMappingKey(child, key)
+	if len(child.Content) == 0 {
+		removeMappingKey(m, parent)
+	}
+}
+
+// Save validates the edited config and writes it atomically, keeping the file mode of
+// an existing file. An invalid config isn't written.
+//
+// The file is one of the layers merged into the config (see Load), so it is validated
+// with Config.ValidateLayer, and may leave the apps to another file. The user config
+// file is also merged onto the system config file and validated with Config.Validate,
+// since no other file is sure to add apps to them: project config files are only read
+// in some directories. So removing the last app of the user config file fails unless
+// the system config file has one.
+func (e *Editor) Save() error {
+	b, err := e.render()
+	if err != nil {
+		return err
+	}
+	cfg := &pubconfig.Config{}
+	positions, err := decode(cfg, e.path, b, &expander{getEnv: e.getEnv, dir: filepath.Dir(e.path)})
+	if err != nil {
+		return err
+	}
+	origins := map[string]pubconfig.Origin{}
+	recordFileOrigins(origins, cfg, e.path, positions)
+	if err := cfg.ValidateLayer(); err != nil {
+		return fmt.Errorf("validate config: %w", Locate(err, origins))
+	}
+	if e.isUserFile() {
+		if err := e.validateMerged(cfg); err != nil {
+			return fmt.Errorf("validate config: %w", Locate(err, origins))
+		}
+	}
+	if err := writeFileAtomic(e.path, b, e.perm, 0o755); err != nil {
+		return fmt.Errorf("write a configuration file: %w", err)
+	}
+	return nil
+}
+
+// isUserFile reports whether the edited file is the user config file (see GetPath).
+func (e *Editor) isUserFile() bool {
+	p, err := GetPath(e.getEnv, e.goos)
+	return err == nil && filepath.Clean(p) == filepath.Clean(e.path)
+}
+
+// validateMerged validates the edited user config file layer merged onto the system
+// config file, if any, as the config.
+func (e *Editor) validateMerged(layer *pubconfig.Config) error {
+	cfg := &pubconfig.Config{}
+	if p := GetSystemPath(e.getEnv, e.goos); p != "" {
+		if err := NewReader(e.getEnv).Read(cfg, p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("read the system config file: %w", err)
+		}
+	}
+	merge(cfg, layer, e.path)
+	return cfg.Validate() //nolint:wrapcheck // Save wraps it
+}
+
+func (e *Editor) render() ([]byte, error) {
+	if e.orig != nil {
+		b, ok, err := splice(e.src, e.orig, e.doc, e.indent)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return b, nil
+		}
+	}
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(e.indent)
+	if err := enc.Encode(e.doc); err != nil {
+		return nil, fmt.Errorf("encode a configuration file as YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode a configuration file as YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// detectIndent returns the indentation of the block mappings and sequences nested in
+// root, 2 when there is none. The encoder indents a sequence in a mapping, so the
+// indentation of a sequence is that of its dashes.
+func detectIndent(root *yaml.Node) int {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if value.Style&yaml.FlowStyle != 0 || len(value.Content) == 0 {
+			continue
+		}
+		switch value.Kind { //nolint:exhaustive // only blocks are indented
+		case yaml.MappingNode:
+			return max(2, value.Content[0].Column-key.Column)
+		case yaml.SequenceNode:
+			return max(2, value.Column-key.Column)
+		}
+	}
+	return 2
+}
+
+// findApp returns the element of apps named name, or nil.
+func findApp(apps *yaml.Node, name string) *yaml.Node {
+	for _, node := range apps.Content {
+		if appName(node) == name {
+			return node
+		}
+	}
+	return nil
+}
+
+func appName(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	if v := mappingValue(node, "name"); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// mappingValue returns the value of key in the mapping m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key of the mapping m to value, appending key when m lacks it. A
+// scalar replacing a scalar is updated in place, so the comments on it are kept.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != key {
+			continue
+		}
+		old := m.Content[i+1]
+		if old.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode {
+			old.Tag, old.Value, old.Style = value.Tag, value.Value, 0
+			return
+		}
+		value.LineComment = old.LineComment
+		m.Content[i+1] = value
+		return
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// setOrRemove sets key of the mapping m to the node value returns when set is true, and
+// otherwise removes key.
+func setOrRemove(m *yaml.Node, key string, set bool, value func() *yaml.Node) {
+	if set {
+		setMappingValue(m, key, value())
+		return
+	}
+	removeMappingKey(m, key)
+}
+
+// removeMappingKey removes key from the mapping m.
+func removeMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = slices.Delete(m.Content, i, i+2)
+			return
+		}
+	}
+}
+
+func strNode(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
+
+func boolNode(b bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}
+}
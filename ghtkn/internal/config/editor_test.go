@@ -0,0 +1,399 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+func noEnv(string) string { return "" }
+
+func TestEditor(t *testing.T) {
+	t.Parallel()
+	t.Run("edit", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "ghtkn.yaml")
+		if err := os.WriteFile(path, []byte(`# ghtkn config
+apps:
+  # my own app
+  - name: personal
+    client_id: Iv1.personal # registered in 2024
+  - name: old
+    client_id: Iv1.old
+  - name: team
+    client_id: Iv1.team
+    git_owner: acme
+min_expiration: 10m # renew early
+open_browser:
+  enable: false
+`), 0o640); err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := config.NewEditor(path, noEnv, "linux")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.SetApp(&pubconfig.App{Name: "team", ClientID: "Iv1.team2", GitOwners: []string{"acme", "acme-*"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.SetApp(&pubconfig.App{
+			Name:          "ghes",
+			ClientID:      "Iv1.ghes",
+			Host:          "ghes.example.com",
+			MinExpiration: "30m",
+			Backend:       &pubconfig.Backend{Type: "agent"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if !e.RemoveApp("old") {
+			t.Error("RemoveApp() = false, want true")
+		}
+		if e.RemoveApp("missing") {
+			t.Error("RemoveApp() = true, want false")
+		}
+		for _, kv := range [][2]string{{"min_expiration", "1h"}, {"backend.type", "text"}} {
+			if err := e.Set(kv[0], kv[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := e.Unset("open_browser.enable"); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `# ghtkn config
+apps:
+  # my own app
+  - name: personal
+    client_id: Iv1.personal # registered in 2024
+  - name: team
+    client_id: Iv1.team2
+    git_owners:
+      - acme
+      - acme-*
+  - name: ghes
+    client_id: Iv1.ghes
+    host: ghes.example.com
+    min_expiration: 30m
+    backend:
+      type: agent
+min_expiration: 1h # renew early
+backend:
+  type: text
+`
+		if diff := cmp.Diff(want, string(b)); diff != "" {
+			t.Error(diff)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0o640 {
+			t.Errorf("file mode = %o, want 640", fi.Mode().Perm())
+		}
+	})
+	t.Run("only comments", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "ghtkn.yaml")
+		content := "# ghtkn config\n# apps:\n#   - name: example\n"
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		e, err := config.NewEditor(path, noEnv, "linux")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.SetApp(&pubconfig.App{Name: "b", ClientID: "Iv1.b"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Save(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := content + "apps:\n  - name: b\n    client_id: Iv1.b\n"
+		if diff := cmp.Diff(want, string(b)); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
+
+func TestEditor_Save_invalid(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "ghtkn.yaml")
+	content := "apps:\n  - name: a\n    client_id: Iv1.a\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := config.NewEditor(path, noEnv, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetApp(&pubconfig.App{Name: "b", ClientID: "Iv1.a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Save(); err == nil {
+		t.Fatal("expected an error for a duplicate client_id but got nil")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != content {
+		t.Errorf("an invalid config was written: %q", b)
+	}
+}
+
+func TestEditor_newFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "ghtkn", "ghtkn.yaml")
+	e, err := config.NewEditor(path, noEnv, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetApp(&pubconfig.App{Name: "a", ClientID: "Iv1.a", Default: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("clipboard.enable", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("clipboard.enable", "maybe"); err == nil {
+		t.Error("expected an error for a non-boolean value but got nil")
+	}
+	if err := e.Set("unknown", "x"); err == nil {
+		t.Error("expected an error for an unknown setting but got nil")
+	}
+	if err := e.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apps:
+  - name: a
+    client_id: Iv1.a
+    default: true
+clipboard:
+  enable: true
+`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEditor_Save_symlink(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "ghtkn.yaml")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("apps:\n  - name: a\n    client_id: Iv1.a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "ghtkn.yaml")
+	if err := os.Symlink(filepath.Join("dotfiles", "ghtkn.yaml"), path); err != nil {
+		t.Skipf("symbolic links aren't supported: %v", err)
+	}
+	e, err := config.NewEditor(path, noEnv, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("clipboard.enable", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Save(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Error("the symbolic link was replaced with a file")
+	}
+	b, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "apps:\n  - name: a\n    client_id: Iv1.a\nclipboard:\n  enable: true\n"
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEditor_Save_keepsFormatting(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "ghtkn.yaml")
+	if err := os.WriteFile(path, []byte(`# ghtkn config
+
+apps:
+    # my own app
+    -   name: personal
+        client_id: Iv1.personal # registered in 2024
+
+    -   name: old
+        client_id: Iv1.old
+
+    # the team's app
+    -   name: team
+        client_id: Iv1.team
+        git_owner: acme
+        # owners of the team
+
+# renew early
+min_expiration:   10m
+
+open_browser:
+    enable: false # I copy the URL
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := config.NewEditor(path, noEnv, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetApp(&pubconfig.App{Name: "team", ClientID: "Iv1.team2", GitOwners: []string{"acme", "acme-*"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetApp(&pubconfig.App{Name: "ghes", ClientID: "Iv1.ghes", Host: "ghes.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if !e.RemoveApp("old") {
+		t.Error("RemoveApp() = false, want true")
+	}
+	if err := e.Set("open_browser.enable", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("backend.type", "text"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# ghtkn config
+
+apps:
+    # my own app
+    -   name: personal
+        client_id: Iv1.personal # registered in 2024
+
+    # the team's app
+    -   name: team
+        client_id: Iv1.team2
+        git_owners:
+            - acme
+            - acme-*
+        # owners of the team
+    -   name: ghes
+        client_id: Iv1.ghes
+        host: ghes.example.com
+
+# renew early
+min_expiration:   10m
+
+open_browser:
+    enable: true # I copy the URL
+backend:
+    type: text
+`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEditor_Save_layer(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "ghtkn.yaml")
+	content := "# set by IT\nbackend:\n  type: keyring\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := config.NewEditor(path, noEnv, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Set("backend.type", "agent"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Save(); err != nil {
+		t.Fatalf("a config file without apps couldn't be saved: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("# set by IT\nbackend:\n  type: agent\n", string(b)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEditor_Save_userConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		system  string
+		wantErr bool
+	}{
+		{
+			name:    "no app is left",
+			wantErr: true,
+		},
+		{
+			name:   "the system config file has an app",
+			system: "apps:\n  - name: org\n    client_id: Iv1.org\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			path := filepath.Join(dir, "ghtkn.yaml")
+			systemPath := filepath.Join(dir, "system.yaml")
+			content := "apps:\n  - name: a\n    client_id: Iv1.a\n"
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.system != "" {
+				if err := os.WriteFile(systemPath, []byte(tt.system), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			envs := map[string]string{"GHTKN_CONFIG": path, "GHTKN_SYSTEM_CONFIG": systemPath}
+			e, err := config.NewEditor(path, func(k string) string { return envs[k] }, "linux")
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.RemoveApp("a")
+			err = e.Save()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Save() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr && string(b) != content {
+				t.Errorf("an invalid config was written: %q", b)
+			}
+		})
+	}
+}
@@ -8,48 +8,132 @@ import (
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
 )
 
-// ApplyEnvOverrides overwrites the Config fields that have a corresponding environment
-// variable, when that variable is set:
-//
-//   - GHTKN_BACKEND -> Backend.Type
-//   - GHTKN_MIN_EXPIRATION -> MinExpiration (a Go duration string, parsed later)
-//   - GHTKN_OPEN_BROWSER -> OpenBrowser.Enable (a boolean parsed by strconv.ParseBool)
-//   - GHTKN_CLIPBOARD -> Clipboard.Enable (a boolean parsed by strconv.ParseBool)
-//
-// A GHTKN_OPEN_BROWSER or GHTKN_CLIPBOARD value that strconv.ParseBool cannot parse is a
-// hard error, so a typo fails fast instead of being silently misinterpreted.
-// SkipAccountPicker has no environment variable, so it is left as read from the file.
-// This is the single place that maps environment variables onto config fields, shared by
-// LoadConfig and the token-retrieval path so their env semantics cannot drift.
-func ApplyEnvOverrides(cfg *pubconfig.Config, getEnv func(string) string) error {
-	if v := getEnv(env.Backend); v != "" {
+// envOverride maps an environment variable onto the config field it overrides.
+type envOverride struct {
+	env     string
+	setting string // The YAML path of the field, such as "backend.type"
+	apply   func(cfg *pubconfig.Config, v string) error
+}
+
+// envOverrides are the environment variables ApplyEnvOverrides applies, in order.
+var envOverrides = []envOverride{ //nolint:gochecknoglobals // a read-only table
+	{env: env.Backend, setting: "backend.type", apply: func(cfg *pubconfig.Config, v string) error {
 		if cfg.Backend == nil {
 			cfg.Backend = &pubconfig.Backend{}
 		}
 		cfg.Backend.Type = v
-	}
-	if v := getEnv(env.MinExpiration); v != "" {
+		return nil
+	}},
+	{env: env.MinExpiration, setting: "min_expiration", apply: func(cfg *pubconfig.Config, v string) error {
 		cfg.MinExpiration = v
-	}
-	if v := getEnv(env.OpenBrowser); v != "" {
-		b, err := strconv.ParseBool(v)
+		return nil
+	}},
+	{env: env.OpenBrowser, setting: "open_browser.enable", apply: func(cfg *pubconfig.Config, v string) error {
+		b, err := parseBoolEnv(env.OpenBrowser, v)
 		if err != nil {
-			return fmt.Errorf("parse %s as a boolean: %w", env.OpenBrowser, err)
+			return err
 		}
 		if cfg.OpenBrowser == nil {
 			cfg.OpenBrowser = &pubconfig.OpenBrowser{}
 		}
 		cfg.OpenBrowser.Enable = &b
-	}
-	if v := getEnv(env.Clipboard); v != "" {
-		b, err := strconv.ParseBool(v)
+		return nil
+	}},
+	{env: env.Clipboard, setting: "clipboard.enable", apply: func(cfg *pubconfig.Config, v string) error {
+		b, err := parseBoolEnv(env.Clipboard, v)
 		if err != nil {
-			return fmt.Errorf("parse %s as a boolean: %w", env.Clipboard, err)
+			return err
 		}
 		if cfg.Clipboard == nil {
 			cfg.Clipboard = &pubconfig.Clipboard{}
 		}
 		cfg.Clipboard.Enable = &b
+		return nil
+	}},
+	{env: env.ProjectConfig, setting: "project_config.enable", apply: func(cfg *pubconfig.Config, v string) error {
+		b, err := parseBoolEnv(env.ProjectConfig, v)
+		if err != nil {
+			return err
+		}
+		if cfg.ProjectConfig == nil {
+			cfg.ProjectConfig = &pubconfig.ProjectConfig{}
+		}
+		cfg.ProjectConfig.Enable = &b
+		return nil
+	}},
+}
+
+// ApplyEnvOverrides overwrites the Config fields that have a corresponding environment
+// variable, when that variable is set, both at the top level and in every app that
+// overrides the field, so the variable takes precedence over the app's value:
+//
+//   - GHTKN_BACKEND -> Backend.Type
+//   - GHTKN_MIN_EXPIRATION -> MinExpiration (a Go duration string, parsed later)
+//   - GHTKN_OPEN_BROWSER -> OpenBrowser.Enable (a boolean parsed by strconv.ParseBool)
+//   - GHTKN_CLIPBOARD -> Clipboard.Enable (a boolean parsed by strconv.ParseBool)
+//   - GHTKN_PROJECT_CONFIG -> ProjectConfig.Enable (a boolean parsed by strconv.ParseBool)
+//
+// A GHTKN_OPEN_BROWSER, GHTKN_CLIPBOARD, or GHTKN_PROJECT_CONFIG value that
+// strconv.ParseBool cannot parse is a hard error, so a typo fails fast instead of being
+// silently misinterpreted.
+// SkipAccountPicker has no environment variable, so it is left as read from the file.
+// This is the single place that maps environment variables onto config fields, shared by
+// LoadConfig and the token-retrieval path so their env semantics cannot drift.
+func ApplyEnvOverrides(cfg *pubconfig.Config, getEnv func(string) string) error {
+	for _, o := range envOverrides {
+		v := getEnv(o.env)
+		if v == "" {
+			continue
+		}
+		if err := o.apply(cfg, v); err != nil {
+			return err
+		}
+		for _, app := range cfg.Apps {
+			overrides := appOverrides(app)
+			if !o.setBy(overrides) {
+				continue
+			}
+			if err := o.apply(overrides, v); err != nil {
+				return err
+			}
+			setAppOverrides(app, overrides)
+		}
 	}
 	return nil
 }
+
+// RecordEnvOrigins records in origins the environment variables ApplyEnvOverrides
+// applies to cfg as the origins of the fields they override.
+func RecordEnvOrigins(origins map[string]pubconfig.Origin, cfg *pubconfig.Config, getEnv func(string) string) {
+	for _, o := range envOverrides {
+		if getEnv(o.env) == "" {
+			continue
+		}
+		origin := pubconfig.Origin{Kind: pubconfig.OriginEnv, Env: o.env}
+		origins[o.setting] = origin
+		for _, app := range cfg.Apps {
+			if o.setBy(appOverrides(app)) {
+				origins[pubconfig.AppSettingName(app.Name, o.setting)] = origin
+			}
+		}
+	}
+}
+
+// setBy reports whether cfg sets the field o overrides.
+func (o envOverride) setBy(cfg *pubconfig.Config) bool {
+	for _, s := range settings {
+		if s.name == o.setting {
+			_, ok := s.value(cfg)
+			return ok
+		}
+	}
+	return false
+}
+
+func parseBoolEnv(name, v string) (bool, error) {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parse %s as a boolean: %w", name, err)
+	}
+	return b, nil
+}
@@ -128,6 +128,27 @@ func TestApplyEnvOverrides(t *testing.T) {
 	}
 }
 
+func TestApplyEnvOverrides_app(t *testing.T) {
+	t.Parallel()
+	cfg := &pubconfig.Config{Apps: []*pubconfig.App{
+		{Name: "a", ClientID: "Iv1.a", Backend: &pubconfig.Backend{Type: "text"}, MinExpiration: "10m"},
+		{Name: "b", ClientID: "Iv1.b"},
+	}}
+	if err := config.ApplyEnvOverrides(cfg, func(k string) string {
+		return map[string]string{"GHTKN_BACKEND": "agent"}[k]
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The environment overrides the app's own backend, but leaves the settings the
+	// environment doesn't set and the apps that don't override the backend alone.
+	if got := cfg.ForApp(cfg.Apps[0]); got.Backend.Type != "agent" || got.MinExpiration != "10m" {
+		t.Errorf("ForApp(a) = backend %q, min_expiration %q, want agent and 10m", got.Backend.Type, got.MinExpiration)
+	}
+	if cfg.Apps[1].Backend != nil {
+		t.Errorf("apps[b].backend = %+v, want nil", cfg.Apps[1].Backend)
+	}
+}
+
 func assertEnable(t *testing.T, field string, got, want *bool) {
 	t.Helper()
 	switch {
@@ -139,3 +160,27 @@ func assertEnable(t *testing.T, field string, got, want *bool) {
 		t.Errorf("%s enable = %v, want %v", field, *got, *want)
 	}
 }
+
+func TestApplyEnvOverrides_projectConfig(t *testing.T) {
+	t.Parallel()
+	cfg := &pubconfig.Config{}
+	if err := config.ApplyEnvOverrides(cfg, func(k string) string {
+		if k == "GHTKN_PROJECT_CONFIG" {
+			return "true"
+		}
+		return ""
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ProjectConfig == nil || cfg.ProjectConfig.Enable == nil || !*cfg.ProjectConfig.Enable {
+		t.Errorf("project_config.enable = %+v, want true", cfg.ProjectConfig)
+	}
+	if err := config.ApplyEnvOverrides(&pubconfig.Config{}, func(k string) string {
+		if k == "GHTKN_PROJECT_CONFIG" {
+			return "yes"
+		}
+		return ""
+	}); err == nil {
+		t.Error("expected an error for an unparsable GHTKN_PROJECT_CONFIG")
+	}
+}
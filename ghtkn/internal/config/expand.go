@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A string value of a config file can refer to the environment, so that one config
+// file, such as one in a dotfiles repository, serves machines whose values differ:
+//
+//   - ${VAR} is replaced with the environment variable VAR, and ${VAR:-default} with
+//     default when VAR is unset or empty. $${ is a literal ${. A default can't contain
+//     a reference, as in ${A:-${B}}.
+//   - A value env:VAR is the environment variable VAR, and a value file:PATH the content
+//     of the file PATH without the trailing newline, such as a client ID kept out of
+//     the repository. A relative PATH is relative to the directory of the config file.
+//     The references in PATH are replaced first.
+//
+// A reference that can't be resolved is an error naming its position, rather than
+// being left in the value. A value's type is decided after the replacement, so
+// "enable: ${CLIPBOARD:-false}" is a boolean.
+
+// expander replaces the references to the environment in the values of a config file.
+type expander struct {
+	getEnv func(string) string
+	// dir is the directory of the config file, which a relative file: path is
+	// relative to.
+	dir string
+}
+
+// expand replaces the references in the string scalars of the parsed config file doc,
+// read from path. It returns an error for each reference that can't be resolved, joined
+// in the order they appear.
+func (x *expander) expand(doc *yaml.Node, path string) error {
+	var errs []error
+	var walk func(node *yaml.Node, name string)
+	walk = func(node *yaml.Node, name string) {
+		switch node.Kind { //nolint:exhaustive // an alias is expanded where its anchor is
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, name)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], joinPath(name, node.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for _, child := range node.Content {
+				walk(child, name+"[]")
+			}
+		case yaml.ScalarNode:
+			if node.ShortTag() != "!!str" {
+				return
+			}
+			v, err := x.expandValue(node.Value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s:%d:%d: %s: %w", path, node.Line, node.Column, name, err))
+				return
+			}
+			if v == node.Value {
+				return
+			}
+			node.Value = v
+			if node.Style == 0 {
+				// Let a plain scalar be typed by its new value.
+				node.Tag = ""
+			}
+		}
+	}
+	walk(doc, "")
+	return errors.Join(errs...)
+}
+
+// expandValue returns the value v refers to.
+func (x *expander) expandValue(v string) (string, error) {
+	v, err := x.interpolate(v)
+	if err != nil {
+		return "", err
+	}
+	if name, ok := strings.CutPrefix(v, "env:"); ok {
+		val := x.getEnv(name)
+		if val == "" {
+			return "", fmt.Errorf("the environment variable %s is unset or empty", name)
+		}
+		return val, nil
+	}
+	if p, ok := strings.CutPrefix(v, "file:"); ok {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(x.dir, p)
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return "", fmt.Errorf("read the file the value refers to: %w", err)
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"), nil
+	}
+	return v, nil
+}
+
+// interpolate replaces ${VAR} and ${VAR:-default} in s.
+func (x *expander) interpolate(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			// $${ is a literal ${.
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		ref, rest, ok := strings.Cut(s[i+2:], "}")
+		if !ok {
+			return "", fmt.Errorf("the reference %q isn't closed with }", s[i:])
+		}
+		if strings.Contains(ref, "${") {
+			return "", fmt.Errorf("the reference %q contains another reference, which isn't supported", s[i:])
+		}
+		name, def, hasDefault := strings.Cut(ref, ":-")
+		if !isEnvName(name) {
+			return "", fmt.Errorf("the reference ${%s} must be ${VAR} or ${VAR:-default}", ref)
+		}
+		val := x.getEnv(name)
+		switch {
+		case val != "":
+		case hasDefault:
+			val = def
+		default:
+			return "", fmt.Errorf("the environment variable %s in ${%s} is unset or empty; give it a default with ${%s:-default}", name, ref, name)
+		}
+		b.WriteString(val)
+		s = rest
+	}
+}
+
+// isEnvName reports whether s is a valid environment variable name in a reference.
+func isEnvName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
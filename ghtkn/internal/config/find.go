@@ -5,30 +5,38 @@ import (
 	"path/filepath"
 
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
 )
 
 // GetPath returns the configuration file path for ghtkn.
 // If GHTKN_CONFIG is set, its value is returned as-is, overriding everything else.
 // Otherwise it combines the XDG_CONFIG_HOME directory with the ghtkn configuration
-// filename; the typical path is $XDG_CONFIG_HOME/ghtkn/ghtkn.yaml.
+// filename; the typical path is $XDG_CONFIG_HOME/ghtkn/ghtkn.yaml. The config file of
+// the profile GHTKN_PROFILE selects is in its directory, such as
+// $XDG_CONFIG_HOME/ghtkn/profiles/<profile>/ghtkn.yaml, or profiles/<profile> in the
+// directory of GHTKN_CONFIG.
 func GetPath(getEnv func(string) string, goos string) (string, error) {
 	if f := getEnv(env.Config); f != "" {
-		return f, nil
+		return profile.OverrideFile(getEnv, f) //nolint:wrapcheck
+	}
+	profileDir, err := profile.Dir(getEnv)
+	if err != nil {
+		return "", err //nolint:wrapcheck
 	}
 	if goos == "windows" {
 		appData := getEnv(env.AppData)
 		if appData != "" {
-			return filepath.Join(appData, "ghtkn", "ghtkn.yaml"), nil
+			return filepath.Join(appData, "ghtkn", profileDir, "ghtkn.yaml"), nil
 		}
 		return "", errors.New("APPDATA is required on Windows")
 	}
 	xdgConfigHome := getEnv(env.XDGConfigHome)
 	if xdgConfigHome != "" {
-		return filepath.Join(xdgConfigHome, "ghtkn", "ghtkn.yaml"), nil
+		return filepath.Join(xdgConfigHome, "ghtkn", profileDir, "ghtkn.yaml"), nil
 	}
 	home := getEnv(env.Home)
 	if home != "" {
-		return filepath.Join(home, ".config", "ghtkn", "ghtkn.yaml"), nil
+		return filepath.Join(home, ".config", "ghtkn", profileDir, "ghtkn.yaml"), nil
 	}
 	return "", errors.New("XDG_CONFIG_HOME or HOME is required on Linux and macOS")
 }
@@ -36,6 +36,34 @@ func TestGetPath(t *testing.T) { //nolint:funlen
 			want:    filepath.Join("/custom", "ghtkn.yaml"),
 			wantErr: false,
 		},
+		// Profiles
+		{
+			name: "GHTKN_PROFILE selects the config file of the profile",
+			envs: map[string]string{
+				"GHTKN_PROFILE":   "work",
+				"XDG_CONFIG_HOME": "/home/user/.config",
+			},
+			goos: "linux",
+			want: filepath.Join("/home", "user", ".config", "ghtkn", "profiles", "work", "ghtkn.yaml"),
+		},
+		{
+			name: "GHTKN_CONFIG in a profile",
+			envs: map[string]string{
+				"GHTKN_CONFIG":  filepath.Join("/custom", "ghtkn.yaml"),
+				"GHTKN_PROFILE": "work",
+			},
+			goos: "linux",
+			want: filepath.Join("/custom", "profiles", "work", "ghtkn.yaml"),
+		},
+		{
+			name: "GHTKN_PROFILE escaping the config directory",
+			envs: map[string]string{
+				"GHTKN_PROFILE":   "../work",
+				"XDG_CONFIG_HOME": "/home/user/.config",
+			},
+			goos:    "linux",
+			wantErr: true,
+		},
 		// Linux/macOS tests
 		{
 			name: "Linux: standard XDG config path",
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+// The config is merged from up to three layers, each overriding the ones before it:
+//
+//  1. the system config file, for apps managed by an organization
+//     (GHTKN_SYSTEM_CONFIG, /etc/ghtkn/ghtkn.yaml, or %ProgramData%\ghtkn\ghtkn.yaml
+//     on Windows),
+//  2. the user config file (see GetPath),
+//  3. when enabled with project_config.enable or GHTKN_PROJECT_CONFIG, the trusted
+//     project config files (.ghtkn.yaml) in the working directory and its parents,
+//     the nearest one last.
+//
+// See merge for how two layers are merged.
+
+// ProjectFileName is the name of a project config file.
+const ProjectFileName = ".ghtkn.yaml"
+
+// FileReader reads a config file.
+type FileReader interface {
+	Read(cfg *pubconfig.Config, configFilePath string) error
+}
+
+// positionReader is a FileReader that also reports the position each field is set at,
+// such as Reader. The origins of the fields read by a FileReader that isn't one have no
+// position.
+type positionReader interface {
+	ReadWithPositions(cfg *pubconfig.Config, configFilePath string) (map[string]Position, error)
+}
+
+// InputLoad is the input of Load.
+type InputLoad struct {
+	Reader FileReader
+	Getenv func(string) string
+	GOOS   string
+	// UserPath is the path of the user config file.
+	UserPath string
+	// Dir is the directory project config files are looked for from. Empty means
+	// project config files aren't read.
+	Dir string
+}
+
+// LoadResult describes the files Load read.
+type LoadResult struct {
+	// Files are the config files merged into the config, in the order they were merged.
+	Files []string
+	// Untrusted are the project config files ignored because they aren't trusted.
+	Untrusted []string
+	// TrustStore is the path of the trust store the project config files are checked
+	// against (see TrustStore), empty when project config files aren't read. Trusting or
+	// untrusting a project changes it, and so which project config files are read.
+	TrustStore string
+	// Origins maps the YAML path of each field a file sets, such as "backend.type" and
+	// "apps[work].client_id" (see pubconfig.AppSettingName), to the file and line that
+	// set it. Pass it to Settings.
+	Origins map[string]pubconfig.Origin
+}
+
+// Load reads the layered config into cfg. A missing system or project config file is
+// skipped, and so is a missing user config file as long as another file is read; when
+// no file is read the error reading the user config file is returned. It doesn't apply
+// the environment overrides or validate the config.
+func Load(cfg *pubconfig.Config, input *InputLoad) (*LoadResult, error) {
+	result := &LoadResult{Origins: map[string]pubconfig.Origin{}}
+	if p := GetSystemPath(input.Getenv, input.GOOS); p != "" {
+		if err := readLayer(cfg, input.Reader, p, result); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	userErr := readLayer(cfg, input.Reader, input.UserPath, result)
+	if userErr != nil && !errors.Is(userErr, fs.ErrNotExist) {
+		return nil, userErr
+	}
+
+	enabled, err := projectConfigEnabled(cfg, input.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	if enabled && input.Dir != "" {
+		if store, err := NewTrustStore(input.Getenv, input.GOOS); err == nil {
+			result.TrustStore = store.path
+		}
+		if err := loadProjectFiles(cfg, input, result); err != nil {
+			return nil, err
+		}
+	}
+	if len(result.Files) == 0 && userErr != nil {
+		return nil, userErr
+	}
+	return result, nil
+}
+
+// readLayer reads the config file p and merges it into cfg.
+func readLayer(cfg *pubconfig.Config, reader FileReader, p string, result *LoadResult) error {
+	layer := &pubconfig.Config{}
+	var positions map[string]Position
+	var err error
+	if pr, ok := reader.(positionReader); ok {
+		positions, err = pr.ReadWithPositions(layer, p)
+	} else {
+		err = reader.Read(layer, p)
+	}
+	if err != nil {
+		return fmt.Errorf("read a config file: %w", slogerr.With(err, "config", p))
+	}
+	merge(cfg, layer, p)
+	recordFileOrigins(result.Origins, layer, p, positions)
+	result.Files = append(result.Files, p)
+	return nil
+}
+
+// loadProjectFiles merges the trusted project config files found from input.Dir into
+// cfg.
+func loadProjectFiles(cfg *pubconfig.Config, input *InputLoad, result *LoadResult) error {
+	files, err := FindProjectFiles(input.Dir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	store, err := NewTrustStore(input.Getenv, input.GOOS)
+	if err != nil {
+		return err
+	}
+	for _, p := range files {
+		// The file is read once and the content that was checked is the content that
+		// is decoded, so the file can't be swapped in between.
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("read a project config file: %w", slogerr.With(err, "config", p))
+		}
+		trusted, err := store.IsTrusted(filepath.Dir(p), b)
+		if err != nil {
+			return err
+		}
+		if !trusted {
+			result.Untrusted = append(result.Untrusted, p)
+			continue
+		}
+		layer := &pubconfig.Config{}
+		// A project config file comes from a repository, so it can't read the
+		// environment or files of the user.
+		positions, err := decode(layer, p, b, nil)
+		if err != nil {
+			return fmt.Errorf("read a project config file: %w", slogerr.With(err, "config", p))
+		}
+		// A project config file can't enable or disable project config files.
+		layer.ProjectConfig = nil
+		merge(cfg, layer, p)
+		recordFileOrigins(result.Origins, layer, p, positions)
+		result.Files = append(result.Files, p)
+	}
+	return nil
+}
+
+// projectConfigEnabled reports whether project config files are read: the
+// GHTKN_PROJECT_CONFIG environment variable, else project_config.enable of the system
+// and user config files, else false.
+func projectConfigEnabled(cfg *pubconfig.Config, getEnv func(string) string) (bool, error) {
+	if v := getEnv(env.ProjectConfig); v != "" {
+		return parseBoolEnv(env.ProjectConfig, v)
+	}
+	if cfg.ProjectConfig == nil || cfg.ProjectConfig.Enable == nil {
+		return false, nil
+	}
+	return *cfg.ProjectConfig.Enable, nil
+}
+
+// GetSystemPath returns the path of the system config file: GHTKN_SYSTEM_CONFIG if set,
+// otherwise /etc/ghtkn/ghtkn.yaml, or %ProgramData%\ghtkn\ghtkn.yaml on Windows. It
+// returns "" on Windows when ProgramData isn't set.
+func GetSystemPath(getEnv func(string) string, goos string) string {
+	if f := getEnv(env.SystemConfig); f != "" {
+		return f
+	}
+	if goos == "windows" {
+		if d := getEnv(env.ProgramData); d != "" {
+			return filepath.Join(d, "ghtkn", "ghtkn.yaml")
+		}
+		return ""
+	}
+	return filepath.Join("/etc", "ghtkn", "ghtkn.yaml")
+}
+
+// FindProjectFiles returns the project config files in dir and its parents, the
+// farthest one first, which is the order they are merged in.
+func FindProjectFiles(dir string) ([]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("get the absolute path of a directory: %w", err)
+	}
+	var files []string
+	for d := dir; ; d = filepath.Dir(d) {
+		p := filepath.Join(d, ProjectFileName)
+		fi, err := os.Stat(p)
+		switch {
+		case err == nil && !fi.IsDir():
+			files = append(files, p)
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("check a project config file: %w", err)
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	slices.Reverse(files)
+	return files, nil
+}
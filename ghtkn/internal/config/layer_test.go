@@ -0,0 +1,142 @@
+package config_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+func TestGetSystemPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		envs map[string]string
+		goos string
+		want string
+	}{
+		{name: "GHTKN_SYSTEM_CONFIG", envs: map[string]string{"GHTKN_SYSTEM_CONFIG": "/custom/ghtkn.yaml"}, goos: "linux", want: "/custom/ghtkn.yaml"},
+		{name: "Linux", goos: "linux", want: filepath.Join("/etc", "ghtkn", "ghtkn.yaml")},
+		{name: "Windows", envs: map[string]string{"ProgramData": `C:\ProgramData`}, goos: "windows", want: filepath.Join(`C:\ProgramData`, "ghtkn", "ghtkn.yaml")},
+		{name: "Windows without ProgramData", goos: "windows", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := config.GetSystemPath(func(k string) string { return tt.envs[k] }, tt.goos)
+			if got != tt.want {
+				t.Errorf("GetSystemPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindProjectFiles(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	for _, dir := range []string{"a", filepath.Join("a", "b", "c")} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, ".ghtkn.yaml"), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A directory named .ghtkn.yaml is not a config file.
+	if err := os.MkdirAll(filepath.Join(root, "a", "b", ".ghtkn.yaml"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := config.FindProjectFiles(filepath.Join(root, "a", "b", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(root, "a", ".ghtkn.yaml"),
+		filepath.Join(root, "a", "b", "c", ".ghtkn.yaml"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "user.yaml")
+	systemPath := filepath.Join(dir, "system.yaml")
+	if err := os.WriteFile(systemPath, []byte("apps:\n  - name: org\n    client_id: Iv1.org\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		envs       map[string]string
+		wantErrIs  error
+		wantApps   []string
+		wantResult *config.LoadResult
+	}{
+		{
+			name:      "no config file is the error of the user config file",
+			envs:      map[string]string{"GHTKN_SYSTEM_CONFIG": filepath.Join(dir, "absent.yaml")},
+			wantErrIs: fs.ErrNotExist,
+		},
+		{
+			name:     "a missing user config file is skipped when the system config file is read",
+			envs:     map[string]string{"GHTKN_SYSTEM_CONFIG": systemPath},
+			wantApps: []string{"org"},
+			wantResult: &config.LoadResult{
+				Files: []string{systemPath},
+				Origins: map[string]pubconfig.Origin{
+					"apps[org]":           {Kind: pubconfig.OriginFile, File: systemPath, Line: 2, Column: 5},
+					"apps[org].name":      {Kind: pubconfig.OriginFile, File: systemPath, Line: 2, Column: 5},
+					"apps[org].client_id": {Kind: pubconfig.OriginFile, File: systemPath, Line: 3, Column: 5},
+				},
+			},
+		},
+		{
+			name: "an invalid GHTKN_PROJECT_CONFIG is an error",
+			envs: map[string]string{"GHTKN_SYSTEM_CONFIG": systemPath, "GHTKN_PROJECT_CONFIG": "maybe"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := &pubconfig.Config{}
+			result, err := config.Load(cfg, &config.InputLoad{
+				Reader:   config.NewReader(os.Getenv),
+				Getenv:   func(k string) string { return tt.envs[k] },
+				GOOS:     "linux",
+				UserPath: userPath,
+				Dir:      dir,
+			})
+			if tt.wantResult == nil {
+				if err == nil {
+					t.Fatal("expected an error but got nil")
+				}
+				if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("error = %v, want it to wrap %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantResult, result); diff != "" {
+				t.Error(diff)
+			}
+			names := make([]string, len(cfg.Apps))
+			for i, app := range cfg.Apps {
+				names[i] = app.Name
+			}
+			if diff := cmp.Diff(tt.wantApps, names); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+)
+
+// merge overlays src, read from the config file source, onto dst, which holds the
+// config files of lower precedence. An app of src replaces the app of dst with the same
+// name as a whole, so an app's fields never come from two files, and is otherwise
+// appended. A default app in src takes the place of the default app of dst. Each other
+// field set in src overrides dst.
+func merge(dst, src *pubconfig.Config, source string) {
+	srcHasDefault := false
+	for _, app := range src.Apps {
+		if app.Default {
+			srcHasDefault = true
+		}
+	}
+	for _, app := range src.Apps {
+		app.Source = source
+		if i := appIndex(dst.Apps, app.Name); i >= 0 {
+			dst.Apps[i] = app
+			continue
+		}
+		dst.Apps = append(dst.Apps, app)
+	}
+	if srcHasDefault {
+		for _, app := range dst.Apps {
+			app.Default = app.Default && app.Source == source
+		}
+	}
+	if src.SkipAccountPicker != nil {
+		dst.SkipAccountPicker = src.SkipAccountPicker
+	}
+	if src.OpenBrowser != nil && src.OpenBrowser.Enable != nil {
+		dst.OpenBrowser = src.OpenBrowser
+	}
+	if src.MinExpiration != "" {
+		dst.MinExpiration = src.MinExpiration
+	}
+	if src.Backend != nil && src.Backend.Type != "" {
+		dst.Backend = src.Backend
+	}
+	if src.Clipboard != nil && src.Clipboard.Enable != nil {
+		dst.Clipboard = src.Clipboard
+	}
+	if src.ProjectConfig != nil && src.ProjectConfig.Enable != nil {
+		dst.ProjectConfig = src.ProjectConfig
+	}
+}
+
+// appIndex returns the index of the app named name in apps, or -1.
+func appIndex(apps []*pubconfig.App, name string) int {
+	for i, app := range apps {
+		if app.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	dst := &pubconfig.Config{
+		Apps: []*pubconfig.App{
+			{Name: "a", ClientID: "Iv1.a", Default: true, Source: "system"},
+			{Name: "b", ClientID: "Iv1.b", GitOwner: "acme", Source: "system"},
+		},
+		MinExpiration: "10m",
+		Backend:       &pubconfig.Backend{Type: "keyring"},
+	}
+	merge(dst, &pubconfig.Config{
+		Apps: []*pubconfig.App{
+			{Name: "b", ClientID: "Iv1.b2"},
+			{Name: "c", ClientID: "Iv1.c", Default: true},
+		},
+		Backend:   &pubconfig.Backend{},
+		Clipboard: &pubconfig.Clipboard{Enable: new(true)},
+	}, "user")
+
+	want := &pubconfig.Config{
+		Apps: []*pubconfig.App{
+			// The default app of the higher layer takes the place of the lower one's.
+			{Name: "a", ClientID: "Iv1.a", Source: "system"},
+			// The app replaces the app with the same name as a whole.
+			{Name: "b", ClientID: "Iv1.b2", Source: "user"},
+			{Name: "c", ClientID: "Iv1.c", Default: true, Source: "user"},
+		},
+		MinExpiration: "10m",
+		// An unset field doesn't override.
+		Backend:   &pubconfig.Backend{Type: "keyring"},
+		Clipboard: &pubconfig.Clipboard{Enable: new(true)},
+	}
+	if diff := cmp.Diff(want, dst); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+)
+
+// setting is a config field other than apps.
+type setting struct {
+	name string // The YAML path, such as "backend.type"
+	def  string // The built-in default, formatted as text
+	app  bool   // Whether an app can override it
+	// value returns the value of the field in cfg formatted as text, and whether it is
+	// set, which is when merge overrides a lower layer with it.
+	value func(cfg *pubconfig.Config) (string, bool)
+}
+
+// settings are the config fields other than apps, in the order Settings reports them.
+var settings = []setting{ //nolint:gochecknoglobals // a read-only table
+	{name: "skip_account_picker", def: "true", value: func(cfg *pubconfig.Config) (string, bool) {
+		return formatBool(cfg.SkipAccountPicker)
+	}},
+	{name: "open_browser.enable", def: "true", app: true, value: func(cfg *pubconfig.Config) (string, bool) {
+		if cfg.OpenBrowser == nil {
+			return "", false
+		}
+		return formatBool(cfg.OpenBrowser.Enable)
+	}},
+	{name: "min_expiration", def: "0s", app: true, value: func(cfg *pubconfig.Config) (string, bool) {
+		return cfg.MinExpiration, cfg.MinExpiration != ""
+	}},
+	{name: "backend.type", def: "keyring", app: true, value: func(cfg *pubconfig.Config) (string, bool) {
+		if cfg.Backend == nil {
+			return "", false
+		}
+		return cfg.Backend.Type, cfg.Backend.Type != ""
+	}},
+	{name: "clipboard.enable", def: "false", app: true, value: func(cfg *pubconfig.Config) (string, bool) {
+		if cfg.Clipboard == nil {
+			return "", false
+		}
+		return formatBool(cfg.Clipboard.Enable)
+	}},
+	{name: "project_config.enable", def: "false", value: func(cfg *pubconfig.Config) (string, bool) {
+		if cfg.ProjectConfig == nil {
+			return "", false
+		}
+		return formatBool(cfg.ProjectConfig.Enable)
+	}},
+}
+
+func formatBool(b *bool) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	return strconv.FormatBool(*b), true
+}
+
+// appOverrides returns the settings app overrides as a Config sharing app's values, so
+// the settings table and the environment overrides apply to an app as to the top
+// level. Write a change back with setAppOverrides.
+func appOverrides(app *pubconfig.App) *pubconfig.Config {
+	return &pubconfig.Config{
+		MinExpiration: app.MinExpiration,
+		Backend:       app.Backend,
+		OpenBrowser:   app.OpenBrowser,
+		Clipboard:     app.Clipboard,
+	}
+}
+
+func setAppOverrides(app *pubconfig.App, cfg *pubconfig.Config) {
+	app.MinExpiration = cfg.MinExpiration
+	app.Backend = cfg.Backend
+	app.OpenBrowser = cfg.OpenBrowser
+	app.Clipboard = cfg.Clipboard
+}
+
+// recordFileOrigins records in origins the fields layer, read from the config file p,
+// sets. positions is the position each field is set at as fieldPositions returns it; it
+// may be nil when the positions are unknown. It mirrors merge: an app replaces the origins of the app
+// with the same name, and a default app is the origin of the default field of every
+// other app, because it is what unsets them.
+func recordFileOrigins(origins map[string]pubconfig.Origin, layer *pubconfig.Config, p string, positions map[string]Position) {
+	at := func(path string) pubconfig.Origin {
+		pos := positions[path]
+		return pubconfig.Origin{Kind: pubconfig.OriginFile, File: p, Line: pos.Line, Column: pos.Column}
+	}
+	for _, s := range settings {
+		if _, ok := s.value(layer); ok {
+			origins[s.name] = at(s.name)
+		}
+	}
+	defaultPath := ""
+	for i, app := range layer.Apps {
+		appName := pubconfig.AppSettingName(app.Name, "")
+		for k := range origins {
+			if k == appName || strings.HasPrefix(k, appName+".") {
+				delete(origins, k)
+			}
+		}
+		prefix := "apps." + strconv.Itoa(i)
+		origins[appName] = at(prefix)
+		for path := range positions {
+			field, ok := strings.CutPrefix(path, prefix+".")
+			if !ok {
+				continue
+			}
+			origins[pubconfig.AppSettingName(app.Name, field)] = at(path)
+		}
+		if app.Default {
+			defaultPath = prefix + ".default"
+		}
+	}
+	if defaultPath == "" {
+		return
+	}
+	for k, o := range origins {
+		if strings.HasPrefix(k, "apps[") && strings.HasSuffix(k, "].default") && o.File != p {
+			origins[k] = at(defaultPath)
+		}
+	}
+}
+
+// Settings returns the effective value and the origin of every field of cfg, the
+// fields other than apps first and then the fields of each app, including the settings
+// it overrides. origins are the origins
+// Load and RecordEnvOrigins recorded; a field without one has its built-in default.
+func Settings(cfg *pubconfig.Config, origins map[string]pubconfig.Origin) []*pubconfig.Setting {
+	defaultOrigin := pubconfig.Origin{Kind: pubconfig.OriginDefault}
+	ret := make([]*pubconfig.Setting, 0, len(settings))
+	for _, s := range settings {
+		v, ok := s.value(cfg)
+		origin, recorded := origins[s.name]
+		if !ok || !recorded {
+			v, origin = s.def, defaultOrigin
+		}
+		ret = append(ret, &pubconfig.Setting{Name: s.name, Value: v, Origin: origin})
+	}
+	for _, app := range cfg.Apps {
+		appOrigin, ok := origins[pubconfig.AppSettingName(app.Name, "")]
+		if !ok {
+			appOrigin = pubconfig.Origin{Kind: pubconfig.OriginFile, File: app.Source}
+		}
+		add := func(field, value string, set bool, def string) {
+			name := pubconfig.AppSettingName(app.Name, field)
+			origin, ok := origins[name]
+			switch {
+			case set && !ok:
+				origin = appOrigin
+			case !set && !ok:
+				value, origin = def, defaultOrigin
+			}
+			ret = append(ret, &pubconfig.Setting{Name: name, Value: value, Origin: origin})
+		}
+		add("name", app.Name, true, "")
+		add("client_id", app.ClientID, true, "")
+		add("host", app.HostName(), app.Host != "", app.HostName())
+		if app.GitOwner != "" {
+			add("git_owner", app.GitOwner, true, "")
+		}
+		if len(app.GitOwners) > 0 {
+			add("git_owners", strings.Join(app.GitOwners, ","), true, "")
+		}
+		add("default", strconv.FormatBool(app.Default), app.Default, "false")
+		overrides := appOverrides(app)
+		for _, s := range settings {
+			if v, ok := s.value(overrides); s.app && ok {
+				add(s.name, v, true, "")
+			}
+		}
+	}
+	return ret
+}
+
+// Locate prefixes the message of each pubconfig.FieldError in err, such as an error of
+// Config.Validate, with the position its field is set at in origins, as
+// "<file>:<line>:<column>: <message>". An error about a field that isn't set in a
+// config file is returned as is.
+func Locate(err error, origins map[string]pubconfig.Origin) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		located := make([]error, len(errs))
+		for i, e := range errs {
+			located[i] = Locate(e, origins)
+		}
+		return errors.Join(located...)
+	}
+	var fe *pubconfig.FieldError
+	if !errors.As(err, &fe) {
+		return err
+	}
+	origin, ok := origins[fe.Field]
+	if !ok || origin.Kind != pubconfig.OriginFile || origin.Line == 0 {
+		return err
+	}
+	return fmt.Errorf("%s: %w", origin, err)
+}
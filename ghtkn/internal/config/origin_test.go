@@ -0,0 +1,119 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+func TestSettings(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	systemPath := filepath.Join(dir, "system.yaml")
+	userPath := filepath.Join(dir, "user.yaml")
+	if err := os.WriteFile(systemPath, []byte(`min_expiration: 10m
+backend:
+  type: text
+apps:
+  - name: org
+    client_id: Iv1.org
+    default: true
+  - name: shared
+    client_id: Iv1.shared
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userPath, []byte(`clipboard:
+  enable: true
+apps:
+  - name: shared
+    client_id: Iv1.mine
+    git_owners: [acme, acme-*]
+    host: ghes.example.com
+  - name: mine
+    client_id: Iv1.other
+    default: true
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	envs := map[string]string{"GHTKN_SYSTEM_CONFIG": systemPath, "GHTKN_BACKEND": "agent"}
+	getEnv := func(k string) string { return envs[k] }
+
+	cfg := &pubconfig.Config{}
+	result, err := config.Load(cfg, &config.InputLoad{
+		Reader:   config.NewReader(os.Getenv),
+		Getenv:   getEnv,
+		GOOS:     "linux",
+		UserPath: userPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ApplyEnvOverrides(cfg, getEnv); err != nil {
+		t.Fatal(err)
+	}
+	config.RecordEnvOrigins(result.Origins, cfg, getEnv)
+
+	def := pubconfig.Origin{Kind: pubconfig.OriginDefault}
+	sys := func(line, column int) pubconfig.Origin {
+		return pubconfig.Origin{Kind: pubconfig.OriginFile, File: systemPath, Line: line, Column: column}
+	}
+	user := func(line, column int) pubconfig.Origin {
+		return pubconfig.Origin{Kind: pubconfig.OriginFile, File: userPath, Line: line, Column: column}
+	}
+	want := []*pubconfig.Setting{
+		{Name: "skip_account_picker", Value: "true", Origin: def},
+		{Name: "open_browser.enable", Value: "true", Origin: def},
+		{Name: "min_expiration", Value: "10m", Origin: sys(1, 1)},
+		{Name: "backend.type", Value: "agent", Origin: pubconfig.Origin{Kind: pubconfig.OriginEnv, Env: "GHTKN_BACKEND"}},
+		{Name: "clipboard.enable", Value: "true", Origin: user(2, 3)},
+		{Name: "project_config.enable", Value: "false", Origin: def},
+		{Name: "apps[org].name", Value: "org", Origin: sys(5, 5)},
+		{Name: "apps[org].client_id", Value: "Iv1.org", Origin: sys(6, 5)},
+		{Name: "apps[org].host", Value: "github.com", Origin: def},
+		// The default app of the user config file unsets it.
+		{Name: "apps[org].default", Value: "false", Origin: user(10, 5)},
+		// The app of the user config file replaces the app as a whole.
+		{Name: "apps[shared].name", Value: "shared", Origin: user(4, 5)},
+		{Name: "apps[shared].client_id", Value: "Iv1.mine", Origin: user(5, 5)},
+		{Name: "apps[shared].host", Value: "ghes.example.com", Origin: user(7, 5)},
+		{Name: "apps[shared].git_owners", Value: "acme,acme-*", Origin: user(6, 5)},
+		{Name: "apps[shared].default", Value: "false", Origin: def},
+		{Name: "apps[mine].name", Value: "mine", Origin: user(8, 5)},
+		{Name: "apps[mine].client_id", Value: "Iv1.other", Origin: user(9, 5)},
+		{Name: "apps[mine].host", Value: "github.com", Origin: def},
+		{Name: "apps[mine].default", Value: "true", Origin: user(10, 5)},
+	}
+	if diff := cmp.Diff(want, config.Settings(cfg, result.Origins)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	t.Parallel()
+	origins := map[string]pubconfig.Origin{
+		"apps[a].git_owner": {Kind: pubconfig.OriginFile, File: "ghtkn.yaml", Line: 4, Column: 5},
+		"apps[b].git_owner": {Kind: pubconfig.OriginFile, File: "ghtkn.yaml", Line: 7, Column: 5},
+	}
+	err := (&pubconfig.Config{Apps: []*pubconfig.App{
+		{Name: "a", ClientID: "Iv1.a", GitOwner: "acme"},
+		{Name: "b", ClientID: "Iv1.b", GitOwner: "acme"},
+		{Name: "c", ClientID: "Iv1.c", GitOwner: "ACME"},
+	}}).Validate()
+	want := `ghtkn.yaml:7:5: repository owners of apps on a host must not overlap: "acme" of "a" and "acme" of "b" can match the same repository on github.com
+repository owners of apps on a host must not overlap: "acme" of "a" and "ACME" of "c" can match the same repository on github.com
+repository owners of apps on a host must not overlap: "acme" of "b" and "ACME" of "c" can match the same repository on github.com`
+	if got := config.Locate(err, origins).Error(); got != want {
+		t.Errorf("Locate() = %q, want %q", got, want)
+	}
+
+	err = (&pubconfig.Config{Apps: []*pubconfig.App{{Name: "a", ClientID: "Iv1.a", GitOwner: "a/b/c"}}}).Validate()
+	if got := config.Locate(err, origins).Error(); !strings.HasPrefix(got, "ghtkn.yaml:4:5: app is invalid: git_owner is invalid") {
+		t.Errorf("Locate() = %q", got)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a 1-based line and column in a config file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// fieldPositions returns the position each field of the parsed config file doc is set
+// at, keyed by its YAML path: mapping keys are joined with "." and a sequence element
+// is keyed by its index, such as "backend.type" and "apps.0.client_id". A field is at
+// its key, and a sequence element at its first token.
+func fieldPositions(doc *yaml.Node) map[string]Position {
+	positions := map[string]Position{}
+	if len(doc.Content) > 0 {
+		walkPositions(doc.Content[0], "", positions)
+	}
+	return positions
+}
+
+func walkPositions(node *yaml.Node, prefix string, positions map[string]Position) {
+	switch node.Kind { //nolint:exhaustive // scalars and aliases have no fields
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			p := joinPath(prefix, key.Value)
+			positions[p] = Position{Line: key.Line, Column: key.Column}
+			walkPositions(value, p, positions)
+		}
+	case yaml.SequenceNode:
+		for i, elem := range node.Content {
+			p := joinPath(prefix, strconv.Itoa(i))
+			positions[p] = Position{Line: elem.Line, Column: elem.Column}
+			walkPositions(elem, p, positions)
+		}
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
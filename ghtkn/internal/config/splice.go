@@ -0,0 +1,461 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A config file is saved by splicing the nodes that changed into its original bytes,
+// rather than by encoding the whole document again, so that the lines no edit touched
+// stay as they were, with their blank lines and indentation. The edited document is
+// compared with a second parse of the original, and a node is matched with the node of
+// the original at the same line and column; the nodes the edits created have no
+// position. An unchanged node keeps its lines. A changed block mapping or sequence is
+// spliced entry by entry: a removed entry's lines are deleted, an added entry is
+// encoded after the last one, and a changed entry is spliced in turn or, when it can't
+// be, such as a scalar or a flow collection, encoded in place of its lines.
+//
+// The lines of an entry are those from its key, or its first token in a sequence, to
+// its last line that isn't blank or only a comment. The full-line comments before and
+// after it therefore stay where they are when it is encoded again, and so the comments
+// attached to its outer nodes aren't encoded.
+
+// lineEdit replaces the original lines from line, starting at the byte col of it, up to
+// end (exclusive) with text. An insertion has end == line.
+type lineEdit struct {
+	line int
+	col  int
+	end  int
+	text []string
+}
+
+// splicer computes the edits from an original config file to an edited one.
+type splicer struct {
+	lines  []string
+	indent int
+	edits  []lineEdit
+}
+
+// splice returns the original file src with the changes of the edited document doc to
+// the original document orig spliced in. ok is false when the changes can't be spliced,
+// such as when the top level isn't a non-empty block mapping, and the document must be
+// encoded as a whole.
+func splice(src []byte, orig, doc *yaml.Node, indent int) (b []byte, ok bool, err error) {
+	if len(doc.Content) == 0 {
+		return nil, false, nil
+	}
+	if len(orig.Content) == 0 {
+		return appendDocument(src, doc.Content[0], indent)
+	}
+	if !sameComments(doc, orig) {
+		return nil, false, nil
+	}
+	root, origRoot := doc.Content[0], orig.Content[0]
+	if !isBlock(origRoot, yaml.MappingNode) || root.Kind != yaml.MappingNode {
+		return nil, false, nil
+	}
+	crlf := bytes.Contains(src, []byte("\r\n"))
+	text := strings.ReplaceAll(string(src), "\r\n", "\n")
+	newline := strings.HasSuffix(text, "\n")
+	s := &splicer{lines: strings.Split(strings.TrimSuffix(text, "\n"), "\n"), indent: indent}
+	ok, err = s.mapping(root, origRoot, len(s.lines))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	// The file ends with as many blank lines as it did, whichever entries were at its
+	// end.
+	out := trimBlank(s.apply(), len(s.lines)-len(trimBlank(s.lines, 0)))
+	if len(out) == 0 {
+		return nil, true, nil
+	}
+	sep := "\n"
+	if crlf {
+		sep = "\r\n"
+	}
+	res := strings.Join(out, sep)
+	if newline || len(s.edits) > 0 {
+		res += sep
+	}
+	return []byte(res), true, nil
+}
+
+// appendDocument returns the original file src, which holds no YAML node, such as a
+// starter config whose settings are all commented out, with the edited top level root
+// appended after it, so its comments are kept. ok is false when src is blank.
+func appendDocument(src []byte, root *yaml.Node, indent int) (b []byte, ok bool, err error) {
+	if len(bytes.TrimSpace(src)) == 0 {
+		return nil, false, nil
+	}
+	if len(root.Content) == 0 {
+		return src, true, nil
+	}
+	s := &splicer{indent: indent}
+	lines, err := s.encode(root, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	sep := "\n"
+	if bytes.Contains(src, []byte("\r\n")) {
+		sep = "\r\n"
+	}
+	text := string(src)
+	if !strings.HasSuffix(text, "\n") {
+		text += sep
+	}
+	return []byte(text + strings.Join(lines, sep) + sep), true, nil
+}
+
+// apply returns the original lines with the edits applied.
+func (s *splicer) apply() []string {
+	edits := slices.Clone(s.edits)
+	slices.SortStableFunc(edits, func(a, b lineEdit) int {
+		return a.line - b.line
+	})
+	var out []string
+	cursor := 0
+	for _, ed := range edits {
+		if ed.line > cursor {
+			out = append(out, s.lines[cursor:ed.line]...)
+			cursor = ed.line
+		}
+		if ed.end == ed.line {
+			out = append(out, ed.text...)
+			continue
+		}
+		text := slices.Clone(ed.text)
+		if prefix := s.lines[ed.line][:ed.col]; len(text) > 0 {
+			text[0] = prefix + text[0]
+		}
+		out = append(out, text...)
+		cursor = max(cursor, ed.end)
+	}
+	return append(out, s.lines[cursor:]...)
+}
+
+// mapping splices the changes of the block mapping n to the non-empty block mapping o
+// of the original, whose lines end before the line limit.
+func (s *splicer) mapping(n, o *yaml.Node, limit int) (bool, error) {
+	var keys []*yaml.Node
+	for i := 0; i+1 < len(o.Content); i += 2 {
+		keys = append(keys, o.Content[i])
+	}
+	bounds := s.bounds(keys, limit)
+	col := o.Content[0].Column - 1
+	mark := len(s.edits)
+	next := 0 // the index of the next entry of o
+	after := -1
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		j := indexAt(keys, key)
+		if j < 0 {
+			if after < 0 {
+				// An entry added before the first one isn't spliced.
+				return s.rollback(mark)
+			}
+			text, err := s.encode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, value}}, col)
+			if err != nil {
+				return false, err
+			}
+			s.edits = append(s.edits, lineEdit{line: after, end: after, text: indentFirst(text, col)})
+			continue
+		}
+		if j < next {
+			// The entries were reordered.
+			return s.rollback(mark)
+		}
+		for ; next < j; next++ {
+			if !s.remove(keys[next].Line-1, bounds[next], col, keys[next].Value) {
+				return s.rollback(mark)
+			}
+		}
+		next++
+		start, end := keys[j].Line-1, bounds[j]
+		after = s.after(end, s.limit(j, keys, limit), col)
+		ovalue := o.Content[2*j+1]
+		if same(key, keys[j]) && same(value, ovalue) {
+			continue
+		}
+		if same(key, keys[j]) && ovalue.Line > keys[j].Line && sameOuter(value, ovalue) {
+			ok, err := s.collection(value, ovalue, s.limit(j, keys, limit))
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				continue
+			}
+		}
+		entry := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{withoutHeadComment(key), withoutFootComments(value)}}
+		entry.Content[0].FootComment = ""
+		text, err := s.encode(entry, col)
+		if err != nil {
+			return false, err
+		}
+		s.edits = append(s.edits, lineEdit{line: start, col: keys[j].Column - 1, end: end, text: text})
+	}
+	for ; next < len(keys); next++ {
+		if !s.remove(keys[next].Line-1, bounds[next], col, keys[next].Value) {
+			return s.rollback(mark)
+		}
+	}
+	return true, nil
+}
+
+// sequence splices the changes of the block sequence n to the non-empty block sequence
+// o of the original, whose lines end before the line limit.
+func (s *splicer) sequence(n, o *yaml.Node, limit int) (bool, error) {
+	items := o.Content
+	bounds := s.bounds(items, limit)
+	dash := o.Column - 1
+	// A new item is indented as the first one is.
+	offset := 2
+	if items[0].Line == o.Line {
+		offset = items[0].Column - o.Column
+	}
+	mark := len(s.edits)
+	next := 0
+	after := -1
+	for _, item := range n.Content {
+		j := indexAt(items, item)
+		if j < 0 {
+			if after < 0 {
+				return s.rollback(mark)
+			}
+			text, err := s.encode(item, dash+offset)
+			if err != nil {
+				return false, err
+			}
+			text[0] = strings.Repeat(" ", dash) + "-" + strings.Repeat(" ", offset-1) + text[0]
+			s.edits = append(s.edits, lineEdit{line: after, end: after, text: text})
+			continue
+		}
+		if j < next {
+			return s.rollback(mark)
+		}
+		for ; next < j; next++ {
+			if !s.remove(items[next].Line-1, bounds[next], dash, "-") {
+				return s.rollback(mark)
+			}
+		}
+		next++
+		oitem := items[j]
+		after = s.after(bounds[j], s.limit(j, items, limit), dash)
+		if same(item, oitem) {
+			continue
+		}
+		if sameOuter(item, oitem) {
+			ok, err := s.collection(item, oitem, s.limit(j, items, limit))
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				continue
+			}
+		}
+		text, err := s.encode(withoutFootComments(withoutHeadComment(item)), oitem.Column-1)
+		if err != nil {
+			return false, err
+		}
+		s.edits = append(s.edits, lineEdit{line: oitem.Line - 1, col: oitem.Column - 1, end: bounds[j], text: text})
+	}
+	for ; next < len(items); next++ {
+		if !s.remove(items[next].Line-1, bounds[next], dash, "-") {
+			return s.rollback(mark)
+		}
+	}
+	return true, nil
+}
+
+// collection splices the changes of n to o when both are non-empty block mappings or
+// sequences.
+func (s *splicer) collection(n, o *yaml.Node, limit int) (bool, error) {
+	if !isBlock(n, o.Kind) || !isBlock(o, o.Kind) {
+		return false, nil
+	}
+	switch o.Kind { //nolint:exhaustive // only collections are spliced
+	case yaml.MappingNode:
+		return s.mapping(n, o, limit)
+	case yaml.SequenceNode:
+		return s.sequence(n, o, limit)
+	}
+	return false, nil
+}
+
+// remove deletes the lines of the entry whose first token, a key or the dash of a
+// sequence item, is at the column col of line start and which ends before end, with the
+// comment lines just above it indented as it is. When the entry is set off by blank
+// lines, the blank lines after it are deleted too. It returns false when the entry
+// doesn't start its line, such as the first entry of a mapping in a sequence.
+func (s *splicer) remove(start, end, col int, token string) bool {
+	if indentation(s.lines[start]) != col || !strings.HasPrefix(s.lines[start][col:], token) {
+		return false
+	}
+	for start > 0 && isComment(s.lines[start-1]) && indentation(s.lines[start-1]) == col {
+		start--
+	}
+	if start > 0 && strings.TrimSpace(s.lines[start-1]) == "" {
+		for end < len(s.lines) && strings.TrimSpace(s.lines[end]) == "" {
+			end++
+		}
+	}
+	s.edits = append(s.edits, lineEdit{line: start, end: end})
+	return true
+}
+
+// rollback drops the edits made since mark and reports that the changes can't be
+// spliced.
+func (s *splicer) rollback(mark int) (bool, error) {
+	s.edits = s.edits[:mark]
+	return false, nil
+}
+
+// bounds returns the line each entry starting at nodes ends before: the line after its
+// last one that isn't blank or only a comment, before the next entry or limit.
+func (s *splicer) bounds(nodes []*yaml.Node, limit int) []int {
+	bounds := make([]int, len(nodes))
+	for i := range nodes {
+		end := s.limit(i, nodes, limit)
+		start := nodes[i].Line - 1
+		for end-1 > start && isFiller(s.lines[end-1]) {
+			end--
+		}
+		bounds[i] = end
+	}
+	return bounds
+}
+
+// after returns the line an entry added after the entry ending before end is inserted
+// at: after the comment lines indented further than the entries, col, which belong to
+// the entry, but before limit.
+func (s *splicer) after(end, limit, col int) int {
+	for end < limit && isComment(s.lines[end]) && indentation(s.lines[end]) > col {
+		end++
+	}
+	return end
+}
+
+// limit returns the line the next entry after nodes[i] starts at, or limit.
+func (s *splicer) limit(i int, nodes []*yaml.Node, limit int) int {
+	if i+1 < len(nodes) {
+		return nodes[i+1].Line - 1
+	}
+	return limit
+}
+
+// encode encodes node and indents each line but the first by col spaces, where the
+// first line is placed at column col.
+func (s *splicer) encode(node *yaml.Node, col int) ([]string, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(s.indent)
+	if err := enc.Encode(node); err != nil {
+		return nil, fmt.Errorf("encode a configuration file as YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode a configuration file as YAML: %w", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	pad := strings.Repeat(" ", col)
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = pad + lines[i]
+		}
+	}
+	return lines, nil
+}
+
+// indentFirst indents the first of lines by col spaces.
+func indentFirst(lines []string, col int) []string {
+	lines[0] = strings.Repeat(" ", col) + lines[0]
+	return lines
+}
+
+// indexAt returns the index of the node of nodes at the position of node, or -1 when
+// node has no position, having been added by an edit.
+func indexAt(nodes []*yaml.Node, node *yaml.Node) int {
+	if node.Line == 0 {
+		return -1
+	}
+	return slices.IndexFunc(nodes, func(o *yaml.Node) bool {
+		return o.Line == node.Line && o.Column == node.Column
+	})
+}
+
+// same reports whether n is the node o of the original, unchanged.
+func same(n, o *yaml.Node) bool {
+	if !sameOuter(n, o) || n.Value != o.Value || n.LineComment != o.LineComment || len(n.Content) != len(o.Content) {
+		return false
+	}
+	for i := range n.Content {
+		if !same(n.Content[i], o.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameOuter reports whether n is at the position of o with its kind, tag, style, and
+// the comments above and below it.
+func sameOuter(n, o *yaml.Node) bool {
+	return n.Kind == o.Kind && n.Tag == o.Tag && n.Style == o.Style &&
+		n.Line == o.Line && n.Column == o.Column && sameComments(n, o)
+}
+
+func sameComments(n, o *yaml.Node) bool {
+	return n.HeadComment == o.HeadComment && n.FootComment == o.FootComment
+}
+
+// isBlock reports whether n is a non-empty block collection of kind.
+func isBlock(n *yaml.Node, kind yaml.Kind) bool {
+	return n.Kind == kind && n.Style&yaml.FlowStyle == 0 && len(n.Content) > 0
+}
+
+// withoutHeadComment returns a copy of n without the comment above it, which stays in
+// the file as is.
+func withoutHeadComment(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.HeadComment = ""
+	return &c
+}
+
+// withoutFootComments returns a copy of n without the comments below it and below its
+// last descendants, which stay in the file as is.
+func withoutFootComments(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.FootComment = ""
+	if last := len(c.Content) - 1; last >= 0 {
+		c.Content = slices.Clone(c.Content)
+		c.Content[last] = withoutFootComments(c.Content[last])
+		if c.Kind == yaml.MappingNode && last > 0 {
+			key := *c.Content[last-1]
+			key.FootComment = ""
+			c.Content[last-1] = &key
+		}
+	}
+	return &c
+}
+
+// trimBlank removes the blank lines at the end of lines but keep of them.
+func trimBlank(lines []string, keep int) []string {
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return lines[:min(end+keep, len(lines))]
+}
+
+func isComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+// isFiller reports whether line is blank or only a comment.
+func isFiller(line string) bool {
+	return strings.TrimSpace(line) == "" || isComment(line)
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
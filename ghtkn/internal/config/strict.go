@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"gopkg.in/yaml.v3"
+)
+
+// A config file is decoded strictly: a key that isn't a field of the mapping it is in,
+// such as a misspelled "min_expiraton" or a "git_owner" outside of an app, is an error
+// instead of being silently ignored. The error names the position of the key and, when
+// it can, the field that was probably meant.
+
+// checkFields returns an error for each key of the parsed config file doc, read from
+// path, that isn't a field of Config, joined in the order the keys appear.
+func checkFields(doc *yaml.Node, path string) error {
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	var errs []error
+	walkFields(doc.Content[0], reflect.TypeFor[pubconfig.Config](), "", path, &errs)
+	return errors.Join(errs...)
+}
+
+func walkFields(node *yaml.Node, t reflect.Type, prefix, path string, errs *[]error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				// A merge key's fields are checked where they are defined.
+				continue
+			}
+			ft, ok := fields[key.Value]
+			if !ok {
+				*errs = append(*errs, fmt.Errorf("%s:%d:%d: unknown field %q%s",
+					path, key.Line, key.Column, joinPath(prefix, key.Value), suggestField(key.Value, fields)))
+				continue
+			}
+			walkFields(value, ft, joinPath(prefix, key.Value), path, errs)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for _, elem := range node.Content {
+			walkFields(elem, t.Elem(), prefix+"[]", path, errs)
+		}
+	}
+}
+
+// yamlFields returns the types of the fields of the struct type t by the key they are
+// decoded from: the name in the yaml tag, else the field name in lower case, as
+// yaml.v3 does.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for f := range t.Fields() {
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// suggestField returns a hint naming the field of fields that key is probably a
+// misspelling of, or the mappings key is a field of when it is a field elsewhere in the
+// config. It returns "" when it has no hint.
+func suggestField(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", max(1, len(key)/3)+1
+	for name := range fields {
+		if d := levenshtein(key, name); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf(", did you mean %q?", best)
+	}
+	if parents := fieldParents(reflect.TypeFor[pubconfig.Config]())[key]; len(parents) > 0 {
+		return fmt.Sprintf(", which is a field of %s", strings.Join(parents, " and "))
+	}
+	return ""
+}
+
+// fieldParents maps each field key under the struct type t to the paths of the
+// mappings it is a field of, with "[]" standing for the elements of a sequence, such as
+// "git_owner" to ["apps[]"]. The top level is "the top level".
+func fieldParents(t reflect.Type) map[string][]string {
+	parents := map[string][]string{}
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+			if t.Kind() == reflect.Slice {
+				prefix += "[]"
+			}
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		parent := prefix
+		if parent == "" {
+			parent = "the top level"
+		}
+		fields := yamlFields(t)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			parents[name] = append(parents[name], parent)
+			walk(fields[name], joinPath(prefix, name))
+		}
+	}
+	walk(t, "")
+	return parents
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
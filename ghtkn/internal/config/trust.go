@@ -0,0 +1,147 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+)
+
+// A project config file is only read once the user has trusted it, like direnv's
+// `direnv allow`. Trusting records the directory of the file with the SHA-256 digest of
+// its content in ${XDG_DATA_HOME}/ghtkn/trusted_projects.json
+// (%LocalAppData%\ghtkn\trusted_projects.json on Windows). A file whose content no
+// longer matches the recorded digest, such as one changed by `git pull`, is untrusted
+// until it is trusted again, so a cloned or updated repository can't redirect tokens.
+
+// TrustStore is the list of trusted project config files.
+type TrustStore struct {
+	path string
+}
+
+// trustFile is the content of the trust store file.
+type trustFile struct {
+	// Projects maps the absolute path of a directory to the hex SHA-256 digest of the
+	// content of its trusted .ghtkn.yaml.
+	Projects map[string]string `json:"projects"`
+}
+
+// NewTrustStore returns the trust store of the user.
+func NewTrustStore(getEnv func(string) string, goos string) (*TrustStore, error) {
+	dir, err := dataDir(getEnv, goos)
+	if err != nil {
+		return nil, err
+	}
+	return &TrustStore{path: filepath.Join(dir, "ghtkn", "trusted_projects.json")}, nil
+}
+
+// dataDir resolves the base data directory. On Windows it is %LocalAppData%; otherwise
+// it honors XDG_DATA_HOME and falls back to $HOME/.local/share.
+func dataDir(getEnv func(string) string, goos string) (string, error) {
+	if goos == "windows" {
+		if d := getEnv(env.LocalAppData); d != "" {
+			return d, nil
+		}
+		return "", errors.New("LocalAppData is required to trust project config files on Windows")
+	}
+	if d := getEnv(env.XDGDataHome); d != "" {
+		return d, nil
+	}
+	if home := getEnv(env.Home); home != "" {
+		return filepath.Join(home, ".local", "share"), nil
+	}
+	return "", errors.New("XDG_DATA_HOME or HOME is required to trust project config files")
+}
+
+// IsTrusted reports whether the project config file in dir with content b is trusted.
+func (s *TrustStore) IsTrusted(dir string, b []byte) (bool, error) {
+	tf, err := s.read()
+	if err != nil {
+		return false, err
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return false, fmt.Errorf("get the absolute path of a directory: %w", err)
+	}
+	digest, ok := tf.Projects[dir]
+	return ok && digest == digestOf(b), nil
+}
+
+// Trust trusts the current content of the project config file in dir.
+func (s *TrustStore) Trust(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("get the absolute path of a directory: %w", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, ProjectFileName))
+	if err != nil {
+		return fmt.Errorf("read a project config file: %w", err)
+	}
+	tf, err := s.read()
+	if err != nil {
+		return err
+	}
+	tf.Projects[dir] = digestOf(b)
+	return s.write(tf)
+}
+
+// Untrust removes the project config file in dir from the trusted files. It is a no-op
+// when the file isn't trusted.
+func (s *TrustStore) Untrust(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("get the absolute path of a directory: %w", err)
+	}
+	tf, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := tf.Projects[dir]; !ok {
+		return nil
+	}
+	delete(tf.Projects, dir)
+	return s.write(tf)
+}
+
+func (s *TrustStore) read() (*trustFile, error) {
+	tf := &trustFile{}
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			tf.Projects = map[string]string{}
+			return tf, nil
+		}
+		return nil, fmt.Errorf("read the trusted project list: %w", err)
+	}
+	if err := json.Unmarshal(b, tf); err != nil {
+		return nil, fmt.Errorf("decode the trusted project list as JSON: %w", err)
+	}
+	if tf.Projects == nil {
+		tf.Projects = map[string]string{}
+	}
+	return tf, nil
+}
+
+// write writes the trust store atomically with file permission 0600, as the text
+// backend writes a token.
+func (s *TrustStore) write(tf *trustFile) error {
+	b, err := json.MarshalIndent(tf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode the trusted project list as JSON: %w", err)
+	}
+	if err := writeFileAtomic(s.path, append(b, '\n'), 0o600, 0o700); err != nil {
+		return fmt.Errorf("write the trusted project list: %w", err)
+	}
+	return nil
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
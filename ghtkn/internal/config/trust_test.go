@@ -0,0 +1,64 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+func TestTrustStore(t *testing.T) {
+	t.Parallel()
+	dataHome := t.TempDir()
+	project := t.TempDir()
+	content := []byte("apps: []\n")
+	if err := os.WriteFile(filepath.Join(project, ".ghtkn.yaml"), content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := config.NewTrustStore(func(k string) string {
+		if k == "XDG_DATA_HOME" {
+			return dataHome
+		}
+		return ""
+	}, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTrusted := func(t *testing.T, b []byte, want bool) {
+		t.Helper()
+		got, err := store.IsTrusted(project, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("IsTrusted() = %v, want %v", got, want)
+		}
+	}
+
+	assertTrusted(t, content, false)
+	if err := store.Trust(project); err != nil {
+		t.Fatal(err)
+	}
+	assertTrusted(t, content, true)
+	// Only the content that was trusted is trusted.
+	assertTrusted(t, []byte("apps: [changed]\n"), false)
+
+	fi, err := os.Stat(filepath.Join(dataHome, "ghtkn", "trusted_projects.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o600 {
+		t.Errorf("the trust store's permission = %o, want 600", perm)
+	}
+
+	if err := store.Untrust(project); err != nil {
+		t.Fatal(err)
+	}
+	assertTrusted(t, content, false)
+	// Untrusting an untrusted file is a no-op.
+	if err := store.Untrust(project); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+const (
+	// defaultPollInterval is how often the config files are checked for changes when
+	// they can't be watched through the OS.
+	defaultPollInterval = 2 * time.Second
+	// settleDelay is how long a change waits for the ones that follow it, since an
+	// editor or a package manager writes a file in several steps.
+	settleDelay = 100 * time.Millisecond
+)
+
+// InputWatch configures NewWatcher.
+type InputWatch struct {
+	// Load reads, merges, and validates the config files, folds the environment
+	// overrides in, and returns the config with the files to watch: the config files,
+	// and the other files the config depends on, such as the trust store of project
+	// config files, which Files doesn't report. A file that doesn't exist yet can be
+	// watched, so creating it is noticed.
+	Load func() (cfg *pubconfig.Config, files, deps []string, err error)
+	// PollInterval is how often the files are checked when they are polled. Zero means
+	// defaultPollInterval.
+	PollInterval time.Duration
+	// Poll polls the files even where they can be watched through the OS.
+	Poll   bool
+	Logger *slog.Logger
+}
+
+// Watcher keeps a parsed and validated config in memory and reloads it when its files
+// change, so a long-running process neither parses the files on every call nor misses
+// an edit. The files are watched with inotify on Linux and polled elsewhere, or when
+// inotify is unavailable.
+type Watcher struct {
+	input *InputWatch
+	mu    sync.RWMutex
+	cfg   *pubconfig.Config
+	files []string
+	// watched are the files whose change triggers a reload: the files and the
+	// dependencies Load returned.
+	watched []string
+	subs    map[int]func(*pubconfig.Reload)
+	nextSub int
+}
+
+// NewWatcher loads the config and returns a Watcher of it. It fails when the config
+// can't be loaded, since there is no last good config to keep yet. Call Run to watch.
+func NewWatcher(input *InputWatch) (*Watcher, error) {
+	if input.PollInterval <= 0 {
+		input.PollInterval = defaultPollInterval
+	}
+	if input.Logger == nil {
+		input.Logger = slog.New(slog.DiscardHandler)
+	}
+	w := &Watcher{input: input, subs: map[int]func(*pubconfig.Reload){}}
+	cfg, files, deps, err := input.Load()
+	if err != nil {
+		return nil, err
+	}
+	w.set(cfg, files, deps)
+	return w, nil
+}
+
+// Config returns the config in effect. It is shared, so it must not be modified.
+func (w *Watcher) Config() *pubconfig.Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cfg
+}
+
+// Files returns the config files that exist among the watched files.
+func (w *Watcher) Files() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.files
+}
+
+// Subscribe registers f to be called after each reload, and returns the function that
+// unregisters it. f is called from the goroutine running Run, so it should return
+// quickly.
+func (w *Watcher) Subscribe(f func(*pubconfig.Reload)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.nextSub
+	w.nextSub++
+	w.subs[id] = f
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subs, id)
+	}
+}
+
+// Reload loads the config again. When it loads, it replaces the config at once, so a
+// concurrent reader sees either the old or the new config as a whole; otherwise the
+// config is kept and the error returned. The subscribers are notified either way.
+func (w *Watcher) Reload() error {
+	cfg, files, deps, err := w.input.Load()
+	if err == nil {
+		w.set(cfg, files, deps)
+	}
+	w.mu.RLock()
+	reload := &pubconfig.Reload{Config: w.cfg, Files: w.files, Err: err}
+	subs := make([]func(*pubconfig.Reload), 0, len(w.subs))
+	for _, id := range slices.Sorted(maps.Keys(w.subs)) {
+		subs = append(subs, w.subs[id])
+	}
+	w.mu.RUnlock()
+	for _, f := range subs {
+		f(reload)
+	}
+	return err
+}
+
+func (w *Watcher) set(cfg *pubconfig.Config, files, deps []string) {
+	var existing []string
+	files = cleanPaths(files)
+	for _, p := range files {
+		if _, err := os.Stat(p); err == nil {
+			existing = append(existing, p)
+		}
+	}
+	watched := cleanPaths(append(slices.Clone(files), deps...))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cfg, w.watched, w.files = cfg, watched, existing
+}
+
+func (w *Watcher) watchedFiles() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.watched
+}
+
+// Run watches the files and reloads the config when they change until ctx is canceled.
+// It watches them through the OS where it can and polls them otherwise.
+func (w *Watcher) Run(ctx context.Context) {
+	if !w.input.Poll {
+		err := w.runNotifier(ctx)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, errNotifierUnsupported) {
+			w.input.Logger.Debug("the config files are polled", "reason", err.Error())
+		} else {
+			slogerr.WithError(w.input.Logger, err).Warn("failed to watch the config files, so they are polled")
+		}
+	}
+	w.runPoller(ctx)
+}
+
+// notifier reports changes of files through the OS.
+type notifier interface {
+	// watch replaces the watched files with paths. A file that doesn't exist is watched
+	// through the nearest directory that does, so creating it is noticed.
+	watch(paths []string) error
+	// events receives a value when a watched file may have changed.
+	events() <-chan struct{}
+	// errors receives an error when the notifier stops working.
+	errors() <-chan error
+	close() error
+}
+
+// errNotifierUnsupported is returned by newNotifier where files can't be watched
+// through the OS.
+var errNotifierUnsupported = errors.New("watching files through the OS is unsupported on this platform")
+
+func (w *Watcher) runNotifier(ctx context.Context) error {
+	n, err := newNotifier()
+	if err != nil {
+		return err
+	}
+	defer n.close()
+	if err := n.watch(w.watchedFiles()); err != nil {
+		return err
+	}
+	// Changes are reloaded once they settle.
+	timer := time.NewTimer(settleDelay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-n.errors():
+			return err
+		case <-n.events():
+			timer.Reset(settleDelay)
+		case <-timer.C:
+			w.reload()
+			// The files to watch, such as project config files, may have changed.
+			if err := n.watch(w.watchedFiles()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (w *Watcher) runPoller(ctx context.Context) {
+	stats := statFiles(w.watchedFiles())
+	ticker := time.NewTicker(w.input.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := statFiles(w.watchedFiles())
+			if slices.Equal(current, stats) {
+				continue
+			}
+			w.reload()
+			stats = statFiles(w.watchedFiles())
+		}
+	}
+}
+
+// reload is Reload for Run, which logs the error instead of returning it.
+func (w *Watcher) reload() {
+	if err := w.Reload(); err != nil {
+		slogerr.WithError(w.input.Logger, err).Warn("failed to reload the config files, so the last good config is kept")
+		return
+	}
+	w.input.Logger.Debug("reloaded the config files")
+}
+
+// fileStat is what the poller compares to tell whether a file changed.
+type fileStat struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statFiles(paths []string) []fileStat {
+	stats := make([]fileStat, len(paths))
+	for i, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		stats[i] = fileStat{exists: true, size: fi.Size(), modTime: fi.ModTime()}
+	}
+	return stats
+}
+
+// cleanPaths returns paths made absolute, without empty and duplicate ones, in order.
+func cleanPaths(paths []string) []string {
+	var cleaned []string
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		if !slices.Contains(cleaned, p) {
+			cleaned = append(cleaned, p)
+		}
+	}
+	return cleaned
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask is the events of a watched directory that can change a file in it. A
+// file is watched through its directory because an atomic write replaces the file,
+// which would end a watch of the file itself.
+const inotifyMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_ATTRIB |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// inotify is the notifier of Linux.
+type inotify struct {
+	fd   int
+	file *os.File
+	ch   chan struct{}
+	errs chan error
+	mu   sync.Mutex
+	// dirs maps each watch descriptor to its directory.
+	dirs    map[int]string
+	targets []string
+}
+
+func newNotifier() (notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("initialize inotify: %w", err)
+	}
+	// A non-blocking file is read through the runtime poller, so close unblocks read.
+	n := &inotify{
+		fd:   fd,
+		file: os.NewFile(uintptr(fd), "inotify"),
+		ch:   make(chan struct{}, 1),
+		errs: make(chan error, 1),
+		dirs: map[int]string{},
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) watch(paths []string) error {
+	var dirs []string
+	for _, p := range paths {
+		if dir := existingDir(filepath.Dir(p)); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.targets = paths
+	for wd, dir := range n.dirs {
+		if !slices.Contains(dirs, dir) {
+			delete(n.dirs, wd)
+			_, _ = unix.InotifyRmWatch(n.fd, uint32(wd)) //nolint:gosec // a watch descriptor is non-negative
+		}
+	}
+	for _, dir := range dirs {
+		wd, err := unix.InotifyAddWatch(n.fd, dir, inotifyMask)
+		if err != nil {
+			return fmt.Errorf("watch the directory of a config file: %w", slogerr.With(err, "dir", dir))
+		}
+		n.dirs[wd] = dir
+	}
+	return nil
+}
+
+func (n *inotify) events() <-chan struct{} {
+	return n.ch
+}
+
+func (n *inotify) errors() <-chan error {
+	return n.errs
+}
+
+func (n *inotify) close() error {
+	return n.file.Close() //nolint:wrapcheck
+}
+
+func (n *inotify) read() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		k, err := n.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				n.errs <- fmt.Errorf("read inotify events: %w", err)
+			}
+			return
+		}
+		if n.changed(buf[:k]) {
+			select {
+			case n.ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// changed reports whether the events in b can have changed a watched file: an event
+// of the file, or of a directory on its path that doesn't exist yet, or the loss of
+// events or of a watch.
+func (n *inotify) changed(b []byte) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	changed := false
+	for off := 0; off+unix.SizeofInotifyEvent <= len(b); {
+		wd := int(int32(binary.NativeEndian.Uint32(b[off:]))) //nolint:gosec // the field is an int32
+		mask := binary.NativeEndian.Uint32(b[off+4:])
+		nameLen := int(binary.NativeEndian.Uint32(b[off+12:]))
+		name := strings.TrimRight(string(b[off+unix.SizeofInotifyEvent:off+unix.SizeofInotifyEvent+nameLen]), "\x00")
+		off += unix.SizeofInotifyEvent + nameLen
+		dir, ok := n.dirs[wd]
+		if mask&unix.IN_Q_OVERFLOW != 0 || (ok && mask&(unix.IN_IGNORED|unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0) {
+			changed = true
+			continue
+		}
+		if !ok || name == "" {
+			continue
+		}
+		p := filepath.Join(dir, name)
+		for _, target := range n.targets {
+			if target == p || strings.HasPrefix(target, p+string(filepath.Separator)) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// existingDir returns dir, or its nearest ancestor that exists.
+func existingDir(dir string) string {
+	for {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package config
+
+func newNotifier() (notifier, error) {
+	return nil, errNotifierUnsupported
+}
@@ -0,0 +1,87 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pubconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+	for _, poll := range []bool{false, true} {
+		t.Run(map[bool]string{false: "notify", true: "poll"}[poll], func(t *testing.T) {
+			t.Parallel()
+			// The user config file is in a directory created after the watch starts.
+			path := filepath.Join(t.TempDir(), "ghtkn", "ghtkn.yaml")
+			load := func() (*pubconfig.Config, []string, []string, error) {
+				cfg := &pubconfig.Config{}
+				if _, err := config.Load(cfg, &config.InputLoad{
+					Reader:   config.NewReader(os.Getenv),
+					Getenv:   func(string) string { return "" },
+					GOOS:     "windows", // No system config file
+					UserPath: path,
+				}); err != nil {
+					return &pubconfig.Config{}, []string{path}, nil, nil //nolint:nilerr // a missing file is an empty config
+				}
+				if err := cfg.Validate(); err != nil {
+					return nil, nil, nil, err //nolint:wrapcheck
+				}
+				return cfg, []string{path}, nil, nil
+			}
+			w, err := config.NewWatcher(&config.InputWatch{Load: load, Poll: poll, PollInterval: 10 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			reloads := make(chan *pubconfig.Reload, 10)
+			unsubscribe := w.Subscribe(func(r *pubconfig.Reload) { reloads <- r })
+			defer unsubscribe()
+			go w.Run(t.Context())
+			// Let the watch start before the files change.
+			time.Sleep(50 * time.Millisecond)
+
+			// write writes content and returns the first reload done accepts, since a write
+			// may be noticed as several changes.
+			write := func(content string, done func(*pubconfig.Reload) bool) *pubconfig.Reload {
+				t.Helper()
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				for {
+					select {
+					case r := <-reloads:
+						if done(r) {
+							return r
+						}
+					case <-time.After(5 * time.Second):
+						t.Fatal("the config wasn't reloaded")
+					}
+				}
+			}
+
+			r := write("apps:\n  - name: a\n    client_id: Iv1.a\n", func(r *pubconfig.Reload) bool {
+				return r.Err != nil || len(r.Config.Apps) > 0
+			})
+			if r.Err != nil || r.Config.Apps[0].Name != "a" || w.Config() != r.Config {
+				t.Fatalf("reload = %+v, want the app a", r)
+			}
+			// Make the modification time differ for the poller.
+			time.Sleep(20 * time.Millisecond)
+			r = write("apps:\n  - name: b\n", func(r *pubconfig.Reload) bool {
+				return r.Err != nil
+			})
+			if r.Err == nil {
+				t.Fatal("reload of an invalid config must fail")
+			}
+			if name := w.Config().Apps[0].Name; name != "a" || r.Config != w.Config() {
+				t.Errorf("the last good config must be kept, got the app %q", name)
+			}
+		})
+	}
+}
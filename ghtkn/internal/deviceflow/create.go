@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"log/slog"
 	"time"
+
+	"github.com/suzuki-shunsuke/go-github-device-flow/deviceflow"
 )
 
 // InputCreate holds the parameters for Create.
 type InputCreate struct {
 	// ClientID is the GitHub App's client ID used to start the device flow. Required.
 	ClientID string
+	// Host is the GitHub host the app is registered on. Empty means github.com.
+	Host string
 	// AppName is the GitHub App name shown in the one-time code prompt. Optional;
 	// when empty, the App Name line is omitted from the message.
 	AppName string
@@ -36,7 +40,7 @@ func (c *Client) Create(ctx context.Context, logger *slog.Logger, input *InputCr
 	if input.ClientID == "" {
 		return nil, errors.New("client id is required")
 	}
-	deviceCode, err := c.input.Client.GetDeviceCode(ctx, input.ClientID)
+	deviceCode, err := c.input.Client.GetDeviceCode(ctx, input.Host, input.ClientID)
 	if err != nil {
 		return nil, fmt.Errorf("get device code: %w", err)
 	}
@@ -45,24 +49,36 @@ func (c *Client) Create(ctx context.Context, logger *slog.Logger, input *InputCr
 		return nil, err
 	}
 
-	token, err := c.input.Client.Poll(ctx, logger, input.ClientID, deviceCode)
+	token, err := c.input.Client.Poll(ctx, logger, input.Host, input.ClientID, deviceCode)
 	if err != nil {
 		return nil, fmt.Errorf("get access token: %w", err)
 	}
 
+	return newAccessToken(token), nil
+}
+
+// newAccessToken converts the library's token response into an AccessToken, turning
+// the relative expires_in values into absolute times. GitHub omits the refresh token
+// (and its expires_in) for a GitHub App with user-token expiration disabled, so both
+// refresh fields are left empty then.
+func newAccessToken(token *deviceflow.AccessToken) *AccessToken {
+	now := time.Now()
 	return &AccessToken{
-		AccessToken:    token.AccessToken,
-		ExpirationDate: expirationDate(token.ExpiresIn),
-	}, nil
+		AccessToken:                token.AccessToken,
+		IssuedAt:                   now,
+		ExpirationDate:             expirationDate(now, token.ExpiresIn),
+		RefreshToken:               token.RefreshToken,
+		RefreshTokenExpirationDate: expirationDate(now, token.RefreshTokenExpiresIn),
+	}
 }
 
 // expirationDate turns GitHub's expires_in (seconds from now) into an absolute time.
 // A GitHub App with user-token expiration disabled returns expires_in=0; that token
 // never expires, so it is represented as the zero time rather than "now", which the
 // expiry checks treat as never-expiring instead of already-expired.
-func expirationDate(expiresIn int) time.Time {
+func expirationDate(now time.Time, expiresIn int) time.Time {
 	if expiresIn == 0 {
 		return time.Time{}
 	}
-	return time.Now().Add(time.Duration(expiresIn) * time.Second)
+	return now.Add(time.Duration(expiresIn) * time.Second)
 }
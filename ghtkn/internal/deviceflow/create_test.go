@@ -24,18 +24,32 @@ type mockDeviceFlow struct {
 	token      *deviceflow.AccessToken
 	getErr     error
 	pollErr    error
+	refreshErr error
+	// gotRefreshToken is the refresh token RefreshToken was called with.
+	gotRefreshToken string
+	// gotHost is the host the last method was called with.
+	gotHost string
 }
 
-func (m *mockDeviceFlow) GetDeviceCode(_ context.Context, _ string) (*pubdeviceflow.DeviceCodeResponse, error) {
+func (m *mockDeviceFlow) GetDeviceCode(_ context.Context, host, _ string) (*pubdeviceflow.DeviceCodeResponse, error) {
 	*m.calls = append(*m.calls, "GetDeviceCode")
+	m.gotHost = host
 	return m.deviceCode, m.getErr
 }
 
-func (m *mockDeviceFlow) Poll(_ context.Context, _ *slog.Logger, _ string, _ *pubdeviceflow.DeviceCodeResponse) (*deviceflow.AccessToken, error) {
+func (m *mockDeviceFlow) Poll(_ context.Context, _ *slog.Logger, host, _ string, _ *pubdeviceflow.DeviceCodeResponse) (*deviceflow.AccessToken, error) {
 	*m.calls = append(*m.calls, "Poll")
+	m.gotHost = host
 	return m.token, m.pollErr
 }
 
+func (m *mockDeviceFlow) RefreshToken(_ context.Context, host, _, refreshToken string) (*deviceflow.AccessToken, error) {
+	*m.calls = append(*m.calls, "RefreshToken")
+	m.gotHost = host
+	m.gotRefreshToken = refreshToken
+	return m.token, m.refreshErr
+}
+
 // mockOnetimeCodeUI is a fake OnetimeCodeUI (the package-local interface) that
 // records what Show received and the order of the call.
 type mockOnetimeCodeUI struct {
@@ -105,7 +119,7 @@ func TestClient_Create_coordination(t *testing.T) {
 		df := &mockDeviceFlow{
 			calls:      &calls,
 			deviceCode: deviceCode,
-			token:      &deviceflow.AccessToken{AccessToken: "gho_testtoken123", ExpiresIn: 28800},
+			token:      &deviceflow.AccessToken{AccessToken: "gho_testtoken123", ExpiresIn: 28800, RefreshToken: "ghr_testtoken123", RefreshTokenExpiresIn: 15811200},
 		}
 		onetime := &mockOnetimeCodeUI{calls: &calls}
 		fixedTime := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
@@ -134,10 +148,20 @@ func TestClient_Create_coordination(t *testing.T) {
 		if tk.AccessToken != "gho_testtoken123" {
 			t.Errorf("AccessToken = %q, want gho_testtoken123", tk.AccessToken)
 		}
+		if !tk.IssuedAt.Equal(fixedTime) {
+			t.Errorf("IssuedAt = %v, want %v", tk.IssuedAt, fixedTime)
+		}
 		wantExpiration := fixedTime.Add(28800 * time.Second)
 		if !tk.ExpirationDate.Equal(wantExpiration) {
 			t.Errorf("ExpirationDate = %v, want %v", tk.ExpirationDate, wantExpiration)
 		}
+		if tk.RefreshToken != "ghr_testtoken123" {
+			t.Errorf("RefreshToken = %q, want %q", tk.RefreshToken, "ghr_testtoken123")
+		}
+		wantRefreshExpiration := fixedTime.Add(15811200 * time.Second)
+		if !tk.RefreshTokenExpirationDate.Equal(wantRefreshExpiration) {
+			t.Errorf("RefreshTokenExpirationDate = %v, want %v", tk.RefreshTokenExpirationDate, wantRefreshExpiration)
+		}
 
 		wantInput := &ui.InputCreate{
 			ClientID:          "test-client-id",
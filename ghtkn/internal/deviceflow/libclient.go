@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	pubdeviceflow "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/deviceflow"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/github"
 	"github.com/suzuki-shunsuke/go-github-device-flow/deviceflow"
 	"github.com/suzuki-shunsuke/slog-error/slogerr"
 )
@@ -15,7 +16,7 @@ import (
 // contract types so the library stays out of the public API, and enriches errors
 // with the HTTP status code and body that the library returns for inspection.
 type libDeviceFlow struct {
-	client *deviceflow.Client
+	httpClient *http.Client
 }
 
 // newLibDeviceFlow builds a libDeviceFlow whose HTTP client, clock, and ticker
@@ -23,14 +24,21 @@ type libDeviceFlow struct {
 // before the library extraction.
 func newLibDeviceFlow(httpClient *http.Client) *libDeviceFlow {
 	return &libDeviceFlow{
-		client: deviceflow.New(&deviceflow.Input{
-			HTTPClient: httpClient,
-		}),
+		httpClient: httpClient,
 	}
 }
 
-func (l *libDeviceFlow) GetDeviceCode(ctx context.Context, clientID string) (*pubdeviceflow.DeviceCodeResponse, error) {
-	deviceCode, resp, body, err := l.client.GetDeviceCode(ctx, clientID)
+// client returns the library client for host. The library only knows github.com, so
+// for another host its requests are redirected by the HTTP client (see
+// github.HTTPClient).
+func (l *libDeviceFlow) client(host string) *deviceflow.Client {
+	return deviceflow.New(&deviceflow.Input{
+		HTTPClient: github.HTTPClient(l.httpClient, host),
+	})
+}
+
+func (l *libDeviceFlow) GetDeviceCode(ctx context.Context, host, clientID string) (*pubdeviceflow.DeviceCodeResponse, error) {
+	deviceCode, resp, body, err := l.client(host).GetDeviceCode(ctx, clientID)
 	if err != nil {
 		if resp != nil {
 			return nil, slogerr.With(err, //nolint:wrapcheck
@@ -48,8 +56,8 @@ func (l *libDeviceFlow) GetDeviceCode(ctx context.Context, clientID string) (*pu
 	}, nil
 }
 
-func (l *libDeviceFlow) Poll(ctx context.Context, logger *slog.Logger, clientID string, deviceCode *pubdeviceflow.DeviceCodeResponse) (*deviceflow.AccessToken, error) {
-	return l.client.Poll(ctx, logger, clientID, &deviceflow.DeviceCodeResponse{ //nolint:wrapcheck
+func (l *libDeviceFlow) Poll(ctx context.Context, logger *slog.Logger, host, clientID string, deviceCode *pubdeviceflow.DeviceCodeResponse) (*deviceflow.AccessToken, error) {
+	return l.client(host).Poll(ctx, logger, clientID, &deviceflow.DeviceCodeResponse{ //nolint:wrapcheck
 		DeviceCode:      deviceCode.DeviceCode,
 		UserCode:        deviceCode.UserCode,
 		VerificationURI: deviceCode.VerificationURI,
@@ -57,3 +65,16 @@ func (l *libDeviceFlow) Poll(ctx context.Context, logger *slog.Logger, clientID
 		Interval:        deviceCode.Interval,
 	}, nil)
 }
+
+func (l *libDeviceFlow) RefreshToken(ctx context.Context, host, clientID, refreshToken string) (*deviceflow.AccessToken, error) {
+	token, resp, body, err := l.client(host).RefreshToken(ctx, clientID, refreshToken)
+	if err != nil {
+		if resp != nil {
+			return nil, slogerr.With(err, //nolint:wrapcheck
+				"status_code", resp.StatusCode,
+				"body", string(body))
+		}
+		return nil, err //nolint:wrapcheck
+	}
+	return token, nil
+}
@@ -0,0 +1,38 @@
+package deviceflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// InputRefresh holds the parameters for Refresh.
+type InputRefresh struct {
+	// ClientID is the GitHub App's client ID the refresh token was issued to. Required.
+	ClientID string
+	// Host is the GitHub host the app is registered on. Empty means github.com.
+	Host string
+	// RefreshToken is the stored refresh token to exchange. Required.
+	RefreshToken string
+}
+
+// Refresh exchanges a refresh token for a new access token with the
+// grant_type=refresh_token request. It is not interactive: nothing is displayed and
+// no user action is needed, so unlike Create it may run on behalf of Get.
+//
+// GitHub rotates the refresh token on every exchange and invalidates the one that was
+// sent, so the caller must persist the returned token, including its new refresh
+// token, or the next refresh fails.
+func (c *Client) Refresh(ctx context.Context, input *InputRefresh) (*AccessToken, error) {
+	if input.ClientID == "" {
+		return nil, errors.New("client id is required")
+	}
+	if input.RefreshToken == "" {
+		return nil, errors.New("refresh token is required")
+	}
+	token, err := c.input.Client.RefreshToken(ctx, input.Host, input.ClientID, input.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("refresh an access token: %w", err)
+	}
+	return newAccessToken(token), nil
+}
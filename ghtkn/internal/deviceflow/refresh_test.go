@@ -0,0 +1,115 @@
+package deviceflow_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	intdeviceflow "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/deviceflow"
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/log"
+	"github.com/suzuki-shunsuke/go-github-device-flow/deviceflow"
+)
+
+// TestClient_Refresh verifies Refresh exchanges the refresh token without showing a
+// one-time code and returns the rotated refresh token with absolute expiration dates.
+func TestClient_Refresh(t *testing.T) {
+	t.Parallel()
+
+	synctest.Test(t, func(t *testing.T) {
+		var calls []string
+		df := &mockDeviceFlow{
+			calls: &calls,
+			token: &deviceflow.AccessToken{
+				AccessToken:           "ghu_new",
+				ExpiresIn:             28800,
+				RefreshToken:          "ghr_new",
+				RefreshTokenExpiresIn: 15811200,
+			},
+		}
+		onetime := &mockOnetimeCodeUI{calls: &calls}
+		input := &intdeviceflow.Input{
+			Stderr:        io.Discard,
+			Logger:        log.NewLogger(),
+			OnetimeCodeUI: onetime,
+			Client:        df,
+		}
+		fixedTime := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		tk, err := intdeviceflow.NewClient(input).Refresh(t.Context(), &intdeviceflow.InputRefresh{
+			ClientID:     "test-client-id",
+			Host:         "ghes.example.com",
+			RefreshToken: "ghr_old",
+		})
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		if df.gotHost != "ghes.example.com" {
+			t.Errorf("RefreshToken was called with the host %q, want %q", df.gotHost, "ghes.example.com")
+		}
+		if diff := cmp.Diff([]string{"RefreshToken"}, calls); diff != "" {
+			t.Errorf("call mismatch (-want +got):\n%s", diff)
+		}
+		if df.gotRefreshToken != "ghr_old" {
+			t.Errorf("RefreshToken was called with %q, want %q", df.gotRefreshToken, "ghr_old")
+		}
+		want := &intdeviceflow.AccessToken{
+			AccessToken:                "ghu_new",
+			IssuedAt:                   fixedTime,
+			ExpirationDate:             fixedTime.Add(28800 * time.Second),
+			RefreshToken:               "ghr_new",
+			RefreshTokenExpirationDate: fixedTime.Add(15811200 * time.Second),
+		}
+		if diff := cmp.Diff(want, tk); diff != "" {
+			t.Errorf("Refresh() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// TestClient_Refresh_error verifies Refresh rejects missing input before contacting
+// GitHub and propagates a failed exchange.
+func TestClient_Refresh_error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		input     *intdeviceflow.InputRefresh
+		err       error
+		wantCalls []string
+	}{
+		{
+			name:  "empty client id",
+			input: &intdeviceflow.InputRefresh{RefreshToken: "ghr_old"},
+		},
+		{
+			name:  "empty refresh token",
+			input: &intdeviceflow.InputRefresh{ClientID: "test-client-id"},
+		},
+		{
+			name:      "exchange fails",
+			input:     &intdeviceflow.InputRefresh{ClientID: "test-client-id", RefreshToken: "ghr_old"},
+			err:       errors.New("bad_refresh_token"),
+			wantCalls: []string{"RefreshToken"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls []string
+			df := &mockDeviceFlow{calls: &calls, refreshErr: tt.err}
+			input := &intdeviceflow.Input{
+				Stderr: io.Discard,
+				Logger: log.NewLogger(),
+				Client: df,
+			}
+			if _, err := intdeviceflow.NewClient(input).Refresh(t.Context(), tt.input); err == nil {
+				t.Fatal("Refresh() expected an error, got nil")
+			}
+			if diff := cmp.Diff(tt.wantCalls, calls); diff != "" {
+				t.Errorf("call mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -38,10 +38,12 @@ type OnetimeCodeUI interface {
 // DeviceFlow talks to GitHub's device flow endpoints. GetDeviceCode returns the
 // SDK's own DeviceCodeResponse because it flows out to OnetimeCodeUI in the public
 // API; the access token stays internal, so Poll returns the library type directly.
+// Each method takes the GitHub host the app is registered on.
 // The production implementation is libDeviceFlow; tests inject a fake.
 type DeviceFlow interface {
-	GetDeviceCode(ctx context.Context, clientID string) (*pubdeviceflow.DeviceCodeResponse, error)
-	Poll(ctx context.Context, logger *slog.Logger, clientID string, deviceCode *pubdeviceflow.DeviceCodeResponse) (*deviceflow.AccessToken, error)
+	GetDeviceCode(ctx context.Context, host, clientID string) (*pubdeviceflow.DeviceCodeResponse, error)
+	Poll(ctx context.Context, logger *slog.Logger, host, clientID string, deviceCode *pubdeviceflow.DeviceCodeResponse) (*deviceflow.AccessToken, error)
+	RefreshToken(ctx context.Context, host, clientID, refreshToken string) (*deviceflow.AccessToken, error)
 }
 
 // NewInput creates a new Input instance with default dependencies.
@@ -56,9 +58,14 @@ func NewInput() *Input {
 }
 
 // AccessToken represents a GitHub App access token with its metadata.
-// It includes the token value, associated app, and expiration date.
+// It includes the token value, associated app, when it was issued and expires, plus
+// the refresh token GitHub issues alongside an expiring token and that refresh token's
+// own expiry.
 type AccessToken struct {
-	App            string    `json:"app"`
-	AccessToken    string    `json:"access_token"`
-	ExpirationDate time.Time `json:"expiration_date"`
+	App                        string    `json:"app"`
+	AccessToken                string    `json:"access_token"`
+	IssuedAt                   time.Time `json:"issued_at"`
+	ExpirationDate             time.Time `json:"expiration_date"`
+	RefreshToken               string    `json:"refresh_token,omitempty"`
+	RefreshTokenExpirationDate time.Time `json:"refresh_token_expiration_date,omitzero"`
 }
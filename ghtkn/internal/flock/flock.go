@@ -0,0 +1,53 @@
+// Package flock provides advisory file locks, which serialize acquiring a token for an
+// app across processes, so that processes finding the same expired token at the same
+// time don't all renew it.
+package flock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often a lock held by another process is tried again.
+const pollInterval = 50 * time.Millisecond
+
+// errLocked is returned by tryLock when another process holds the lock.
+var errLocked = errors.New("the file is locked by another process")
+
+// Lock takes the exclusive lock of the file at path, creating the file and its
+// directory, and waits for another process holding it to release it until ctx is
+// done. The returned function releases the lock. The lock is released by the OS when
+// the process exits, so a crashed process never leaves it held. On a platform
+// without file locks it only creates the file.
+func Lock(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create the lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open the lock file: %w", err)
+	}
+	for {
+		err := tryLock(f)
+		if err == nil {
+			return func() {
+				_ = unlock(f)
+				_ = f.Close()
+			}, nil
+		}
+		if !errors.Is(err, errLocked) {
+			_ = f.Close()
+			return nil, fmt.Errorf("lock the lock file: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, fmt.Errorf("wait for the lock held by another process: %w", ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
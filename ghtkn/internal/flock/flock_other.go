@@ -0,0 +1,9 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package flock
+
+import "os"
+
+func tryLock(*os.File) error { return nil }
+
+func unlock(*os.File) error { return nil }
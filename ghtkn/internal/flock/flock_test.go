@@ -0,0 +1,33 @@
+package flock_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/flock"
+)
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "locks", "Iv1.x.lock")
+	unlock, err := flock.Lock(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	// The lock is exclusive: another holder waits until its context is done.
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := flock.Lock(ctx, path); err == nil {
+		t.Fatal("Lock() of a held lock must wait until the context is done")
+	}
+
+	unlock()
+	unlock2, err := flock.Lock(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Lock() after the release error = %v", err)
+	}
+	unlock2()
+}
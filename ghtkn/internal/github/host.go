@@ -0,0 +1,61 @@
+package github
+
+import (
+	"net/http"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/api"
+)
+
+// APIBaseURL returns the REST API base URL of host: https://api.github.com for
+// github.com, and https://<host>/api/v3 for a GitHub Enterprise Server.
+func APIBaseURL(host string) string {
+	if host == "" || host == api.DefaultHost {
+		return defaultBaseURL
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// HTTPClient returns an HTTP client that sends to host the requests made for
+// github.com and api.github.com, so that the libraries ghtkn uses for the device flow
+// and the credential revocation, which only know github.com, work with a GitHub
+// Enterprise Server. For github.com it returns httpClient as is. When httpClient is
+// nil, http.DefaultClient is used.
+func HTTPClient(httpClient *http.Client, host string) *http.Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if host == "" || host == api.DefaultHost {
+		return httpClient
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c := *httpClient
+	c.Transport = &hostTransport{base: base, host: host}
+	return &c
+}
+
+// hostTransport rewrites the URLs of requests for github.com and api.github.com to a
+// GitHub Enterprise Server, where the web endpoints (such as /login/device/code) are at
+// https://<host> and the REST API is at https://<host>/api/v3.
+type hostTransport struct {
+	base http.RoundTripper
+	host string
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.URL.Host {
+	case api.DefaultHost:
+		req = req.Clone(req.Context())
+		req.URL.Host = t.host
+		req.Host = ""
+	case "api.github.com":
+		req = req.Clone(req.Context())
+		req.URL.Host = t.host
+		req.URL.Path = "/api/v3" + req.URL.Path
+		req.URL.RawPath = ""
+		req.Host = ""
+	}
+	return t.base.RoundTrip(req) //nolint:wrapcheck
+}
@@ -0,0 +1,99 @@
+package github
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIBaseURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "", want: "https://api.github.com"},
+		{host: "github.com", want: "https://api.github.com"},
+		{host: "ghes.example.com", want: "https://ghes.example.com/api/v3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			t.Parallel()
+
+			if got := APIBaseURL(tt.host); got != tt.want {
+				t.Errorf("APIBaseURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordURL is an http.RoundTripper that records the URL of the request it receives.
+type recordURL struct {
+	url string
+}
+
+func (r *recordURL) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.url = req.URL.String()
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		host string
+		url  string
+		want string
+	}{
+		{
+			name: "github.com is not rewritten",
+			host: "github.com",
+			url:  "https://github.com/login/device/code",
+			want: "https://github.com/login/device/code",
+		},
+		{
+			name: "the web endpoint is sent to the host",
+			host: "ghes.example.com",
+			url:  "https://github.com/login/oauth/access_token",
+			want: "https://ghes.example.com/login/oauth/access_token",
+		},
+		{
+			name: "the API is sent to /api/v3 of the host",
+			host: "ghes.example.com",
+			url:  "https://api.github.com/credentials/revoke",
+			want: "https://ghes.example.com/api/v3/credentials/revoke",
+		},
+		{
+			name: "another host is not rewritten",
+			host: "ghes.example.com",
+			url:  "https://example.com/foo",
+			want: "https://example.com/foo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &recordURL{}
+			c := HTTPClient(&http.Client{Transport: rec}, tt.host)
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if rec.url != tt.want {
+				t.Errorf("URL = %q, want %q", rec.url, tt.want)
+			}
+			if req.URL.String() != tt.url {
+				t.Errorf("the original request was modified: %q", req.URL.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package github
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/suzuki-shunsuke/go-revoke-github-access-token/revoke"
+)
+
+// Revoker revokes credentials with the credential revocation API of the host they
+// were issued by.
+type Revoker struct {
+	httpClient *http.Client
+}
+
+// NewRevoker creates a Revoker. When httpClient is nil, http.DefaultClient is used.
+func NewRevoker(httpClient *http.Client) *Revoker {
+	return &Revoker{httpClient: httpClient}
+}
+
+// Revoke revokes tokens, which must all have been issued by host.
+func (r *Revoker) Revoke(ctx context.Context, host string, tokens []string) error {
+	return revoke.New(HTTPClient(r.httpClient, host)).Revoke(ctx, tokens) //nolint:wrapcheck
+}
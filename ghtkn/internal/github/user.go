@@ -0,0 +1,84 @@
+// Package github calls the GitHub REST API endpoints ghtkn needs besides the OAuth
+// device flow and the credential revocation API, which have their own libraries.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/suzuki-shunsuke/slog-error/slogerr"
+)
+
+// defaultBaseURL is the REST API base URL of github.com.
+const defaultBaseURL = "https://api.github.com"
+
+// apiVersion is the X-GitHub-Api-Version sent with each request.
+const apiVersion = "2022-11-28"
+
+// Client calls the GitHub REST API.
+type Client struct {
+	httpClient *http.Client
+	// baseURL overrides the API base URL of the host (see APIBaseURL). Tests set it to
+	// a local server.
+	baseURL string
+}
+
+// New creates a Client. When httpClient is nil, http.DefaultClient is used.
+func New(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		httpClient: httpClient,
+	}
+}
+
+func (c *Client) apiBaseURL(host string) string {
+	if c.baseURL != "" {
+		return c.baseURL
+	}
+	return APIBaseURL(host)
+}
+
+// GetLogin returns the login of the user the access token for host authenticates as,
+// using the GET /user endpoint. A GitHub App user access token can always call it,
+// whatever permissions the app has.
+func (c *Client) GetLogin(ctx context.Context, host, accessToken string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiBaseURL(host)+"/user", nil)
+	if err != nil {
+		return "", fmt.Errorf("create a request to get the authenticated user: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("X-GitHub-Api-Version", apiVersion)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("send a request to get the authenticated user: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read the response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", slogerr.With(errors.New("get the authenticated user"), //nolint:wrapcheck
+			"status_code", resp.StatusCode,
+			"body", string(body))
+	}
+	user := &struct {
+		Login string `json:"login"`
+	}{}
+	if err := json.Unmarshal(body, user); err != nil {
+		return "", fmt.Errorf("unmarshal the response body as JSON: %w", err)
+	}
+	if user.Login == "" {
+		return "", errors.New("the response has no login")
+	}
+	return user.Login, nil
+}
@@ -0,0 +1,72 @@
+package github
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_GetLogin(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "login",
+			status: http.StatusOK,
+			body:   `{"login":"octocat","id":1}`,
+			want:   "octocat",
+		},
+		{
+			name:    "unauthorized",
+			status:  http.StatusUnauthorized,
+			body:    `{"message":"Bad credentials"}`,
+			wantErr: true,
+		},
+		{
+			name:    "no login",
+			status:  http.StatusOK,
+			body:    `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			status:  http.StatusOK,
+			body:    `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/user" {
+					t.Errorf("path = %s, want /user", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer ghu_token" {
+					t.Errorf("Authorization = %q, want %q", got, "Bearer ghu_token")
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(srv.Close)
+
+			c := New(srv.Client())
+			c.baseURL = srv.URL
+			got, err := c.GetLogin(t.Context(), "github.com", "ghu_token")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetLogin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetLogin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
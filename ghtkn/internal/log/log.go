@@ -5,6 +5,7 @@ package log
 import (
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"time"
 
@@ -37,6 +38,24 @@ func NewLogger() *publog.Logger {
 			// writes to stderr too, so also emitting a slog record would double it.
 			fmt.Fprintf(stderr, "WARNING: ghtkn agent: %s\n", message) //nolint:errcheck
 		},
+		FailedToRefreshAccessToken: func(logger *slog.Logger, err error) {
+			slogerr.WithError(logger, err).Warn("failed to refresh the access token with the stored refresh token")
+		},
+		IgnoredUntrustedProjectConfig: func(logger *slog.Logger, path string) {
+			logger.Warn("ignored the project config file because it isn't trusted. Review it and trust it to use it", "config", path)
+		},
+		SelectedBackend: func(logger *slog.Logger, backend string) {
+			logger.Debug("selected the backend", "backend", backend)
+		},
+		FellBackToPlaintextBackend: func(logger *slog.Logger, backend string) {
+			logger.Warn("access tokens are stored in plaintext because no other backend is available. Run the keyring or the ghtkn agent, or configure the file backend, to encrypt them", "backend", backend)
+		},
+		InsecureTokenFile: func(logger *slog.Logger, path string, mode fs.FileMode) {
+			logger.Warn("the token file can be read by other users. Restrict its permission to 0600, and revoke the token if it may have leaked", "path", path, "mode", mode.Perm().String())
+		},
+		InsecureTokenDir: func(logger *slog.Logger, path string, mode fs.FileMode) {
+			logger.Warn("the token directory can be accessed by other users. Restrict its permission to 0700", "path", path, "mode", mode.Perm().String())
+		},
 	}
 }
 
@@ -63,4 +82,22 @@ func InitLogger(l *publog.Logger) {
 	if l.AgentWarning == nil {
 		l.AgentWarning = defaultLogger.AgentWarning
 	}
+	if l.FailedToRefreshAccessToken == nil {
+		l.FailedToRefreshAccessToken = defaultLogger.FailedToRefreshAccessToken
+	}
+	if l.IgnoredUntrustedProjectConfig == nil {
+		l.IgnoredUntrustedProjectConfig = defaultLogger.IgnoredUntrustedProjectConfig
+	}
+	if l.SelectedBackend == nil {
+		l.SelectedBackend = defaultLogger.SelectedBackend
+	}
+	if l.FellBackToPlaintextBackend == nil {
+		l.FellBackToPlaintextBackend = defaultLogger.FellBackToPlaintextBackend
+	}
+	if l.InsecureTokenFile == nil {
+		l.InsecureTokenFile = defaultLogger.InsecureTokenFile
+	}
+	if l.InsecureTokenDir == nil {
+		l.InsecureTokenDir = defaultLogger.InsecureTokenDir
+	}
 }
@@ -29,6 +29,24 @@ func TestNewLogger(t *testing.T) {
 	if logger.AccessTokenIsNotFoundInBackend == nil {
 		t.Error("AccessTokenIsNotFoundInBackend function is nil")
 	}
+	if logger.FailedToRefreshAccessToken == nil {
+		t.Error("FailedToRefreshAccessToken function is nil")
+	}
+	if logger.IgnoredUntrustedProjectConfig == nil {
+		t.Error("IgnoredUntrustedProjectConfig function is nil")
+	}
+	if logger.SelectedBackend == nil {
+		t.Error("SelectedBackend function is nil")
+	}
+	if logger.FellBackToPlaintextBackend == nil {
+		t.Error("FellBackToPlaintextBackend function is nil")
+	}
+	if logger.InsecureTokenFile == nil {
+		t.Error("InsecureTokenFile function is nil")
+	}
+	if logger.InsecureTokenDir == nil {
+		t.Error("InsecureTokenDir function is nil")
+	}
 }
 
 func TestLogger_Expire(t *testing.T) {
@@ -105,3 +123,41 @@ func TestLogger_AccessTokenIsNotFoundInBackend(t *testing.T) {
 		t.Errorf("Expected log to contain 'access token is not found in backend', got: %s", output)
 	}
 }
+
+func TestLogger_FailedToRefreshAccessToken(t *testing.T) {
+	var buf bytes.Buffer
+	slogger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	logger := log.NewLogger()
+
+	logger.FailedToRefreshAccessToken(slogger, errors.New("bad_refresh_token"))
+
+	output := buf.String()
+	if !strings.Contains(output, "failed to refresh the access token") {
+		t.Errorf("Expected log to contain 'failed to refresh the access token', got: %s", output)
+	}
+	if !strings.Contains(output, "bad_refresh_token") {
+		t.Errorf("Expected log to contain error message, got: %s", output)
+	}
+}
+
+func TestLogger_IgnoredUntrustedProjectConfig(t *testing.T) {
+	var buf bytes.Buffer
+	slogger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	logger := log.NewLogger()
+
+	logger.IgnoredUntrustedProjectConfig(slogger, "/src/repo/.ghtkn.yaml")
+
+	output := buf.String()
+	if !strings.Contains(output, "isn't trusted") {
+		t.Errorf("Expected log to contain \"isn't trusted\", got: %s", output)
+	}
+	if !strings.Contains(output, "/src/repo/.ghtkn.yaml") {
+		t.Errorf("Expected log to contain the path, got: %s", output)
+	}
+}
@@ -0,0 +1,100 @@
+// Package profile resolves the profile ghtkn runs in. A profile namespaces the config
+// file, the keyring service, the directories of the text and file backends, and the
+// socket of the agent together, so that the tokens of one profile, such as a work
+// identity, are never read or revoked from another, such as a personal one.
+//
+// The profile is GHTKN_PROFILE. The default profile, when it is unset, keeps the paths
+// and the keyring service ghtkn has always used; the files of a profile are kept in
+// profiles/<name> under the directories of ghtkn, such as
+// $XDG_CONFIG_HOME/ghtkn/profiles/work/ghtkn.yaml. A path set explicitly with an
+// environment variable, such as GHTKN_CONFIG or GHTKN_TEXT_BACKEND_DIR, is used as is
+// in the default profile, and namespaced in the same way in a profile (see OverrideDir
+// and OverrideFile), so the variable set for every profile doesn't make them share
+// tokens.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/env"
+)
+
+// Name returns the profile GHTKN_PROFILE selects, "" for the default profile. It fails
+// when the name is invalid (see Validate).
+func Name(getEnv func(string) string) (string, error) {
+	name := getEnv(env.Profile)
+	if name == "" {
+		return "", nil
+	}
+	if err := Validate(name); err != nil {
+		return "", fmt.Errorf("%s is invalid: %w", env.Profile, err)
+	}
+	return name, nil
+}
+
+// Validate checks that name can be part of a path and of a keyring service: it must
+// consist of letters, digits, '.', '-', and '_', and not start with '.', so the files of
+// a profile can't be kept outside of the directories of ghtkn.
+func Validate(name string) error {
+	if name == "" {
+		return errors.New("the profile is empty")
+	}
+	if name[0] == '.' {
+		return fmt.Errorf("the profile must not start with '.': %s", name)
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '.' && r != '-' && r != '_' {
+			return fmt.Errorf("the profile must consist of letters, digits, '.', '-', and '_': %q", name)
+		}
+	}
+	return nil
+}
+
+// Dir returns the directory the files of the profile GHTKN_PROFILE selects are kept in,
+// relative to a directory of ghtkn such as $XDG_CONFIG_HOME/ghtkn: "" for the default
+// profile, and profiles/<name> otherwise.
+func Dir(getEnv func(string) string) (string, error) {
+	name, err := Name(getEnv)
+	if err != nil || name == "" {
+		return "", err
+	}
+	return filepath.Join("profiles", name), nil
+}
+
+// OverrideDir returns the directory dir an environment variable such as
+// GHTKN_TEXT_BACKEND_DIR sets, in the profile GHTKN_PROFILE selects: dir itself for the
+// default profile, and dir/profiles/<name> otherwise.
+func OverrideDir(getEnv func(string) string, dir string) (string, error) {
+	profileDir, err := Dir(getEnv)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, profileDir), nil
+}
+
+// OverrideFile returns the file path an environment variable such as GHTKN_CONFIG
+// sets, in the profile GHTKN_PROFILE selects: path itself for the default profile, and
+// profiles/<name>/<file name> in the directory of path otherwise.
+func OverrideFile(getEnv func(string) string, path string) (string, error) {
+	profileDir, err := Dir(getEnv)
+	if err != nil || profileDir == "" {
+		return path, err
+	}
+	return filepath.Join(filepath.Dir(path), profileDir, filepath.Base(path)), nil
+}
+
+// Getenv returns getEnv with GHTKN_PROFILE set to name, so that the paths resolved
+// with it are those of the profile name. It returns getEnv itself when name is empty.
+func Getenv(getEnv func(string) string, name string) func(string) string {
+	if name == "" {
+		return getEnv
+	}
+	return func(k string) string {
+		if k == env.Profile {
+			return name
+		}
+		return getEnv(k)
+	}
+}
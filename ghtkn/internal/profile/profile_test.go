@@ -0,0 +1,58 @@
+package profile_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/profile"
+)
+
+func TestDir(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		profile string
+		want    string
+		wantErr bool
+	}{
+		{name: "default profile"},
+		{name: "profile", profile: "work", want: "profiles/work"},
+		{name: "escaping the directory", profile: "../work", wantErr: true},
+		{name: "hidden", profile: ".work", wantErr: true},
+		{name: "path separator", profile: `a\b`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := profile.Dir(func(k string) string {
+				if k == "GHTKN_PROFILE" {
+					return tt.profile
+				}
+				return ""
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Dir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if filepath.ToSlash(got) != tt.want {
+				t.Errorf("Dir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetenv(t *testing.T) {
+	t.Parallel()
+	getEnv := func(k string) string {
+		return map[string]string{"GHTKN_PROFILE": "personal", "HOME": "/home/me"}[k]
+	}
+	if got := profile.Getenv(getEnv, "")("GHTKN_PROFILE"); got != "personal" {
+		t.Errorf("GHTKN_PROFILE = %q, want the environment's", got)
+	}
+	work := profile.Getenv(getEnv, "work")
+	if got := work("GHTKN_PROFILE"); got != "work" {
+		t.Errorf("GHTKN_PROFILE = %q, want work", got)
+	}
+	if got := work("HOME"); got != "/home/me" {
+		t.Errorf("HOME = %q, want /home/me", got)
+	}
+}
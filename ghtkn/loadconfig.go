@@ -17,45 +17,91 @@ type InputLoadConfig struct {
 	// A non-empty value wins over GHTKN_CONFIG and the default path, which lets a
 	// caller honor its own -c/--config flag.
 	ConfigFilePath string
+	// Dir is the directory project config files (.ghtkn.yaml) are looked for from when
+	// they are enabled. Empty means the working directory.
+	Dir string
 }
 
-// LoadConfig finds the ghtkn configuration file (input.ConfigFilePath, then
-// GHTKN_CONFIG, then the XDG/OS default path), reads it with the SDK's own YAML
-// decoder, and applies every environment override that maps onto a config field, so
-// the returned Config reflects the file plus environment. A missing config file yields
-// an empty Config with the environment overrides still applied. input may be nil,
-// which is the same as an empty ConfigFilePath.
+// LoadConfig finds the ghtkn configuration files, reads them with the SDK's own YAML
+// decoder, merges them, and applies every environment override that maps onto a config
+// field, so the returned Config reflects the files plus environment. The files are, in
+// increasing precedence:
+//
+//  1. the system config file (GHTKN_SYSTEM_CONFIG, /etc/ghtkn/ghtkn.yaml, or
+//     %ProgramData%\ghtkn\ghtkn.yaml on Windows), for apps managed by an organization,
+//  2. the user config file (input.ConfigFilePath, then GHTKN_CONFIG, then the XDG/OS
+//     default path),
+//  3. when project_config.enable or GHTKN_PROJECT_CONFIG enables them, the .ghtkn.yaml
+//     files in input.Dir and its parents that the user has trusted with
+//     TrustProjectConfig, the nearest one last. Untrusted ones are ignored.
+//
+// A string value of the system and user config files may refer to the environment, so
+// that one file serves machines whose values differ: ${VAR} and ${VAR:-default} are
+// replaced with the environment variable, a value env:VAR is the environment variable,
+// and a value file:PATH is the content of the file, relative to the config file's
+// directory. A reference that can't be resolved is an error naming its position.
+//
+// An app replaces the app with the same name from a file of lower precedence, and its
+// Source is the file it was read from. Missing config files yield an empty Config with
+// the environment overrides still applied. input may be nil, which is the same as an
+// empty InputLoadConfig.
 //
 // Per-call flag overrides (e.g. -min-expiration, -clipboard) are NOT reflected here;
 // those are applied at Get time on top of this value.
 func LoadConfig(input *InputLoadConfig) (*config.Config, error) {
-	path := ""
-	if input != nil {
-		path = input.ConfigFilePath
+	if input == nil {
+		input = &InputLoadConfig{}
 	}
-	return loadConfig(os.Getenv, runtime.GOOS, path)
+	dir := input.Dir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("get the working directory: %w", err)
+		}
+		dir = wd
+	}
+	return loadConfig(os.Getenv, runtime.GOOS, input.ConfigFilePath, dir)
 }
 
 // loadConfig is the implementation of LoadConfig with getEnv and goos injected so the
 // path and environment branches can be tested without touching the real environment.
 // path is the explicitly requested config file path; it is auto-detected when empty.
-func loadConfig(getEnv func(string) string, goos, path string) (*config.Config, error) {
+// dir is the directory project config files are looked for from.
+func loadConfig(getEnv func(string) string, goos, path, dir string) (*config.Config, error) {
+	cfg, _, err := loadConfigWithOrigins(getEnv, goos, path, dir)
+	return cfg, err
+}
+
+// loadConfigWithOrigins is loadConfig that also returns what intconfig.Load read, with
+// the environment overrides recorded in its Origins.
+func loadConfigWithOrigins(getEnv func(string) string, goos, path, dir string) (*config.Config, *intconfig.LoadResult, error) {
 	cfg := &config.Config{}
 	if path == "" {
 		p, err := intconfig.GetPath(getEnv, goos)
 		if err != nil {
-			return nil, err //nolint:wrapcheck // GetPath returns a descriptive error
+			return nil, nil, err //nolint:wrapcheck // GetPath returns a descriptive error
 		}
 		path = p
 	}
 	// A missing config file is not an error: the returned Config is empty and the
 	// environment overrides below still apply. Reader.Read wraps os.Open, so a missing
 	// file surfaces as fs.ErrNotExist; reading directly avoids a Stat/Read race.
-	if err := intconfig.NewReader().Read(cfg, path); err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return nil, fmt.Errorf("read the config file: %w", err)
+	result, err := intconfig.Load(cfg, &intconfig.InputLoad{
+		Reader:   intconfig.NewReader(getEnv),
+		Getenv:   getEnv,
+		GOOS:     goos,
+		UserPath: path,
+		Dir:      dir,
+	})
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("read the config file: %w", err)
+		}
+		result = &intconfig.LoadResult{Origins: map[string]config.Origin{}}
 	}
 	if err := intconfig.ApplyEnvOverrides(cfg, getEnv); err != nil {
-		return nil, fmt.Errorf("apply environment overrides: %w", err)
+		return nil, nil, fmt.Errorf("apply environment overrides: %w", err)
 	}
-	return cfg, nil
+	intconfig.RecordEnvOrigins(result.Origins, cfg, getEnv)
+	return cfg, result, nil
 }
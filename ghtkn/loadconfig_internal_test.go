@@ -6,7 +6,9 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	intconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
 )
 
 func Test_loadConfig(t *testing.T) {
@@ -152,9 +154,11 @@ min_expiration: 5m
 			default:
 				env["GHTKN_CONFIG"] = filepath.Join(t.TempDir(), "absent.yaml")
 			}
+			// Keep the host's system config file out of the test.
+			env["GHTKN_SYSTEM_CONFIG"] = filepath.Join(t.TempDir(), "absent.yaml")
 			getEnv := func(k string) string { return env[k] }
 
-			cfg, err := loadConfig(getEnv, "linux", path)
+			cfg, err := loadConfig(getEnv, "linux", path, t.TempDir())
 			if tt.wantErr {
 				if err == nil {
 					t.Fatal("loadConfig() expected an error, got nil")
@@ -219,3 +223,135 @@ func fmtBoolPtr(b *bool) string {
 	}
 	return "false"
 }
+
+// Test_loadConfig_layers verifies that the system, user, and trusted project config
+// files are merged in that order, that each app reports the file it came from, and that
+// an untrusted or changed project config file is ignored.
+func Test_loadConfig_layers(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	const systemYAML = `apps:
+  - name: org
+    client_id: Iv1.org
+    git_owner: acme
+  - name: shared
+    client_id: Iv1.shared-system
+backend:
+  type: keyring
+min_expiration: 10m
+`
+	const userYAML = `apps:
+  - name: personal
+    client_id: Iv1.personal
+  - name: shared
+    client_id: Iv1.shared-user
+project_config:
+  enable: true
+min_expiration: 20m
+`
+	const projectYAML = `apps:
+  - name: project
+    client_id: Iv1.project
+    default: true
+min_expiration: 30m
+project_config:
+  enable: false
+`
+	const nestedYAML = `apps:
+  - name: nested
+    client_id: Iv1.nested
+`
+
+	tests := []struct {
+		name       string
+		trust      []string // project directories to trust, relative to the project root
+		change     bool     // change the outer project config file after trusting it
+		env        map[string]string
+		wantApps   map[string]string // app name -> the base name of its source's directory
+		wantMinExp string
+	}{
+		{
+			name:       "untrusted project config files are ignored",
+			wantApps:   map[string]string{"org": "system", "shared": "user", "personal": "user"},
+			wantMinExp: "20m",
+		},
+		{
+			name:       "trusted project config files are merged, the nearest last",
+			trust:      []string{".", "sub"},
+			wantApps:   map[string]string{"org": "system", "shared": "user", "personal": "user", "project": "project", "nested": "sub"},
+			wantMinExp: "30m",
+		},
+		{
+			name:       "a project config file changed after it was trusted is ignored",
+			trust:      []string{".", "sub"},
+			change:     true,
+			wantApps:   map[string]string{"org": "system", "shared": "user", "personal": "user", "nested": "sub"},
+			wantMinExp: "20m",
+		},
+		{
+			name:       "GHTKN_PROJECT_CONFIG disables project config files",
+			trust:      []string{".", "sub"},
+			env:        map[string]string{"GHTKN_PROJECT_CONFIG": "false"},
+			wantApps:   map[string]string{"org": "system", "shared": "user", "personal": "user"},
+			wantMinExp: "20m",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			write := func(p, content string) string {
+				t.Helper()
+				p = filepath.Join(root, filepath.FromSlash(p))
+				if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				return p
+			}
+			env := map[string]string{
+				"GHTKN_SYSTEM_CONFIG": write("system/ghtkn.yaml", systemYAML),
+				"GHTKN_CONFIG":        write("user/ghtkn.yaml", userYAML),
+				"XDG_DATA_HOME":       filepath.Join(root, "data"),
+			}
+			maps.Copy(env, tt.env)
+			getEnv := func(k string) string { return env[k] }
+			write("project/.ghtkn.yaml", projectYAML)
+			write("project/sub/.ghtkn.yaml", nestedYAML)
+
+			store, err := intconfig.NewTrustStore(getEnv, "linux")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, dir := range tt.trust {
+				if err := store.Trust(filepath.Join(root, "project", dir)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.change {
+				write("project/.ghtkn.yaml", projectYAML+"skip_account_picker: false\n")
+			}
+
+			cfg, err := loadConfig(getEnv, "linux", "", filepath.Join(root, "project", "sub"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotApps := map[string]string{}
+			for _, app := range cfg.Apps {
+				gotApps[app.Name] = filepath.Base(filepath.Dir(app.Source))
+			}
+			if diff := cmp.Diff(tt.wantApps, gotApps); diff != "" {
+				t.Errorf("apps mismatch (-want +got):\n%s", diff)
+			}
+			if cfg.MinExpiration != tt.wantMinExp {
+				t.Errorf("min_expiration = %q, want %q", cfg.MinExpiration, tt.wantMinExp)
+			}
+			if got := backendType(cfg); got != "keyring" {
+				t.Errorf("backend.type = %q, want keyring", got)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package log
 
 import (
 	"io"
+	"io/fs"
 	"log/slog"
 	"time"
 )
@@ -24,4 +25,23 @@ type Logger struct {
 	// AgentWarning logs a security-relevant warning returned by the ghtkn agent
 	// (e.g. a still-valid refresh token that failed to refresh, a possible leak).
 	AgentWarning func(logger *slog.Logger, stderr io.Writer, message string)
+	// FailedToRefreshAccessToken logs when an expiring access token can't be renewed with
+	// the stored refresh token on a client-side backend (keyring, text file).
+	FailedToRefreshAccessToken func(logger *slog.Logger, err error)
+	// IgnoredUntrustedProjectConfig logs when a project config file (.ghtkn.yaml) is
+	// ignored because the user hasn't trusted it, or it has changed since.
+	IgnoredUntrustedProjectConfig func(logger *slog.Logger, path string)
+	// SelectedBackend logs which backend was selected from the backends backend.type
+	// falls back through (auto or a list).
+	SelectedBackend func(logger *slog.Logger, backend string)
+	// FellBackToPlaintextBackend logs when the backend selected from the backends
+	// backend.type falls back through stores tokens in plaintext, because the ones
+	// before it are unavailable.
+	FellBackToPlaintextBackend func(logger *slog.Logger, backend string)
+	// InsecureTokenFile logs when a token file of the text or file backend can be read
+	// by users other than its owner, so the token may have leaked.
+	InsecureTokenFile func(logger *slog.Logger, path string, mode fs.FileMode)
+	// InsecureTokenDir logs when the directory the text or file backend stores token
+	// files in can be read or listed by users other than its owner.
+	InsecureTokenDir func(logger *slog.Logger, path string, mode fs.FileMode)
 }
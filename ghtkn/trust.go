@@ -0,0 +1,31 @@
+package ghtkn
+
+import (
+	"os"
+	"runtime"
+
+	intconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+// TrustProjectConfig trusts the project config file (.ghtkn.yaml) in dir, so that
+// LoadConfig and token retrieval read it when project config files are enabled. Only
+// its current content is trusted: once the file changes, it is ignored until it is
+// trusted again. Review the file before trusting it, since it can change which GitHub
+// App, and so which account, a token is created for.
+func TrustProjectConfig(dir string) error {
+	store, err := intconfig.NewTrustStore(os.Getenv, runtime.GOOS)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	return store.Trust(dir) //nolint:wrapcheck
+}
+
+// UntrustProjectConfig stops trusting the project config file in dir. It is a no-op when
+// the file isn't trusted.
+func UntrustProjectConfig(dir string) error {
+	store, err := intconfig.NewTrustStore(os.Getenv, runtime.GOOS)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	return store.Untrust(dir) //nolint:wrapcheck
+}
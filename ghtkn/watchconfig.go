@@ -0,0 +1,58 @@
+package ghtkn
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/config"
+	intconfig "github.com/suzuki-shunsuke/ghtkn-go-sdk/ghtkn/internal/config"
+)
+
+// ConfigWatcher keeps the configuration in memory and reloads it when the config files
+// change. See Client.WatchConfig.
+type ConfigWatcher struct {
+	w *intconfig.Watcher
+}
+
+// WatchConfig loads the configuration and keeps it in memory until ctx is canceled,
+// reloading it when the config files change: they are watched with inotify on Linux
+// and polled elsewhere. Meanwhile Get, Auth, Revoke, and the token sources of c use the
+// kept config rather than reading the files on every call, as long as they are given
+// the same config file path, so a daemon doesn't parse the files per token and sees an
+// app renamed or removed as soon as the file is saved. When project config files are
+// enabled, trusting or untrusting a project reloads the config too.
+//
+// A change that makes the config unreadable or invalid keeps the last good config; the
+// subscribers are told the error. The environment overrides are applied at each reload.
+// WatchConfig fails when the config can't be loaded to begin with. input may be nil.
+func (c *Client) WatchConfig(ctx context.Context, logger *slog.Logger, input *InputWatchConfig) (*ConfigWatcher, error) {
+	w, err := c.tm.WatchConfig(ctx, logger, input)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigWatcher{w: w}, nil
+}
+
+// Config returns the config in effect. It is shared, so it must not be modified.
+func (w *ConfigWatcher) Config() *config.Config {
+	return w.w.Config()
+}
+
+// Files returns the config files the config in effect was read from.
+func (w *ConfigWatcher) Files() []string {
+	return w.w.Files()
+}
+
+// Subscribe registers f to be called after each reload with the config in effect, and
+// the error when the reload failed and the last good config was kept. It returns the
+// function that unregisters f. f is called from the watching goroutine, so it should
+// return quickly.
+func (w *ConfigWatcher) Subscribe(f func(*ConfigReload)) func() {
+	return w.w.Subscribe(f)
+}
+
+// Reload reloads the config now, such as on SIGHUP, and notifies the subscribers. It
+// returns the error when the config can't be loaded, in which case it is kept.
+func (w *ConfigWatcher) Reload() error {
+	return w.w.Reload()
+}
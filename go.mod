@@ -10,9 +10,10 @@ require (
 	github.com/suzuki-shunsuke/go-revoke-github-access-token v0.0.2
 	github.com/suzuki-shunsuke/slog-error v0.2.2
 	github.com/zalando/go-keyring v0.2.8
+	golang.org/x/crypto v0.57.0
 	golang.org/x/oauth2 v0.36.0
-	golang.org/x/sys v0.47.0
-	golang.org/x/term v0.45.0
+	golang.org/x/sys v0.48.0
+	golang.org/x/term v0.46.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 